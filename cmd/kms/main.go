@@ -0,0 +1,60 @@
+// Command kms runs a local stand-in for a key management service, for the
+// kms provider of MASTER_KEYS. Its keys are named in KMS_KEYS, ie:
+// prod:<key>,staging:<key>, each 32 bytes encoded as hex or base64.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/kms"
+)
+
+func main() {
+	port := os.Getenv("KMS_PORT")
+	if port == "" {
+		port = "9100"
+	}
+
+	keys, err := parseKeys(os.Getenv("KMS_KEYS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	server, err := kms.NewServer(keys, os.Getenv("KMS_TOKEN"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	router := http.NewServeMux()
+	server.RegisterRoutes(router)
+
+	log.Printf("KMS started on :%s with %d keys", port, len(keys))
+	if err := http.ListenAndServe(":"+port, router); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseKeys parses keys as <name>:<key> separated by commas
+func parseKeys(s string) (map[string][]byte, error) {
+	if s == "" {
+		return nil, errors.New("KMS_KEYS is required")
+	}
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(s, ",") {
+		name, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" {
+			return nil, errors.New("KMS_KEYS must be <name>:<key> separated by commas")
+		}
+		key, err := encryption.ParseKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", name, err)
+		}
+		keys[name] = key
+	}
+	return keys, nil
+}
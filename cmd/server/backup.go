@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/rodrwan/secretly/internal/backup"
+	"github.com/rodrwan/secretly/internal/config"
+	"github.com/rodrwan/secretly/internal/encryption"
+)
+
+// backupCommand writes a backup of the database, the server can keep running
+func backupCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := flags.String("o", "", "file to write the backup to, - for stdout (default: a new file in BACKUP_DIR)")
+	flags.Parse(args)
+
+	cipher, err := backupCipher(cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openBackupDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var w io.Writer
+	switch *output {
+	case "":
+		file, err := backup.WriteFile(ctx, db, cipher, cfg.BackupDir)
+		if err != nil {
+			return err
+		}
+		log.Printf("backup: wrote %s", file)
+		return nil
+	case "-":
+		w = os.Stdout
+	default:
+		file, err := os.OpenFile(*output, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	header, err := backup.Create(ctx, db, w, cipher)
+	if err != nil {
+		return err
+	}
+	log.Printf("backup: wrote schema version %d, %d bytes", header.SchemaVersion, header.Size)
+	return nil
+}
+
+// restoreCommand replaces the database with a backup, the server must be
+// stopped
+func restoreCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	force := flags.Bool("force", false, "replace an existing database")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: restore [-force] <file>")
+	}
+
+	if cfg.DBDriver != config.DriverSQLite {
+		return errors.New("restore only supports the sqlite driver, use pg_restore with postgres")
+	}
+	cipher, err := backupCipher(cfg)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(cfg.DBPath); err == nil && !*force {
+		return fmt.Errorf("%s already exists, stop the server and pass -force to replace it", cfg.DBPath)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header, err := backup.Restore(ctx, file, cipher, cfg.DBPath)
+	if err != nil {
+		return err
+	}
+	log.Printf("restore: restored the backup of %s to %s", header.CreatedAt.Format("2006-01-02 15:04:05"), cfg.DBPath)
+	return nil
+}
+
+// openBackupDatabase opens the SQLite database to back up. It isn't migrated,
+// the backup has the schema version it was left at.
+func openBackupDatabase(cfg *config.Config) (*sql.DB, error) {
+	if cfg.DBDriver != config.DriverSQLite {
+		return nil, errors.New("backup only supports the sqlite driver, use pg_dump with postgres")
+	}
+	if _, err := os.Stat(cfg.DBPath); err != nil {
+		return nil, err
+	}
+	return sql.Open("sqlite", cfg.DBPath)
+}
+
+// backupCipher is the cipher of BACKUP_KEY
+func backupCipher(cfg *config.Config) (*encryption.Cipher, error) {
+	if cfg.BackupKey == "" {
+		return nil, errors.New("BACKUP_KEY is required to back up")
+	}
+	key, err := encryption.ParseKey(cfg.BackupKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BACKUP_KEY: %w", err)
+	}
+	return encryption.NewCipher(key)
+}
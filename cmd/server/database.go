@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/pressly/goose/v3"
+	"github.com/rodrwan/secretly/internal/config"
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/database/postgres"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// openDatabase connects to the database of cfg.DBDriver and runs its migrations
+func openDatabase(cfg *config.Config) (*database.DB, error) {
+	var (
+		db         *database.DB
+		dialect    string
+		migrations fs.FS
+	)
+
+	switch cfg.DBDriver {
+	case config.DriverSQLite:
+		sqlDB, err := sql.Open("sqlite", cfg.DBPath)
+		if err != nil {
+			return nil, err
+		}
+		db, dialect, migrations = database.NewSQLiteDB(sqlDB), "sqlite3", database.Migrations
+	case config.DriverPostgres:
+		if cfg.DatabaseURL == "" {
+			return nil, errors.New("DATABASE_URL is required with the postgres driver")
+		}
+		sqlDB, err := sql.Open("pgx", cfg.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		db, dialect, migrations = postgres.NewDB(sqlDB), "postgres", postgres.Migrations
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q, must be %s or %s", cfg.DBDriver, config.DriverSQLite, config.DriverPostgres)
+	}
+
+	// Run migrations
+	goose.SetBaseFS(migrations)
+	if err := goose.SetDialect(dialect); err != nil {
+		return nil, err
+	}
+	goose.SetLogger(log.New(os.Stdout, "goose: ", log.LstdFlags))
+
+	if err := goose.Up(db.DB, "migrations"); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
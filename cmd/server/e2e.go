@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rodrwan/secretly/internal/e2e"
+)
+
+// keygenCommand creates an identity of an end-to-end encrypted environment,
+// the identity is written to a file only its owner can read and the
+// recipient is printed to share it with the team
+func keygenCommand(args []string) error {
+	flags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	output := flags.String("o", "", "file to write the identity to (default: stdout)")
+	flags.Parse(args)
+
+	identity, err := e2e.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		fmt.Printf("# Recipient: %s\n%s\n", identity.Recipient(), identity)
+		return nil
+	}
+	if err := os.WriteFile(*output, []byte(identity.String()+"\n"), 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("Recipient: %s\n", identity.Recipient())
+	return nil
+}
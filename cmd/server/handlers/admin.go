@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/keyring"
+)
+
+func registerAdminRoutes(router *http.ServeMux, handler *Handler) {
+	// Re-wrap every value with the active master key in the background
+	router.HandleFunc("POST /api/v1/admin/rekey", handler.Call(handler.startRekey))
+	// Get the progress of the running or last rekey
+	router.HandleFunc("GET /api/v1/admin/rekey", handler.Call(handler.getRekey))
+}
+
+// errUnauthenticatedAdmin is returned when an admin endpoint is called by an
+// actor anyone could claim
+var errUnauthenticatedAdmin = errors.New("admin endpoints require authenticated actors, see TLS_CLIENT_CA_FILE and TRUST_ACTOR_HEADER")
+
+// authorizeAdmin verifies the actor of a request is authenticated and
+// administers the server
+func (eh *Handler) authorizeAdmin(r *http.Request) (Response, error) {
+	if !authenticated(r) {
+		return Response{
+			Code:    http.StatusForbidden,
+			Message: "Forbidden",
+			Error:   errUnauthenticatedAdmin.Error(),
+		}, errUnauthenticatedAdmin
+	}
+	if !slices.Contains(eh.admins, actor(r)) {
+		err := errors.New("only server admins can call this endpoint")
+		return Response{
+			Code:    http.StatusForbidden,
+			Message: "Forbidden",
+			Error:   err.Error(),
+		}, err
+	}
+	return Response{}, nil
+}
+
+// rekeyer returns the rekeyer of the handler, or a response explaining
+// values aren't encrypted
+func (eh *Handler) rekeyer() (*keyring.Rekeyer, Response, error) {
+	if eh.rekey == nil {
+		err := errors.New("values are stored in plaintext, there are no master keys")
+		return nil, Response{
+			Code:    http.StatusConflict,
+			Message: "Encryption is disabled",
+			Error:   err.Error(),
+		}, err
+	}
+	return eh.rekey, Response{}, nil
+}
+
+func (eh *Handler) startRekey(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	if resp, err := eh.authorizeAdmin(r); err != nil {
+		return resp, err
+	}
+	rekeyer, resp, err := eh.rekeyer()
+	if err != nil {
+		return resp, err
+	}
+
+	// The rekey outlives the request
+	if err := rekeyer.Start(eh.rekeyCtx); err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, keyring.ErrRekeyRunning) {
+			code = http.StatusConflict
+		}
+		return Response{
+			Code:    code,
+			Message: "Failed to start the rekey",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Data:    rekeyer.Progress(),
+		Code:    http.StatusAccepted,
+		Message: "Rekey started",
+	}, nil
+}
+
+func (eh *Handler) getRekey(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	if resp, err := eh.authorizeAdmin(r); err != nil {
+		return resp, err
+	}
+	rekeyer, resp, err := eh.rekeyer()
+	if err != nil {
+		return resp, err
+	}
+
+	return Response{
+		Data:    rekeyer.Progress(),
+		Code:    http.StatusOK,
+		Message: "Rekey progress retrieved",
+	}, nil
+}
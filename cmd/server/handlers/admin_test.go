@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rodrwan/secretly/internal/database/dbtest"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+func TestAuthorizeAdmin(t *testing.T) {
+	tests := []struct {
+		name          string
+		authenticated bool
+		actor         string
+		want          int
+	}{
+		// Without encryption an admin gets past the check to a conflict
+		{"admin", true, "ops", http.StatusConflict},
+		{"not an admin", true, "alice", http.StatusForbidden},
+		{"anonymous", true, "", http.StatusForbidden},
+		{"claimed admin", false, "ops", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.SQLite(t)
+			c := newClient(t, db, store.NewSQL(db),
+				WithAdmins([]string{"ops"}),
+				WithAuthenticatedActors(tt.authenticated),
+			)
+			for _, method := range []string{http.MethodPost, http.MethodGet} {
+				if resp := c.do(t, method, "/api/v1/admin/rekey", tt.actor, ""); resp.Code != tt.want {
+					t.Errorf("%s /api/v1/admin/rekey = %d %s, want %d", method, resp.Code, resp.Error, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/store"
+	"github.com/rodrwan/secretly/internal/webhook"
+)
+
+func registerCloneRoutes(router *http.ServeMux, handler *Handler) {
+	// Create a new environment with the values of environment {id}
+	router.HandleFunc("POST /api/v1/env/{id}/clone", handler.Call(handler.cloneEnvironment))
+}
+
+type CloneRequest struct {
+	Name string `json:"name"`
+	// Prefix only copies the keys starting with it, ie: APP_
+	Prefix string `json:"prefix"`
+	// Overrides replace the value of a key, or add it when it isn't copied
+	Overrides map[string]string `json:"overrides"`
+}
+
+func (eh *Handler) cloneEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to clone environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	var request CloneRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to clone environment",
+			Error:   err.Error(),
+		}, err
+	}
+	source, err := eh.store.GetEnvironment(r.Context(), envID)
+	if errors.Is(err, store.ErrNotFound) {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to clone environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	// Overrides of an end-to-end encrypted environment are encrypted by the client
+	overrides := make([]Value, 0, len(request.Overrides))
+	for key, v := range request.Overrides {
+		overrides = append(overrides, Value{Key: key, Value: v})
+	}
+	if resp, err := checkE2EValues(source.E2E, overrides); err != nil {
+		return resp, err
+	}
+
+	// The clone belongs to the project of the source environment
+	if resp, err := checkEnvironmentName(r.Context(), eh.store, source.ProjectID, request.Name); err != nil {
+		return resp, err
+	}
+
+	var (
+		newEnv store.Environment
+		keys   []string
+	)
+	err = eh.storeTx(r.Context(), func(s store.Store, db database.Querier) error {
+		valuesFromStore, err := s.GetValues(r.Context(), source.ID)
+		if err != nil {
+			return err
+		}
+		// Environments outside the database have no schemas
+		var schemas []database.ValueSchema
+		if eh.sqlStore() {
+			schemas, err = db.GetValueSchemasByEnvironmentID(r.Context(), source.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		newEnv, err = s.CreateEnvironment(r.Context(), source.ProjectID, request.Name)
+		if err != nil {
+			return err
+		}
+		// The copied ciphertext stays readable by the same clients only
+		if source.E2E {
+			newEnv, err = s.MarkEnvironmentE2E(r.Context(), newEnv.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Schemas are copied first so the copied values are validated
+		for _, schema := range schemas {
+			if !strings.HasPrefix(schema.Key, request.Prefix) {
+				continue
+			}
+			if _, err := db.UpsertValueSchema(r.Context(), database.UpsertValueSchemaParams{
+				EnvironmentID: newEnv.ID,
+				Key:           schema.Key,
+				Type:          schema.Type,
+				Pattern:       schema.Pattern,
+				Enum:          schema.Enum,
+				Min:           schema.Min,
+				Max:           schema.Max,
+				Required:      schema.Required,
+			}); err != nil {
+				return err
+			}
+		}
+
+		copied := make(map[string]bool)
+		for _, value := range fromStoreValues(valuesFromStore, false) {
+			if !strings.HasPrefix(value.Key, request.Prefix) {
+				continue
+			}
+			v, overridden := request.Overrides[value.Key]
+			if !overridden {
+				v = value.Value
+			}
+			stored, _, err := s.SetValue(r.Context(), newEnv.ID, value.Key, v)
+			if err != nil {
+				return err
+			}
+			// Metadata describes the key, so overridden values keep it too
+			if value.Description != "" || value.Owner != "" || value.Link != "" || len(value.Tags) > 0 {
+				if _, err := s.SetValueMetadata(r.Context(), newEnv.ID, value.Key, value.Metadata.toStore()); err != nil {
+					return err
+				}
+			}
+			// Overridden values are new, they don't inherit the expiration
+			if value.ExpiresAt != nil && !overridden {
+				if err := setExpiry(r.Context(), s, stored, value); err != nil {
+					return err
+				}
+			}
+			copied[value.Key] = true
+			keys = append(keys, value.Key)
+		}
+
+		for key, v := range request.Overrides {
+			if copied[key] {
+				continue
+			}
+			if _, _, err := s.SetValue(r.Context(), newEnv.ID, key, v); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if errors.Is(err, store.ErrExists) {
+		// A concurrent request took the name after it was checked
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Invalid environment name",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return valueErrorResponse("Failed to clone environment", err), err
+	}
+
+	eh.publish(r, webhook.NewEvent(webhook.EventEnvironmentCreated, newEnv.ID, newEnv.Name, keys...))
+
+	return Response{
+		Code:    http.StatusCreated,
+		Message: "Environment cloned",
+		Data:    fromStoreEnvironment(newEnv, nil),
+	}, nil
+}
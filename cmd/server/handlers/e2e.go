@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rodrwan/secretly/internal/e2e"
+)
+
+// errE2EServerValue is returned when the server would have to read or
+// produce the plaintext of a value of an end-to-end encrypted environment
+var errE2EServerValue = errors.New("the values of an end-to-end encrypted environment are only readable by its clients")
+
+// checkE2EValues refuses values the server could read in an end-to-end
+// encrypted environment, the clients must encrypt them
+func checkE2EValues(encrypted bool, values []Value) (Response, error) {
+	if !encrypted {
+		return Response{}, nil
+	}
+	for _, value := range values {
+		var err error
+		switch {
+		case value.Generate != nil:
+			err = fmt.Errorf("%s can't be generated: %w", value.Key, errE2EServerValue)
+		case !e2e.IsCiphertext(value.Value):
+			err = fmt.Errorf("%s must be encrypted by the client, with the %s prefix", value.Key, e2e.Prefix)
+		}
+		if err != nil {
+			return Response{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid value for " + value.Key,
+				Error:   err.Error(),
+			}, err
+		}
+	}
+	return Response{}, nil
+}
@@ -2,43 +2,117 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/generator"
+	"github.com/rodrwan/secretly/internal/store"
+	"github.com/rodrwan/secretly/internal/webhook"
 )
 
-func RegisterRoutes(router *http.ServeMux, db database.Querier) {
-	handler := NewHandler(db)
+func RegisterRoutes(router *http.ServeMux, db database.Querier, opts ...Option) {
+	handler := NewHandler(db, opts...)
 	// Get all available environments
-	router.HandleFunc("GET /api/v1/env", handler.Call(getEnvironments))
+	router.HandleFunc("GET /api/v1/env", handler.Call(handler.getEnvironments))
 	// Create a new environment
-	router.HandleFunc("POST /api/v1/env", handler.Call(createEnvironment))
+	router.HandleFunc("POST /api/v1/env", handler.Call(handler.createEnvironment))
 	// Get a specific environment
-	router.HandleFunc("GET /api/v1/env/{id}", handler.Call(getEnvironment))
+	router.HandleFunc("GET /api/v1/env/{id}", handler.Call(handler.getEnvironment))
 	// Update a specific environment
-	router.HandleFunc("PUT /api/v1/env/{id}", handler.Call(updateEnvironment))
+	router.HandleFunc("PUT /api/v1/env/{id}", handler.Call(handler.updateEnvironment))
 	// Delete a specific environment
-	router.HandleFunc("DELETE /api/v1/env/{id}", handler.Call(deleteEnvironment))
-	// Delete a specific value
-	router.HandleFunc("DELETE /api/v1/env/{id}/value/{key}", handler.Call(deleteValue))
+	router.HandleFunc("DELETE /api/v1/env/{id}", handler.Call(handler.deleteEnvironment))
+
+	registerValueRoutes(router, handler)
+	registerWebhookRoutes(router, handler)
+	registerRotationRoutes(router, handler)
+	registerExpiryRoutes(router, handler)
+	registerSchemaRoutes(router, handler)
+	registerPromotionRoutes(router, handler)
+	registerCloneRoutes(router, handler)
+	registerEphemeralRoutes(router, handler)
+	registerProjectRoutes(router, handler)
+	registerTrashRoutes(router, handler)
+	registerMetadataRoutes(router, handler)
+	registerSearchRoutes(router, handler)
+	registerAdminRoutes(router, handler)
+	registerSealRoutes(router, handler)
 }
 
 type Environment struct {
-	ID     int64   `json:"id"`
-	Name   string  `json:"name"`
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// E2E is set when the values are encrypted by the clients, the server
+	// only has their ciphertext
+	E2E bool `json:"e2e,omitempty"`
+	Metadata
 	Values []Value `json:"values"`
 }
 
+// fromStoreEnvironment is toEnvironment for environments of the store
+func fromStoreEnvironment(env store.Environment, values []Value) Environment {
+	if values == nil {
+		values = make([]Value, 0)
+	}
+	return Environment{
+		ID:        env.ID,
+		Name:      env.Name,
+		ExpiresAt: env.ExpiresAt,
+		E2E:       env.E2E,
+		Metadata:  fromStoreMetadata(env.Metadata),
+		Values:    values,
+	}
+}
+
+func toEnvironment(env database.Environment, values []Value) Environment {
+	if values == nil {
+		values = make([]Value, 0)
+	}
+	environment := Environment{
+		ID:       env.ID,
+		Name:     env.Name,
+		E2E:      env.E2e,
+		Metadata: toMetadata(env.Description, env.Owner, env.Link, env.Tags),
+		Values:   values,
+	}
+	if env.ExpiresAt.Valid {
+		environment.ExpiresAt = &env.ExpiresAt.Time
+	}
+	return environment
+}
+
 type Value struct {
-	ID    int64  `json:"id"`
-	Key   string `json:"key"`
-	Value string `json:"value"`
+	ID        int64      `json:"id"`
+	Key       string     `json:"key"`
+	Value     string     `json:"value"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// TTL sets expires_at relative to the time of the write, ie: 72h
+	TTL string `json:"ttl,omitempty"`
+	// Expired is only set when expired values are requested with ?include_expired=true
+	Expired bool `json:"expired,omitempty"`
+	// Generate produces the value server-side instead of sending it, ie:
+	// {"type": "password", "length": 32, "charset": "alnum+symbols"}
+	Generate *generator.Spec `json:"generate,omitempty"`
+	// Type is the type of the key's schema, if it has one. It is ignored on writes.
+	Type string `json:"type,omitempty"`
+	// Metadata is ignored on writes, it is set with PUT /api/v1/env/{id}/value/{key}/metadata
+	Metadata
 }
 
 type Request struct {
 	Name   string  `json:"name"`
 	Values []Value `json:"values"`
+	// TTL makes the environment ephemeral, it is deleted once it expires, ie: 168h
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// E2E creates an end-to-end encrypted environment, its values must be
+	// encrypted by the clients
+	E2E bool `json:"e2e,omitempty"`
 }
 
 type UpdateEnvironmentRequest struct {
@@ -46,35 +120,37 @@ type UpdateEnvironmentRequest struct {
 	Values        []Value `json:"values"`
 }
 
-func getEnvironments(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+func (eh *Handler) getEnvironments(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	// Environments of the {project} path value or ?project=, the default project otherwise
+	project, resp, err := requestProject(r.Context(), db, r)
+	if err != nil {
+		return resp, err
+	}
+	if resp, err := authorize(r.Context(), db, r, project.ID, RoleRead); err != nil {
+		return resp, err
+	}
+
+	// Keep the environments tagged with ?tag=
+	tag := r.URL.Query().Get("tag")
+
 	// Get data from query params, ie: ?name=development
-	var envsFromDB []database.Environment
 	name := r.URL.Query().Get("name")
-	if name != "" {
-		env, err := db.GetEnvironmentByName(r.Context(), name)
-		if err != nil {
-			return Response{
-				Code:    http.StatusInternalServerError,
-				Message: "Failed to get environment",
-				Error:   err.Error(),
-			}, err
-		}
-		envsFromDB = []database.Environment{env}
-	} else {
-		envs, err := db.GetAllEnvironments(r.Context())
-		if err != nil {
-			return Response{
-				Code:    http.StatusInternalServerError,
-				Message: "Failed to get environments",
-				Error:   err.Error(),
-			}, err
-		}
-		envsFromDB = envs
+	if name == "" {
+		return eh.listEnvironments(r, project.ID, tag)
+	}
+
+	env, err := eh.store.GetEnvironmentByName(r.Context(), project.ID, name)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get environment",
+			Error:   err.Error(),
+		}, err
 	}
 
 	envs := make([]Environment, 0)
-	for _, env := range envsFromDB {
-		valuesFromDB, err := db.GetValuesByEnvironmentID(r.Context(), env.ID)
+	if tag == "" || fromStoreMetadata(env.Metadata).hasTag(tag) {
+		values, err := eh.store.GetValues(r.Context(), env.ID)
 		if err != nil {
 			return Response{
 				Code:    http.StatusInternalServerError,
@@ -83,20 +159,7 @@ func getEnvironments(db database.Querier, w http.ResponseWriter, r *http.Request
 			}, err
 		}
 
-		values := make([]Value, 0)
-		for _, value := range valuesFromDB {
-			values = append(values, Value{
-				ID:    value.ID,
-				Key:   value.Key,
-				Value: value.Value,
-			})
-		}
-
-		envs = append(envs, Environment{
-			ID:     env.ID,
-			Name:   env.Name,
-			Values: values,
-		})
+		envs = append(envs, fromStoreEnvironment(env, fromStoreValues(values, includeExpired(r))))
 	}
 
 	return Response{
@@ -106,7 +169,48 @@ func getEnvironments(db database.Querier, w http.ResponseWriter, r *http.Request
 	}, nil
 }
 
-func createEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+// listEnvironments gets a page of the environments of a project, see
+// parseEnvironmentPage for its query params
+func (eh *Handler) listEnvironments(r *http.Request, projectID int64, tag string) (Response, error) {
+	page, resp, err := parseEnvironmentPage(r, projectID)
+	if err != nil {
+		return resp, err
+	}
+
+	envs, nextCursor, err := page.list(r, eh.store)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get environments",
+			Error:   err.Error(),
+		}, err
+	}
+
+	// Filtered after paging so the cursor still points after the whole page
+	if tag != "" {
+		envs = slices.DeleteFunc(envs, func(env Environment) bool {
+			return !env.hasTag(tag)
+		})
+	}
+
+	data, err := selectFields(envs, page.fields)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get environments",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:       http.StatusOK,
+		Message:    "Environments retrieved",
+		Data:       data,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+func (eh *Handler) createEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
 	var request Request
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		return Response{
@@ -116,40 +220,115 @@ func createEnvironment(db database.Querier, w http.ResponseWriter, r *http.Reque
 		}, err
 	}
 
-	newEnv, err := db.CreateEnvironment(r.Context(), request.Name)
+	if resp, err := checkE2EValues(request.E2E, request.Values); err != nil {
+		return resp, err
+	}
+	if resp, err := prepareValues(request.Values); err != nil {
+		return resp, err
+	}
+
+	expiresAt, ephemeral, err := parseExpiry(request.TTL, request.ExpiresAt)
 	if err != nil {
 		return Response{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to create environment",
+			Code:    http.StatusBadRequest,
+			Message: "Invalid environment expiration",
 			Error:   err.Error(),
 		}, err
 	}
 
-	if len(request.Values) > 0 {
+	project, resp, err := requestProject(r.Context(), db, r)
+	if err != nil {
+		return resp, err
+	}
+	if resp, err := authorize(r.Context(), db, r, project.ID, RoleWrite); err != nil {
+		return resp, err
+	}
+	if resp, err := checkEnvironmentName(r.Context(), eh.store, project.ID, request.Name); err != nil {
+		return resp, err
+	}
+
+	var (
+		newEnv store.Environment
+		keys   = make([]string, 0, len(request.Values))
+	)
+	// The environment is stored with its values or not at all
+	err = eh.store.InTx(r.Context(), func(s store.Store) error {
+		var err error
+		newEnv, err = s.CreateEnvironment(r.Context(), project.ID, request.Name)
+		if errors.Is(err, store.ErrExists) {
+			// A concurrent request took the name after it was checked
+			resp = Response{
+				Code:    http.StatusConflict,
+				Message: "Invalid environment name",
+				Error:   err.Error(),
+			}
+			return err
+		}
+		if err != nil {
+			resp = Response{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to create environment",
+				Error:   err.Error(),
+			}
+			return err
+		}
+
+		if ephemeral {
+			newEnv, err = s.SetEnvironmentExpiry(r.Context(), newEnv.ID, &expiresAt.Time)
+			if err != nil {
+				resp = Response{
+					Code:    http.StatusInternalServerError,
+					Message: "Failed to set environment expiration",
+					Error:   err.Error(),
+				}
+				return err
+			}
+		}
+
+		if request.E2E {
+			newEnv, err = s.MarkEnvironmentE2E(r.Context(), newEnv.ID)
+			if err != nil {
+				resp = Response{
+					Code:    http.StatusInternalServerError,
+					Message: "Failed to create environment",
+					Error:   err.Error(),
+				}
+				return err
+			}
+		}
+
 		for _, value := range request.Values {
-			_, err := db.CreateValue(r.Context(), database.CreateValueParams{
-				EnvironmentID: newEnv.ID,
-				Key:           value.Key,
-				Value:         value.Value,
-			})
+			stored, _, err := s.SetValue(r.Context(), newEnv.ID, value.Key, value.Value)
 			if err != nil {
-				return Response{
+				resp = valueErrorResponse("Failed to create value", err)
+				return err
+			}
+			if err := setExpiry(r.Context(), s, stored, value); err != nil {
+				resp = Response{
 					Code:    http.StatusInternalServerError,
-					Message: "Failed to create value",
+					Message: "Failed to set value expiration",
 					Error:   err.Error(),
-				}, err
+				}
+				return err
 			}
+			keys = append(keys, value.Key)
 		}
+		return nil
+	})
+	if err != nil {
+		return resp, err
 	}
 
+	eh.publish(r, webhook.NewEvent(webhook.EventEnvironmentCreated, newEnv.ID, newEnv.Name, keys...))
+
 	return Response{
 		Code:    http.StatusCreated,
 		Message: "Environment created",
-		Data:    newEnv,
+		Data:    fromStoreEnvironment(newEnv, nil),
 	}, nil
 }
 
-func getEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+func (eh *Handler) getEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
 	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		return Response{
@@ -159,7 +338,7 @@ func getEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request)
 		}, err
 	}
 
-	envFromDB, err := db.GetEnvironment(r.Context(), envID)
+	envFromStore, err := eh.store.GetEnvironment(r.Context(), envID)
 	if err != nil {
 		return Response{
 			Code:    http.StatusInternalServerError,
@@ -168,7 +347,7 @@ func getEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request)
 		}, err
 	}
 
-	valuesFromDB, err := db.GetValuesByEnvironmentID(r.Context(), envFromDB.ID)
+	values, err := eh.store.GetValues(r.Context(), envFromStore.ID)
 	if err != nil {
 		return Response{
 			Code:    http.StatusInternalServerError,
@@ -177,20 +356,7 @@ func getEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request)
 		}, err
 	}
 
-	values := make([]Value, 0)
-	for _, value := range valuesFromDB {
-		values = append(values, Value{
-			ID:    value.ID,
-			Key:   value.Key,
-			Value: value.Value,
-		})
-	}
-
-	env := Environment{
-		ID:     envFromDB.ID,
-		Name:   envFromDB.Name,
-		Values: values,
-	}
+	env := fromStoreEnvironment(envFromStore, withTag(r, fromStoreValues(values, includeExpired(r))))
 
 	return Response{
 		Code:    http.StatusOK,
@@ -199,7 +365,7 @@ func getEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request)
 	}, nil
 }
 
-func updateEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+func (eh *Handler) updateEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
 	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		return Response{
@@ -218,43 +384,55 @@ func updateEnvironment(db database.Querier, w http.ResponseWriter, r *http.Reque
 		}, err
 	}
 
-	for _, value := range request.Values {
-		// Verificar si el valor ya existe para esta clave en el environment
-		existingValue, err := db.GetValueByKey(r.Context(), database.GetValueByKeyParams{
-			EnvironmentID: envID,
-			Key:           value.Key,
-		})
+	env, err := eh.store.GetEnvironment(r.Context(), envID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to update environment",
+			Error:   err.Error(),
+		}, err
+	}
 
-		if err != nil {
-			// Si no existe, crear un nuevo valor
-			_, err = db.CreateValue(r.Context(), database.CreateValueParams{
-				EnvironmentID: envID,
-				Key:           value.Key,
-				Value:         value.Value,
-			})
+	if resp, err := checkE2EValues(env.E2E, request.Values); err != nil {
+		return resp, err
+	}
+	if resp, err := prepareValues(request.Values); err != nil {
+		return resp, err
+	}
+
+	if resp, err := eh.validateValues(r.Context(), db, envID, request.Values); err != nil {
+		return resp, err
+	}
+
+	var resp Response
+	keys := make([]string, 0, len(request.Values))
+	// Either all the values are stored or none of them
+	err = eh.store.InTx(r.Context(), func(s store.Store) error {
+		for _, value := range request.Values {
+			// Create the value if the key doesn't exist yet, otherwise store a new version
+			stored, _, err := s.SetValue(r.Context(), envID, value.Key, value.Value)
 			if err != nil {
-				return Response{
-					Code:    http.StatusInternalServerError,
-					Message: "Failed to create value",
-					Error:   err.Error(),
-				}, err
+				resp = valueErrorResponse("Failed to update value", err)
+				return err
 			}
-		} else {
-			// Si existe, actualizar el valor
-			_, err = db.UpdateValue(r.Context(), database.UpdateValueParams{
-				ID:    existingValue.ID,
-				Value: value.Value,
-			})
-			if err != nil {
-				return Response{
+			if err := setExpiry(r.Context(), s, stored, value); err != nil {
+				resp = Response{
 					Code:    http.StatusInternalServerError,
-					Message: "Failed to update value",
+					Message: "Failed to set value expiration",
 					Error:   err.Error(),
-				}, err
+				}
+				return err
 			}
+			keys = append(keys, value.Key)
 		}
+		return nil
+	})
+	if err != nil {
+		return resp, err
 	}
 
+	eh.publish(r, webhook.NewEvent(webhook.EventEnvironmentUpdated, env.ID, env.Name, keys...))
+
 	return Response{
 		Code:    http.StatusOK,
 		Message: "Environment updated",
@@ -262,7 +440,7 @@ func updateEnvironment(db database.Querier, w http.ResponseWriter, r *http.Reque
 	}, nil
 }
 
-func deleteEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+func (eh *Handler) deleteEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
 	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		return Response{
@@ -272,7 +450,7 @@ func deleteEnvironment(db database.Querier, w http.ResponseWriter, r *http.Reque
 		}, err
 	}
 
-	err = db.DeleteEnvironment(r.Context(), envID)
+	env, err := eh.store.GetEnvironment(r.Context(), envID)
 	if err != nil {
 		return Response{
 			Code:    http.StatusInternalServerError,
@@ -281,53 +459,33 @@ func deleteEnvironment(db database.Querier, w http.ResponseWriter, r *http.Reque
 		}, err
 	}
 
-	return Response{
-		Code:    http.StatusOK,
-		Message: "Environment deleted",
-		Data:    nil,
-	}, nil
-}
-
-func deleteValue(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
-	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	values, err := eh.store.GetValues(r.Context(), envID)
 	if err != nil {
-		return Response{
-			Code:    http.StatusBadRequest,
-			Message: "Failed to delete value",
-			Error:   err.Error(),
-		}, err
-	}
-
-	// Search for environment
-	if _, err := db.GetEnvironment(r.Context(), envID); err != nil {
 		return Response{
 			Code:    http.StatusInternalServerError,
-			Message: "Failed to delete value",
+			Message: "Failed to delete environment",
 			Error:   err.Error(),
 		}, err
 	}
 
-	keyID, err := strconv.ParseInt(r.PathValue("key"), 10, 64)
-	if err != nil {
+	// With the SQL store, deleted environments stay in the trash until they are purged
+	if err := eh.store.DeleteEnvironment(r.Context(), envID); err != nil {
 		return Response{
-			Code:    http.StatusBadRequest,
-			Message: "Failed to delete value",
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete environment",
 			Error:   err.Error(),
 		}, err
 	}
 
-	err = db.DeleteValue(r.Context(), keyID)
-	if err != nil {
-		return Response{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to delete value",
-			Error:   err.Error(),
-		}, err
+	keys := make([]string, 0, len(values))
+	for _, value := range values {
+		keys = append(keys, value.Key)
 	}
+	eh.publish(r, webhook.NewEvent(webhook.EventEnvironmentDeleted, env.ID, env.Name, keys...))
 
 	return Response{
 		Code:    http.StatusOK,
-		Message: "Value deleted",
+		Message: "Environment deleted",
 		Data:    nil,
 	}, nil
 }
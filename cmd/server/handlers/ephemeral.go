@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+func registerEphemeralRoutes(router *http.ServeMux, handler *Handler) {
+	// Push back the expiration of an ephemeral environment
+	router.HandleFunc("POST /api/v1/env/{id}/extend", handler.Call(handler.extendEnvironment))
+	// Keep an ephemeral environment forever
+	router.HandleFunc("POST /api/v1/env/{id}/pin", handler.Call(handler.pinEnvironment))
+	// Get the audit log of the environments of a project: expirations,
+	// extensions and pins, ie: ?limit=20
+	router.HandleFunc("GET /api/v1/projects/{project}/events", handler.Call(getEnvironmentEvents))
+}
+
+const defaultEnvironmentEventsLimit = 50
+
+type EnvironmentEvent struct {
+	ID              int64     `json:"id"`
+	EnvironmentID   int64     `json:"environment_id"`
+	EnvironmentName string    `json:"environment_name"`
+	Event           string    `json:"event"`
+	Actor           string    `json:"actor"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type ExtendEnvironmentRequest struct {
+	// TTL is added to the current expiration, or to now when it already passed
+	TTL string `json:"ttl,omitempty"`
+	// ExpiresAt replaces the expiration
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (eh *Handler) extendEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to extend environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	var request ExtendEnvironmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to extend environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	expiresAt, ok, err := parseExpiry(request.TTL, request.ExpiresAt)
+	if err == nil && !ok {
+		err = errors.New("one of ttl and expires_at is required")
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid environment expiration",
+			Error:   err.Error(),
+		}, err
+	}
+
+	env, err := eh.store.GetEnvironment(r.Context(), envID)
+	if errors.Is(err, store.ErrNotFound) {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to extend environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if env.ExpiresAt == nil {
+		err := errors.New("environment is not ephemeral")
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Failed to extend environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	// A ttl extends the current expiration when it is still ahead
+	if request.TTL != "" {
+		now := time.Now().UTC()
+		if env.ExpiresAt.After(now) {
+			expiresAt.Time = env.ExpiresAt.Add(expiresAt.Time.Sub(now))
+		}
+	}
+
+	err = eh.storeTx(r.Context(), func(s store.Store, db database.Querier) error {
+		env, err = s.SetEnvironmentExpiry(r.Context(), env.ID, &expiresAt.Time)
+		if err != nil {
+			return err
+		}
+		return database.RecordEnvironmentEvent(r.Context(), db, env.ProjectID, env.ID, env.Name, database.EventEnvironmentExtended, actor(r))
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to extend environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Environment extended",
+		Data:    fromStoreEnvironment(env, nil),
+	}, nil
+}
+
+func (eh *Handler) pinEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to pin environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	var env store.Environment
+	err = eh.storeTx(r.Context(), func(s store.Store, db database.Querier) error {
+		env, err = s.SetEnvironmentExpiry(r.Context(), envID, nil)
+		if err != nil {
+			return err
+		}
+		return database.RecordEnvironmentEvent(r.Context(), db, env.ProjectID, env.ID, env.Name, database.EventEnvironmentPinned, actor(r))
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to pin environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Environment pinned",
+		Data:    fromStoreEnvironment(env, nil),
+	}, nil
+}
+
+func getEnvironmentEvents(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	limit := int64(defaultEnvironmentEventsLimit)
+	if param := r.URL.Query().Get("limit"); param != "" {
+		l, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || l <= 0 {
+			err = fmt.Errorf("invalid limit %q", param)
+			return Response{
+				Code:    http.StatusBadRequest,
+				Message: "Failed to get events",
+				Error:   err.Error(),
+			}, err
+		}
+		limit = l
+	}
+
+	project, resp, err := requestProject(r.Context(), db, r)
+	if err != nil {
+		return resp, err
+	}
+
+	eventsFromDB, err := db.GetEnvironmentEventsByProjectID(r.Context(), database.GetEnvironmentEventsByProjectIDParams{
+		ProjectID: project.ID,
+		Limit:     limit,
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get events",
+			Error:   err.Error(),
+		}, err
+	}
+
+	events := make([]EnvironmentEvent, 0, len(eventsFromDB))
+	for _, e := range eventsFromDB {
+		events = append(events, EnvironmentEvent{
+			ID:              e.ID,
+			EnvironmentID:   e.EnvironmentID,
+			EnvironmentName: e.EnvironmentName,
+			Event:           e.Event,
+			Actor:           e.Actor,
+			CreatedAt:       e.CreatedAt,
+		})
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Events retrieved",
+		Data:    events,
+	}, nil
+}
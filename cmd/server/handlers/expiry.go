@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+const defaultExpiringWithin = 7 * 24 * time.Hour
+
+func registerExpiryRoutes(router *http.ServeMux, handler *Handler) {
+	// Get the values expiring soon across the environments the actor can
+	// read, ie: ?within=72h
+	router.HandleFunc("GET /api/v1/expiring-soon", handler.Call(handler.sqlStorage(getExpiringValues)))
+}
+
+type ExpiringValue struct {
+	ID              int64     `json:"id"`
+	EnvironmentID   int64     `json:"environment_id"`
+	EnvironmentName string    `json:"environment_name"`
+	Key             string    `json:"key"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	Expired         bool      `json:"expired"`
+}
+
+func isExpired(value database.EnvironmentValue, now time.Time) bool {
+	return value.ExpiresAt.Valid && !value.ExpiresAt.Time.After(now)
+}
+
+// toValues converts stored values to their API representation. Expired
+// values are omitted unless includeExpired is set, in which case they are
+// flagged.
+func toValues(valuesFromDB []database.EnvironmentValue, includeExpired bool) []Value {
+	now := time.Now().UTC()
+	values := make([]Value, 0)
+	for _, value := range valuesFromDB {
+		expired := isExpired(value, now)
+		if expired && !includeExpired {
+			continue
+		}
+
+		v := Value{
+			ID:       value.ID,
+			Key:      value.Key,
+			Value:    value.Value,
+			Expired:  expired,
+			Metadata: toMetadata(value.Description, value.Owner, value.Link, value.Tags),
+		}
+		if value.ExpiresAt.Valid {
+			v.ExpiresAt = &value.ExpiresAt.Time
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// fromStoreValues is toValues for values of the store
+func fromStoreValues(valuesFromStore []store.Value, includeExpired bool) []Value {
+	now := time.Now().UTC()
+	values := make([]Value, 0)
+	for _, value := range valuesFromStore {
+		expired := value.ExpiresAt != nil && !value.ExpiresAt.After(now)
+		if expired && !includeExpired {
+			continue
+		}
+
+		values = append(values, Value{
+			ID:        value.ID,
+			Key:       value.Key,
+			Value:     value.Value,
+			ExpiresAt: value.ExpiresAt,
+			Expired:   expired,
+			Type:      value.Type,
+			Metadata:  fromStoreMetadata(value.Metadata),
+		})
+	}
+	return values
+}
+
+// includeExpired reports whether expired values were requested with ?include_expired=true
+func includeExpired(r *http.Request) bool {
+	return r.URL.Query().Get("include_expired") == "true"
+}
+
+// expiry returns the expiration requested for a value, either as an
+// absolute expires_at or a ttl relative to now. ok is false when the
+// request doesn't set one.
+func (v Value) expiry() (expiresAt sql.NullTime, ok bool, err error) {
+	return parseExpiry(v.TTL, v.ExpiresAt)
+}
+
+// parseExpiry returns the expiration set by either a ttl relative to now or
+// an absolute expiresAt. ok is false when neither is set.
+func parseExpiry(ttl string, expiresAt *time.Time) (sql.NullTime, bool, error) {
+	switch {
+	case ttl != "" && expiresAt != nil:
+		return sql.NullTime{}, false, errors.New("only one of ttl and expires_at can be set")
+	case ttl != "":
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return sql.NullTime{}, false, err
+		}
+		if d <= 0 {
+			return sql.NullTime{}, false, errors.New("ttl must be positive")
+		}
+		return sql.NullTime{Time: time.Now().UTC().Add(d), Valid: true}, true, nil
+	case expiresAt != nil:
+		return sql.NullTime{Time: expiresAt.UTC(), Valid: true}, true, nil
+	}
+	return sql.NullTime{}, false, nil
+}
+
+// validateExpiry checks the expiration of every value before anything is written
+func validateExpiry(values []Value) (Response, error) {
+	for _, value := range values {
+		if _, _, err := value.expiry(); err != nil {
+			return Response{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid expiration for " + value.Key,
+				Error:   err.Error(),
+			}, err
+		}
+	}
+	return Response{}, nil
+}
+
+// setExpiry stores the expiration requested for a value, if any
+func setExpiry(ctx context.Context, s store.Store, stored store.Value, value Value) error {
+	expiresAt, ok, err := value.expiry()
+	if err != nil || !ok {
+		return err
+	}
+
+	_, err = s.SetValueExpiry(ctx, stored.EnvironmentID, stored.Key, &expiresAt.Time)
+	return err
+}
+
+func getExpiringValues(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	within := defaultExpiringWithin
+	if param := r.URL.Query().Get("within"); param != "" {
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			return Response{
+				Code:    http.StatusBadRequest,
+				Message: "Failed to get expiring values",
+				Error:   err.Error(),
+			}, err
+		}
+		within = d
+	}
+
+	now := time.Now().UTC()
+	valuesFromDB, err := db.GetExpiringValues(r.Context(), sql.NullTime{Time: now.Add(within), Valid: true})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get expiring values",
+			Error:   err.Error(),
+		}, err
+	}
+
+	// Values of projects the actor can't read are left out
+	scope := newSearchScope(db, r)
+	values := make([]ExpiringValue, 0)
+	for _, value := range valuesFromDB {
+		allowed, err := scope.can(value.ProjectID, RoleRead)
+		if err != nil {
+			return Response{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to get expiring values",
+				Error:   err.Error(),
+			}, err
+		}
+		if !allowed {
+			continue
+		}
+		values = append(values, ExpiringValue{
+			ID:              value.ID,
+			EnvironmentID:   value.EnvironmentID,
+			EnvironmentName: value.Name,
+			Key:             value.Key,
+			ExpiresAt:       value.ExpiresAt.Time,
+			Expired:         !value.ExpiresAt.Time.After(now),
+		})
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Expiring values retrieved",
+		Data:    values,
+	}, nil
+}
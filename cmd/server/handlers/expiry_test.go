@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/rodrwan/secretly/internal/database/dbtest"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+func TestExpiringValuesPermissions(t *testing.T) {
+	db := dbtest.SQLite(t)
+	c := newClient(t, db, store.NewSQL(db))
+	for _, project := range []string{"api", "billing"} {
+		c.mustDo(t, http.MethodPost, "/api/v1/projects", `{"name": "`+project+`"}`, nil)
+		c.createEnvironment(t, project, `{"name": "production", "values": [
+			{"key": "`+project+`_TOKEN", "value": "s3cret", "ttl": "1h"},
+			{"key": "`+project+`_HOST", "value": "localhost"}
+		]}`)
+	}
+	if resp := c.do(t, http.MethodPut, "/api/v1/projects/billing/permissions/alice", "alice", `{"role": "admin"}`); resp.Code != http.StatusOK {
+		t.Fatalf("setting a permission = %d %s", resp.Code, resp.Error)
+	}
+
+	tests := []struct {
+		actor string
+		want  []string
+	}{
+		{"alice", []string{"api_TOKEN", "billing_TOKEN"}},
+		{"bob", []string{"api_TOKEN"}},
+		{"", []string{"api_TOKEN"}},
+	}
+	for _, tt := range tests {
+		resp := c.do(t, http.MethodGet, "/api/v1/expiring-soon?within=2h", tt.actor, "")
+		if resp.Code != http.StatusOK {
+			t.Fatalf("as %q = %d %s", tt.actor, resp.Code, resp.Error)
+		}
+		var values []ExpiringValue
+		if err := json.Unmarshal(resp.Data, &values); err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, value := range values {
+			keys = append(keys, value.Key)
+		}
+		slices.Sort(keys)
+		if !slices.Equal(keys, tt.want) {
+			t.Errorf("expiring values of %q = %v, want %v", tt.actor, keys, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rodrwan/secretly/internal/generator"
+)
+
+// prepareValues validates the values of a write request and replaces the
+// ones with a generator spec by a freshly generated value. Nothing is
+// written until every value is valid.
+func prepareValues(values []Value) (Response, error) {
+	if resp, err := validateExpiry(values); err != nil {
+		return resp, err
+	}
+
+	for i, value := range values {
+		if value.Generate == nil {
+			continue
+		}
+
+		if value.Value != "" {
+			err := errors.New("value and generate are mutually exclusive")
+			return Response{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid value for " + value.Key,
+				Error:   err.Error(),
+			}, err
+		}
+
+		generated, err := generator.Generate(*value.Generate)
+		if err != nil {
+			return Response{
+				Code:    http.StatusBadRequest,
+				Message: "Failed to generate value for " + value.Key,
+				Error:   err.Error(),
+			}, err
+		}
+		values[i].Value = generated
+	}
+
+	return Response{}, nil
+}
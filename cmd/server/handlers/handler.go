@@ -1,33 +1,296 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/keyring"
+	"github.com/rodrwan/secretly/internal/rotation"
+	"github.com/rodrwan/secretly/internal/store"
+	"github.com/rodrwan/secretly/internal/webhook"
 	"go.uber.org/zap"
 )
 
+// ActorHeader identifies who makes a request, ie: for the promotion history
+const ActorHeader = "X-Secretly-Actor"
+
+const anonymousActor = "anonymous"
+
 type handlerFunc func(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error)
 
 type Handler struct {
 	db database.Querier
+	// store keeps environments and their values, the rest of the data is
+	// only in db
+	store     store.Store
+	webhooks  *webhook.Dispatcher
+	scheduler *rotation.Scheduler
+	// protectRequired refuses to delete the value of a required key
+	protectRequired bool
+	// sqlDB runs multi-step writes in a transaction
+	sqlDB *database.DB
+	// trashRetention is how long deleted environments and values can be restored
+	trashRetention time.Duration
+	// valueHashSearch lets project admins find values by their hash
+	valueHashSearch bool
+	// readOnly refuses every request that isn't a GET
+	readOnly bool
+	// admins are the actors allowed to call the admin endpoints
+	admins []string
+	// rekey re-wraps values with the active master key, until rekeyCtx is
+	// cancelled. It is nil when values aren't encrypted.
+	rekey    *keyring.Rekeyer
+	rekeyCtx context.Context
+	// seal is the master key split in shares, every request but the seal
+	// endpoints is refused while it is sealed. It is nil when there is none.
+	seal *keyring.ShamirProvider
+	// authenticatedActors is set when ActorHeader is set by the server or a
+	// proxy that authenticated the client, project permissions are only
+	// enforced for authenticated actors
+	authenticatedActors bool
 }
 
-func NewHandler(db database.Querier) *Handler {
-	return &Handler{db: db}
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithWebhooks publishes change events to the given dispatcher
+func WithWebhooks(dispatcher *webhook.Dispatcher) Option {
+	return func(h *Handler) {
+		h.webhooks = dispatcher
+	}
+}
+
+// WithRotation enables rotation policies using the given scheduler
+func WithRotation(scheduler *rotation.Scheduler) Option {
+	return func(h *Handler) {
+		h.scheduler = scheduler
+	}
+}
+
+// WithRequiredKeyProtection refuses to delete values of keys whose schema
+// marks them as required
+func WithRequiredKeyProtection(protect bool) Option {
+	return func(h *Handler) {
+		h.protectRequired = protect
+	}
+}
+
+// WithTransactions runs multi-step writes, like promotions, in a
+// transaction on db
+func WithTransactions(db *database.DB) Option {
+	return func(h *Handler) {
+		h.sqlDB = db
+	}
+}
+
+// WithStore keeps environments and their values in s instead of the
+// database of the handler
+func WithStore(s store.Store) Option {
+	return func(h *Handler) {
+		h.store = s
+	}
+}
+
+// WithTrashRetention sets how long deleted environments and values can be
+// restored before they are purged
+func WithTrashRetention(retention time.Duration) Option {
+	return func(h *Handler) {
+		h.trashRetention = retention
+	}
+}
+
+// WithValueHashSearch lets project admins search values by their SHA-256
+// hash, ie: to find where a leaked secret is used
+func WithValueHashSearch(enabled bool) Option {
+	return func(h *Handler) {
+		h.valueHashSearch = enabled
+	}
+}
+
+// WithReadOnly refuses every request that could change data, ie: when
+// serving a checked out repository
+func WithReadOnly(readOnly bool) Option {
+	return func(h *Handler) {
+		h.readOnly = readOnly
+	}
+}
+
+// WithAdmins lets actors call the admin endpoints
+func WithAdmins(actors []string) Option {
+	return func(h *Handler) {
+		h.admins = actors
+	}
+}
+
+// WithRekey lets admins re-wrap every value with the active master key,
+// a nil rekeyer when values aren't encrypted. Rekeys run in the background
+// until ctx is cancelled.
+func WithRekey(ctx context.Context, rekeyer *keyring.Rekeyer) Option {
+	return func(h *Handler) {
+		h.rekey = rekeyer
+		h.rekeyCtx = ctx
+	}
+}
+
+// WithSeal refuses requests until the master key split in shares is
+// unsealed, a nil provider when there is none
+func WithSeal(provider *keyring.ShamirProvider) Option {
+	return func(h *Handler) {
+		h.seal = provider
+	}
+}
+
+// WithAuthenticatedActors trusts ActorHeader, it is set after the client
+// is authenticated, ie: by its certificate or an authenticating proxy
+func WithAuthenticatedActors(authenticated bool) Option {
+	return func(h *Handler) {
+		h.authenticatedActors = authenticated
+	}
+}
+
+func NewHandler(db database.Querier, opts ...Option) *Handler {
+	h := &Handler{db: db}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.store == nil {
+		h.store = store.NewSQLQuerier(db)
+		if h.sqlDB != nil {
+			h.store = store.NewSQL(h.sqlDB)
+		}
+	}
+	return h
+}
+
+// publish sends an event to webhook subscribers. Failures are logged and
+// never fail the request, the change has already been stored.
+func (eh *Handler) publish(r *http.Request, event webhook.Event) {
+	if err := eh.webhooks.Publish(r.Context(), event); err != nil {
+		zap.L().Error("Failed to publish event",
+			zap.String("path", r.URL.Path),
+			zap.String("event", event.Type),
+			zap.Error(err),
+		)
+	}
+}
+
+// inTx runs fn in a transaction when the handler has a database, and
+// directly against its querier otherwise
+func (eh *Handler) inTx(ctx context.Context, fn func(db database.Querier) error) error {
+	if eh.sqlDB == nil {
+		return fn(eh.db)
+	}
+	return database.InTx(ctx, eh.sqlDB, fn)
+}
+
+// storeTx runs fn in a transaction of the store. db runs the queries of the
+// features built on the SQL schema, in the same transaction when the store
+// is SQL.
+func (eh *Handler) storeTx(ctx context.Context, fn func(s store.Store, db database.Querier) error) error {
+	return eh.store.InTx(ctx, func(s store.Store) error {
+		db := eh.db
+		if sqlStore, ok := s.(*store.SQL); ok {
+			db = sqlStore.Querier()
+		}
+		return fn(s, db)
+	})
+}
+
+// sqlStore reports whether environments are stored in the database, the
+// features built on the SQL schema, ie: value schemas, only apply to them
+func (eh *Handler) sqlStore() bool {
+	_, ok := eh.store.(*store.SQL)
+	return ok
+}
+
+// errSQLStorage is returned by the features built on the SQL schema when
+// environments are stored elsewhere
+var errSQLStorage = errors.New("only supported when environments are stored in the database, STORAGE=sql")
+
+// sqlStorage refuses the requests of a feature built on the SQL schema when
+// environments are stored elsewhere, ie: by the git storage. Its tables refer
+// to environments by their ID in the database.
+func (eh *Handler) sqlStorage(handler handlerFunc) handlerFunc {
+	return func(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+		if !eh.sqlStore() {
+			return Response{
+				Code:    http.StatusNotImplemented,
+				Message: "Not supported by the storage",
+				Error:   errSQLStorage.Error(),
+			}, errSQLStorage
+		}
+		return handler(db, w, r)
+	}
+}
+
+// authenticatedKey marks the requests whose actor was authenticated
+type authenticatedKey struct{}
+
+// authenticated reports whether the actor of the request was authenticated,
+// otherwise ActorHeader is whatever the client sent
+func authenticated(r *http.Request) bool {
+	ok, _ := r.Context().Value(authenticatedKey{}).(bool)
+	return ok
+}
+
+// actor returns who makes the request, as sent in ActorHeader
+func actor(r *http.Request) string {
+	if actor := r.Header.Get(ActorHeader); actor != "" {
+		return actor
+	}
+	return anonymousActor
 }
 
 func (eh *Handler) Call(handler handlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		resp, err := handler(eh.db, w, r)
-		if err != nil {
-			Error(w, r, resp.Code, resp.Message, err)
+		if eh.readOnly && r.Method != http.MethodGet {
+			Error(w, r, http.StatusForbidden, "The server is read-only", store.ErrReadOnly)
+			return
+		}
+		if eh.seal != nil && eh.seal.Status().Sealed {
+			Error(w, r, http.StatusServiceUnavailable, "The server is sealed", keyring.ErrSealed)
 			return
 		}
 
-		Success(w, r, resp.Code, resp.Message, resp.Data)
+		eh.serve(w, r, handler)
+	}
+}
+
+// CallSealed is Call for the endpoints that work while the server is sealed
+// or read-only, they don't change data
+func (eh *Handler) CallSealed(handler handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		eh.serve(w, r, handler)
+	}
+}
+
+func (eh *Handler) serve(w http.ResponseWriter, r *http.Request, handler handlerFunc) {
+	if eh.authenticatedActors {
+		r = r.WithContext(context.WithValue(r.Context(), authenticatedKey{}, true))
+	}
+
+	if resp, err := eh.authorizeRequest(eh.db, r); err != nil {
+		Error(w, r, resp.Code, resp.Message, err)
+		return
 	}
+
+	// Stores that keep a history attribute the changes to the actor, when
+	// it can't be spoofed
+	if actor := r.Header.Get(ActorHeader); actor != "" && authenticated(r) {
+		r = r.WithContext(store.WithAuthor(r.Context(), actor))
+	}
+
+	resp, err := handler(eh.db, w, r)
+	if err != nil {
+		Error(w, r, resp.Code, resp.Message, err)
+		return
+	}
+
+	Success(w, r, resp)
 }
 
 type Response struct {
@@ -35,14 +298,17 @@ type Response struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
 	Error   string      `json:"error"`
+	// NextCursor gets the next page of a paginated list, it is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
-func Success(w http.ResponseWriter, r *http.Request, code int, message string, data interface{}) {
+func Success(w http.ResponseWriter, r *http.Request, resp Response) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(Response{
-		Code:    code,
-		Message: message,
-		Data:    data,
+		Code:       resp.Code,
+		Message:    resp.Message,
+		Data:       resp.Data,
+		NextCursor: resp.NextCursor,
 	})
 }
 
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/database/dbtest"
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+func TestSQLStorage(t *testing.T) {
+	db := dbtest.SQLite(t)
+	testRoutes(t, db, store.NewSQL(db))
+}
+
+func TestGitStorage(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	key := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := encryption.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := store.NewGit(context.Background(), t.TempDir(), cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := dbtest.SQLite(t)
+	testRoutes(t, db, g)
+
+	t.Run("SQL only", func(t *testing.T) {
+		c := newClient(t, db, g)
+		env := c.createEnvironment(t, "api", `{"name": "sql-only"}`)
+
+		requests := []struct{ method, path, body string }{
+			{http.MethodPost, "/api/v1/promote", `{"from": "sql-only", "to": "production"}`},
+			{http.MethodGet, "/api/v1/promotions", ""},
+			{http.MethodGet, "/api/v1/env/" + itoa(env.ID) + "/schema", ""},
+			{http.MethodGet, "/api/v1/env/" + itoa(env.ID) + "/rotations", ""},
+			{http.MethodGet, "/api/v1/env/" + itoa(env.ID) + "/webhooks", ""},
+			{http.MethodGet, "/api/v1/trash", ""},
+			{http.MethodGet, "/api/v1/search?q=API", ""},
+			{http.MethodGet, "/api/v1/expiring-soon", ""},
+		}
+		for _, request := range requests {
+			if resp := c.do(t, request.method, request.path, "", request.body); resp.Code != http.StatusNotImplemented {
+				t.Errorf("%s %s = %d %s, want 501", request.method, request.path, resp.Code, resp.Error)
+			}
+		}
+	})
+}
+
+// client calls the routes of a handler
+type client struct {
+	server *httptest.Server
+	store  store.Store
+}
+
+type testResponse struct {
+	Data    json.RawMessage `json:"data"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+}
+
+// newClient serves the routes of a handler with authenticated actors, opts
+// override it
+func newClient(t *testing.T, db *database.DB, s store.Store, opts ...Option) *client {
+	t.Helper()
+	router := http.NewServeMux()
+	RegisterRoutes(router, db.Querier(), append([]Option{
+		WithTransactions(db),
+		WithStore(s),
+		WithAuthenticatedActors(true),
+	}, opts...)...)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return &client{server: server, store: s}
+}
+
+// do sends a request as actor, none when empty, and decodes its response
+func (c *client) do(t *testing.T, method, path, actor, body string) testResponse {
+	t.Helper()
+	req, err := http.NewRequest(method, c.server.URL+path, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actor != "" {
+		req.Header.Set(ActorHeader, actor)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var resp testResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return resp
+}
+
+// mustDo is do for requests that must succeed, it decodes their data in v
+func (c *client) mustDo(t *testing.T, method, path, body string, v any) {
+	t.Helper()
+	resp := c.do(t, method, path, "", body)
+	if resp.Code != http.StatusOK && resp.Code != http.StatusCreated {
+		t.Fatalf("%s %s = %d %s: %s", method, path, resp.Code, resp.Message, resp.Error)
+	}
+	if v != nil {
+		if err := json.Unmarshal(resp.Data, v); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+}
+
+func (c *client) createEnvironment(t *testing.T, project, body string) Environment {
+	t.Helper()
+	var env Environment
+	c.mustDo(t, http.MethodPost, "/api/v1/projects/"+project+"/env", body, &env)
+	return env
+}
+
+func (c *client) value(t *testing.T, envID int64, key string) (store.Value, error) {
+	t.Helper()
+	return c.store.GetValue(context.Background(), envID, key)
+}
+
+func itoa(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// testRoutes runs the routes of environments and values against the
+// environments of s
+func testRoutes(t *testing.T, db *database.DB, s store.Store) {
+	c := newClient(t, db, s)
+	c.mustDo(t, http.MethodPost, "/api/v1/projects", `{"name": "api"}`, nil)
+	env := c.createEnvironment(t, "api", `{"name": "staging", "values": [
+		{"key": "API_KEY", "value": "v1"},
+		{"key": "DEBUG", "value": "true"},
+		{"key": "PORT", "value": "8080"},
+		{"key": "HOST", "value": "localhost"}
+	]}`)
+	envPath := "/api/v1/env/" + itoa(env.ID)
+	ctx := context.Background()
+
+	t.Run("rename", func(t *testing.T) {
+		c.mustDo(t, http.MethodPut, envPath, `{"values": [{"key": "API_KEY", "value": "v2"}]}`, nil)
+		c.mustDo(t, http.MethodPost, envPath+"/value/API_KEY/rename", `{"to": "TOKEN"}`, nil)
+
+		if _, err := c.value(t, env.ID, "API_KEY"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("API_KEY after the rename: %v", err)
+		}
+		versions, err := s.GetVersions(ctx, env.ID, "TOKEN")
+		if err != nil || len(versions) != 2 || versions[0].Value != "v2" {
+			t.Errorf("versions of TOKEN = %+v, %v", versions, err)
+		}
+
+		if resp := c.do(t, http.MethodPost, envPath+"/value/TOKEN/rename", "", `{"to": "DEBUG"}`); resp.Code != http.StatusConflict {
+			t.Errorf("renaming to a set key = %d, want 409", resp.Code)
+		}
+		if resp := c.do(t, http.MethodPost, envPath+"/value/MISSING/rename", "", `{"to": "OTHER"}`); resp.Code != http.StatusNotFound {
+			t.Errorf("renaming a missing key = %d, want 404", resp.Code)
+		}
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		c.mustDo(t, http.MethodPut, envPath+"/metadata", `{"owner": "alice", "tags": ["critical"]}`, nil)
+		if got, _ := s.GetEnvironment(ctx, env.ID); got.Owner != "alice" || !slices.Equal(got.Tags, []string{"critical"}) {
+			t.Errorf("metadata of the environment = %+v", got.Metadata)
+		}
+
+		c.mustDo(t, http.MethodPut, envPath+"/value/TOKEN/metadata", `{"description": "Payments API"}`, nil)
+		if got, _ := c.value(t, env.ID, "TOKEN"); got.Description != "Payments API" {
+			t.Errorf("metadata of TOKEN = %+v", got.Metadata)
+		}
+		if resp := c.do(t, http.MethodPut, "/api/v1/env/999999/metadata", "", `{"owner": "alice"}`); resp.Code != http.StatusNotFound {
+			t.Errorf("metadata of a missing environment = %d, want 404", resp.Code)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		c.mustDo(t, http.MethodDelete, envPath+"/value/DEBUG", "", nil)
+		if _, err := c.value(t, env.ID, "DEBUG"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("DEBUG after its deletion: %v", err)
+		}
+		if resp := c.do(t, http.MethodDelete, envPath+"/value/DEBUG", "", ""); resp.Code != http.StatusNotFound {
+			t.Errorf("deleting twice = %d, want 404", resp.Code)
+		}
+
+		c.mustDo(t, http.MethodPost, envPath+"/values/delete", `{"keys": ["PORT", "HOST"]}`, nil)
+		values, err := s.GetValues(ctx, env.ID)
+		if err != nil || len(values) != 1 || values[0].Key != "TOKEN" {
+			t.Errorf("values after the bulk deletion = %+v, %v", values, err)
+		}
+	})
+
+	t.Run("clone", func(t *testing.T) {
+		var clone Environment
+		c.mustDo(t, http.MethodPost, envPath+"/clone", `{"name": "preview", "overrides": {"PORT": "9090"}}`, &clone)
+
+		source, err := s.GetEnvironment(ctx, env.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.GetEnvironmentByName(ctx, source.ProjectID, "preview")
+		if err != nil || got.ID != clone.ID {
+			t.Fatalf("clone = %+v, %v", got, err)
+		}
+		token, err := c.value(t, clone.ID, "TOKEN")
+		if err != nil || token.Value != "v2" || token.Description != "Payments API" {
+			t.Errorf("TOKEN of the clone = %+v, %v", token, err)
+		}
+		if port, _ := c.value(t, clone.ID, "PORT"); port.Value != "9090" {
+			t.Errorf("PORT of the clone = %q, want the override", port.Value)
+		}
+
+		if resp := c.do(t, http.MethodPost, envPath+"/clone", "", `{"name": "preview"}`); resp.Code != http.StatusConflict {
+			t.Errorf("cloning to a taken name = %d, want 409", resp.Code)
+		}
+	})
+
+	t.Run("ephemeral", func(t *testing.T) {
+		preview := c.createEnvironment(t, "api", `{"name": "pr-1", "ttl": "1h"}`)
+		previewPath := "/api/v1/env/" + itoa(preview.ID)
+		if preview.ExpiresAt == nil {
+			t.Fatal("an environment with a TTL has no expiration")
+		}
+
+		c.mustDo(t, http.MethodPost, previewPath+"/extend", `{"ttl": "24h"}`, nil)
+		got, err := s.GetEnvironment(ctx, preview.ID)
+		if err != nil || got.ExpiresAt == nil || got.ExpiresAt.Sub(*preview.ExpiresAt) < 23*time.Hour {
+			t.Errorf("expiration after extending = %v, %v, want 24h after %v", got.ExpiresAt, err, preview.ExpiresAt)
+		}
+
+		c.mustDo(t, http.MethodPost, previewPath+"/pin", "", nil)
+		if got, _ := s.GetEnvironment(ctx, preview.ID); got.ExpiresAt != nil {
+			t.Errorf("a pinned environment expires at %v", got.ExpiresAt)
+		}
+		if resp := c.do(t, http.MethodPost, previewPath+"/extend", "", `{"ttl": "1h"}`); resp.Code != http.StatusConflict {
+			t.Errorf("extending a pinned environment = %d, want 409", resp.Code)
+		}
+	})
+
+	t.Run("permissions", func(t *testing.T) {
+		c.mustDo(t, http.MethodPost, "/api/v1/projects", `{"name": "billing"}`, nil)
+		billing := c.createEnvironment(t, "billing", `{"name": "production", "values": [{"key": "TOKEN", "value": "s3cret"}]}`)
+		billingPath := "/api/v1/env/" + itoa(billing.ID)
+		if resp := c.do(t, http.MethodPut, "/api/v1/projects/billing/permissions/alice", "alice", `{"role": "admin"}`); resp.Code != http.StatusOK {
+			t.Fatalf("setting the first admin = %d %s", resp.Code, resp.Error)
+		}
+
+		// The permissions of the project of the environment apply
+		requests := []struct {
+			method, path, actor, body string
+			want                      int
+		}{
+			{http.MethodGet, billingPath, "alice", "", http.StatusOK},
+			{http.MethodGet, billingPath, "bob", "", http.StatusForbidden},
+			{http.MethodGet, billingPath, "", "", http.StatusForbidden},
+			{http.MethodPost, billingPath + "/value/TOKEN/rename", "bob", `{"to": "KEY"}`, http.StatusForbidden},
+			{http.MethodDelete, billingPath + "/value/TOKEN", "bob", "", http.StatusForbidden},
+			{http.MethodPut, billingPath + "/metadata", "bob", `{"owner": "bob"}`, http.StatusForbidden},
+			// Other projects stay open
+			{http.MethodGet, envPath, "bob", "", http.StatusOK},
+		}
+		for _, request := range requests {
+			if resp := c.do(t, request.method, request.path, request.actor, request.body); resp.Code != request.want {
+				t.Errorf("%s %s as %q = %d %s, want %d", request.method, request.path, request.actor, resp.Code, resp.Error, request.want)
+			}
+		}
+		if _, err := c.value(t, billing.ID, "TOKEN"); err != nil {
+			t.Errorf("a forbidden request changed TOKEN: %v", err)
+		}
+	})
+}
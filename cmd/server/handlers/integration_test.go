@@ -0,0 +1,21 @@
+//go:build integration
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/rodrwan/secretly/internal/database/dbtest"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+// TestIntegration serves the routes from every database, PostgreSQL needs
+// DATABASE_URL
+func TestIntegration(t *testing.T) {
+	for _, driver := range dbtest.Drivers {
+		t.Run(driver.Name, func(t *testing.T) {
+			db := driver.Open(t)
+			testRoutes(t, db, store.NewSQL(db))
+		})
+	}
+}
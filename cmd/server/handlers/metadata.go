@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+func registerMetadataRoutes(router *http.ServeMux, handler *Handler) {
+	// Set the description, owner, tags and link of environment {id}
+	router.HandleFunc("PUT /api/v1/env/{id}/metadata", handler.Call(handler.setEnvironmentMetadata))
+	// Set the description, owner, tags and link of {key} in environment {id}
+	router.HandleFunc("PUT /api/v1/env/{id}/value/{key}/metadata", handler.Call(handler.setValueMetadata))
+}
+
+// Metadata documents an environment or a value. It is never part of the value itself.
+type Metadata struct {
+	Description string   `json:"description,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Link points to more documentation, ie: a runbook
+	Link string `json:"link,omitempty"`
+}
+
+// toMetadata converts the stored metadata columns. Tags are stored as a JSON array.
+func toMetadata(description, owner, link, tags string) Metadata {
+	m := Metadata{
+		Description: description,
+		Owner:       owner,
+		Link:        link,
+	}
+	if err := json.Unmarshal([]byte(tags), &m.Tags); err != nil || len(m.Tags) == 0 {
+		m.Tags = nil
+	}
+	return m
+}
+
+func fromStoreMetadata(m store.Metadata) Metadata {
+	return Metadata{
+		Description: m.Description,
+		Owner:       m.Owner,
+		Tags:        m.Tags,
+		Link:        m.Link,
+	}
+}
+
+func (m Metadata) toStore() store.Metadata {
+	return store.Metadata{
+		Description: m.Description,
+		Owner:       m.Owner,
+		Link:        m.Link,
+		Tags:        m.Tags,
+	}
+}
+
+// normalize trims the metadata, removes empty and duplicated tags and
+// validates the link
+func (m *Metadata) normalize() error {
+	m.Description = strings.TrimSpace(m.Description)
+	m.Owner = strings.TrimSpace(m.Owner)
+	m.Link = strings.TrimSpace(m.Link)
+
+	tags := make([]string, 0, len(m.Tags))
+	for _, tag := range m.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	m.Tags = tags
+
+	if m.Link != "" {
+		u, err := url.Parse(m.Link)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid link %q", m.Link)
+		}
+	}
+	return nil
+}
+
+// hasTag reports whether the metadata is tagged with tag
+func (m Metadata) hasTag(tag string) bool {
+	return slices.Contains(m.Tags, tag)
+}
+
+func decodeMetadata(r *http.Request) (Metadata, Response, error) {
+	var m Metadata
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		return Metadata{}, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to set metadata",
+			Error:   err.Error(),
+		}, err
+	}
+	if err := m.normalize(); err != nil {
+		return Metadata{}, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid metadata",
+			Error:   err.Error(),
+		}, err
+	}
+	return m, Response{}, nil
+}
+
+func (eh *Handler) setEnvironmentMetadata(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to set metadata",
+			Error:   err.Error(),
+		}, err
+	}
+
+	m, resp, err := decodeMetadata(r)
+	if err != nil {
+		return resp, err
+	}
+
+	env, err := eh.store.SetEnvironmentMetadata(r.Context(), envID, m.toStore())
+	if errors.Is(err, store.ErrNotFound) {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set metadata",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Metadata set",
+		Data:    fromStoreEnvironment(env, nil),
+	}, nil
+}
+
+func (eh *Handler) setValueMetadata(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	m, resp, err := decodeMetadata(r)
+	if err != nil {
+		return resp, err
+	}
+
+	env, value, resp, err := eh.getStoreValue(r)
+	if err != nil {
+		return resp, err
+	}
+
+	value, err = eh.store.SetValueMetadata(r.Context(), env.ID, value.Key, m.toStore())
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set metadata",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Metadata set",
+		Data:    fromStoreValues([]store.Value{value}, true)[0],
+	}, nil
+}
+
+// withTag keeps the values tagged with ?tag=, all of them when it isn't set
+func withTag(r *http.Request, values []Value) []Value {
+	tag := r.URL.Query().Get("tag")
+	if tag == "" {
+		return values
+	}
+	return slices.DeleteFunc(values, func(v Value) bool {
+		return !v.hasTag(tag)
+	})
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// environmentFields can be selected with ?fields=, ie: ?fields=id,name
+var environmentFields = []string{"id", "name", "expires_at", "e2e", "description", "owner", "tags", "link", "values"}
+
+// environmentCursor points after the last environment of a page. It is sent
+// to clients base64 encoded, they shouldn't rely on its contents.
+type environmentCursor struct {
+	Sort string `json:"s"`
+	Name string `json:"n"`
+	ID   int64  `json:"i"`
+}
+
+func (c environmentCursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseEnvironmentCursor(cursor, sort string) (environmentCursor, error) {
+	var c environmentCursor
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || c.ID <= 0 {
+		return environmentCursor{}, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	if c.Sort != sort {
+		return environmentCursor{}, fmt.Errorf("the cursor was created for sort %q", c.Sort)
+	}
+	return c, nil
+}
+
+// environmentPage is a page of environments requested with ?limit=,
+// ?cursor=, ?sort=, ?prefix= and ?fields=
+type environmentPage struct {
+	params store.ListOptions
+	fields []string
+}
+
+func parseEnvironmentPage(r *http.Request, projectID int64) (environmentPage, Response, error) {
+	query := r.URL.Query()
+	page := environmentPage{
+		params: store.ListOptions{
+			ProjectID:  projectID,
+			NamePrefix: query.Get("prefix"),
+			Sort:       store.SortByCreation,
+			Limit:      defaultPageSize,
+			WithValues: true,
+		},
+	}
+
+	invalid := func(err error) (environmentPage, Response, error) {
+		return environmentPage{}, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid page",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if param := query.Get("limit"); param != "" {
+		limit, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			return invalid(fmt.Errorf("limit must be between 1 and %d", maxPageSize))
+		}
+		page.params.Limit = limit
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		sorts := []string{store.SortByName, store.SortByNameDesc, store.SortByCreation, store.SortByCreationDesc}
+		if !slices.Contains(sorts, sort) {
+			return invalid(fmt.Errorf("unknown sort %q, must be one of %s", sort, strings.Join(sorts, ", ")))
+		}
+		page.params.Sort = sort
+	}
+
+	if param := query.Get("cursor"); param != "" {
+		cursor, err := parseEnvironmentCursor(param, page.params.Sort)
+		if err != nil {
+			return invalid(err)
+		}
+		page.params.AfterName = cursor.Name
+		page.params.AfterID = cursor.ID
+	}
+
+	if param := query.Get("fields"); param != "" {
+		for _, field := range strings.Split(param, ",") {
+			field = strings.TrimSpace(field)
+			if !slices.Contains(environmentFields, field) {
+				return invalid(fmt.Errorf("unknown field %q, must be one of %s", field, strings.Join(environmentFields, ", ")))
+			}
+			page.fields = append(page.fields, field)
+		}
+		page.params.WithValues = slices.Contains(page.fields, "values")
+	}
+
+	return page, Response{}, nil
+}
+
+// list gets the environments of the page and the cursor of the next one,
+// empty when it is the last page
+func (p environmentPage) list(r *http.Request, s store.Store) ([]Environment, string, error) {
+	// One more environment tells whether there is a next page
+	params := p.params
+	params.Limit++
+	envsFromStore, err := s.ListEnvironments(r.Context(), params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	envs := make([]Environment, 0, len(envsFromStore))
+	for _, env := range envsFromStore {
+		envs = append(envs, fromStoreEnvironment(env, fromStoreValues(env.Values, includeExpired(r))))
+	}
+	if int64(len(envs)) <= p.params.Limit {
+		return envs, "", nil
+	}
+
+	envs = envs[:p.params.Limit]
+	last := envs[len(envs)-1]
+	cursor := environmentCursor{Sort: p.params.Sort, Name: last.Name, ID: last.ID}
+	return envs, cursor.String(), nil
+}
+
+// selectFields keeps the given fields of each environment, all of them when
+// no fields are given
+func selectFields(envs []Environment, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return envs, nil
+	}
+
+	selected := make([]map[string]json.RawMessage, 0, len(envs))
+	for _, env := range envs {
+		b, err := json.Marshal(env)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(b, &all); err != nil {
+			return nil, err
+		}
+
+		env := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				env[field] = value
+			}
+		}
+		selected = append(selected, env)
+	}
+	return selected, nil
+}
@@ -0,0 +1,598 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+// DefaultProject holds the environments created without a project
+const DefaultProject = "default"
+
+// Project roles, each one includes the permissions of the previous ones
+const (
+	RoleRead  = "read"
+	RoleWrite = "write"
+	RoleAdmin = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleRead:  1,
+	RoleWrite: 2,
+	RoleAdmin: 3,
+}
+
+var errForbidden = errors.New("forbidden")
+
+// errUnauthenticatedActor is returned when a project with permissions is
+// accessed by an actor anyone could claim
+var errUnauthenticatedActor = errors.New("project permissions require authenticated actors, see TLS_CLIENT_CA_FILE and TRUST_ACTOR_HEADER")
+
+func registerProjectRoutes(router *http.ServeMux, handler *Handler) {
+	// Get all projects
+	router.HandleFunc("GET /api/v1/projects", handler.Call(getProjects))
+	// Create a new project
+	router.HandleFunc("POST /api/v1/projects", handler.Call(createProject))
+	// Get a project and the names of its environments
+	router.HandleFunc("GET /api/v1/projects/{project}", handler.Call(handler.getProject))
+	// Delete an empty project
+	router.HandleFunc("DELETE /api/v1/projects/{project}", handler.Call(handler.deleteProject))
+
+	// Environments of a project, addressed by name
+	router.HandleFunc("GET /api/v1/projects/{project}/env", handler.Call(handler.getEnvironments))
+	router.HandleFunc("POST /api/v1/projects/{project}/env", handler.Call(handler.createEnvironment))
+	router.HandleFunc("GET /api/v1/projects/{project}/env/{env}", handler.Call(handler.scoped(handler.getEnvironment)))
+	router.HandleFunc("PUT /api/v1/projects/{project}/env/{env}", handler.Call(handler.scoped(handler.updateEnvironment)))
+	router.HandleFunc("DELETE /api/v1/projects/{project}/env/{env}", handler.Call(handler.scoped(handler.deleteEnvironment)))
+
+	// Get the permissions of a project
+	router.HandleFunc("GET /api/v1/projects/{project}/permissions", handler.Call(getProjectPermissions))
+	// Grant a role to an actor
+	router.HandleFunc("PUT /api/v1/projects/{project}/permissions/{actor}", handler.Call(setProjectPermission))
+	// Revoke the role of an actor
+	router.HandleFunc("DELETE /api/v1/projects/{project}/permissions/{actor}", handler.Call(deleteProjectPermission))
+}
+
+type Project struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Environments []string  `json:"environments,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ProjectRequest struct {
+	Name string `json:"name"`
+}
+
+type ProjectPermission struct {
+	Actor string `json:"actor"`
+	Role  string `json:"role"`
+}
+
+type ProjectPermissionRequest struct {
+	Role string `json:"role"`
+}
+
+func toProject(p database.Project) Project {
+	return Project{
+		ID:        p.ID,
+		Name:      p.Name,
+		CreatedAt: p.CreatedAt,
+	}
+}
+
+// requestProject returns the project a request refers to, from the {project}
+// path value or the ?project= query param, defaulting to DefaultProject
+func requestProject(ctx context.Context, db database.Querier, r *http.Request) (database.Project, Response, error) {
+	name := r.PathValue("project")
+	if name == "" {
+		name = r.URL.Query().Get("project")
+	}
+	return projectByName(ctx, db, name)
+}
+
+// projectByName returns the project with the given name, DefaultProject when empty
+func projectByName(ctx context.Context, db database.Querier, name string) (database.Project, Response, error) {
+	if name == "" {
+		name = DefaultProject
+	}
+
+	project, err := db.GetProjectByName(ctx, name)
+	if err != nil {
+		err = fmt.Errorf("project %s: %w", name, err)
+		code := http.StatusInternalServerError
+		if errors.Is(err, sql.ErrNoRows) {
+			code = http.StatusNotFound
+		}
+		return database.Project{}, Response{
+			Code:    code,
+			Message: "Project not found",
+			Error:   err.Error(),
+		}, err
+	}
+	return project, Response{}, nil
+}
+
+// authorize checks the actor of the request has at least role in a project.
+// Projects without permissions are open to everyone, projects with them are
+// closed to requests without an authenticated actor.
+func authorize(ctx context.Context, db database.Querier, r *http.Request, projectID int64, role string) (Response, error) {
+	count, err := db.CountProjectPermissions(ctx, projectID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to check permissions",
+			Error:   err.Error(),
+		}, err
+	}
+	if count == 0 {
+		return Response{}, nil
+	}
+	if !authenticated(r) {
+		err := fmt.Errorf("%w: %w", errForbidden, errUnauthenticatedActor)
+		return Response{
+			Code:    http.StatusForbidden,
+			Message: "Forbidden",
+			Error:   err.Error(),
+		}, err
+	}
+
+	permission, err := db.GetProjectPermission(ctx, database.GetProjectPermissionParams{
+		ProjectID: projectID,
+		Actor:     actor(r),
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to check permissions",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil || roleRanks[permission.Role] < roleRanks[role] {
+		err := fmt.Errorf("%w: %s requires the %s role", errForbidden, actor(r), role)
+		return Response{
+			Code:    http.StatusForbidden,
+			Message: "Forbidden",
+			Error:   err.Error(),
+		}, err
+	}
+	return Response{}, nil
+}
+
+// authorizeRequest checks the permissions of requests to a project or to an
+// environment: reads need the read role and any other method the write role.
+// Requests that don't refer to one are checked by their handler.
+func (eh *Handler) authorizeRequest(db database.Querier, r *http.Request) (Response, error) {
+	var projectID int64
+	switch {
+	case r.PathValue("project") != "":
+		project, err := db.GetProjectByName(r.Context(), r.PathValue("project"))
+		if err != nil {
+			// Let the handler report the missing project
+			return Response{}, nil
+		}
+		projectID = project.ID
+	case r.PathValue("id") != "":
+		envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			return Response{}, nil
+		}
+		env, err := eh.store.GetEnvironment(r.Context(), envID)
+		if err != nil {
+			return Response{}, nil
+		}
+		projectID = env.ProjectID
+	default:
+		return Response{}, nil
+	}
+
+	role := RoleWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		role = RoleRead
+	}
+	return authorize(r.Context(), db, r, projectID, role)
+}
+
+// checkEnvironmentName verifies name is valid and not taken in a project
+func checkEnvironmentName(ctx context.Context, s store.Store, projectID int64, name string) (Response, error) {
+	if name == "" {
+		err := errors.New("name is required")
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid environment name",
+			Error:   err.Error(),
+		}, err
+	}
+
+	_, err := s.GetEnvironmentByName(ctx, projectID, name)
+	switch {
+	case err == nil:
+		err := fmt.Errorf("environment %s already exists", name)
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Invalid environment name",
+			Error:   err.Error(),
+		}, err
+	case !errors.Is(err, store.ErrNotFound):
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Invalid environment name",
+			Error:   err.Error(),
+		}, err
+	}
+	return Response{}, nil
+}
+
+// scoped resolves the {env} name of a project route to its environment {id}
+// before calling handler
+func (eh *Handler) scoped(handler handlerFunc) handlerFunc {
+	return func(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+		project, resp, err := requestProject(r.Context(), db, r)
+		if err != nil {
+			return resp, err
+		}
+
+		env, err := eh.store.GetEnvironmentByName(r.Context(), project.ID, r.PathValue("env"))
+		if err != nil {
+			return Response{
+				Code:    http.StatusNotFound,
+				Message: "Environment not found",
+				Error:   err.Error(),
+			}, err
+		}
+
+		r.SetPathValue("id", strconv.FormatInt(env.ID, 10))
+		return handler(db, w, r)
+	}
+}
+
+func getProjects(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	projectsFromDB, err := db.GetAllProjects(r.Context())
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get projects",
+			Error:   err.Error(),
+		}, err
+	}
+
+	projects := make([]Project, 0, len(projectsFromDB))
+	for _, project := range projectsFromDB {
+		projects = append(projects, toProject(project))
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Projects retrieved",
+		Data:    projects,
+	}, nil
+}
+
+func createProject(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	var request ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to create project",
+			Error:   err.Error(),
+		}, err
+	}
+	if request.Name == "" {
+		err := errors.New("name is required")
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to create project",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if _, err := db.GetProjectByName(r.Context(), request.Name); err == nil {
+		err := fmt.Errorf("project %s already exists", request.Name)
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Failed to create project",
+			Error:   err.Error(),
+		}, err
+	}
+
+	project, err := db.CreateProject(r.Context(), request.Name)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to create project",
+			Error:   err.Error(),
+		}, err
+	}
+
+	// A known creator administers the project, anonymous or unauthenticated
+	// ones leave it open
+	if creator := actor(r); creator != anonymousActor && authenticated(r) {
+		if _, err := db.UpsertProjectPermission(r.Context(), database.UpsertProjectPermissionParams{
+			ProjectID: project.ID,
+			Actor:     creator,
+			Role:      RoleAdmin,
+		}); err != nil {
+			return Response{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to create project",
+				Error:   err.Error(),
+			}, err
+		}
+	}
+
+	return Response{
+		Code:    http.StatusCreated,
+		Message: "Project created",
+		Data:    toProject(project),
+	}, nil
+}
+
+func (eh *Handler) getProject(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	projectFromDB, resp, err := requestProject(r.Context(), db, r)
+	if err != nil {
+		return resp, err
+	}
+
+	envs, err := eh.store.ListEnvironments(r.Context(), store.ListOptions{
+		ProjectID: projectFromDB.ID,
+		Sort:      store.SortByCreation,
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get project",
+			Error:   err.Error(),
+		}, err
+	}
+
+	project := toProject(projectFromDB)
+	project.Environments = make([]string, 0, len(envs))
+	for _, env := range envs {
+		project.Environments = append(project.Environments, env.Name)
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Project retrieved",
+		Data:    project,
+	}, nil
+}
+
+func (eh *Handler) deleteProject(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	project, resp, err := requestProject(r.Context(), db, r)
+	if err != nil {
+		return resp, err
+	}
+	if resp, err := authorize(r.Context(), db, r, project.ID, RoleAdmin); err != nil {
+		return resp, err
+	}
+
+	if project.Name == DefaultProject {
+		err := errors.New("the default project can't be deleted")
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Failed to delete project",
+			Error:   err.Error(),
+		}, err
+	}
+
+	envs, err := eh.store.ListEnvironments(r.Context(), store.ListOptions{
+		ProjectID: project.ID,
+		Sort:      store.SortByCreation,
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete project",
+			Error:   err.Error(),
+		}, err
+	}
+	if len(envs) > 0 {
+		err := fmt.Errorf("project %s still has %d environments", project.Name, len(envs))
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Failed to delete project",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if err := db.DeleteProjectPermissions(r.Context(), project.ID); err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete project",
+			Error:   err.Error(),
+		}, err
+	}
+	if err := db.DeleteProject(r.Context(), project.ID); err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete project",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Project deleted",
+		Data:    nil,
+	}, nil
+}
+
+func getProjectPermissions(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	project, resp, err := requestProject(r.Context(), db, r)
+	if err != nil {
+		return resp, err
+	}
+
+	permissionsFromDB, err := db.GetProjectPermissions(r.Context(), project.ID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get permissions",
+			Error:   err.Error(),
+		}, err
+	}
+
+	permissions := make([]ProjectPermission, 0, len(permissionsFromDB))
+	for _, permission := range permissionsFromDB {
+		permissions = append(permissions, ProjectPermission{
+			Actor: permission.Actor,
+			Role:  permission.Role,
+		})
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Permissions retrieved",
+		Data:    permissions,
+	}, nil
+}
+
+// keepsAdmin reports whether a project still has an admin once the role of
+// actor changes to role, an empty role meaning it is revoked
+func keepsAdmin(ctx context.Context, db database.Querier, projectID int64, actor, role string) (bool, error) {
+	permissions, err := db.GetProjectPermissions(ctx, projectID)
+	if err != nil {
+		return false, err
+	}
+
+	remaining := 0
+	for _, permission := range permissions {
+		if permission.Actor == actor {
+			continue
+		}
+		remaining++
+		if permission.Role == RoleAdmin {
+			return true, nil
+		}
+	}
+	// Revoking the last permission opens the project again
+	return role == RoleAdmin || (role == "" && remaining == 0), nil
+}
+
+func setProjectPermission(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	// Permissions granted to actors anyone can claim protect nothing
+	if !authenticated(r) {
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Project permissions are disabled",
+			Error:   errUnauthenticatedActor.Error(),
+		}, errUnauthenticatedActor
+	}
+
+	project, resp, err := requestProject(r.Context(), db, r)
+	if err != nil {
+		return resp, err
+	}
+	if resp, err := authorize(r.Context(), db, r, project.ID, RoleAdmin); err != nil {
+		return resp, err
+	}
+
+	var request ProjectPermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to set permission",
+			Error:   err.Error(),
+		}, err
+	}
+	if _, ok := roleRanks[request.Role]; !ok {
+		err := fmt.Errorf("unknown role %q, must be one of %s, %s, %s", request.Role, RoleRead, RoleWrite, RoleAdmin)
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to set permission",
+			Error:   err.Error(),
+		}, err
+	}
+
+	ok, err := keepsAdmin(r.Context(), db, project.ID, r.PathValue("actor"), request.Role)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set permission",
+			Error:   err.Error(),
+		}, err
+	}
+	if !ok {
+		err := errors.New("a project with permissions needs an admin")
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Failed to set permission",
+			Error:   err.Error(),
+		}, err
+	}
+
+	permission, err := db.UpsertProjectPermission(r.Context(), database.UpsertProjectPermissionParams{
+		ProjectID: project.ID,
+		Actor:     r.PathValue("actor"),
+		Role:      request.Role,
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set permission",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Permission set",
+		Data: ProjectPermission{
+			Actor: permission.Actor,
+			Role:  permission.Role,
+		},
+	}, nil
+}
+
+func deleteProjectPermission(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	project, resp, err := requestProject(r.Context(), db, r)
+	if err != nil {
+		return resp, err
+	}
+	if resp, err := authorize(r.Context(), db, r, project.ID, RoleAdmin); err != nil {
+		return resp, err
+	}
+
+	ok, err := keepsAdmin(r.Context(), db, project.ID, r.PathValue("actor"), "")
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete permission",
+			Error:   err.Error(),
+		}, err
+	}
+	if !ok {
+		err := errors.New("a project with permissions needs an admin")
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Failed to delete permission",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if err := db.DeleteProjectPermission(r.Context(), database.DeleteProjectPermissionParams{
+		ProjectID: project.ID,
+		Actor:     r.PathValue("actor"),
+	}); err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete permission",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Permission deleted",
+		Data:    nil,
+	}, nil
+}
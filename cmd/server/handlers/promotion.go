@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/webhook"
+)
+
+const defaultPromotionsLimit = 50
+
+func registerPromotionRoutes(router *http.ServeMux, handler *Handler) {
+	// Compare two environments, ie: ?from=staging&to=production
+	router.HandleFunc("GET /api/v1/diff", handler.Call(handler.sqlStorage(diffEnvironments)))
+	// Copy keys from one environment into another
+	router.HandleFunc("POST /api/v1/promote", handler.Call(handler.sqlStorage(handler.promote)))
+	// Get the latest promotions of the projects the actor can read, ie: ?limit=20
+	router.HandleFunc("GET /api/v1/promotions", handler.Call(handler.sqlStorage(getPromotions)))
+}
+
+// DiffEntry compares a key between two environments. Values are only
+// included when requested with ?reveal=true, hashes can be compared instead.
+type DiffEntry struct {
+	Key       string `json:"key"`
+	FromHash  string `json:"from_hash,omitempty"`
+	ToHash    string `json:"to_hash,omitempty"`
+	FromValue string `json:"from_value,omitempty"`
+	ToValue   string `json:"to_value,omitempty"`
+}
+
+type Diff struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Added keys only exist in From
+	Added []DiffEntry `json:"added"`
+	// Removed keys only exist in To
+	Removed []DiffEntry `json:"removed"`
+	// Changed keys exist in both with different values
+	Changed []DiffEntry `json:"changed"`
+	// Unchanged keys exist in both with the same value
+	Unchanged []string `json:"unchanged"`
+}
+
+type PromoteRequest struct {
+	// Project of both environments, the default project when empty
+	Project string   `json:"project,omitempty"`
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Keys    []string `json:"keys"`
+}
+
+type Promotion struct {
+	ID                int64     `json:"id"`
+	FromEnvironmentID int64     `json:"from_environment_id"`
+	ToEnvironmentID   int64     `json:"to_environment_id"`
+	Keys              []string  `json:"keys"`
+	Actor             string    `json:"actor"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func toPromotion(p database.Promotion) (Promotion, error) {
+	var keys []string
+	if err := json.Unmarshal([]byte(p.Keys), &keys); err != nil {
+		return Promotion{}, err
+	}
+	return Promotion{
+		ID:                p.ID,
+		FromEnvironmentID: p.FromEnvironmentID,
+		ToEnvironmentID:   p.ToEnvironmentID,
+		Keys:              keys,
+		Actor:             p.Actor,
+		CreatedAt:         p.CreatedAt,
+	}, nil
+}
+
+// environmentValues returns the environment with the given name in a
+// project and its current values by key
+func environmentValues(ctx context.Context, db database.Querier, projectID int64, name string) (database.Environment, map[string]string, error) {
+	env, err := db.GetEnvironmentByName(ctx, database.GetEnvironmentByNameParams{
+		ProjectID: projectID,
+		Name:      name,
+	})
+	if err != nil {
+		return database.Environment{}, nil, fmt.Errorf("environment %s: %w", name, err)
+	}
+
+	valuesFromDB, err := db.GetValuesByEnvironmentID(ctx, env.ID)
+	if err != nil {
+		return database.Environment{}, nil, err
+	}
+
+	values := make(map[string]string, len(valuesFromDB))
+	for _, value := range toValues(valuesFromDB, false) {
+		values[value.Key] = value.Value
+	}
+	return env, values, nil
+}
+
+func diffEnvironments(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" || to == "" {
+		err := errors.New("from and to are required")
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to diff environments",
+			Error:   err.Error(),
+		}, err
+	}
+	reveal := r.URL.Query().Get("reveal") == "true"
+
+	// Both environments belong to ?project=, the default project otherwise
+	project, resp, err := requestProject(r.Context(), db, r)
+	if err != nil {
+		return resp, err
+	}
+	if resp, err := authorize(r.Context(), db, r, project.ID, RoleRead); err != nil {
+		return resp, err
+	}
+
+	_, sourceValues, err := environmentValues(r.Context(), db, project.ID, from)
+	if err != nil {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Failed to diff environments",
+			Error:   err.Error(),
+		}, err
+	}
+	_, targetValues, err := environmentValues(r.Context(), db, project.ID, to)
+	if err != nil {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Failed to diff environments",
+			Error:   err.Error(),
+		}, err
+	}
+
+	// Hashes are keyed for this response only, so they can be compared
+	// with each other but not used to guess low entropy values
+	hashKey := make([]byte, 32)
+	if _, err := rand.Read(hashKey); err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to diff environments",
+			Error:   err.Error(),
+		}, err
+	}
+	hash := func(value string) string {
+		mac := hmac.New(sha256.New, hashKey)
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	diff := Diff{
+		From:      from,
+		To:        to,
+		Added:     make([]DiffEntry, 0),
+		Removed:   make([]DiffEntry, 0),
+		Changed:   make([]DiffEntry, 0),
+		Unchanged: make([]string, 0),
+	}
+
+	keys := make([]string, 0, len(sourceValues)+len(targetValues))
+	for key := range sourceValues {
+		keys = append(keys, key)
+	}
+	for key := range targetValues {
+		if _, ok := sourceValues[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		fromValue, inFrom := sourceValues[key]
+		toValue, inTo := targetValues[key]
+
+		entry := DiffEntry{Key: key}
+		if inFrom {
+			entry.FromHash = hash(fromValue)
+		}
+		if inTo {
+			entry.ToHash = hash(toValue)
+		}
+		if reveal {
+			entry.FromValue, entry.ToValue = fromValue, toValue
+		}
+
+		switch {
+		case !inTo:
+			diff.Added = append(diff.Added, entry)
+		case !inFrom:
+			diff.Removed = append(diff.Removed, entry)
+		case fromValue != toValue:
+			diff.Changed = append(diff.Changed, entry)
+		default:
+			diff.Unchanged = append(diff.Unchanged, key)
+		}
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Environments compared",
+		Data:    diff,
+	}, nil
+}
+
+func (eh *Handler) promote(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	var request PromoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to promote",
+			Error:   err.Error(),
+		}, err
+	}
+	if request.From == "" || request.To == "" || len(request.Keys) == 0 {
+		err := errors.New("from, to and keys are required")
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to promote",
+			Error:   err.Error(),
+		}, err
+	}
+	if request.From == request.To {
+		err := errors.New("from and to must be different environments")
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to promote",
+			Error:   err.Error(),
+		}, err
+	}
+
+	project, resp, err := projectByName(r.Context(), db, request.Project)
+	if err != nil {
+		return resp, err
+	}
+	if resp, err := authorize(r.Context(), db, r, project.ID, RoleWrite); err != nil {
+		return resp, err
+	}
+
+	var (
+		target    database.Environment
+		promotion database.Promotion
+		// code is the status of the errors of the request, not the database
+		code int
+	)
+	err = eh.inTx(r.Context(), func(db database.Querier) error {
+		source, sourceValues, err := environmentValues(r.Context(), db, project.ID, request.From)
+		if errors.Is(err, sql.ErrNoRows) {
+			code = http.StatusNotFound
+		}
+		if err != nil {
+			return err
+		}
+		target, err = db.GetEnvironmentByName(r.Context(), database.GetEnvironmentByNameParams{
+			ProjectID: project.ID,
+			Name:      request.To,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			code = http.StatusNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("environment %s: %w", request.To, err)
+		}
+		// Ciphertext would be served as plaintext, and the other way around
+		if source.E2e != target.E2e {
+			code = http.StatusBadRequest
+			return fmt.Errorf("%s and %s must both be end-to-end encrypted or neither", request.From, request.To)
+		}
+
+		for _, key := range request.Keys {
+			value, ok := sourceValues[key]
+			if !ok {
+				code = http.StatusBadRequest
+				return fmt.Errorf("%s is not set in %s", key, request.From)
+			}
+			if _, _, err := database.SetValue(r.Context(), db, target.ID, key, value); err != nil {
+				return err
+			}
+		}
+
+		keys, err := json.Marshal(request.Keys)
+		if err != nil {
+			return err
+		}
+		promotion, err = db.CreatePromotion(r.Context(), database.CreatePromotionParams{
+			FromEnvironmentID: source.ID,
+			ToEnvironmentID:   target.ID,
+			Keys:              string(keys),
+			Actor:             actor(r),
+		})
+		return err
+	})
+	if err != nil {
+		resp := valueErrorResponse("Failed to promote", err)
+		if code != 0 {
+			resp.Code = code
+		}
+		return resp, err
+	}
+
+	eh.publish(r, webhook.NewEvent(webhook.EventEnvironmentUpdated, target.ID, target.Name, request.Keys...))
+
+	result, err := toPromotion(promotion)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to promote",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Keys promoted",
+		Data:    result,
+	}, nil
+}
+
+func getPromotions(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	limit := int64(defaultPromotionsLimit)
+	if param := r.URL.Query().Get("limit"); param != "" {
+		l, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || l <= 0 {
+			err = fmt.Errorf("invalid limit %q", param)
+			return Response{
+				Code:    http.StatusBadRequest,
+				Message: "Failed to get promotions",
+				Error:   err.Error(),
+			}, err
+		}
+		limit = l
+	}
+
+	// Promotions are listed newest first, skipping the ones of projects the
+	// actor can't read, until there are limit of them
+	promotions := make([]Promotion, 0, limit)
+	readable := make(map[int64]bool)
+	for beforeID := int64(math.MaxInt64); int64(len(promotions)) < limit; {
+		page, err := db.GetPromotionsBefore(r.Context(), database.GetPromotionsBeforeParams{
+			BeforeID: beforeID,
+			Limit:    limit,
+		})
+		if err != nil {
+			return Response{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to get promotions",
+				Error:   err.Error(),
+			}, err
+		}
+
+		for _, p := range page {
+			if int64(len(promotions)) == limit {
+				break
+			}
+			canRead, ok := readable[p.ProjectID]
+			if !ok {
+				resp, err := authorize(r.Context(), db, r, p.ProjectID, RoleRead)
+				if err != nil && !errors.Is(err, errForbidden) {
+					return resp, err
+				}
+				canRead = err == nil
+				readable[p.ProjectID] = canRead
+			}
+			if !canRead {
+				continue
+			}
+
+			promotion, err := toPromotion(database.Promotion{
+				ID:                p.ID,
+				FromEnvironmentID: p.FromEnvironmentID,
+				ToEnvironmentID:   p.ToEnvironmentID,
+				Keys:              p.Keys,
+				Actor:             p.Actor,
+				CreatedAt:         p.CreatedAt,
+			})
+			if err != nil {
+				return Response{
+					Code:    http.StatusInternalServerError,
+					Message: "Failed to get promotions",
+					Error:   err.Error(),
+				}, err
+			}
+			promotions = append(promotions, promotion)
+		}
+
+		if int64(len(page)) < limit {
+			break
+		}
+		beforeID = page[len(page)-1].ID
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Promotions retrieved",
+		Data:    promotions,
+	}, nil
+}
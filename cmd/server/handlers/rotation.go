@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+)
+
+const minRotationInterval = time.Minute
+
+func registerRotationRoutes(router *http.ServeMux, handler *Handler) {
+	// Get the rotation policies of an environment
+	router.HandleFunc("GET /api/v1/env/{id}/rotations", handler.Call(handler.sqlStorage(getRotationPolicies)))
+	// Create or replace the rotation policy of a key
+	router.HandleFunc("PUT /api/v1/env/{id}/rotations/{key}", handler.Call(handler.sqlStorage(handler.setRotationPolicy)))
+	// Remove the rotation policy of a key
+	router.HandleFunc("DELETE /api/v1/env/{id}/rotations/{key}", handler.Call(handler.sqlStorage(deleteRotationPolicy)))
+	// Rotate a key now
+	router.HandleFunc("POST /api/v1/env/{id}/rotations/{key}/rotate", handler.Call(handler.sqlStorage(handler.rotateValue)))
+}
+
+type RotationPolicy struct {
+	ID             int64           `json:"id"`
+	Key            string          `json:"key"`
+	Rotator        string          `json:"rotator"`
+	Params         json.RawMessage `json:"params"`
+	Interval       string          `json:"interval"`
+	NextRotationAt time.Time       `json:"next_rotation_at"`
+	LastRotatedAt  *time.Time      `json:"last_rotated_at"`
+	Failures       int64           `json:"failures"`
+	LastError      string          `json:"last_error"`
+}
+
+type RotationPolicyRequest struct {
+	Rotator string          `json:"rotator"`
+	Params  json.RawMessage `json:"params"`
+	// Interval between rotations, ie: 720h
+	Interval string `json:"interval"`
+	// RotateNow schedules the first rotation immediately instead of after Interval
+	RotateNow bool `json:"rotate_now"`
+}
+
+func toRotationPolicy(p database.RotationPolicy, key string) RotationPolicy {
+	policy := RotationPolicy{
+		ID:             p.ID,
+		Key:            key,
+		Rotator:        p.Rotator,
+		Params:         json.RawMessage(p.Params),
+		Interval:       (time.Duration(p.IntervalSeconds) * time.Second).String(),
+		NextRotationAt: p.NextRotationAt,
+		Failures:       p.Failures,
+		LastError:      p.LastError,
+	}
+	if p.LastRotatedAt.Valid {
+		policy.LastRotatedAt = &p.LastRotatedAt.Time
+	}
+	return policy
+}
+
+// getEnvironmentValue gets the value of the {key} path parameter in environment {id}
+func getEnvironmentValue(db database.Querier, r *http.Request) (database.EnvironmentValue, Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return database.EnvironmentValue{}, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid environment id",
+			Error:   err.Error(),
+		}, err
+	}
+
+	value, err := db.GetValueByKey(r.Context(), database.GetValueByKeyParams{
+		EnvironmentID: envID,
+		Key:           r.PathValue("key"),
+	})
+	if err != nil {
+		return database.EnvironmentValue{}, Response{
+			Code:    http.StatusNotFound,
+			Message: "Value not found",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return value, Response{}, nil
+}
+
+func getRotationPolicies(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to get rotation policies",
+			Error:   err.Error(),
+		}, err
+	}
+
+	policiesFromDB, err := db.GetRotationPoliciesByEnvironmentID(r.Context(), envID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get rotation policies",
+			Error:   err.Error(),
+		}, err
+	}
+
+	policies := make([]RotationPolicy, 0)
+	for _, p := range policiesFromDB {
+		policies = append(policies, toRotationPolicy(database.RotationPolicy{
+			ID:              p.ID,
+			ValueID:         p.ValueID,
+			Rotator:         p.Rotator,
+			Params:          p.Params,
+			IntervalSeconds: p.IntervalSeconds,
+			NextRotationAt:  p.NextRotationAt,
+			LastRotatedAt:   p.LastRotatedAt,
+			Failures:        p.Failures,
+			LastError:       p.LastError,
+		}, p.Key))
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Rotation policies retrieved",
+		Data:    policies,
+	}, nil
+}
+
+func (eh *Handler) setRotationPolicy(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	if eh.scheduler == nil {
+		err := errors.New("rotation is disabled")
+		return Response{
+			Code:    http.StatusNotImplemented,
+			Message: "Failed to set rotation policy",
+			Error:   err.Error(),
+		}, err
+	}
+
+	var request RotationPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to set rotation policy",
+			Error:   err.Error(),
+		}, err
+	}
+
+	interval, err := time.ParseDuration(request.Interval)
+	if err == nil && interval < minRotationInterval {
+		err = errors.New("interval must be at least " + minRotationInterval.String())
+	}
+	if err == nil {
+		_, err = eh.scheduler.Registry().Get(request.Rotator)
+	}
+	if err == nil && len(request.Params) > 0 {
+		var params map[string]any
+		if jsonErr := json.Unmarshal(request.Params, &params); jsonErr != nil {
+			err = errors.New("params must be a json object")
+		}
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to set rotation policy",
+			Error:   err.Error(),
+		}, err
+	}
+
+	value, resp, err := getEnvironmentValue(db, r)
+	if err != nil {
+		return resp, err
+	}
+	// Rotators produce the new values on the server
+	env, err := db.GetEnvironment(r.Context(), value.EnvironmentID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set rotation policy",
+			Error:   err.Error(),
+		}, err
+	}
+	if env.E2e {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to set rotation policy",
+			Error:   errE2EServerValue.Error(),
+		}, errE2EServerValue
+	}
+
+	params := "{}"
+	if len(request.Params) > 0 {
+		params = string(request.Params)
+	}
+
+	next := time.Now().UTC()
+	if !request.RotateNow {
+		next = next.Add(interval)
+	}
+
+	policy, err := db.UpsertRotationPolicy(r.Context(), database.UpsertRotationPolicyParams{
+		ValueID:         value.ID,
+		Rotator:         request.Rotator,
+		Params:          params,
+		IntervalSeconds: int64(interval / time.Second),
+		NextRotationAt:  next,
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set rotation policy",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Rotation policy saved",
+		Data:    toRotationPolicy(policy, value.Key),
+	}, nil
+}
+
+func deleteRotationPolicy(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	value, resp, err := getEnvironmentValue(db, r)
+	if err != nil {
+		return resp, err
+	}
+
+	if err := db.DeleteRotationPolicyByValueID(r.Context(), value.ID); err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete rotation policy",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Rotation policy deleted",
+		Data:    nil,
+	}, nil
+}
+
+func (eh *Handler) rotateValue(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	if eh.scheduler == nil {
+		err := errors.New("rotation is disabled")
+		return Response{
+			Code:    http.StatusNotImplemented,
+			Message: "Failed to rotate value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	value, resp, err := getEnvironmentValue(db, r)
+	if err != nil {
+		return resp, err
+	}
+
+	policy, err := db.GetRotationPolicyByValueID(r.Context(), value.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Value has no rotation policy",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to rotate value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	rotated, err := eh.scheduler.Rotate(r.Context(), policy)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadGateway,
+			Message: "Failed to rotate value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Value rotated",
+		Data: Value{
+			ID:    rotated.ID,
+			Key:   rotated.Key,
+			Value: rotated.Value,
+		},
+	}, nil
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/schema"
+)
+
+func registerSchemaRoutes(router *http.ServeMux, handler *Handler) {
+	// Get the value schemas of an environment
+	router.HandleFunc("GET /api/v1/env/{id}/schema", handler.Call(handler.sqlStorage(getValueSchemas)))
+	// Create or replace the schema of a key
+	router.HandleFunc("PUT /api/v1/env/{id}/schema/{key}", handler.Call(handler.sqlStorage(setValueSchema)))
+	// Remove the schema of a key
+	router.HandleFunc("DELETE /api/v1/env/{id}/schema/{key}", handler.Call(handler.sqlStorage(deleteValueSchema)))
+	// Check the values of an environment against its schemas
+	router.HandleFunc("GET /api/v1/env/{id}/check", handler.Call(handler.sqlStorage(checkEnvironment)))
+}
+
+type ValueSchema struct {
+	ID        int64       `json:"id"`
+	Key       string      `json:"key"`
+	Rule      schema.Rule `json:"rule"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// EnvironmentCheck compares the values of an environment with its manifest,
+// the keys that have a schema
+type EnvironmentCheck struct {
+	Complete bool `json:"complete"`
+	// Missing are the required keys without a value
+	Missing []string `json:"missing"`
+	// Extra are the keys with a value but no schema
+	Extra []string `json:"extra"`
+}
+
+func toValueSchema(s database.ValueSchema) (ValueSchema, error) {
+	rule, err := s.Rule()
+	if err != nil {
+		return ValueSchema{}, err
+	}
+	return ValueSchema{
+		ID:        s.ID,
+		Key:       s.Key,
+		Rule:      rule,
+		UpdatedAt: s.UpdatedAt,
+	}, nil
+}
+
+func toNullFloat64(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
+
+// validateValue checks a value against the schema of its key, environments
+// outside the database have no schemas
+func (eh *Handler) validateValue(ctx context.Context, db database.Querier, envID int64, key, value string) error {
+	if !eh.sqlStore() {
+		return nil
+	}
+	return database.ValidateValue(ctx, db, envID, key, value)
+}
+
+// validateValues checks the values of a write request against the schemas
+// of environment envID before anything is written
+func (eh *Handler) validateValues(ctx context.Context, db database.Querier, envID int64, values []Value) (Response, error) {
+	for _, value := range values {
+		if err := eh.validateValue(ctx, db, envID, value.Key, value.Value); err != nil {
+			return valueErrorResponse("Invalid value for "+value.Key, err), err
+		}
+	}
+	return Response{}, nil
+}
+
+// valueErrorResponse reports a failed write, as a bad request when the
+// value doesn't satisfy its schema
+func valueErrorResponse(message string, err error) Response {
+	code := http.StatusInternalServerError
+	if schema.IsValidationError(err) {
+		code = http.StatusBadRequest
+	}
+	return Response{
+		Code:    code,
+		Message: message,
+		Error:   err.Error(),
+	}
+}
+
+// withTypes sets the type of the values that have a schema
+func withTypes(ctx context.Context, db database.Querier, envID int64, values []Value) ([]Value, error) {
+	schemas, err := db.GetValueSchemasByEnvironmentID(ctx, envID)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]string, len(schemas))
+	for _, s := range schemas {
+		types[s.Key] = s.Type
+	}
+	for i, value := range values {
+		values[i].Type = types[value.Key]
+	}
+	return values, nil
+}
+
+func getValueSchemas(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to get value schemas",
+			Error:   err.Error(),
+		}, err
+	}
+
+	schemasFromDB, err := db.GetValueSchemasByEnvironmentID(r.Context(), envID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get value schemas",
+			Error:   err.Error(),
+		}, err
+	}
+
+	schemas := make([]ValueSchema, 0, len(schemasFromDB))
+	for _, s := range schemasFromDB {
+		valueSchema, err := toValueSchema(s)
+		if err != nil {
+			return Response{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to get value schemas",
+				Error:   err.Error(),
+			}, err
+		}
+		schemas = append(schemas, valueSchema)
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Value schemas retrieved",
+		Data:    schemas,
+	}, nil
+}
+
+func setValueSchema(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to set value schema",
+			Error:   err.Error(),
+		}, err
+	}
+
+	var rule schema.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to set value schema",
+			Error:   err.Error(),
+		}, err
+	}
+	if rule.Type == "" {
+		rule.Type = schema.TypeString
+	}
+	if err := rule.Check(); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid value schema",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if _, err := db.GetEnvironment(r.Context(), envID); err != nil {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found",
+			Error:   err.Error(),
+		}, err
+	}
+
+	key := r.PathValue("key")
+
+	// The current value, if any, must already satisfy the new schema
+	value, err := db.GetValueByKey(r.Context(), database.GetValueByKeyParams{
+		EnvironmentID: envID,
+		Key:           key,
+	})
+	switch {
+	case err == nil:
+		if err := rule.Validate(key, value.Value); err != nil {
+			return Response{
+				Code:    http.StatusConflict,
+				Message: "Current value doesn't satisfy the schema",
+				Error:   err.Error(),
+			}, err
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set value schema",
+			Error:   err.Error(),
+		}, err
+	}
+
+	enum := rule.Enum
+	if enum == nil {
+		enum = []string{}
+	}
+	encodedEnum, err := json.Marshal(enum)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set value schema",
+			Error:   err.Error(),
+		}, err
+	}
+
+	stored, err := db.UpsertValueSchema(r.Context(), database.UpsertValueSchemaParams{
+		EnvironmentID: envID,
+		Key:           key,
+		Type:          rule.Type,
+		Pattern:       rule.Pattern,
+		Enum:          string(encodedEnum),
+		Min:           toNullFloat64(rule.Min),
+		Max:           toNullFloat64(rule.Max),
+		Required:      rule.Required,
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set value schema",
+			Error:   err.Error(),
+		}, err
+	}
+
+	valueSchema, err := toValueSchema(stored)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to set value schema",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Value schema set",
+		Data:    valueSchema,
+	}, nil
+}
+
+func deleteValueSchema(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to delete value schema",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if err := db.DeleteValueSchema(r.Context(), database.DeleteValueSchemaParams{
+		EnvironmentID: envID,
+		Key:           r.PathValue("key"),
+	}); err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete value schema",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Value schema deleted",
+		Data:    nil,
+	}, nil
+}
+
+func checkEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to check environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if _, err := db.GetEnvironment(r.Context(), envID); err != nil {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found",
+			Error:   err.Error(),
+		}, err
+	}
+
+	schemas, err := db.GetValueSchemasByEnvironmentID(r.Context(), envID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to check environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	valuesFromDB, err := db.GetValuesByEnvironmentID(r.Context(), envID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to check environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	// Expired values are not served, so they don't count as set
+	set := make(map[string]bool)
+	for _, value := range toValues(valuesFromDB, false) {
+		set[value.Key] = true
+	}
+
+	check := EnvironmentCheck{
+		Missing: make([]string, 0),
+		Extra:   make([]string, 0),
+	}
+	declared := make(map[string]bool, len(schemas))
+	for _, s := range schemas {
+		declared[s.Key] = true
+		if s.Required && !set[s.Key] {
+			check.Missing = append(check.Missing, s.Key)
+		}
+	}
+	// Without a manifest every key would be extra
+	if len(schemas) > 0 {
+		for _, value := range valuesFromDB {
+			if set[value.Key] && !declared[value.Key] {
+				check.Extra = append(check.Extra, value.Key)
+			}
+		}
+	}
+	check.Complete = len(check.Missing) == 0
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Environment checked",
+		Data:    check,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/keyring"
+	"go.uber.org/zap"
+)
+
+func registerSealRoutes(router *http.ServeMux, handler *Handler) {
+	// Get whether the server is sealed and how many shares were given
+	router.HandleFunc("GET /api/v1/seal", handler.CallSealed(handler.getSealStatus))
+	// Give a share of the master key, the server is unsealed once the
+	// threshold is reached
+	router.HandleFunc("POST /api/v1/unseal", handler.CallSealed(handler.unseal))
+	// Forget the master key until it is unsealed again
+	router.HandleFunc("POST /api/v1/seal", handler.CallSealed(handler.sealServer))
+}
+
+type UnsealRequest struct {
+	// Share is a share of the master key, as hex or base64
+	Share string `json:"share"`
+}
+
+// sealer returns the master key split in shares, or a response explaining
+// there is none
+func (eh *Handler) sealer() (*keyring.ShamirProvider, Response, error) {
+	if eh.seal == nil {
+		err := errors.New("no master key is split in shares")
+		return nil, Response{
+			Code:    http.StatusConflict,
+			Message: "Sealing is disabled",
+			Error:   err.Error(),
+		}, err
+	}
+	return eh.seal, Response{}, nil
+}
+
+func (eh *Handler) getSealStatus(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	provider, resp, err := eh.sealer()
+	if err != nil {
+		return resp, err
+	}
+
+	return Response{
+		Data:    provider.Status(),
+		Code:    http.StatusOK,
+		Message: "Seal status retrieved",
+	}, nil
+}
+
+func (eh *Handler) unseal(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	provider, resp, err := eh.sealer()
+	if err != nil {
+		return resp, err
+	}
+
+	var request UnsealRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to unseal",
+			Error:   err.Error(),
+		}, err
+	}
+	share, err := encryption.ParseBytes(request.Share)
+	if err == nil && len(share) == 0 {
+		err = errors.New("share is required")
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid share",
+			Error:   err.Error(),
+		}, err
+	}
+
+	wasSealed := provider.Status().Sealed
+	status, err := provider.Unseal(share)
+	if err != nil {
+		// Every error is about the shares given
+		message := "Invalid share"
+		if errors.Is(err, keyring.ErrShareMismatch) {
+			message = "The shares don't recover the master key, give them again"
+		}
+		return Response{
+			Data:    status,
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Error:   err.Error(),
+		}, err
+	}
+
+	message := "Share accepted"
+	if wasSealed && !status.Sealed {
+		message = "Server unsealed"
+		zap.L().Info("Server unsealed", zap.String("actor", actor(r)))
+	}
+	return Response{
+		Data:    status,
+		Code:    http.StatusOK,
+		Message: message,
+	}, nil
+}
+
+func (eh *Handler) sealServer(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	if resp, err := eh.authorizeAdmin(r); err != nil {
+		return resp, err
+	}
+	provider, resp, err := eh.sealer()
+	if err != nil {
+		return resp, err
+	}
+
+	provider.Seal()
+	zap.L().Info("Server sealed", zap.String("actor", actor(r)))
+
+	return Response{
+		Data:    provider.Status(),
+		Code:    http.StatusOK,
+		Message: "Server sealed",
+	}, nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/rodrwan/secretly/internal/database/dbtest"
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/keyring"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+// sealedClient serves a handler sealed by a master key split in 5 shares, 3
+// of them unseal it
+func sealedClient(t *testing.T, opts ...Option) (*client, *keyring.ShamirProvider, [][]byte) {
+	t.Helper()
+	key := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	shares, check, err := keyring.SplitKey(key, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := keyring.NewShamirProvider("1", 3, check)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := dbtest.SQLite(t)
+	c := newClient(t, db, store.NewSQL(db), append([]Option{WithSeal(provider), WithAdmins([]string{"ops"})}, opts...)...)
+	return c, provider, shares
+}
+
+func unsealBody(share []byte) string {
+	return `{"share": "` + hex.EncodeToString(share) + `"}`
+}
+
+func TestSealUnseal(t *testing.T) {
+	c, provider, shares := sealedClient(t)
+
+	if resp := c.do(t, http.MethodGet, "/api/v1/projects", "", ""); resp.Code != http.StatusServiceUnavailable {
+		t.Errorf("a request to a sealed server = %d, want 503", resp.Code)
+	}
+
+	for i, share := range shares[:3] {
+		resp := c.do(t, http.MethodPost, "/api/v1/unseal", "", unsealBody(share))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("share %d = %d %s", i+1, resp.Code, resp.Error)
+		}
+	}
+	if provider.Status().Sealed {
+		t.Fatal("the server is sealed after 3 shares")
+	}
+	if resp := c.do(t, http.MethodGet, "/api/v1/projects", "", ""); resp.Code != http.StatusOK {
+		t.Errorf("a request to the unsealed server = %d %s", resp.Code, resp.Error)
+	}
+
+	if resp := c.do(t, http.MethodPost, "/api/v1/seal", "ops", ""); resp.Code != http.StatusOK {
+		t.Fatalf("sealing as an admin = %d %s", resp.Code, resp.Error)
+	}
+	if !provider.Status().Sealed {
+		t.Error("the server isn't sealed")
+	}
+	if resp := c.do(t, http.MethodGet, "/api/v1/projects", "", ""); resp.Code != http.StatusServiceUnavailable {
+		t.Errorf("a request to the sealed server = %d, want 503", resp.Code)
+	}
+
+	t.Run("shares of another key", func(t *testing.T) {
+		other, _, err := keyring.SplitKey(make([]byte, encryption.KeySize), 3, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp testResponse
+		for _, share := range other {
+			resp = c.do(t, http.MethodPost, "/api/v1/unseal", "", unsealBody(share))
+		}
+		if resp.Code != http.StatusBadRequest || !provider.Status().Sealed {
+			t.Errorf("unsealing with the shares of another key = %d, sealed %v", resp.Code, provider.Status().Sealed)
+		}
+	})
+}
+
+func TestSealSpoofedActor(t *testing.T) {
+	tests := []struct {
+		name          string
+		authenticated bool
+		actor         string
+	}{
+		{"claimed admin", false, "ops"},
+		{"not an admin", true, "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, provider, shares := sealedClient(t, WithAuthenticatedActors(tt.authenticated))
+			for _, share := range shares[:3] {
+				if _, err := provider.Unseal(share); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if resp := c.do(t, http.MethodPost, "/api/v1/seal", tt.actor, ""); resp.Code != http.StatusForbidden {
+				t.Errorf("sealing = %d %s, want 403", resp.Code, resp.Error)
+			}
+			if provider.Status().Sealed {
+				t.Error("the server was sealed")
+			}
+		})
+	}
+}
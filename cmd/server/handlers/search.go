@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rodrwan/secretly/internal/database"
+)
+
+const defaultSearchLimit = 50
+
+const (
+	SearchKindEnvironment = "environment"
+	SearchKindValue       = "value"
+
+	// SearchMatchMetadata results matched the name, key, description or tags
+	SearchMatchMetadata = "metadata"
+	// SearchMatchValueHash results matched ?value_hash=
+	SearchMatchValueHash = "value_hash"
+)
+
+func registerSearchRoutes(router *http.ServeMux, handler *Handler) {
+	// Search environments and keys by name, description and tags, ie: ?q=stripe
+	router.HandleFunc("GET /api/v1/search", handler.Call(handler.sqlStorage(handler.search)))
+}
+
+type SearchResult struct {
+	Kind            string `json:"kind"`
+	Project         string `json:"project"`
+	EnvironmentID   int64  `json:"environment_id"`
+	EnvironmentName string `json:"environment_name"`
+	// Key is only set for values
+	Key string `json:"key,omitempty"`
+	Metadata
+	Match string `json:"match"`
+}
+
+// ftsQuery turns free text into a full text query matching all of its words,
+// the last one as a prefix so results show up while typing
+func ftsQuery(q string) string {
+	words := strings.Fields(q)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	if len(words) > 0 {
+		words[len(words)-1] += "*"
+	}
+	return strings.Join(words, " ")
+}
+
+// searchScope keeps track of the projects the actor can see while searching,
+// or listing the values expiring soon
+type searchScope struct {
+	db      database.Querier
+	r       *http.Request
+	names   map[int64]string
+	allowed map[string]bool
+}
+
+func newSearchScope(db database.Querier, r *http.Request) *searchScope {
+	return &searchScope{
+		db:      db,
+		r:       r,
+		names:   make(map[int64]string),
+		allowed: make(map[string]bool),
+	}
+}
+
+// can reports whether the actor has role in the project. Results of other
+// projects are left out instead of failing the search.
+func (s *searchScope) can(projectID int64, role string) (bool, error) {
+	cacheKey := fmt.Sprintf("%d:%s", projectID, role)
+	if allowed, ok := s.allowed[cacheKey]; ok {
+		return allowed, nil
+	}
+
+	_, err := authorize(s.r.Context(), s.db, s.r, projectID, role)
+	if err != nil && !errors.Is(err, errForbidden) {
+		return false, err
+	}
+	s.allowed[cacheKey] = err == nil
+	return err == nil, nil
+}
+
+func (s *searchScope) projectName(projectID int64) (string, error) {
+	if name, ok := s.names[projectID]; ok {
+		return name, nil
+	}
+	project, err := s.db.GetProject(s.r.Context(), projectID)
+	if err != nil {
+		return "", err
+	}
+	s.names[projectID] = project.Name
+	return project.Name, nil
+}
+
+func (eh *Handler) search(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	valueHash := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("value_hash")))
+	if q == "" && valueHash == "" {
+		err := errors.New("q or value_hash is required")
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to search",
+			Error:   err.Error(),
+		}, err
+	}
+
+	limit := defaultSearchLimit
+	if param := r.URL.Query().Get("limit"); param != "" {
+		l, err := strconv.Atoi(param)
+		if err != nil || l <= 0 {
+			err = fmt.Errorf("invalid limit %q", param)
+			return Response{
+				Code:    http.StatusBadRequest,
+				Message: "Failed to search",
+				Error:   err.Error(),
+			}, err
+		}
+		limit = l
+	}
+
+	// Searches every project the actor can read, unless ?project= is given
+	var projectID int64
+	if name := r.URL.Query().Get("project"); name != "" {
+		project, resp, err := projectByName(r.Context(), db, name)
+		if err != nil {
+			return resp, err
+		}
+		if resp, err := authorize(r.Context(), db, r, project.ID, RoleRead); err != nil {
+			return resp, err
+		}
+		projectID = project.ID
+	}
+	inScope := func(id int64) bool {
+		return projectID == 0 || projectID == id
+	}
+
+	scope := newSearchScope(db, r)
+	results := make([]SearchResult, 0)
+
+	if q != "" {
+		envs, err := db.SearchEnvironments(r.Context(), ftsQuery(q))
+		if err != nil {
+			return searchErrorResponse(err), err
+		}
+		for _, env := range envs {
+			if !inScope(env.ProjectID) {
+				continue
+			}
+			result, ok, err := scope.result(env.ProjectID, RoleRead)
+			if err != nil {
+				return searchErrorResponse(err), err
+			}
+			if !ok {
+				continue
+			}
+			result.Kind = SearchKindEnvironment
+			result.EnvironmentID = env.ID
+			result.EnvironmentName = env.Name
+			result.Metadata = toMetadata(env.Description, env.Owner, env.Link, env.Tags)
+			result.Match = SearchMatchMetadata
+			results = append(results, result)
+		}
+
+		values, err := db.SearchValues(r.Context(), ftsQuery(q))
+		if err != nil {
+			return searchErrorResponse(err), err
+		}
+		for _, value := range values {
+			if !inScope(value.ProjectID) {
+				continue
+			}
+			result, ok, err := scope.result(value.ProjectID, RoleRead)
+			if err != nil {
+				return searchErrorResponse(err), err
+			}
+			if !ok {
+				continue
+			}
+			result.Kind = SearchKindValue
+			result.EnvironmentID = value.EnvironmentID
+			result.EnvironmentName = value.Name
+			result.Key = value.Key
+			result.Metadata = toMetadata(value.Description, value.Owner, value.Link, value.Tags)
+			result.Match = SearchMatchMetadata
+			results = append(results, result)
+		}
+	}
+
+	if valueHash != "" {
+		matches, resp, err := eh.searchValueHash(db, r, scope, valueHash, projectID)
+		if err != nil {
+			return resp, err
+		}
+		results = append(results, matches...)
+	}
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Search results",
+		Data:    results,
+	}, nil
+}
+
+// result starts a result in the project, ok is false when the actor lacks role in it
+func (s *searchScope) result(projectID int64, role string) (SearchResult, bool, error) {
+	allowed, err := s.can(projectID, role)
+	if err != nil || !allowed {
+		return SearchResult{}, false, err
+	}
+	project, err := s.projectName(projectID)
+	if err != nil {
+		return SearchResult{}, false, err
+	}
+	return SearchResult{Project: project}, true, nil
+}
+
+// searchValueHash finds the values whose SHA-256 is valueHash. Values are
+// only compared in the projects where the actor is an admin.
+func (eh *Handler) searchValueHash(db database.Querier, r *http.Request, scope *searchScope, valueHash string, projectID int64) ([]SearchResult, Response, error) {
+	if !eh.valueHashSearch {
+		err := fmt.Errorf("%w: value hash search is disabled", errForbidden)
+		return nil, Response{
+			Code:    http.StatusForbidden,
+			Message: "Forbidden",
+			Error:   err.Error(),
+		}, err
+	}
+
+	want, err := hex.DecodeString(valueHash)
+	if err == nil && len(want) != sha256.Size {
+		err = errors.New("value_hash must be a hex encoded SHA-256")
+	}
+	if err != nil {
+		return nil, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to search",
+			Error:   err.Error(),
+		}, err
+	}
+
+	projectIDs := []int64{projectID}
+	if projectID == 0 {
+		projects, err := db.GetAllProjects(r.Context())
+		if err != nil {
+			return nil, searchErrorResponse(err), err
+		}
+		projectIDs = projectIDs[:0]
+		for _, project := range projects {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	} else if resp, err := authorize(r.Context(), db, r, projectID, RoleAdmin); err != nil {
+		return nil, resp, err
+	}
+
+	results := make([]SearchResult, 0)
+	for _, id := range projectIDs {
+		result, ok, err := scope.result(id, RoleAdmin)
+		if err != nil {
+			return nil, searchErrorResponse(err), err
+		}
+		if !ok {
+			continue
+		}
+
+		values, err := db.GetValuesByProjectID(r.Context(), id)
+		if err != nil {
+			return nil, searchErrorResponse(err), err
+		}
+		for _, value := range values {
+			sum := sha256.Sum256([]byte(value.Value))
+			if subtle.ConstantTimeCompare(sum[:], want) != 1 {
+				continue
+			}
+			match := result
+			match.Kind = SearchKindValue
+			match.EnvironmentID = value.EnvironmentID
+			match.EnvironmentName = value.Name
+			match.Key = value.Key
+			match.Metadata = toMetadata(value.Description, value.Owner, value.Link, value.Tags)
+			match.Match = SearchMatchValueHash
+			results = append(results, match)
+		}
+	}
+
+	return results, Response{}, nil
+}
+
+func searchErrorResponse(err error) Response {
+	return Response{
+		Code:    http.StatusInternalServerError,
+		Message: "Failed to search",
+		Error:   err.Error(),
+	}
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/store"
+	"github.com/rodrwan/secretly/internal/webhook"
+)
+
+func registerTrashRoutes(router *http.ServeMux, handler *Handler) {
+	// Get the deleted environments and values of a project, ie: ?project=billing
+	router.HandleFunc("GET /api/v1/trash", handler.Call(handler.sqlStorage(handler.getTrash)))
+	// Restore a deleted environment with its values
+	router.HandleFunc("POST /api/v1/trash/environments/{trashed}/restore", handler.Call(handler.sqlStorage(handler.restoreEnvironment)))
+	// Permanently delete an environment from the trash
+	router.HandleFunc("DELETE /api/v1/trash/environments/{trashed}", handler.Call(handler.sqlStorage(handler.purgeEnvironment)))
+	// Restore a deleted value
+	router.HandleFunc("POST /api/v1/trash/values/{trashed}/restore", handler.Call(handler.sqlStorage(handler.restoreValue)))
+	// Permanently delete a value from the trash
+	router.HandleFunc("DELETE /api/v1/trash/values/{trashed}", handler.Call(handler.sqlStorage(handler.purgeValue)))
+}
+
+type TrashedEnvironment struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+type TrashedValue struct {
+	ID              int64     `json:"id"`
+	EnvironmentID   int64     `json:"environment_id"`
+	EnvironmentName string    `json:"environment_name"`
+	Key             string    `json:"key"`
+	DeletedAt       time.Time `json:"deleted_at"`
+	PurgeAt         time.Time `json:"purge_at"`
+}
+
+type Trash struct {
+	Environments []TrashedEnvironment `json:"environments"`
+	Values       []TrashedValue       `json:"values"`
+}
+
+// restorable reports whether something deleted at deletedAt is still within
+// the retention window
+func (eh *Handler) restorable(deletedAt sql.NullTime) bool {
+	return eh.trashRetention <= 0 || time.Now().UTC().Before(deletedAt.Time.Add(eh.trashRetention))
+}
+
+func trashedID(r *http.Request) (int64, Response, error) {
+	id, err := strconv.ParseInt(r.PathValue("trashed"), 10, 64)
+	if err != nil {
+		return 0, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid id",
+			Error:   err.Error(),
+		}, err
+	}
+	return id, Response{}, nil
+}
+
+func (eh *Handler) getTrash(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	project, resp, err := requestProject(r.Context(), db, r)
+	if err != nil {
+		return resp, err
+	}
+	if resp, err := authorize(r.Context(), db, r, project.ID, RoleRead); err != nil {
+		return resp, err
+	}
+
+	envs, err := db.GetDeletedEnvironmentsByProjectID(r.Context(), project.ID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get trash",
+			Error:   err.Error(),
+		}, err
+	}
+
+	values, err := db.GetDeletedValuesByProjectID(r.Context(), project.ID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get trash",
+			Error:   err.Error(),
+		}, err
+	}
+
+	trash := Trash{
+		Environments: make([]TrashedEnvironment, 0, len(envs)),
+		Values:       make([]TrashedValue, 0, len(values)),
+	}
+	for _, env := range envs {
+		trash.Environments = append(trash.Environments, TrashedEnvironment{
+			ID:        env.ID,
+			Name:      env.Name,
+			DeletedAt: env.DeletedAt.Time,
+			PurgeAt:   env.DeletedAt.Time.Add(eh.trashRetention),
+		})
+	}
+	for _, value := range values {
+		trash.Values = append(trash.Values, TrashedValue{
+			ID:              value.ID,
+			EnvironmentID:   value.EnvironmentID,
+			EnvironmentName: value.Name,
+			Key:             value.Key,
+			DeletedAt:       value.DeletedAt.Time,
+			PurgeAt:         value.DeletedAt.Time.Add(eh.trashRetention),
+		})
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Trash retrieved",
+		Data:    trash,
+	}, nil
+}
+
+func (eh *Handler) restoreEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, resp, err := trashedID(r)
+	if err != nil {
+		return resp, err
+	}
+
+	env, err := db.GetDeletedEnvironment(r.Context(), envID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found in the trash",
+			Error:   err.Error(),
+		}, err
+	}
+	if resp, err := authorize(r.Context(), db, r, env.ProjectID, RoleWrite); err != nil {
+		return resp, err
+	}
+
+	if !eh.restorable(env.DeletedAt) {
+		err := errors.New("the retention window has passed")
+		return Response{
+			Code:    http.StatusGone,
+			Message: "Failed to restore environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	// Another environment may have taken the name in the meantime
+	if resp, err := checkEnvironmentName(r.Context(), store.NewSQLQuerier(db), env.ProjectID, env.Name); err != nil {
+		return resp, err
+	}
+
+	restored, err := db.RestoreEnvironment(r.Context(), env.ID)
+	if database.IsUniqueViolation(err) {
+		err = fmt.Errorf("environment %s: %w", env.Name, store.ErrExists)
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Invalid environment name",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to restore environment",
+			Error:   err.Error(),
+		}, err
+	}
+	env = restored
+
+	values, err := db.GetValuesByEnvironmentID(r.Context(), env.ID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to restore environment",
+			Error:   err.Error(),
+		}, err
+	}
+	keys := make([]string, 0, len(values))
+	for _, value := range values {
+		keys = append(keys, value.Key)
+	}
+	eh.publish(r, webhook.NewEvent(webhook.EventEnvironmentRestored, env.ID, env.Name, keys...))
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Environment restored",
+		Data:    toEnvironment(env, toValues(values, false)),
+	}, nil
+}
+
+func (eh *Handler) purgeEnvironment(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, resp, err := trashedID(r)
+	if err != nil {
+		return resp, err
+	}
+
+	env, err := db.GetDeletedEnvironment(r.Context(), envID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found in the trash",
+			Error:   err.Error(),
+		}, err
+	}
+	if resp, err := authorize(r.Context(), db, r, env.ProjectID, RoleWrite); err != nil {
+		return resp, err
+	}
+
+	if err := eh.inTx(r.Context(), func(db database.Querier) error {
+		return database.PurgeEnvironment(r.Context(), db, env.ID)
+	}); err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to purge environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Environment purged",
+		Data:    nil,
+	}, nil
+}
+
+// trashedValue gets the deleted value {trashed} and checks the actor can
+// write to its environment, which must not be deleted itself
+func trashedValue(db database.Querier, r *http.Request) (database.EnvironmentValue, database.Environment, Response, error) {
+	valueID, resp, err := trashedID(r)
+	if err != nil {
+		return database.EnvironmentValue{}, database.Environment{}, resp, err
+	}
+
+	value, err := db.GetDeletedValue(r.Context(), valueID)
+	if err != nil {
+		return database.EnvironmentValue{}, database.Environment{}, Response{
+			Code:    http.StatusNotFound,
+			Message: "Value not found in the trash",
+			Error:   err.Error(),
+		}, err
+	}
+
+	env, err := db.GetEnvironment(r.Context(), value.EnvironmentID)
+	if err != nil {
+		err = fmt.Errorf("environment of the value: %w", err)
+		return database.EnvironmentValue{}, database.Environment{}, Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found",
+			Error:   err.Error(),
+		}, err
+	}
+	if resp, err := authorize(r.Context(), db, r, env.ProjectID, RoleWrite); err != nil {
+		return database.EnvironmentValue{}, database.Environment{}, resp, err
+	}
+
+	return value, env, Response{}, nil
+}
+
+func (eh *Handler) restoreValue(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	value, env, resp, err := trashedValue(db, r)
+	if err != nil {
+		return resp, err
+	}
+
+	if !eh.restorable(value.DeletedAt) {
+		err := errors.New("the retention window has passed")
+		return Response{
+			Code:    http.StatusGone,
+			Message: "Failed to restore value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	// The key may have been set again in the meantime
+	_, err = db.GetValueByKey(r.Context(), database.GetValueByKeyParams{
+		EnvironmentID: env.ID,
+		Key:           value.Key,
+	})
+	switch {
+	case err == nil:
+		err := fmt.Errorf("%s is already set", value.Key)
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Failed to restore value",
+			Error:   err.Error(),
+		}, err
+	case !errors.Is(err, sql.ErrNoRows):
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to restore value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if _, err := db.RestoreValue(r.Context(), value.ID); err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to restore value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	eh.publish(r, webhook.NewEvent(webhook.EventValueRestored, env.ID, env.Name, value.Key))
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Value restored",
+		Data:    nil,
+	}, nil
+}
+
+func (eh *Handler) purgeValue(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	value, _, resp, err := trashedValue(db, r)
+	if err != nil {
+		return resp, err
+	}
+
+	if err := eh.inTx(r.Context(), func(db database.Querier) error {
+		return database.PurgeValue(r.Context(), db, value.ID)
+	}); err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to purge value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Value purged",
+		Data:    nil,
+	}, nil
+}
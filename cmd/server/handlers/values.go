@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/store"
+	"github.com/rodrwan/secretly/internal/webhook"
+)
+
+func registerValueRoutes(router *http.ServeMux, handler *Handler) {
+	// Get the versions of {key} in environment {id}, the latest first
+	router.HandleFunc("GET /api/v1/env/{id}/value/{key}/versions", handler.Call(handler.getValueVersions))
+	// Delete the value of {key} in environment {id}
+	router.HandleFunc("DELETE /api/v1/env/{id}/value/{key}", handler.Call(handler.deleteValue))
+	// Rename {key} in environment {id}, keeping its value, versions and policies
+	router.HandleFunc("POST /api/v1/env/{id}/value/{key}/rename", handler.Call(handler.renameValue))
+	// Delete several values of environment {id} at once
+	router.HandleFunc("POST /api/v1/env/{id}/values/delete", handler.Call(handler.deleteValues))
+}
+
+type RenameValueRequest struct {
+	// To is the new key
+	To string `json:"to"`
+}
+
+type DeleteValuesRequest struct {
+	Keys []string `json:"keys"`
+}
+
+type ValueVersion struct {
+	Version   int64     `json:"version"`
+	Value     string    `json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// checkRequired refuses to remove a required key when required keys are protected
+func (eh *Handler) checkRequired(ctx context.Context, db database.Querier, envID int64, key, message string) (Response, error) {
+	if !eh.protectRequired || !eh.sqlStore() {
+		return Response{}, nil
+	}
+
+	valueSchema, err := db.GetValueSchemaByKey(ctx, database.GetValueSchemaByKeyParams{
+		EnvironmentID: envID,
+		Key:           key,
+	})
+	if err == nil && valueSchema.Required {
+		err := fmt.Errorf("%s is a required key", key)
+		return Response{
+			Code:    http.StatusConflict,
+			Message: message,
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{}, nil
+}
+
+// getStoreValue gets environment {id} and the value of its {key} path
+// parameter from the store
+func (eh *Handler) getStoreValue(r *http.Request) (store.Environment, store.Value, Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return store.Environment{}, store.Value{}, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid environment id",
+			Error:   err.Error(),
+		}, err
+	}
+
+	env, err := eh.store.GetEnvironment(r.Context(), envID)
+	if errors.Is(err, store.ErrNotFound) {
+		return store.Environment{}, store.Value{}, Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return store.Environment{}, store.Value{}, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get environment",
+			Error:   err.Error(),
+		}, err
+	}
+
+	value, err := eh.store.GetValue(r.Context(), env.ID, r.PathValue("key"))
+	if errors.Is(err, store.ErrNotFound) {
+		return store.Environment{}, store.Value{}, Response{
+			Code:    http.StatusNotFound,
+			Message: "Value not found",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return store.Environment{}, store.Value{}, Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return env, value, Response{}, nil
+}
+
+func (eh *Handler) deleteValue(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	env, value, resp, err := eh.getStoreValue(r)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp, err := eh.checkRequired(r.Context(), db, env.ID, value.Key, "Failed to delete value"); err != nil {
+		return resp, err
+	}
+
+	// With the SQL store, deleted values stay in the trash until they are purged
+	if err := eh.store.InTx(r.Context(), func(s store.Store) error {
+		return s.DeleteValue(r.Context(), env.ID, value.Key)
+	}); err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	eh.publish(r, webhook.NewEvent(webhook.EventValueDeleted, env.ID, env.Name, value.Key))
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Value deleted",
+		Data:    nil,
+	}, nil
+}
+
+func (eh *Handler) getValueVersions(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to get versions",
+			Error:   err.Error(),
+		}, err
+	}
+
+	versionsFromStore, err := eh.store.GetVersions(r.Context(), envID, r.PathValue("key"))
+	if errors.Is(err, store.ErrNotFound) {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Value not found",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get versions",
+			Error:   err.Error(),
+		}, err
+	}
+
+	versions := make([]ValueVersion, 0, len(versionsFromStore))
+	for _, version := range versionsFromStore {
+		versions = append(versions, ValueVersion(version))
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Versions retrieved",
+		Data:    versions,
+	}, nil
+}
+
+func (eh *Handler) renameValue(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	var request RenameValueRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to rename value",
+			Error:   err.Error(),
+		}, err
+	}
+	if request.To == "" {
+		err := errors.New("to is required")
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to rename value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	env, value, resp, err := eh.getStoreValue(r)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp, err := eh.checkRequired(r.Context(), db, env.ID, value.Key, "Failed to rename value"); err != nil {
+		return resp, err
+	}
+
+	// The value must be valid for the schema of its new key
+	if err := eh.validateValue(r.Context(), db, env.ID, request.To, value.Value); err != nil {
+		return valueErrorResponse("Failed to rename value", err), err
+	}
+
+	renamed, err := eh.store.RenameValue(r.Context(), env.ID, value.Key, request.To)
+	if errors.Is(err, store.ErrExists) {
+		err := fmt.Errorf("%s is already set", request.To)
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Failed to rename value",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to rename value",
+			Error:   err.Error(),
+		}, err
+	}
+
+	eh.publish(r, webhook.NewEvent(webhook.EventValueRenamed, env.ID, env.Name, value.Key, renamed.Key))
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Value renamed",
+		Data:    fromStoreValues([]store.Value{renamed}, true)[0],
+	}, nil
+}
+
+func (eh *Handler) deleteValues(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to delete values",
+			Error:   err.Error(),
+		}, err
+	}
+
+	var request DeleteValuesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to delete values",
+			Error:   err.Error(),
+		}, err
+	}
+	if len(request.Keys) == 0 {
+		err := errors.New("keys are required")
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to delete values",
+			Error:   err.Error(),
+		}, err
+	}
+
+	env, err := eh.store.GetEnvironment(r.Context(), envID)
+	if errors.Is(err, store.ErrNotFound) {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete values",
+			Error:   err.Error(),
+		}, err
+	}
+
+	keys := slices.Clone(request.Keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	for _, key := range keys {
+		if resp, err := eh.checkRequired(r.Context(), db, env.ID, key, "Failed to delete values"); err != nil {
+			return resp, err
+		}
+	}
+
+	// Either all the keys are deleted or none of them
+	err = eh.store.InTx(r.Context(), func(s store.Store) error {
+		for _, key := range keys {
+			if err := s.DeleteValue(r.Context(), env.ID, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Value not found",
+			Error:   err.Error(),
+		}, err
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete values",
+			Error:   err.Error(),
+		}, err
+	}
+
+	eh.publish(r, webhook.NewEvent(webhook.EventValueDeleted, env.ID, env.Name, keys...))
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Values deleted",
+		Data:    keys,
+	}, nil
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/webhook"
+)
+
+const deliveryHistoryLimit = 100
+
+func registerWebhookRoutes(router *http.ServeMux, handler *Handler) {
+	// Get the webhooks of an environment
+	router.HandleFunc("GET /api/v1/env/{id}/webhooks", handler.Call(handler.sqlStorage(getWebhooks)))
+	// Subscribe a webhook to an environment
+	router.HandleFunc("POST /api/v1/env/{id}/webhooks", handler.Call(handler.sqlStorage(createWebhook)))
+	// Delete a webhook
+	router.HandleFunc("DELETE /api/v1/env/{id}/webhooks/{webhook}", handler.Call(handler.sqlStorage(handler.deleteWebhook)))
+	// Get the delivery history of a webhook
+	router.HandleFunc("GET /api/v1/env/{id}/webhooks/{webhook}/deliveries", handler.Call(handler.sqlStorage(getWebhookDeliveries)))
+	// Queue a failed or dead-lettered delivery again
+	router.HandleFunc("POST /api/v1/env/{id}/webhooks/{webhook}/deliveries/{delivery}/retry", handler.Call(handler.sqlStorage(retryWebhookDelivery)))
+}
+
+type Webhook struct {
+	ID            int64     `json:"id"`
+	EnvironmentID int64     `json:"environment_id"`
+	URL           string    `json:"url"`
+	Secret        string    `json:"secret,omitempty"`
+	Active        bool      `json:"active"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhook_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int64           `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error"`
+	ResponseCode  int64           `json:"response_code"`
+	DeliveredAt   *time.Time      `json:"delivered_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+func toWebhook(w database.Webhook) Webhook {
+	return Webhook{
+		ID:            w.ID,
+		EnvironmentID: w.EnvironmentID,
+		URL:           w.Url,
+		Active:        w.Active,
+		CreatedAt:     w.CreatedAt,
+	}
+}
+
+func toWebhookDelivery(d database.WebhookDelivery) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:            d.ID,
+		WebhookID:     d.WebhookID,
+		Event:         d.Event,
+		Payload:       json.RawMessage(d.Payload),
+		Status:        d.Status,
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		LastError:     d.LastError,
+		ResponseCode:  d.ResponseCode,
+		CreatedAt:     d.CreatedAt,
+	}
+	if d.DeliveredAt.Valid {
+		delivery.DeliveredAt = &d.DeliveredAt.Time
+	}
+	return delivery
+}
+
+// getEnvironmentWebhook gets a webhook making sure it belongs to the environment in the path
+func getEnvironmentWebhook(db database.Querier, r *http.Request) (database.Webhook, Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return database.Webhook{}, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid environment id",
+			Error:   err.Error(),
+		}, err
+	}
+
+	webhookID, err := strconv.ParseInt(r.PathValue("webhook"), 10, 64)
+	if err != nil {
+		return database.Webhook{}, Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid webhook id",
+			Error:   err.Error(),
+		}, err
+	}
+
+	wh, err := db.GetWebhook(r.Context(), webhookID)
+	if err == nil && wh.EnvironmentID != envID {
+		err = errors.New("webhook does not belong to environment")
+	}
+	if err != nil {
+		return database.Webhook{}, Response{
+			Code:    http.StatusNotFound,
+			Message: "Webhook not found",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return wh, Response{}, nil
+}
+
+func getWebhooks(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to get webhooks",
+			Error:   err.Error(),
+		}, err
+	}
+
+	webhooksFromDB, err := db.GetWebhooksByEnvironmentID(r.Context(), envID)
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get webhooks",
+			Error:   err.Error(),
+		}, err
+	}
+
+	webhooks := make([]Webhook, 0)
+	for _, wh := range webhooksFromDB {
+		webhooks = append(webhooks, toWebhook(wh))
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Webhooks retrieved",
+		Data:    webhooks,
+	}, nil
+}
+
+func createWebhook(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	envID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to create webhook",
+			Error:   err.Error(),
+		}, err
+	}
+
+	var request CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to create webhook",
+			Error:   err.Error(),
+		}, err
+	}
+
+	target, err := url.Parse(request.URL)
+	if err == nil && (target.Scheme != "http" && target.Scheme != "https" || target.Host == "") {
+		err = errors.New("url must be an absolute http(s) url")
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to create webhook",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if _, err := db.GetEnvironment(r.Context(), envID); err != nil {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Environment not found",
+			Error:   err.Error(),
+		}, err
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		secret, err = webhook.GenerateSecret()
+		if err != nil {
+			return Response{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to create webhook",
+				Error:   err.Error(),
+			}, err
+		}
+	}
+
+	wh, err := db.CreateWebhook(r.Context(), database.CreateWebhookParams{
+		EnvironmentID: envID,
+		Url:           request.URL,
+		Secret:        secret,
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to create webhook",
+			Error:   err.Error(),
+		}, err
+	}
+
+	// The secret is only returned once, when the webhook is created
+	created := toWebhook(wh)
+	created.Secret = wh.Secret
+
+	return Response{
+		Code:    http.StatusCreated,
+		Message: "Webhook created",
+		Data:    created,
+	}, nil
+}
+
+func (eh *Handler) deleteWebhook(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	wh, resp, err := getEnvironmentWebhook(db, r)
+	if err != nil {
+		return resp, err
+	}
+
+	// The deliveries go with the webhook, the worker can't send them anymore
+	err = eh.inTx(r.Context(), func(db database.Querier) error {
+		if err := db.DeleteWebhookDeliveriesByWebhookID(r.Context(), wh.ID); err != nil {
+			return err
+		}
+		return db.DeleteWebhook(r.Context(), wh.ID)
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete webhook",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Webhook deleted",
+		Data:    nil,
+	}, nil
+}
+
+func getWebhookDeliveries(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	wh, resp, err := getEnvironmentWebhook(db, r)
+	if err != nil {
+		return resp, err
+	}
+
+	deliveriesFromDB, err := db.GetWebhookDeliveriesByWebhookID(r.Context(), database.GetWebhookDeliveriesByWebhookIDParams{
+		WebhookID: wh.ID,
+		Limit:     deliveryHistoryLimit,
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get deliveries",
+			Error:   err.Error(),
+		}, err
+	}
+
+	deliveries := make([]WebhookDelivery, 0)
+	for _, d := range deliveriesFromDB {
+		deliveries = append(deliveries, toWebhookDelivery(d))
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Deliveries retrieved",
+		Data:    deliveries,
+	}, nil
+}
+
+func retryWebhookDelivery(db database.Querier, w http.ResponseWriter, r *http.Request) (Response, error) {
+	wh, resp, err := getEnvironmentWebhook(db, r)
+	if err != nil {
+		return resp, err
+	}
+
+	deliveryID, err := strconv.ParseInt(r.PathValue("delivery"), 10, 64)
+	if err != nil {
+		return Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid delivery id",
+			Error:   err.Error(),
+		}, err
+	}
+
+	delivery, err := db.GetWebhookDelivery(r.Context(), deliveryID)
+	if err == nil && delivery.WebhookID != wh.ID {
+		err = errors.New("delivery does not belong to webhook")
+	}
+	if err != nil {
+		return Response{
+			Code:    http.StatusNotFound,
+			Message: "Delivery not found",
+			Error:   err.Error(),
+		}, err
+	}
+
+	if delivery.Status == webhook.StatusSucceeded {
+		err := errors.New("delivery already succeeded")
+		return Response{
+			Code:    http.StatusConflict,
+			Message: "Failed to retry delivery",
+			Error:   err.Error(),
+		}, err
+	}
+
+	updated, err := db.UpdateWebhookDelivery(r.Context(), database.UpdateWebhookDeliveryParams{
+		ID:            delivery.ID,
+		Status:        webhook.StatusPending,
+		Attempts:      0,
+		NextAttemptAt: time.Now().UTC(),
+		LastError:     delivery.LastError,
+		ResponseCode:  delivery.ResponseCode,
+		DeliveredAt:   delivery.DeliveredAt,
+	})
+	if err != nil {
+		return Response{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to retry delivery",
+			Error:   err.Error(),
+		}, err
+	}
+
+	return Response{
+		Code:    http.StatusOK,
+		Message: "Delivery queued",
+		Data:    toWebhookDelivery(updated),
+	}, nil
+}
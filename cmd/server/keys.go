@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/rodrwan/secretly/internal/config"
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/keyring"
+	"github.com/rodrwan/secretly/internal/kms"
+)
+
+// openKeyring opens the master keys of cfg, nil when values are stored in
+// plaintext. The master key split in shares is returned sealed, nil when
+// there is none.
+func openKeyring(cfg *config.Config) (*keyring.Keyring, *keyring.ShamirProvider, error) {
+	if cfg.MasterKeys == "" {
+		return nil, nil, nil
+	}
+
+	var providers []keyring.KeyProvider
+	var sealed *keyring.ShamirProvider
+	for _, entry := range strings.Split(cfg.MasterKeys, ",") {
+		provider, err := openKeyProvider(cfg, strings.TrimSpace(entry))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid MASTER_KEYS: %w", err)
+		}
+		if p, ok := provider.(*keyring.ShamirProvider); ok {
+			if sealed != nil {
+				return nil, nil, errors.New("invalid MASTER_KEYS: only one master key can be split in shares")
+			}
+			sealed = p
+		}
+		providers = append(providers, provider)
+	}
+	keys, err := keyring.New(providers[0], providers[1:]...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid MASTER_KEYS: %w", err)
+	}
+	return keys, sealed, nil
+}
+
+// openKeyProvider opens the provider of a master key of MASTER_KEYS
+func openKeyProvider(cfg *config.Config, entry string) (keyring.KeyProvider, error) {
+	id, spec, ok := strings.Cut(entry, ":")
+	if !ok {
+		return nil, errors.New("master keys must be <id>:<key> or <id>:<provider>[:<argument>] separated by commas")
+	}
+
+	provider, argument, _ := strings.Cut(spec, ":")
+	switch provider {
+	case config.KeyProviderFile:
+		return keyring.NewKeyFileProvider(id, argument)
+	case config.KeyProviderPassphrase:
+		salt, err := encryption.ParseBytes(cfg.MasterPassphraseSalt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MASTER_PASSPHRASE_SALT: %w", err)
+		}
+		return keyring.NewPassphraseProvider(id, cfg.MasterPassphrase, salt)
+	case config.KeyProviderKMS:
+		if argument == "" {
+			return nil, fmt.Errorf("master key %q needs the name of its KMS key, %s:kms:<name>", id, id)
+		}
+		return keyring.NewKMSProvider(id, argument, kms.NewClient(cfg.KMSURL, cfg.KMSToken)), nil
+	case config.KeyProviderShamir:
+		check, err := encryption.ParseBytes(cfg.UnsealKeyCheck)
+		if err != nil {
+			return nil, fmt.Errorf("invalid UNSEAL_KEY_CHECK: %w", err)
+		}
+		return keyring.NewShamirProvider(id, cfg.UnsealThreshold, check)
+	default:
+		key, err := encryption.ParseKey(spec)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q: %w", id, err)
+		}
+		return keyring.NewKeyProvider(id, key)
+	}
+}
+
+// rekeyCommand re-wraps every value with the active master key, the server
+// can keep running
+func rekeyCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("rekey", flag.ExitOnError)
+	batchSize := flags.Int("batch-size", cfg.RekeyBatchSize, "number of values re-wrapped per transaction")
+	flags.Parse(args)
+
+	keys, sealed, err := openKeyring(cfg)
+	if err != nil {
+		return err
+	}
+	if keys == nil {
+		return errors.New("MASTER_KEYS is required to rekey")
+	}
+	if sealed != nil {
+		return errors.New("a master key is split in shares, rekey through the admin endpoint of the unsealed server")
+	}
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return keyring.NewRekeyer(db, keys, *batchSize).Run(ctx)
+}
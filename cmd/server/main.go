@@ -1,60 +1,186 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 
-	"github.com/pressly/goose/v3"
 	"github.com/rodrwan/secretly/cmd/server/handlers"
+	"github.com/rodrwan/secretly/internal/backup"
+	"github.com/rodrwan/secretly/internal/certs"
 	"github.com/rodrwan/secretly/internal/config"
-	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/expiry"
+	"github.com/rodrwan/secretly/internal/janitor"
+	"github.com/rodrwan/secretly/internal/keyring"
+	"github.com/rodrwan/secretly/internal/rotation"
 	"github.com/rodrwan/secretly/internal/web"
-
-	_ "modernc.org/sqlite"
+	"github.com/rodrwan/secretly/internal/webhook"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.New()
 
-	db, err := sql.Open("sqlite", cfg.DBPath)
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), cfg, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Encrypt values at rest
+	keys, sealed, err := openKeyring(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
-	queries := database.New(db)
+	var rekeyer *keyring.Rekeyer
+	if keys != nil {
+		db = db.Wrap(keys.Querier)
+		rekeyer = keyring.NewRekeyer(db, keys, cfg.RekeyBatchSize)
+	}
+	if sealed != nil {
+		log.Printf("The server is sealed, unseal it with %d shares of master key %s", cfg.UnsealThreshold, sealed.ID())
+	}
+	queries := db.Querier()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	// Run migrations
-	goose.SetBaseFS(database.Migrations)
-	if err := goose.SetDialect("sqlite3"); err != nil {
+	envStore, err := openStore(ctx, cfg, db)
+	if err != nil {
 		log.Fatal(err)
 	}
-	goose.SetLogger(log.New(os.Stdout, "goose: ", log.LstdFlags))
 
-	// Run migrations
-	if err := goose.Up(db, "migrations"); err != nil {
+	// Deliver webhooks in the background
+	dispatcher := webhook.NewDispatcher(queries)
+	worker := webhook.NewWorker(queries, cfg.WebhookPollInterval, cfg.WebhookMaxAttempts)
+	go worker.Run(ctx)
+
+	// Rotate values with a rotation policy in the background
+	scheduler := rotation.NewScheduler(db, rotation.NewRegistry(), dispatcher, cfg.RotationPollInterval, cfg.RotationRetryDelay)
+
+	// Remove expired values in the background
+	reaper, err := expiry.NewReaper(queries, dispatcher, cfg.ExpiryAction, cfg.ExpiryInterval)
+	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Values can't be read or written while the master key is sealed
+	if sealed != nil {
+		isSealed := func() bool { return sealed.Status().Sealed }
+		scheduler.PauseWhile(isSealed)
+		reaper.PauseWhile(isSealed)
+	}
+	go scheduler.Run(ctx)
+	go reaper.Run(ctx)
+
+	// Trash expired ephemeral environments and purge the trash in the background
+	envJanitor := janitor.NewJanitor(db, envStore, dispatcher, cfg.JanitorInterval, cfg.TrashRetention)
+	go envJanitor.Run(ctx)
+
+	// Back up the database in the background
+	if cfg.BackupInterval > 0 {
+		if cfg.DBDriver != config.DriverSQLite {
+			log.Fatal("BACKUP_INTERVAL only supports the sqlite driver, use pg_dump with postgres")
+		}
+		if cfg.BackupRetention < 1 {
+			log.Fatal("BACKUP_RETENTION must keep at least one backup")
+		}
+		cipher, err := backupCipher(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		backups := backup.NewScheduler(db.DB, cipher, cfg.BackupDir, cfg.BackupInterval, cfg.BackupRetention)
+		go backups.Run(ctx)
+	}
+
+	// Serve over TLS, authenticating the clients with a certificate
+	tlsConfig, reloader, err := serverTLS(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientCerts := tlsConfig != nil && tlsConfig.ClientCAs != nil
+
 	// Server configuration
 	router := http.NewServeMux()
 
 	// Configure web handler
 	webHandler := web.NewHandler(queries)
 	webHandler.RegisterRoutes(router)
-	handlers.RegisterRoutes(router, queries)
+	handlers.RegisterRoutes(router, queries,
+		handlers.WithWebhooks(dispatcher),
+		handlers.WithRotation(scheduler),
+		handlers.WithRequiredKeyProtection(cfg.ProtectRequiredKeys),
+		handlers.WithTransactions(db),
+		handlers.WithStore(envStore),
+		handlers.WithReadOnly(cfg.Storage == config.StorageGit && cfg.GitStorageReadOnly),
+		handlers.WithTrashRetention(cfg.TrashRetention),
+		handlers.WithValueHashSearch(cfg.SearchValueHashes),
+		handlers.WithAdmins(cfg.AdminActors),
+		handlers.WithRekey(ctx, rekeyer),
+		handlers.WithSeal(sealed),
+		handlers.WithAuthenticatedActors(clientCerts || cfg.TrustActorHeader),
+	)
 
 	// Wrap the router with middleware
 	handler := panicMiddleware(router)
 
+	if clientCerts {
+		identities, err := certs.ParseIdentities(cfg.TLSClientIdentities)
+		if err != nil {
+			log.Fatal(err)
+		}
+		handler = clientCertMiddleware(handler, identities)
+	}
+	server := &http.Server{
+		Addr:      ":" + cfg.Port,
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}
+
 	// Start server
-	log.Printf("Server started on :%s", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, handler); err != nil {
+	if tlsConfig == nil {
+		log.Printf("Server started on :%s", cfg.Port)
+		err = server.ListenAndServe()
+	} else {
+		go reloader.Run(ctx)
+		log.Printf("Server started on :%s with TLS", cfg.Port)
+		err = server.ListenAndServeTLS("", "")
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+// runCommand runs the command name of the server binary instead of the server
+func runCommand(ctx context.Context, cfg *config.Config, name string, args []string) error {
+	switch name {
+	case "backup":
+		return backupCommand(ctx, cfg, args)
+	case "restore":
+		return restoreCommand(ctx, cfg, args)
+	case "rekey":
+		return rekeyCommand(ctx, cfg, args)
+	case "split":
+		return splitCommand(cfg, args)
+	case "unseal":
+		return unsealCommand(ctx, cfg, args)
+	case "seal":
+		return sealCommand(ctx, cfg, args)
+	case "keygen":
+		return keygenCommand(args)
+	default:
+		return fmt.Errorf("unknown command %q, must be backup, restore, rekey, split, unseal, seal or keygen", name)
+	}
+}
+
 // Create middleware wrapper to handle panics
 func panicMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rodrwan/secretly/cmd/server/handlers"
+	"github.com/rodrwan/secretly/internal/config"
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/keyring"
+)
+
+// splitCommand splits a master key in shares, a new one unless -key-file is
+// given, and prints them with the settings of the shamir provider
+func splitCommand(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("split", flag.ExitOnError)
+	parts := flags.Int("shares", 5, "number of shares")
+	threshold := flags.Int("threshold", cfg.UnsealThreshold, "number of shares that unseal the key")
+	keyFile := flags.String("key-file", "", "file with the key to split, as hex or base64 (default: a new key)")
+	flags.Parse(args)
+
+	key := make([]byte, encryption.KeySize)
+	if *keyFile != "" {
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return err
+		}
+		if key, err = encryption.ParseKey(string(data)); err != nil {
+			return fmt.Errorf("invalid key file %s: %w", *keyFile, err)
+		}
+	} else if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	defer clear(key)
+
+	shares, check, err := keyring.SplitKey(key, *parts, *threshold)
+	if err != nil {
+		return err
+	}
+	for i, share := range shares {
+		fmt.Printf("Share %d: %s\n", i+1, hex.EncodeToString(share))
+	}
+	fmt.Println()
+	fmt.Printf("UNSEAL_THRESHOLD=%d\n", *threshold)
+	fmt.Printf("UNSEAL_KEY_CHECK=%s\n", hex.EncodeToString(check))
+	return nil
+}
+
+// unsealCommand gives a share read from stdin to a running server
+func unsealCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("unseal", flag.ExitOnError)
+	server := newClientFlags(flags, cfg)
+	flags.Parse(args)
+
+	client, err := server.client()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(os.Stderr, "Share: ")
+	share, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && share == "" {
+		return errors.New("no share was given on stdin")
+	}
+
+	status, err := callSeal(ctx, client, *server.addr+"/api/v1/unseal", "", handlers.UnsealRequest{Share: strings.TrimSpace(share)})
+	if err != nil {
+		return err
+	}
+	if status.Sealed {
+		fmt.Printf("Sealed, %d of %d shares given\n", status.Progress, status.Threshold)
+	} else {
+		fmt.Println("Unsealed")
+	}
+	return nil
+}
+
+// sealCommand seals a running server
+func sealCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("seal", flag.ExitOnError)
+	server := newClientFlags(flags, cfg)
+	actor := flags.String("actor", "", "admin actor sealing the server, one of ADMIN_ACTORS")
+	flags.Parse(args)
+
+	client, err := server.client()
+	if err != nil {
+		return err
+	}
+	if _, err := callSeal(ctx, client, *server.addr+"/api/v1/seal", *actor, nil); err != nil {
+		return err
+	}
+	fmt.Println("Sealed")
+	return nil
+}
+
+// callSeal posts body to a seal endpoint and returns the seal status
+func callSeal(ctx context.Context, client *http.Client, url, actor string, body any) (keyring.SealStatus, error) {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return keyring.SealStatus{}, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &buf)
+	if err != nil {
+		return keyring.SealStatus{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if actor != "" {
+		req.Header.Set(handlers.ActorHeader, actor)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return keyring.SealStatus{}, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Data  keyring.SealStatus `json:"data"`
+		Code  int                `json:"code"`
+		Error string             `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return keyring.SealStatus{}, fmt.Errorf("invalid response from %s: %w", url, err)
+	}
+	if response.Error != "" {
+		return keyring.SealStatus{}, fmt.Errorf("%s: %s", url, response.Error)
+	}
+	return response.Data, nil
+}
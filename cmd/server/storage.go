@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rodrwan/secretly/internal/config"
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+// openStore opens the storage of environments and their values of cfg.Storage
+func openStore(ctx context.Context, cfg *config.Config, db *database.DB) (store.Store, error) {
+	switch cfg.Storage {
+	case config.StorageSQL:
+		return store.NewSQL(db), nil
+	case config.StorageGit:
+		if cfg.GitStorageKey == "" {
+			return nil, errors.New("GIT_STORAGE_KEY is required with the git storage")
+		}
+		key, err := encryption.ParseKey(cfg.GitStorageKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GIT_STORAGE_KEY: %w", err)
+		}
+		cipher, err := encryption.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return store.NewGit(ctx, cfg.GitStoragePath, cipher,
+			store.GitReadOnly(cfg.GitStorageReadOnly),
+			store.GitAuthor(cfg.GitStorageAuthor),
+		)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE %q, must be %s or %s", cfg.Storage, config.StorageSQL, config.StorageGit)
+	}
+}
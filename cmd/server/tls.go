@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/rodrwan/secretly/cmd/server/handlers"
+	"github.com/rodrwan/secretly/internal/certs"
+	"github.com/rodrwan/secretly/internal/config"
+)
+
+// serverTLS returns the TLS configuration of the server and the reloader of
+// its certificate, nil when the server serves plain HTTP
+func serverTLS(cfg *config.Config) (*tls.Config, *certs.Reloader, error) {
+	if cfg.TLSCertFile == "" && cfg.TLSKeyFile == "" {
+		if cfg.TLSClientCAFile != "" {
+			return nil, nil, errors.New("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil, nil
+	}
+	if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+		return nil, nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.TLSReloadInterval <= 0 {
+		return nil, nil, errors.New("TLS_RELOAD_INTERVAL must be positive")
+	}
+
+	reloader, err := certs.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSReloadInterval)
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if cfg.TLSClientCAFile != "" {
+		if tlsConfig.ClientCAs, err = certs.LoadCertPool(cfg.TLSClientCAFile); err != nil {
+			return nil, nil, fmt.Errorf("invalid TLS_CLIENT_CA_FILE: %w", err)
+		}
+		switch cfg.TLSClientAuth {
+		case config.ClientAuthOptional:
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		case config.ClientAuthRequire:
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		default:
+			return nil, nil, fmt.Errorf("unknown TLS_CLIENT_AUTH %q, must be %s or %s", cfg.TLSClientAuth, config.ClientAuthOptional, config.ClientAuthRequire)
+		}
+	}
+	return tlsConfig, reloader, nil
+}
+
+// clientCertMiddleware makes the identity of a verified client certificate
+// the actor of the request, replacing the actor header the client sent. A
+// request without a verified certificate has no actor, the header can't be
+// trusted.
+func clientCertMiddleware(next http.Handler, identities certs.Identities) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := ""
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			actor = identities.Actor(r.TLS.PeerCertificates[0])
+		}
+		if actor != "" {
+			r.Header.Set(handlers.ActorHeader, actor)
+		} else {
+			r.Header.Del(handlers.ActorHeader)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientFlags are the flags of the commands calling a running server
+type clientFlags struct {
+	addr     *string
+	caFile   *string
+	certFile *string
+	keyFile  *string
+}
+
+// newClientFlags adds the address of the server to flags, https when the
+// server serves TLS, and the certificates to call it with
+func newClientFlags(flags *flag.FlagSet, cfg *config.Config) *clientFlags {
+	scheme := "http"
+	if cfg.TLSCertFile != "" {
+		scheme = "https"
+	}
+	return &clientFlags{
+		addr:     flags.String("addr", scheme+"://localhost:"+cfg.Port, "address of the server"),
+		caFile:   flags.String("ca-file", "", "CA bundle verifying the certificate of the server (default: the system's)"),
+		certFile: flags.String("cert-file", "", "client certificate, with -key-file"),
+		keyFile:  flags.String("key-file", "", "key of the client certificate"),
+	}
+}
+
+// client returns an HTTP client calling the server with the certificates
+// of the flags
+func (f *clientFlags) client() (*http.Client, error) {
+	if *f.caFile == "" && *f.certFile == "" && *f.keyFile == "" {
+		return http.DefaultClient, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if *f.caFile != "" {
+		pool, err := certs.LoadCertPool(*f.caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if *f.certFile != "" || *f.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(*f.certFile, *f.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,268 @@
+// Package backup writes encrypted, checksummed archives of a SQLite database
+// while it is in use and restores them
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/encryption"
+
+	_ "modernc.org/sqlite"
+)
+
+// magic starts every archive
+const magic = "secretly-backup\n"
+
+// format is the version of the archive layout
+const format = 1
+
+// Header describes an archive. It is readable without the key but can't be
+// modified without failing the restore.
+type Header struct {
+	Format    int       `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	// SchemaVersion is the latest migration applied to the database
+	SchemaVersion int64 `json:"schema_version"`
+	// SHA256 is the checksum of the database file
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Create writes an archive of db to w. The database is copied with VACUUM
+// INTO, so it can keep being written while the backup runs.
+func Create(ctx context.Context, db *sql.DB, w io.Writer, cipher *encryption.Cipher) (Header, error) {
+	dir, err := os.MkdirTemp("", "secretly-backup-")
+	if err != nil {
+		return Header{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	snapshot := filepath.Join(dir, "secretly.db")
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", snapshot); err != nil {
+		return Header{}, fmt.Errorf("failed to copy the database: %w", err)
+	}
+	data, err := os.ReadFile(snapshot)
+	if err != nil {
+		return Header{}, err
+	}
+
+	version, err := schemaVersion(ctx, snapshot)
+	if err != nil {
+		return Header{}, err
+	}
+
+	checksum := sha256.Sum256(data)
+	header := Header{
+		Format:        format,
+		CreatedAt:     time.Now().UTC(),
+		SchemaVersion: version,
+		SHA256:        hex.EncodeToString(checksum[:]),
+		Size:          int64(len(data)),
+	}
+	headerLine, err := json.Marshal(header)
+	if err != nil {
+		return Header{}, err
+	}
+	headerLine = append(headerLine, '\n')
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return Header{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return Header{}, err
+	}
+
+	// The header is authenticated with the data
+	sealed, err := cipher.Seal(compressed.Bytes(), append([]byte(magic), headerLine...))
+	if err != nil {
+		return Header{}, err
+	}
+
+	for _, part := range [][]byte{[]byte(magic), headerLine, sealed} {
+		if _, err := w.Write(part); err != nil {
+			return Header{}, err
+		}
+	}
+	return header, nil
+}
+
+// ReadHeader reads the header of an archive without decrypting it
+func ReadHeader(r io.Reader) (Header, error) {
+	header, _, err := readHeader(bufio.NewReader(r))
+	return header, err
+}
+
+func readHeader(r *bufio.Reader) (Header, []byte, error) {
+	start := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, start); err != nil || string(start) != magic {
+		return Header{}, nil, errors.New("not a secretly backup")
+	}
+
+	headerLine, err := r.ReadBytes('\n')
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("invalid backup header: %w", err)
+	}
+	var header Header
+	if err := json.Unmarshal(headerLine, &header); err != nil {
+		return Header{}, nil, fmt.Errorf("invalid backup header: %w", err)
+	}
+	if header.Format != format {
+		return Header{}, nil, fmt.Errorf("unsupported backup format %d", header.Format)
+	}
+	return header, append(start, headerLine...), nil
+}
+
+// Restore replaces the database at path with the one in the archive. The
+// archive must have been created by a server whose migrations this one
+// knows, older ones are migrated on startup. The server must be stopped.
+func Restore(ctx context.Context, r io.Reader, cipher *encryption.Cipher, path string) (Header, error) {
+	br := bufio.NewReader(r)
+	header, authenticated, err := readHeader(br)
+	if err != nil {
+		return Header{}, err
+	}
+
+	latest, err := LatestMigration()
+	if err != nil {
+		return Header{}, err
+	}
+	if header.SchemaVersion > latest {
+		return Header{}, fmt.Errorf("the backup has schema version %d, newer than the latest migration of this server %d, upgrade the server first", header.SchemaVersion, latest)
+	}
+
+	sealed, err := io.ReadAll(br)
+	if err != nil {
+		return Header{}, err
+	}
+	compressed, err := cipher.Open(sealed, authenticated)
+	if err != nil {
+		return Header{}, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return Header{}, err
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return Header{}, err
+	}
+
+	checksum := sha256.Sum256(data)
+	if hex.EncodeToString(checksum[:]) != header.SHA256 || int64(len(data)) != header.Size {
+		return Header{}, errors.New("the checksum of the backup doesn't match")
+	}
+
+	// Written next to the database so it can be renamed over it
+	restored := path + ".restore"
+	if err := os.WriteFile(restored, data, 0o600); err != nil {
+		return Header{}, err
+	}
+	defer os.Remove(restored)
+
+	if err := check(ctx, restored, header.SchemaVersion); err != nil {
+		return Header{}, err
+	}
+
+	// A journal left by the previous database would be replayed over the restored one
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return Header{}, err
+		}
+	}
+	if err := os.Rename(restored, path); err != nil {
+		return Header{}, err
+	}
+	return header, nil
+}
+
+// check verifies a restored database is intact and at the schema version
+// of its header
+func check(ctx context.Context, path string, schemaVersion int64) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var integrity string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&integrity); err != nil {
+		return err
+	}
+	if integrity != "ok" {
+		return fmt.Errorf("the restored database is corrupted: %s", integrity)
+	}
+
+	version, err := dbVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	if version != schemaVersion {
+		return fmt.Errorf("the restored database has schema version %d, the backup header says %d", version, schemaVersion)
+	}
+	return nil
+}
+
+// LatestMigration is the version of the latest SQLite migration
+func LatestMigration() (int64, error) {
+	files, err := fs.Glob(database.Migrations, "migrations/*.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, file := range files {
+		version, err := goose.NumericComponent(file)
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+func schemaVersion(ctx context.Context, path string) (int64, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	return dbVersion(ctx, db)
+}
+
+func dbVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	provider, err := newProvider(db)
+	if err != nil {
+		return 0, err
+	}
+	version, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the schema version: %w", err)
+	}
+	return version, nil
+}
+
+func newProvider(db *sql.DB) (*goose.Provider, error) {
+	migrations, err := fs.Sub(database.Migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectSQLite3, db, migrations)
+}
@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+)
+
+// fileLayout names backups by their creation time, so they sort by it
+const fileLayout = "secretly-20060102T150405Z.backup"
+
+// Scheduler backs up the database to a directory at an interval and only
+// keeps the latest backups
+type Scheduler struct {
+	db        *sql.DB
+	cipher    *encryption.Cipher
+	dir       string
+	interval  time.Duration
+	retention int
+}
+
+// NewScheduler creates a new scheduler keeping the latest retention backups in dir
+func NewScheduler(db *sql.DB, cipher *encryption.Cipher, dir string, interval time.Duration, retention int) *Scheduler {
+	return &Scheduler{
+		db:        db,
+		cipher:    cipher,
+		dir:       dir,
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+// Run backs up the database until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		file, err := WriteFile(ctx, s.db, s.cipher, s.dir)
+		if err != nil {
+			log.Printf("backup: %v", err)
+			continue
+		}
+		log.Printf("backup: wrote %s", file)
+
+		if err := s.Prune(); err != nil {
+			log.Printf("backup: %v", err)
+		}
+	}
+}
+
+// WriteFile writes a backup of db to a new file of dir and returns its path.
+// It is only named as a backup once complete, so a failed one is never
+// restored.
+func WriteFile(ctx context.Context, db *sql.DB, cipher *encryption.Cipher, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(dir, time.Now().UTC().Format(fileLayout))
+	partial, err := os.OpenFile(file+".partial", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(partial.Name())
+
+	if _, err := Create(ctx, db, partial, cipher); err != nil {
+		partial.Close()
+		return "", fmt.Errorf("failed to back up: %w", err)
+	}
+	if err := partial.Close(); err != nil {
+		return "", err
+	}
+	return file, os.Rename(partial.Name(), file)
+}
+
+// Prune deletes all but the latest retention backups of the directory
+func (s *Scheduler) Prune() error {
+	files, err := filepath.Glob(filepath.Join(s.dir, "secretly-*.backup"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(files)
+
+	for len(files) > s.retention {
+		if err := os.Remove(files[0]); err != nil {
+			return fmt.Errorf("failed to delete old backup: %w", err)
+		}
+		log.Printf("backup: deleted %s", files[0])
+		files = files[1:]
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Package certs serves the server over TLS with a certificate reloaded when
+// its files change, and identifies the clients authenticated with a
+// certificate.
+package certs
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadCertPool reads a bundle of PEM certificates, ie: the CAs verifying
+// client certificates
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s has no PEM certificates", file)
+	}
+	return pool, nil
+}
+
+// Identities maps the common names of the subjects of client certificates
+// to the actors they authenticate
+type Identities map[string]string
+
+// ParseIdentities decodes mappings of a common name to an actor,
+// <common name>=<actor>
+func ParseIdentities(mappings []string) (Identities, error) {
+	identities := Identities{}
+	for _, mapping := range mappings {
+		name, actor, ok := strings.Cut(mapping, "=")
+		name, actor = strings.TrimSpace(name), strings.TrimSpace(actor)
+		if !ok || name == "" || actor == "" {
+			return nil, fmt.Errorf("invalid identity %q, must be <common name>=<actor>", mapping)
+		}
+		identities[name] = actor
+	}
+	return identities, nil
+}
+
+// Actor returns the actor authenticated by cert, its mapped identity or the
+// common name of its subject when it has none
+func (i Identities) Actor(cert *x509.Certificate) string {
+	if actor, ok := i[cert.Subject.CommonName]; ok {
+		return actor
+	}
+	return cert.Subject.CommonName
+}
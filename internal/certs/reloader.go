@@ -0,0 +1,105 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves a certificate and its key from files, and loads them
+// again when they change, ie: when they are renewed
+type Reloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	// modTimes are the modification times of the files when cert was loaded
+	modTimes [2]time.Time
+}
+
+// NewReloader loads the certificate of certFile and keyFile, Run checks
+// whether they changed at interval
+func NewReloader(certFile, keyFile string, interval time.Duration) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the certificate loaded last, it is the
+// GetCertificate of a tls.Config
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Run reloads the certificate when its files change until ctx is cancelled.
+// A certificate that fails to load is logged and the previous one is kept.
+func (r *Reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := r.Reload()
+		if err != nil {
+			log.Printf("certs: %v", err)
+			continue
+		}
+		if reloaded {
+			log.Printf("certs: reloaded %s", r.certFile)
+		}
+	}
+}
+
+// Reload loads the certificate again when its files changed since it was
+// loaded and reports whether it did
+func (r *Reloader) Reload() (bool, error) {
+	modTimes, err := r.stat()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	changed := r.cert == nil || modTimes != r.modTimes
+	r.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load certificate %s: %w", r.certFile, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modTimes = modTimes
+	return true, nil
+}
+
+// stat returns the modification times of the certificate and key files
+func (r *Reloader) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
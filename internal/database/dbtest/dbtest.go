@@ -0,0 +1,120 @@
+// Package dbtest opens migrated databases for tests.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/database/postgres"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// Driver opens a migrated database of a driver for a test
+type Driver struct {
+	Name string
+	Open func(t testing.TB) *database.DB
+}
+
+// Drivers are the databases the integration tests run against
+var Drivers = []Driver{
+	{Name: "sqlite", Open: SQLite},
+	{Name: "postgres", Open: Postgres},
+}
+
+// SQLite opens a new SQLite database in a temporary directory, with every
+// migration applied. It is closed when the test ends.
+func SQLite(t testing.TB) *database.DB {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "secretly.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrate(t, sqlDB, goose.DialectSQLite3, database.Migrations)
+	return database.NewSQLiteDB(sqlDB)
+}
+
+// Postgres opens the PostgreSQL database of DATABASE_URL with every
+// migration applied in a new schema, dropped when the test ends. The test is
+// skipped when DATABASE_URL isn't set.
+func Postgres(t testing.TB) *database.DB {
+	t.Helper()
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+
+	admin, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	schema := "secretly_test_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+	})
+
+	sqlDB, err := sql.Open("pgx", withSearchPath(databaseURL, schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrate(t, sqlDB, goose.DialectPostgres, postgres.Migrations)
+	return postgres.NewDB(sqlDB)
+}
+
+// withSearchPath sets the schema of the connections of a database URL, or
+// of a keyword/value connection string
+func withSearchPath(databaseURL, schema string) string {
+	if !strings.Contains(databaseURL, "://") {
+		return databaseURL + " search_path=" + schema
+	}
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return databaseURL
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// migrate applies the migrations of the migrations directory of fsys
+func migrate(t testing.TB, sqlDB *sql.DB, dialect goose.Dialect, fsys fs.FS) {
+	t.Helper()
+	migrations, err := fs.Sub(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := goose.NewProvider(dialect, sqlDB, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PurgeEnvironment permanently deletes an environment with its values,
+// their history, rotation policies, schemas and webhooks. Run it in a
+// transaction to never leave a partially deleted environment behind.
+func PurgeEnvironment(ctx context.Context, q Querier, environmentID int64) error {
+	if err := q.DeleteRotationPoliciesByEnvironmentID(ctx, environmentID); err != nil {
+		return err
+	}
+	if err := q.DeleteValueVersionsByEnvironmentID(ctx, environmentID); err != nil {
+		return err
+	}
+	if err := q.DeleteValuesByEnvironmentID(ctx, environmentID); err != nil {
+		return err
+	}
+	if err := q.DeleteValueSchemasByEnvironmentID(ctx, environmentID); err != nil {
+		return err
+	}
+	if err := q.DeleteWebhookDeliveriesByEnvironmentID(ctx, environmentID); err != nil {
+		return err
+	}
+	if err := q.DeleteWebhooksByEnvironmentID(ctx, environmentID); err != nil {
+		return err
+	}
+	return q.DeleteEnvironment(ctx, environmentID)
+}
+
+// Events of the lifecycle of environments kept in their audit log
+const (
+	EventEnvironmentExpired  = "environment.expired"
+	EventEnvironmentExtended = "environment.extended"
+	EventEnvironmentPinned   = "environment.pinned"
+)
+
+// RecordEnvironmentEvent adds event to the audit log of projectID, about
+// the environment environmentID named environmentName. The environment may
+// be kept outside the database, ie: by the git storage.
+func RecordEnvironmentEvent(ctx context.Context, q Querier, projectID, environmentID int64, environmentName, event, actor string) error {
+	_, err := q.CreateEnvironmentEvent(ctx, CreateEnvironmentEventParams{
+		ProjectID:       projectID,
+		EnvironmentID:   environmentID,
+		EnvironmentName: environmentName,
+		Event:           event,
+		Actor:           actor,
+	})
+	return err
+}
+
+// PurgeValue permanently deletes a value with its history and rotation policy
+func PurgeValue(ctx context.Context, q Querier, valueID int64) error {
+	if err := q.DeleteRotationPolicyByValueID(ctx, valueID); err != nil {
+		return err
+	}
+	if err := q.DeleteValueVersions(ctx, valueID); err != nil {
+		return err
+	}
+	return q.DeleteValue(ctx, valueID)
+}
+
+// Orders of ListEnvironments
+const (
+	SortByName         = "name"
+	SortByNameDesc     = "-name"
+	SortByCreation     = "created_at"
+	SortByCreationDesc = "-created_at"
+)
+
+// ListEnvironmentsParams selects a page of the environments of a project.
+// AfterName and AfterID identify the last environment of the previous page,
+// AfterID is 0 for the first page. A Limit of 0 gets every environment.
+type ListEnvironmentsParams struct {
+	ProjectID  int64
+	NamePrefix string
+	Sort       string
+	AfterName  string
+	AfterID    int64
+	Limit      int64
+	WithValues bool
+}
+
+// EnvironmentListRow is an environment of a page joined with one of its
+// values. The value columns are null when the environment has no values or
+// they weren't requested.
+type EnvironmentListRow ListEnvironmentsByNameRow
+
+// ListEnvironments gets a page of environments with their values in a single
+// query, rows of the same environment are consecutive and ordered by key
+func ListEnvironments(ctx context.Context, q Querier, arg ListEnvironmentsParams) ([]EnvironmentListRow, error) {
+	namePrefix := likeEscaper.Replace(arg.NamePrefix) + "%"
+	if arg.Limit <= 0 {
+		arg.Limit = math.MaxInt64
+	}
+
+	var page []EnvironmentListRow
+	switch arg.Sort {
+	case SortByName:
+		rows, err := q.ListEnvironmentsByName(ctx, ListEnvironmentsByNameParams{
+			WithValues: arg.WithValues,
+			ProjectID:  arg.ProjectID,
+			NamePrefix: namePrefix,
+			AfterName:  arg.AfterName,
+			Limit:      arg.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			page = append(page, EnvironmentListRow(row))
+		}
+	case SortByNameDesc:
+		rows, err := q.ListEnvironmentsByNameDesc(ctx, ListEnvironmentsByNameDescParams{
+			WithValues: arg.WithValues,
+			ProjectID:  arg.ProjectID,
+			NamePrefix: namePrefix,
+			FirstPage:  arg.AfterID == 0,
+			BeforeName: arg.AfterName,
+			Limit:      arg.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			page = append(page, EnvironmentListRow(row))
+		}
+	case SortByCreation:
+		rows, err := q.ListEnvironmentsByCreation(ctx, ListEnvironmentsByCreationParams{
+			WithValues: arg.WithValues,
+			ProjectID:  arg.ProjectID,
+			NamePrefix: namePrefix,
+			AfterID:    arg.AfterID,
+			Limit:      arg.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			page = append(page, EnvironmentListRow(row))
+		}
+	case SortByCreationDesc:
+		beforeID := arg.AfterID
+		if beforeID == 0 {
+			beforeID = math.MaxInt64
+		}
+		rows, err := q.ListEnvironmentsByCreationDesc(ctx, ListEnvironmentsByCreationDescParams{
+			WithValues: arg.WithValues,
+			ProjectID:  arg.ProjectID,
+			NamePrefix: namePrefix,
+			BeforeID:   beforeID,
+			Limit:      arg.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			page = append(page, EnvironmentListRow(row))
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort %q", arg.Sort)
+	}
+	return page, nil
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, the queries use \ as
+// their escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -0,0 +1,20 @@
+package database
+
+import "errors"
+
+// sqliteConstraintUnique is the extended code of SQLite unique violations
+const sqliteConstraintUnique = 2067
+
+// IsUniqueViolation reports whether err violates a unique index, in either
+// dialect, ie: when a concurrent request inserted the same row first
+func IsUniqueViolation(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		return pgErr.SQLState() == "23505"
+	}
+	var sqliteErr interface{ Code() int }
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqliteConstraintUnique
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE environment_values ADD COLUMN expires_at DATETIME;
+
+CREATE INDEX idx_environment_values_expires_at ON environment_values (expires_at);
+
+CREATE TABLE expired_values (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    value_id INTEGER NOT NULL,
+    environment_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    expired_at DATETIME NOT NULL,
+    archived_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_expired_values_environment_id ON expired_values (environment_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE expired_values;
+DROP INDEX idx_environment_values_expires_at;
+ALTER TABLE environment_values DROP COLUMN expires_at;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE value_schemas (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    environment_id INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    type TEXT NOT NULL DEFAULT 'string',
+    pattern TEXT NOT NULL DEFAULT '',
+    enum TEXT NOT NULL DEFAULT '[]',
+    min REAL,
+    max REAL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (environment_id) REFERENCES environment (id),
+    UNIQUE (environment_id, key)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE value_schemas;
+-- +goose StatementEnd
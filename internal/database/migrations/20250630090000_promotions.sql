@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE promotions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_environment_id INTEGER NOT NULL,
+    to_environment_id INTEGER NOT NULL,
+    keys TEXT NOT NULL DEFAULT '[]',
+    actor TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (from_environment_id) REFERENCES environment (id),
+    FOREIGN KEY (to_environment_id) REFERENCES environment (id)
+);
+
+CREATE INDEX idx_promotions_created_at ON promotions (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE promotions;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE environment ADD COLUMN expires_at DATETIME;
+
+CREATE INDEX idx_environment_expires_at ON environment (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_environment_expires_at;
+
+ALTER TABLE environment DROP COLUMN expires_at;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing environments move to the default project
+INSERT INTO projects (id, name) VALUES (1, 'default');
+
+ALTER TABLE environment ADD COLUMN project_id INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX idx_environment_project_id_name ON environment (project_id, name);
+
+CREATE TABLE project_permissions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id INTEGER NOT NULL,
+    actor TEXT NOT NULL,
+    role TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects (id),
+    UNIQUE (project_id, actor)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE project_permissions;
+
+DROP INDEX idx_environment_project_id_name;
+
+ALTER TABLE environment DROP COLUMN project_id;
+
+DROP TABLE projects;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE environment ADD COLUMN deleted_at DATETIME;
+
+ALTER TABLE environment_values ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX idx_environment_deleted_at ON environment (deleted_at);
+CREATE INDEX idx_environment_values_deleted_at ON environment_values (deleted_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_environment_values_deleted_at;
+DROP INDEX idx_environment_deleted_at;
+
+ALTER TABLE environment_values DROP COLUMN deleted_at;
+
+ALTER TABLE environment DROP COLUMN deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE environment ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE environment ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE environment ADD COLUMN link TEXT NOT NULL DEFAULT '';
+-- tags is a JSON array of strings
+ALTER TABLE environment ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';
+
+ALTER TABLE environment_values ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE environment_values ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE environment_values ADD COLUMN link TEXT NOT NULL DEFAULT '';
+ALTER TABLE environment_values ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE environment_values DROP COLUMN tags;
+ALTER TABLE environment_values DROP COLUMN link;
+ALTER TABLE environment_values DROP COLUMN owner;
+ALTER TABLE environment_values DROP COLUMN description;
+
+ALTER TABLE environment DROP COLUMN tags;
+ALTER TABLE environment DROP COLUMN link;
+ALTER TABLE environment DROP COLUMN owner;
+ALTER TABLE environment DROP COLUMN description;
+-- +goose StatementEnd
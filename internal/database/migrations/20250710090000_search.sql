@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Full text indexes over key names and metadata, values are never indexed.
+-- Their rowid is the id of the environment or value, triggers keep them in
+-- sync and leave deleted rows out.
+CREATE VIRTUAL TABLE environment_search USING fts5(name, description, tags);
+
+CREATE VIRTUAL TABLE value_search USING fts5(key, description, tags);
+
+INSERT INTO environment_search (rowid, name, description, tags)
+SELECT id, name, description, tags FROM environment WHERE deleted_at IS NULL;
+
+INSERT INTO value_search (rowid, key, description, tags)
+SELECT id, key, description, tags FROM environment_values WHERE deleted_at IS NULL;
+
+CREATE TRIGGER environment_search_insert AFTER INSERT ON environment BEGIN
+    INSERT INTO environment_search (rowid, name, description, tags)
+    SELECT new.id, new.name, new.description, new.tags WHERE new.deleted_at IS NULL;
+END;
+
+CREATE TRIGGER environment_search_update AFTER UPDATE OF name, description, tags, deleted_at ON environment BEGIN
+    DELETE FROM environment_search WHERE rowid = old.id;
+    INSERT INTO environment_search (rowid, name, description, tags)
+    SELECT new.id, new.name, new.description, new.tags WHERE new.deleted_at IS NULL;
+END;
+
+CREATE TRIGGER environment_search_delete AFTER DELETE ON environment BEGIN
+    DELETE FROM environment_search WHERE rowid = old.id;
+END;
+
+CREATE TRIGGER value_search_insert AFTER INSERT ON environment_values BEGIN
+    INSERT INTO value_search (rowid, key, description, tags)
+    SELECT new.id, new.key, new.description, new.tags WHERE new.deleted_at IS NULL;
+END;
+
+CREATE TRIGGER value_search_update AFTER UPDATE OF key, description, tags, deleted_at ON environment_values BEGIN
+    DELETE FROM value_search WHERE rowid = old.id;
+    INSERT INTO value_search (rowid, key, description, tags)
+    SELECT new.id, new.key, new.description, new.tags WHERE new.deleted_at IS NULL;
+END;
+
+CREATE TRIGGER value_search_delete AFTER DELETE ON environment_values BEGIN
+    DELETE FROM value_search WHERE rowid = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER value_search_delete;
+DROP TRIGGER value_search_update;
+DROP TRIGGER value_search_insert;
+DROP TRIGGER environment_search_delete;
+DROP TRIGGER environment_search_update;
+DROP TRIGGER environment_search_insert;
+
+DROP TABLE value_search;
+
+DROP TABLE environment_search;
+-- +goose StatementEnd
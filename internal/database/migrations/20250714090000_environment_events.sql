@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Audit log of the lifecycle of environments. Rows outlive the environment,
+-- so there is no foreign key and the name is kept.
+CREATE TABLE environment_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id INTEGER NOT NULL,
+    environment_id INTEGER NOT NULL,
+    environment_name TEXT NOT NULL,
+    event TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_environment_events_project_id ON environment_events (project_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE environment_events;
+-- +goose StatementEnd
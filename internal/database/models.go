@@ -5,21 +5,156 @@
 package database
 
 import (
+	"database/sql"
 	"time"
 )
 
 type Environment struct {
+	ID          int64        `db:"id" json:"id"`
+	Name        string       `db:"name" json:"name"`
+	CreatedAt   time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time    `db:"updated_at" json:"updated_at"`
+	ExpiresAt   sql.NullTime `db:"expires_at" json:"expires_at"`
+	ProjectID   int64        `db:"project_id" json:"project_id"`
+	DeletedAt   sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	Description string       `db:"description" json:"description"`
+	Owner       string       `db:"owner" json:"owner"`
+	Link        string       `db:"link" json:"link"`
+	Tags        string       `db:"tags" json:"tags"`
+	E2e         bool         `db:"e2e" json:"e2e"`
+}
+
+type EnvironmentEvent struct {
+	ID              int64     `db:"id" json:"id"`
+	ProjectID       int64     `db:"project_id" json:"project_id"`
+	EnvironmentID   int64     `db:"environment_id" json:"environment_id"`
+	EnvironmentName string    `db:"environment_name" json:"environment_name"`
+	Event           string    `db:"event" json:"event"`
+	Actor           string    `db:"actor" json:"actor"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+}
+
+type EnvironmentSearch struct {
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+	Tags        string `db:"tags" json:"tags"`
+}
+
+type EnvironmentValue struct {
+	ID            int64        `db:"id" json:"id"`
+	EnvironmentID int64        `db:"environment_id" json:"environment_id"`
+	Key           string       `db:"key" json:"key"`
+	Value         string       `db:"value" json:"value"`
+	CreatedAt     time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time    `db:"updated_at" json:"updated_at"`
+	Version       int64        `db:"version" json:"version"`
+	ExpiresAt     sql.NullTime `db:"expires_at" json:"expires_at"`
+	DeletedAt     sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	Description   string       `db:"description" json:"description"`
+	Owner         string       `db:"owner" json:"owner"`
+	Link          string       `db:"link" json:"link"`
+	Tags          string       `db:"tags" json:"tags"`
+}
+
+type ExpiredValue struct {
+	ID            int64     `db:"id" json:"id"`
+	ValueID       int64     `db:"value_id" json:"value_id"`
+	EnvironmentID int64     `db:"environment_id" json:"environment_id"`
+	Key           string    `db:"key" json:"key"`
+	Value         string    `db:"value" json:"value"`
+	Version       int64     `db:"version" json:"version"`
+	ExpiredAt     time.Time `db:"expired_at" json:"expired_at"`
+	ArchivedAt    time.Time `db:"archived_at" json:"archived_at"`
+}
+
+type Project struct {
 	ID        int64     `db:"id" json:"id"`
 	Name      string    `db:"name" json:"name"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
-type EnvironmentValue struct {
+type ProjectPermission struct {
+	ID        int64     `db:"id" json:"id"`
+	ProjectID int64     `db:"project_id" json:"project_id"`
+	Actor     string    `db:"actor" json:"actor"`
+	Role      string    `db:"role" json:"role"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type Promotion struct {
+	ID                int64     `db:"id" json:"id"`
+	FromEnvironmentID int64     `db:"from_environment_id" json:"from_environment_id"`
+	ToEnvironmentID   int64     `db:"to_environment_id" json:"to_environment_id"`
+	Keys              string    `db:"keys" json:"keys"`
+	Actor             string    `db:"actor" json:"actor"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+}
+
+type RotationPolicy struct {
+	ID              int64        `db:"id" json:"id"`
+	ValueID         int64        `db:"value_id" json:"value_id"`
+	Rotator         string       `db:"rotator" json:"rotator"`
+	Params          string       `db:"params" json:"params"`
+	IntervalSeconds int64        `db:"interval_seconds" json:"interval_seconds"`
+	NextRotationAt  time.Time    `db:"next_rotation_at" json:"next_rotation_at"`
+	LastRotatedAt   sql.NullTime `db:"last_rotated_at" json:"last_rotated_at"`
+	Failures        int64        `db:"failures" json:"failures"`
+	LastError       string       `db:"last_error" json:"last_error"`
+	CreatedAt       time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time    `db:"updated_at" json:"updated_at"`
+}
+
+type ValueSchema struct {
+	ID            int64           `db:"id" json:"id"`
+	EnvironmentID int64           `db:"environment_id" json:"environment_id"`
+	Key           string          `db:"key" json:"key"`
+	Type          string          `db:"type" json:"type"`
+	Pattern       string          `db:"pattern" json:"pattern"`
+	Enum          string          `db:"enum" json:"enum"`
+	Min           sql.NullFloat64 `db:"min" json:"min"`
+	Max           sql.NullFloat64 `db:"max" json:"max"`
+	CreatedAt     time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time       `db:"updated_at" json:"updated_at"`
+	Required      bool            `db:"required" json:"required"`
+}
+
+type ValueSearch struct {
+	Key         string `db:"key" json:"key"`
+	Description string `db:"description" json:"description"`
+	Tags        string `db:"tags" json:"tags"`
+}
+
+type ValueVersion struct {
+	ID        int64     `db:"id" json:"id"`
+	ValueID   int64     `db:"value_id" json:"value_id"`
+	Version   int64     `db:"version" json:"version"`
+	Value     string    `db:"value" json:"value"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type Webhook struct {
 	ID            int64     `db:"id" json:"id"`
 	EnvironmentID int64     `db:"environment_id" json:"environment_id"`
-	Key           string    `db:"key" json:"key"`
-	Value         string    `db:"value" json:"value"`
+	Url           string    `db:"url" json:"url"`
+	Secret        string    `db:"secret" json:"secret"`
+	Active        bool      `db:"active" json:"active"`
 	CreatedAt     time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
 }
+
+type WebhookDelivery struct {
+	ID            int64        `db:"id" json:"id"`
+	WebhookID     int64        `db:"webhook_id" json:"webhook_id"`
+	Event         string       `db:"event" json:"event"`
+	Payload       string       `db:"payload" json:"payload"`
+	Status        string       `db:"status" json:"status"`
+	Attempts      int64        `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time    `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     string       `db:"last_error" json:"last_error"`
+	ResponseCode  int64        `db:"response_code" json:"response_code"`
+	DeliveredAt   sql.NullTime `db:"delivered_at" json:"delivered_at"`
+	CreatedAt     time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time    `db:"updated_at" json:"updated_at"`
+}
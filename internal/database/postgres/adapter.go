@@ -0,0 +1,530 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/rodrwan/secretly/internal/database"
+)
+
+// NewDB wraps a PostgreSQL database, its queries are generated from
+// queries.sql in this package
+func NewDB(db *sql.DB) *database.DB {
+	return database.NewDB(db, NewQuerier)
+}
+
+// NewQuerier returns the PostgreSQL queries as a database.Querier. The rows
+// of both dialects have the same columns so they convert to each other.
+func NewQuerier(db database.DBTX) database.Querier {
+	return adapter{q: New(db)}
+}
+
+type adapter struct {
+	q *Queries
+}
+
+var _ database.Querier = adapter{}
+
+func convertAll[T, U any](items []T, convert func(T) U) []U {
+	if items == nil {
+		return nil
+	}
+	converted := make([]U, 0, len(items))
+	for _, item := range items {
+		converted = append(converted, convert(item))
+	}
+	return converted
+}
+
+// ftsWord is a word of a full text query, see tsQuery
+var ftsWord = regexp.MustCompile(`"((?:[^"]|"")*)"(\*?)`)
+
+// tsQuery converts a SQLite full text query, quoted words with an optional
+// prefix *, ie: "stripe" "ke"*, into a tsquery: 'stripe' & 'ke':*
+func tsQuery(query string) string {
+	var words []string
+	for _, m := range ftsWord.FindAllStringSubmatch(query, -1) {
+		word := strings.ReplaceAll(m[1], `""`, `"`)
+		word = "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(word) + "'"
+		if m[2] != "" {
+			word += ":*"
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " & ")
+}
+
+func (a adapter) ArchiveExpiredValue(ctx context.Context, arg database.ArchiveExpiredValueParams) (database.ExpiredValue, error) {
+	i, err := a.q.ArchiveExpiredValue(ctx, ArchiveExpiredValueParams(arg))
+	return database.ExpiredValue(i), err
+}
+
+func (a adapter) CountCiphertexts(ctx context.Context) (int64, error) {
+	return a.q.CountCiphertexts(ctx)
+}
+
+func (a adapter) CountProjectPermissions(ctx context.Context, projectID int64) (int64, error) {
+	return a.q.CountProjectPermissions(ctx, projectID)
+}
+
+func (a adapter) CreateEnvironment(ctx context.Context, arg database.CreateEnvironmentParams) (database.Environment, error) {
+	i, err := a.q.CreateEnvironment(ctx, CreateEnvironmentParams(arg))
+	return database.Environment(i), err
+}
+
+func (a adapter) CreateEnvironmentEvent(ctx context.Context, arg database.CreateEnvironmentEventParams) (database.EnvironmentEvent, error) {
+	i, err := a.q.CreateEnvironmentEvent(ctx, CreateEnvironmentEventParams(arg))
+	return database.EnvironmentEvent(i), err
+}
+
+func (a adapter) CreateProject(ctx context.Context, name string) (database.Project, error) {
+	i, err := a.q.CreateProject(ctx, name)
+	return database.Project(i), err
+}
+
+func (a adapter) CreatePromotion(ctx context.Context, arg database.CreatePromotionParams) (database.Promotion, error) {
+	i, err := a.q.CreatePromotion(ctx, CreatePromotionParams(arg))
+	return database.Promotion(i), err
+}
+
+func (a adapter) CreateValue(ctx context.Context, arg database.CreateValueParams) (database.EnvironmentValue, error) {
+	i, err := a.q.CreateValue(ctx, CreateValueParams(arg))
+	return database.EnvironmentValue(i), err
+}
+
+func (a adapter) CreateValueVersion(ctx context.Context, arg database.CreateValueVersionParams) (database.ValueVersion, error) {
+	i, err := a.q.CreateValueVersion(ctx, CreateValueVersionParams(arg))
+	return database.ValueVersion(i), err
+}
+
+func (a adapter) CreateWebhook(ctx context.Context, arg database.CreateWebhookParams) (database.Webhook, error) {
+	i, err := a.q.CreateWebhook(ctx, CreateWebhookParams(arg))
+	return database.Webhook(i), err
+}
+
+func (a adapter) CreateWebhookDelivery(ctx context.Context, arg database.CreateWebhookDeliveryParams) (database.WebhookDelivery, error) {
+	i, err := a.q.CreateWebhookDelivery(ctx, CreateWebhookDeliveryParams(arg))
+	return database.WebhookDelivery(i), err
+}
+
+func (a adapter) DeleteEnvironment(ctx context.Context, id int64) error {
+	return a.q.DeleteEnvironment(ctx, id)
+}
+
+func (a adapter) DeleteProject(ctx context.Context, id int64) error {
+	return a.q.DeleteProject(ctx, id)
+}
+
+func (a adapter) DeleteProjectPermission(ctx context.Context, arg database.DeleteProjectPermissionParams) error {
+	return a.q.DeleteProjectPermission(ctx, DeleteProjectPermissionParams(arg))
+}
+
+func (a adapter) DeleteProjectPermissions(ctx context.Context, projectID int64) error {
+	return a.q.DeleteProjectPermissions(ctx, projectID)
+}
+
+func (a adapter) DeleteRotationPoliciesByEnvironmentID(ctx context.Context, environmentID int64) error {
+	return a.q.DeleteRotationPoliciesByEnvironmentID(ctx, environmentID)
+}
+
+func (a adapter) DeleteRotationPolicy(ctx context.Context, id int64) error {
+	return a.q.DeleteRotationPolicy(ctx, id)
+}
+
+func (a adapter) DeleteRotationPolicyByValueID(ctx context.Context, valueID int64) error {
+	return a.q.DeleteRotationPolicyByValueID(ctx, valueID)
+}
+
+func (a adapter) DeleteValue(ctx context.Context, id int64) error {
+	return a.q.DeleteValue(ctx, id)
+}
+
+func (a adapter) DeleteValueSchema(ctx context.Context, arg database.DeleteValueSchemaParams) error {
+	return a.q.DeleteValueSchema(ctx, DeleteValueSchemaParams(arg))
+}
+
+func (a adapter) DeleteValueSchemasByEnvironmentID(ctx context.Context, environmentID int64) error {
+	return a.q.DeleteValueSchemasByEnvironmentID(ctx, environmentID)
+}
+
+func (a adapter) DeleteValueVersions(ctx context.Context, valueID int64) error {
+	return a.q.DeleteValueVersions(ctx, valueID)
+}
+
+func (a adapter) DeleteValueVersionsByEnvironmentID(ctx context.Context, environmentID int64) error {
+	return a.q.DeleteValueVersionsByEnvironmentID(ctx, environmentID)
+}
+
+func (a adapter) DeleteValuesByEnvironmentID(ctx context.Context, environmentID int64) error {
+	return a.q.DeleteValuesByEnvironmentID(ctx, environmentID)
+}
+
+func (a adapter) DeleteWebhook(ctx context.Context, id int64) error {
+	return a.q.DeleteWebhook(ctx, id)
+}
+
+func (a adapter) DeleteWebhookDeliveriesByEnvironmentID(ctx context.Context, environmentID int64) error {
+	return a.q.DeleteWebhookDeliveriesByEnvironmentID(ctx, environmentID)
+}
+
+func (a adapter) DeleteWebhookDeliveriesByWebhookID(ctx context.Context, webhookID int64) error {
+	return a.q.DeleteWebhookDeliveriesByWebhookID(ctx, webhookID)
+}
+
+func (a adapter) DeleteWebhooksByEnvironmentID(ctx context.Context, environmentID int64) error {
+	return a.q.DeleteWebhooksByEnvironmentID(ctx, environmentID)
+}
+
+func (a adapter) GetActiveWebhooksByEnvironmentID(ctx context.Context, environmentID int64) ([]database.Webhook, error) {
+	items, err := a.q.GetActiveWebhooksByEnvironmentID(ctx, environmentID)
+	return convertAll(items, func(i Webhook) database.Webhook { return database.Webhook(i) }), err
+}
+
+func (a adapter) GetAllEnvironments(ctx context.Context) ([]database.Environment, error) {
+	items, err := a.q.GetAllEnvironments(ctx)
+	return convertAll(items, func(i Environment) database.Environment { return database.Environment(i) }), err
+}
+
+func (a adapter) GetAllProjects(ctx context.Context) ([]database.Project, error) {
+	items, err := a.q.GetAllProjects(ctx)
+	return convertAll(items, func(i Project) database.Project { return database.Project(i) }), err
+}
+
+func (a adapter) GetAllValues(ctx context.Context) ([]database.EnvironmentValue, error) {
+	items, err := a.q.GetAllValues(ctx)
+	return convertAll(items, func(i EnvironmentValue) database.EnvironmentValue { return database.EnvironmentValue(i) }), err
+}
+
+func (a adapter) GetDeletedEnvironment(ctx context.Context, id int64) (database.Environment, error) {
+	i, err := a.q.GetDeletedEnvironment(ctx, id)
+	return database.Environment(i), err
+}
+
+func (a adapter) GetDeletedEnvironmentsByProjectID(ctx context.Context, projectID int64) ([]database.Environment, error) {
+	items, err := a.q.GetDeletedEnvironmentsByProjectID(ctx, projectID)
+	return convertAll(items, func(i Environment) database.Environment { return database.Environment(i) }), err
+}
+
+func (a adapter) GetDeletedValue(ctx context.Context, id int64) (database.EnvironmentValue, error) {
+	i, err := a.q.GetDeletedValue(ctx, id)
+	return database.EnvironmentValue(i), err
+}
+
+func (a adapter) GetDeletedValuesByProjectID(ctx context.Context, projectID int64) ([]database.GetDeletedValuesByProjectIDRow, error) {
+	items, err := a.q.GetDeletedValuesByProjectID(ctx, projectID)
+	return convertAll(items, func(i GetDeletedValuesByProjectIDRow) database.GetDeletedValuesByProjectIDRow {
+		return database.GetDeletedValuesByProjectIDRow(i)
+	}), err
+}
+
+func (a adapter) GetDueRotationPolicies(ctx context.Context, arg database.GetDueRotationPoliciesParams) ([]database.RotationPolicy, error) {
+	items, err := a.q.GetDueRotationPolicies(ctx, GetDueRotationPoliciesParams(arg))
+	return convertAll(items, func(i RotationPolicy) database.RotationPolicy { return database.RotationPolicy(i) }), err
+}
+
+func (a adapter) GetDueWebhookDeliveries(ctx context.Context, arg database.GetDueWebhookDeliveriesParams) ([]database.WebhookDelivery, error) {
+	items, err := a.q.GetDueWebhookDeliveries(ctx, GetDueWebhookDeliveriesParams(arg))
+	return convertAll(items, func(i WebhookDelivery) database.WebhookDelivery { return database.WebhookDelivery(i) }), err
+}
+
+func (a adapter) GetEnvironment(ctx context.Context, id int64) (database.Environment, error) {
+	i, err := a.q.GetEnvironment(ctx, id)
+	return database.Environment(i), err
+}
+
+func (a adapter) GetEnvironmentByName(ctx context.Context, arg database.GetEnvironmentByNameParams) (database.Environment, error) {
+	i, err := a.q.GetEnvironmentByName(ctx, GetEnvironmentByNameParams(arg))
+	return database.Environment(i), err
+}
+
+func (a adapter) GetEnvironmentEventsByProjectID(ctx context.Context, arg database.GetEnvironmentEventsByProjectIDParams) ([]database.EnvironmentEvent, error) {
+	items, err := a.q.GetEnvironmentEventsByProjectID(ctx, GetEnvironmentEventsByProjectIDParams(arg))
+	return convertAll(items, func(i EnvironmentEvent) database.EnvironmentEvent { return database.EnvironmentEvent(i) }), err
+}
+
+func (a adapter) GetEnvironmentsByProjectID(ctx context.Context, projectID int64) ([]database.Environment, error) {
+	items, err := a.q.GetEnvironmentsByProjectID(ctx, projectID)
+	return convertAll(items, func(i Environment) database.Environment { return database.Environment(i) }), err
+}
+
+func (a adapter) GetExpiredEnvironments(ctx context.Context, arg database.GetExpiredEnvironmentsParams) ([]database.Environment, error) {
+	items, err := a.q.GetExpiredEnvironments(ctx, GetExpiredEnvironmentsParams(arg))
+	return convertAll(items, func(i Environment) database.Environment { return database.Environment(i) }), err
+}
+
+func (a adapter) GetExpiredValueCiphertexts(ctx context.Context, arg database.GetExpiredValueCiphertextsParams) ([]database.GetExpiredValueCiphertextsRow, error) {
+	items, err := a.q.GetExpiredValueCiphertexts(ctx, GetExpiredValueCiphertextsParams(arg))
+	return convertAll(items, func(i GetExpiredValueCiphertextsRow) database.GetExpiredValueCiphertextsRow {
+		return database.GetExpiredValueCiphertextsRow(i)
+	}), err
+}
+
+func (a adapter) GetExpiredValues(ctx context.Context, arg database.GetExpiredValuesParams) ([]database.EnvironmentValue, error) {
+	items, err := a.q.GetExpiredValues(ctx, GetExpiredValuesParams(arg))
+	return convertAll(items, func(i EnvironmentValue) database.EnvironmentValue { return database.EnvironmentValue(i) }), err
+}
+
+func (a adapter) GetExpiringValues(ctx context.Context, expiresAt sql.NullTime) ([]database.GetExpiringValuesRow, error) {
+	items, err := a.q.GetExpiringValues(ctx, expiresAt)
+	return convertAll(items, func(i GetExpiringValuesRow) database.GetExpiringValuesRow { return database.GetExpiringValuesRow(i) }), err
+}
+
+func (a adapter) GetProject(ctx context.Context, id int64) (database.Project, error) {
+	i, err := a.q.GetProject(ctx, id)
+	return database.Project(i), err
+}
+
+func (a adapter) GetProjectByName(ctx context.Context, name string) (database.Project, error) {
+	i, err := a.q.GetProjectByName(ctx, name)
+	return database.Project(i), err
+}
+
+func (a adapter) GetProjectPermission(ctx context.Context, arg database.GetProjectPermissionParams) (database.ProjectPermission, error) {
+	i, err := a.q.GetProjectPermission(ctx, GetProjectPermissionParams(arg))
+	return database.ProjectPermission(i), err
+}
+
+func (a adapter) GetProjectPermissions(ctx context.Context, projectID int64) ([]database.ProjectPermission, error) {
+	items, err := a.q.GetProjectPermissions(ctx, projectID)
+	return convertAll(items, func(i ProjectPermission) database.ProjectPermission { return database.ProjectPermission(i) }), err
+}
+
+func (a adapter) GetPromotionsBefore(ctx context.Context, arg database.GetPromotionsBeforeParams) ([]database.GetPromotionsBeforeRow, error) {
+	items, err := a.q.GetPromotionsBefore(ctx, GetPromotionsBeforeParams(arg))
+	return convertAll(items, func(i GetPromotionsBeforeRow) database.GetPromotionsBeforeRow {
+		return database.GetPromotionsBeforeRow(i)
+	}), err
+}
+
+func (a adapter) GetPurgeableEnvironments(ctx context.Context, arg database.GetPurgeableEnvironmentsParams) ([]database.Environment, error) {
+	items, err := a.q.GetPurgeableEnvironments(ctx, GetPurgeableEnvironmentsParams(arg))
+	return convertAll(items, func(i Environment) database.Environment { return database.Environment(i) }), err
+}
+
+func (a adapter) GetPurgeableValues(ctx context.Context, arg database.GetPurgeableValuesParams) ([]database.EnvironmentValue, error) {
+	items, err := a.q.GetPurgeableValues(ctx, GetPurgeableValuesParams(arg))
+	return convertAll(items, func(i EnvironmentValue) database.EnvironmentValue { return database.EnvironmentValue(i) }), err
+}
+
+func (a adapter) GetRotationPoliciesByEnvironmentID(ctx context.Context, environmentID int64) ([]database.GetRotationPoliciesByEnvironmentIDRow, error) {
+	items, err := a.q.GetRotationPoliciesByEnvironmentID(ctx, environmentID)
+	return convertAll(items, func(i GetRotationPoliciesByEnvironmentIDRow) database.GetRotationPoliciesByEnvironmentIDRow {
+		return database.GetRotationPoliciesByEnvironmentIDRow(i)
+	}), err
+}
+
+func (a adapter) GetRotationPolicy(ctx context.Context, id int64) (database.RotationPolicy, error) {
+	i, err := a.q.GetRotationPolicy(ctx, id)
+	return database.RotationPolicy(i), err
+}
+
+func (a adapter) GetRotationPolicyByValueID(ctx context.Context, valueID int64) (database.RotationPolicy, error) {
+	i, err := a.q.GetRotationPolicyByValueID(ctx, valueID)
+	return database.RotationPolicy(i), err
+}
+
+func (a adapter) GetValue(ctx context.Context, id int64) (database.EnvironmentValue, error) {
+	i, err := a.q.GetValue(ctx, id)
+	return database.EnvironmentValue(i), err
+}
+
+func (a adapter) GetValueByKey(ctx context.Context, arg database.GetValueByKeyParams) (database.EnvironmentValue, error) {
+	i, err := a.q.GetValueByKey(ctx, GetValueByKeyParams(arg))
+	return database.EnvironmentValue(i), err
+}
+
+func (a adapter) GetValueCiphertexts(ctx context.Context, arg database.GetValueCiphertextsParams) ([]database.GetValueCiphertextsRow, error) {
+	items, err := a.q.GetValueCiphertexts(ctx, GetValueCiphertextsParams(arg))
+	return convertAll(items, func(i GetValueCiphertextsRow) database.GetValueCiphertextsRow {
+		return database.GetValueCiphertextsRow(i)
+	}), err
+}
+
+func (a adapter) GetValueSchemaByKey(ctx context.Context, arg database.GetValueSchemaByKeyParams) (database.ValueSchema, error) {
+	i, err := a.q.GetValueSchemaByKey(ctx, GetValueSchemaByKeyParams(arg))
+	return database.ValueSchema(i), err
+}
+
+func (a adapter) GetValueSchemasByEnvironmentID(ctx context.Context, environmentID int64) ([]database.ValueSchema, error) {
+	items, err := a.q.GetValueSchemasByEnvironmentID(ctx, environmentID)
+	return convertAll(items, func(i ValueSchema) database.ValueSchema { return database.ValueSchema(i) }), err
+}
+
+func (a adapter) GetValueVersions(ctx context.Context, valueID int64) ([]database.ValueVersion, error) {
+	items, err := a.q.GetValueVersions(ctx, valueID)
+	return convertAll(items, func(i ValueVersion) database.ValueVersion { return database.ValueVersion(i) }), err
+}
+
+func (a adapter) GetValuesByEnvironmentID(ctx context.Context, environmentID int64) ([]database.EnvironmentValue, error) {
+	items, err := a.q.GetValuesByEnvironmentID(ctx, environmentID)
+	return convertAll(items, func(i EnvironmentValue) database.EnvironmentValue { return database.EnvironmentValue(i) }), err
+}
+
+func (a adapter) GetValuesByProjectID(ctx context.Context, projectID int64) ([]database.GetValuesByProjectIDRow, error) {
+	items, err := a.q.GetValuesByProjectID(ctx, projectID)
+	return convertAll(items, func(i GetValuesByProjectIDRow) database.GetValuesByProjectIDRow {
+		return database.GetValuesByProjectIDRow(i)
+	}), err
+}
+
+func (a adapter) GetVersionCiphertexts(ctx context.Context, arg database.GetVersionCiphertextsParams) ([]database.GetVersionCiphertextsRow, error) {
+	items, err := a.q.GetVersionCiphertexts(ctx, GetVersionCiphertextsParams(arg))
+	return convertAll(items, func(i GetVersionCiphertextsRow) database.GetVersionCiphertextsRow {
+		return database.GetVersionCiphertextsRow(i)
+	}), err
+}
+
+func (a adapter) GetWebhook(ctx context.Context, id int64) (database.Webhook, error) {
+	i, err := a.q.GetWebhook(ctx, id)
+	return database.Webhook(i), err
+}
+
+func (a adapter) GetWebhookDeliveriesByWebhookID(ctx context.Context, arg database.GetWebhookDeliveriesByWebhookIDParams) ([]database.WebhookDelivery, error) {
+	items, err := a.q.GetWebhookDeliveriesByWebhookID(ctx, GetWebhookDeliveriesByWebhookIDParams(arg))
+	return convertAll(items, func(i WebhookDelivery) database.WebhookDelivery { return database.WebhookDelivery(i) }), err
+}
+
+func (a adapter) GetWebhookDelivery(ctx context.Context, id int64) (database.WebhookDelivery, error) {
+	i, err := a.q.GetWebhookDelivery(ctx, id)
+	return database.WebhookDelivery(i), err
+}
+
+func (a adapter) GetWebhooksByEnvironmentID(ctx context.Context, environmentID int64) ([]database.Webhook, error) {
+	items, err := a.q.GetWebhooksByEnvironmentID(ctx, environmentID)
+	return convertAll(items, func(i Webhook) database.Webhook { return database.Webhook(i) }), err
+}
+
+func (a adapter) ListEnvironmentsByCreation(ctx context.Context, arg database.ListEnvironmentsByCreationParams) ([]database.ListEnvironmentsByCreationRow, error) {
+	items, err := a.q.ListEnvironmentsByCreation(ctx, ListEnvironmentsByCreationParams(arg))
+	return convertAll(items, func(i ListEnvironmentsByCreationRow) database.ListEnvironmentsByCreationRow {
+		return database.ListEnvironmentsByCreationRow(i)
+	}), err
+}
+
+func (a adapter) ListEnvironmentsByCreationDesc(ctx context.Context, arg database.ListEnvironmentsByCreationDescParams) ([]database.ListEnvironmentsByCreationDescRow, error) {
+	items, err := a.q.ListEnvironmentsByCreationDesc(ctx, ListEnvironmentsByCreationDescParams(arg))
+	return convertAll(items, func(i ListEnvironmentsByCreationDescRow) database.ListEnvironmentsByCreationDescRow {
+		return database.ListEnvironmentsByCreationDescRow(i)
+	}), err
+}
+
+func (a adapter) ListEnvironmentsByName(ctx context.Context, arg database.ListEnvironmentsByNameParams) ([]database.ListEnvironmentsByNameRow, error) {
+	items, err := a.q.ListEnvironmentsByName(ctx, ListEnvironmentsByNameParams(arg))
+	return convertAll(items, func(i ListEnvironmentsByNameRow) database.ListEnvironmentsByNameRow {
+		return database.ListEnvironmentsByNameRow(i)
+	}), err
+}
+
+func (a adapter) ListEnvironmentsByNameDesc(ctx context.Context, arg database.ListEnvironmentsByNameDescParams) ([]database.ListEnvironmentsByNameDescRow, error) {
+	items, err := a.q.ListEnvironmentsByNameDesc(ctx, ListEnvironmentsByNameDescParams(arg))
+	return convertAll(items, func(i ListEnvironmentsByNameDescRow) database.ListEnvironmentsByNameDescRow {
+		return database.ListEnvironmentsByNameDescRow(i)
+	}), err
+}
+
+func (a adapter) MarkEnvironmentE2E(ctx context.Context, id int64) (database.Environment, error) {
+	i, err := a.q.MarkEnvironmentE2E(ctx, id)
+	return database.Environment(i), err
+}
+
+func (a adapter) MarkRotationFailed(ctx context.Context, arg database.MarkRotationFailedParams) (database.RotationPolicy, error) {
+	i, err := a.q.MarkRotationFailed(ctx, MarkRotationFailedParams(arg))
+	return database.RotationPolicy(i), err
+}
+
+func (a adapter) MarkRotationSucceeded(ctx context.Context, arg database.MarkRotationSucceededParams) (database.RotationPolicy, error) {
+	i, err := a.q.MarkRotationSucceeded(ctx, MarkRotationSucceededParams(arg))
+	return database.RotationPolicy(i), err
+}
+
+func (a adapter) RenameValue(ctx context.Context, arg database.RenameValueParams) (database.EnvironmentValue, error) {
+	i, err := a.q.RenameValue(ctx, RenameValueParams(arg))
+	return database.EnvironmentValue(i), err
+}
+
+func (a adapter) RestoreEnvironment(ctx context.Context, id int64) (database.Environment, error) {
+	i, err := a.q.RestoreEnvironment(ctx, id)
+	return database.Environment(i), err
+}
+
+func (a adapter) RestoreValue(ctx context.Context, id int64) (database.EnvironmentValue, error) {
+	i, err := a.q.RestoreValue(ctx, id)
+	return database.EnvironmentValue(i), err
+}
+
+func (a adapter) SearchEnvironments(ctx context.Context, query string) ([]database.Environment, error) {
+	items, err := a.q.SearchEnvironments(ctx, tsQuery(query))
+	return convertAll(items, func(i Environment) database.Environment { return database.Environment(i) }), err
+}
+
+func (a adapter) SearchValues(ctx context.Context, query string) ([]database.SearchValuesRow, error) {
+	items, err := a.q.SearchValues(ctx, tsQuery(query))
+	return convertAll(items, func(i SearchValuesRow) database.SearchValuesRow { return database.SearchValuesRow(i) }), err
+}
+
+func (a adapter) SetEnvironmentExpiry(ctx context.Context, arg database.SetEnvironmentExpiryParams) (database.Environment, error) {
+	i, err := a.q.SetEnvironmentExpiry(ctx, SetEnvironmentExpiryParams(arg))
+	return database.Environment(i), err
+}
+
+func (a adapter) SetEnvironmentMetadata(ctx context.Context, arg database.SetEnvironmentMetadataParams) (database.Environment, error) {
+	i, err := a.q.SetEnvironmentMetadata(ctx, SetEnvironmentMetadataParams(arg))
+	return database.Environment(i), err
+}
+
+func (a adapter) SetExpiredValueCiphertext(ctx context.Context, arg database.SetExpiredValueCiphertextParams) error {
+	return a.q.SetExpiredValueCiphertext(ctx, SetExpiredValueCiphertextParams(arg))
+}
+
+func (a adapter) SetValueCiphertext(ctx context.Context, arg database.SetValueCiphertextParams) error {
+	return a.q.SetValueCiphertext(ctx, SetValueCiphertextParams(arg))
+}
+
+func (a adapter) SetValueExpiry(ctx context.Context, arg database.SetValueExpiryParams) (database.EnvironmentValue, error) {
+	i, err := a.q.SetValueExpiry(ctx, SetValueExpiryParams(arg))
+	return database.EnvironmentValue(i), err
+}
+
+func (a adapter) SetValueMetadata(ctx context.Context, arg database.SetValueMetadataParams) (database.EnvironmentValue, error) {
+	i, err := a.q.SetValueMetadata(ctx, SetValueMetadataParams(arg))
+	return database.EnvironmentValue(i), err
+}
+
+func (a adapter) SetVersionCiphertext(ctx context.Context, arg database.SetVersionCiphertextParams) error {
+	return a.q.SetVersionCiphertext(ctx, SetVersionCiphertextParams(arg))
+}
+
+func (a adapter) SoftDeleteEnvironment(ctx context.Context, arg database.SoftDeleteEnvironmentParams) error {
+	return a.q.SoftDeleteEnvironment(ctx, SoftDeleteEnvironmentParams(arg))
+}
+
+func (a adapter) SoftDeleteValue(ctx context.Context, arg database.SoftDeleteValueParams) error {
+	return a.q.SoftDeleteValue(ctx, SoftDeleteValueParams(arg))
+}
+
+func (a adapter) UpdateValue(ctx context.Context, arg database.UpdateValueParams) (database.EnvironmentValue, error) {
+	i, err := a.q.UpdateValue(ctx, UpdateValueParams(arg))
+	return database.EnvironmentValue(i), err
+}
+
+func (a adapter) UpdateWebhookDelivery(ctx context.Context, arg database.UpdateWebhookDeliveryParams) (database.WebhookDelivery, error) {
+	i, err := a.q.UpdateWebhookDelivery(ctx, UpdateWebhookDeliveryParams(arg))
+	return database.WebhookDelivery(i), err
+}
+
+func (a adapter) UpsertProjectPermission(ctx context.Context, arg database.UpsertProjectPermissionParams) (database.ProjectPermission, error) {
+	i, err := a.q.UpsertProjectPermission(ctx, UpsertProjectPermissionParams(arg))
+	return database.ProjectPermission(i), err
+}
+
+func (a adapter) UpsertRotationPolicy(ctx context.Context, arg database.UpsertRotationPolicyParams) (database.RotationPolicy, error) {
+	i, err := a.q.UpsertRotationPolicy(ctx, UpsertRotationPolicyParams(arg))
+	return database.RotationPolicy(i), err
+}
+
+func (a adapter) UpsertValueSchema(ctx context.Context, arg database.UpsertValueSchemaParams) (database.ValueSchema, error) {
+	i, err := a.q.UpsertValueSchema(ctx, UpsertValueSchemaParams(arg))
+	return database.ValueSchema(i), err
+}
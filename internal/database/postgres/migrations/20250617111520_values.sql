@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE environment_values (
+    id BIGSERIAL PRIMARY KEY,
+    environment_id BIGINT NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (environment_id) REFERENCES environment (id)
+);
+
+CREATE INDEX idx_environment_values_environment_id ON environment_values (environment_id);
+CREATE INDEX idx_environment_values_key ON environment_values (key);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE environment_values;
+-- +goose StatementEnd
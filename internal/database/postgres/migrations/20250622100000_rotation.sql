@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE environment_values ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+CREATE TABLE value_versions (
+    id BIGSERIAL PRIMARY KEY,
+    value_id BIGINT NOT NULL,
+    version BIGINT NOT NULL,
+    value TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (value_id) REFERENCES environment_values (id)
+);
+
+CREATE UNIQUE INDEX idx_value_versions_value_id_version ON value_versions (value_id, version);
+
+INSERT INTO value_versions (value_id, version, value)
+SELECT id, version, value FROM environment_values;
+
+CREATE TABLE rotation_policies (
+    id BIGSERIAL PRIMARY KEY,
+    value_id BIGINT NOT NULL UNIQUE,
+    rotator TEXT NOT NULL,
+    params TEXT NOT NULL DEFAULT '{}',
+    interval_seconds BIGINT NOT NULL,
+    next_rotation_at TIMESTAMPTZ NOT NULL,
+    last_rotated_at TIMESTAMPTZ,
+    failures BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (value_id) REFERENCES environment_values (id)
+);
+
+CREATE INDEX idx_rotation_policies_next_rotation_at ON rotation_policies (next_rotation_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE rotation_policies;
+DROP TABLE value_versions;
+ALTER TABLE environment_values DROP COLUMN version;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE environment_values ADD COLUMN expires_at TIMESTAMPTZ;
+
+CREATE INDEX idx_environment_values_expires_at ON environment_values (expires_at);
+
+CREATE TABLE expired_values (
+    id BIGSERIAL PRIMARY KEY,
+    value_id BIGINT NOT NULL,
+    environment_id BIGINT NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    version BIGINT NOT NULL,
+    expired_at TIMESTAMPTZ NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_expired_values_environment_id ON expired_values (environment_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE expired_values;
+DROP INDEX idx_environment_values_expires_at;
+ALTER TABLE environment_values DROP COLUMN expires_at;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE value_schemas (
+    id BIGSERIAL PRIMARY KEY,
+    environment_id BIGINT NOT NULL,
+    key TEXT NOT NULL,
+    type TEXT NOT NULL DEFAULT 'string',
+    pattern TEXT NOT NULL DEFAULT '',
+    enum TEXT NOT NULL DEFAULT '[]',
+    min DOUBLE PRECISION,
+    max DOUBLE PRECISION,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (environment_id) REFERENCES environment (id),
+    UNIQUE (environment_id, key)
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE value_schemas;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE promotions (
+    id BIGSERIAL PRIMARY KEY,
+    from_environment_id BIGINT NOT NULL,
+    to_environment_id BIGINT NOT NULL,
+    keys TEXT NOT NULL DEFAULT '[]',
+    actor TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (from_environment_id) REFERENCES environment (id),
+    FOREIGN KEY (to_environment_id) REFERENCES environment (id)
+);
+
+CREATE INDEX idx_promotions_created_at ON promotions (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE promotions;
+-- +goose StatementEnd
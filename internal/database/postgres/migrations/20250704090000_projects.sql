@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE projects (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing environments move to the default project, the first one of the sequence
+INSERT INTO projects (name) VALUES ('default');
+
+ALTER TABLE environment ADD COLUMN project_id BIGINT NOT NULL DEFAULT 1;
+
+CREATE INDEX idx_environment_project_id_name ON environment (project_id, name);
+
+CREATE TABLE project_permissions (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT NOT NULL,
+    actor TEXT NOT NULL,
+    role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects (id),
+    UNIQUE (project_id, actor)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE project_permissions;
+
+DROP INDEX idx_environment_project_id_name;
+
+ALTER TABLE environment DROP COLUMN project_id;
+
+DROP TABLE projects;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Full text indexes over key names and metadata, values are never indexed.
+-- The queries must use the same expressions for the indexes to be used.
+CREATE INDEX idx_environment_search ON environment
+USING GIN (to_tsvector('simple', name || ' ' || description || ' ' || tags));
+
+CREATE INDEX idx_environment_values_search ON environment_values
+USING GIN (to_tsvector('simple', key || ' ' || description || ' ' || tags));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_environment_values_search;
+
+DROP INDEX idx_environment_search;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Values of end-to-end encrypted environments are encrypted by the clients,
+-- the server only stores their ciphertext
+ALTER TABLE environment ADD COLUMN e2e BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE environment DROP COLUMN e2e;
+-- +goose StatementEnd
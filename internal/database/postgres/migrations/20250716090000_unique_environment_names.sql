@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Environments created concurrently with the same name keep the oldest one's
+-- name, the others get their id appended so the index can be created
+UPDATE environment SET name = name || '-' || id
+WHERE deleted_at IS NULL AND id NOT IN (
+    SELECT MIN(id) FROM environment WHERE deleted_at IS NULL GROUP BY project_id, name
+);
+
+-- Names are unique in a project, deleted environments don't hold theirs
+CREATE UNIQUE INDEX idx_environment_project_id_name_unique ON environment (project_id, name)
+WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_environment_project_id_name_unique;
+-- +goose StatementEnd
@@ -0,0 +1,415 @@
+-- name: CreateEnvironment :one
+INSERT INTO environment (project_id, name) VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetEnvironment :one
+SELECT * FROM environment WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetAllEnvironments :many
+SELECT * FROM environment WHERE deleted_at IS NULL;
+
+-- name: DeleteEnvironment :exec
+DELETE FROM environment WHERE id = $1;
+
+-- name: GetEnvironmentByName :one
+SELECT * FROM environment WHERE project_id = $1 AND name = $2 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetEnvironmentsByProjectID :many
+SELECT * FROM environment WHERE project_id = $1 AND deleted_at IS NULL;
+
+-- name: ListEnvironmentsByName :many
+-- Environments of a page ordered by name, joined with their values
+SELECT e.*, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(sqlc.arg(with_values) AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = sqlc.arg(project_id) AND deleted_at IS NULL AND name LIKE sqlc.arg(name_prefix) ESCAPE '\'
+        AND name > sqlc.arg(after_name)
+    ORDER BY name
+    LIMIT CAST(sqlc.arg(limit) AS BIGINT)
+)
+ORDER BY e.name, v.key;
+
+-- name: ListEnvironmentsByNameDesc :many
+SELECT e.*, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(sqlc.arg(with_values) AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = sqlc.arg(project_id) AND deleted_at IS NULL AND name LIKE sqlc.arg(name_prefix) ESCAPE '\'
+        AND (CAST(sqlc.arg(first_page) AS BOOLEAN) OR name < sqlc.arg(before_name))
+    ORDER BY name DESC
+    LIMIT CAST(sqlc.arg(limit) AS BIGINT)
+)
+ORDER BY e.name DESC, v.key;
+
+-- name: ListEnvironmentsByCreation :many
+-- Environments of a page in creation order, ids are increasing
+SELECT e.*, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(sqlc.arg(with_values) AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = sqlc.arg(project_id) AND deleted_at IS NULL AND name LIKE sqlc.arg(name_prefix) ESCAPE '\'
+        AND id > sqlc.arg(after_id)
+    ORDER BY id
+    LIMIT CAST(sqlc.arg(limit) AS BIGINT)
+)
+ORDER BY e.id, v.key;
+
+-- name: ListEnvironmentsByCreationDesc :many
+SELECT e.*, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(sqlc.arg(with_values) AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = sqlc.arg(project_id) AND deleted_at IS NULL AND name LIKE sqlc.arg(name_prefix) ESCAPE '\'
+        AND id < sqlc.arg(before_id)
+    ORDER BY id DESC
+    LIMIT CAST(sqlc.arg(limit) AS BIGINT)
+)
+ORDER BY e.id DESC, v.key;
+
+-- name: CreateValue :one
+INSERT INTO environment_values (environment_id, key, value) VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetValue :one
+SELECT * FROM environment_values WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetAllValues :many
+SELECT * FROM environment_values WHERE deleted_at IS NULL;
+
+-- name: DeleteValue :exec
+DELETE FROM environment_values WHERE id = $1;
+
+-- name: GetValuesByEnvironmentID :many
+SELECT * FROM environment_values WHERE environment_id = $1 AND deleted_at IS NULL;
+
+-- name: GetValueByKey :one
+SELECT * FROM environment_values WHERE environment_id = $1 AND key = $2 AND deleted_at IS NULL LIMIT 1;
+
+-- name: UpdateValue :one
+UPDATE environment_values SET value = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING *;
+
+-- name: RenameValue :one
+UPDATE environment_values SET key = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING *;
+
+-- name: SetValueExpiry :one
+UPDATE environment_values SET expires_at = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING *;
+
+-- name: GetExpiredValues :many
+SELECT v.* FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE v.expires_at IS NOT NULL AND v.expires_at <= sqlc.arg(expires_at) AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY v.expires_at LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: GetExpiringValues :many
+SELECT v.id, v.environment_id, e.project_id, e.name, v.key, v.expires_at FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE v.expires_at IS NOT NULL AND v.expires_at <= $1 AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY v.expires_at;
+
+-- name: ArchiveExpiredValue :one
+INSERT INTO expired_values (value_id, environment_id, key, value, version, expired_at) VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: CreateValueVersion :one
+INSERT INTO value_versions (value_id, version, value) VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetValueVersions :many
+SELECT * FROM value_versions WHERE value_id = $1 ORDER BY version DESC;
+
+-- name: DeleteValueVersions :exec
+DELETE FROM value_versions WHERE value_id = $1;
+
+-- name: CreateWebhook :one
+INSERT INTO webhooks (environment_id, url, secret) VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks WHERE id = $1 LIMIT 1;
+
+-- name: GetWebhooksByEnvironmentID :many
+SELECT * FROM webhooks WHERE environment_id = $1;
+
+-- name: GetActiveWebhooksByEnvironmentID :many
+SELECT * FROM webhooks WHERE environment_id = $1 AND active;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks WHERE id = $1;
+
+-- name: DeleteWebhookDeliveriesByWebhookID :exec
+DELETE FROM webhook_deliveries WHERE webhook_id = $1;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at) VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries WHERE id = $1 LIMIT 1;
+
+-- name: GetWebhookDeliveriesByWebhookID :many
+SELECT * FROM webhook_deliveries WHERE webhook_id = sqlc.arg(webhook_id) ORDER BY id DESC LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: GetDueWebhookDeliveries :many
+SELECT * FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= sqlc.arg(next_attempt_at) ORDER BY next_attempt_at LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4, response_code = $5, delivered_at = $6, updated_at = CURRENT_TIMESTAMP
+WHERE id = $7 RETURNING *;
+
+
+-- name: UpsertRotationPolicy :one
+INSERT INTO rotation_policies (value_id, rotator, params, interval_seconds, next_rotation_at) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (value_id) DO UPDATE SET
+    rotator = excluded.rotator,
+    params = excluded.params,
+    interval_seconds = excluded.interval_seconds,
+    next_rotation_at = excluded.next_rotation_at,
+    failures = 0,
+    last_error = '',
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetRotationPolicy :one
+SELECT * FROM rotation_policies WHERE id = $1 LIMIT 1;
+
+-- name: GetRotationPolicyByValueID :one
+SELECT * FROM rotation_policies WHERE value_id = $1 LIMIT 1;
+
+-- name: GetRotationPoliciesByEnvironmentID :many
+SELECT p.*, v.key FROM rotation_policies p
+JOIN environment_values v ON v.id = p.value_id
+WHERE v.environment_id = $1;
+
+-- name: GetDueRotationPolicies :many
+SELECT p.* FROM rotation_policies p
+JOIN environment_values v ON v.id = p.value_id
+JOIN environment e ON e.id = v.environment_id
+WHERE p.next_rotation_at <= sqlc.arg(next_rotation_at) AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY p.next_rotation_at LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: MarkRotationSucceeded :one
+UPDATE rotation_policies
+SET next_rotation_at = $1, last_rotated_at = $2, failures = 0, last_error = '', updated_at = CURRENT_TIMESTAMP
+WHERE id = $3 RETURNING *;
+
+-- name: MarkRotationFailed :one
+UPDATE rotation_policies
+SET next_rotation_at = $1, failures = failures + 1, last_error = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $3 RETURNING *;
+
+-- name: DeleteRotationPolicy :exec
+DELETE FROM rotation_policies WHERE id = $1;
+
+-- name: DeleteRotationPolicyByValueID :exec
+DELETE FROM rotation_policies WHERE value_id = $1;
+
+-- name: UpsertValueSchema :one
+INSERT INTO value_schemas (environment_id, key, type, pattern, enum, min, max, required) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (environment_id, key) DO UPDATE SET
+    type = excluded.type,
+    required = excluded.required,
+    pattern = excluded.pattern,
+    enum = excluded.enum,
+    min = excluded.min,
+    max = excluded.max,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetValueSchemaByKey :one
+SELECT * FROM value_schemas WHERE environment_id = $1 AND key = $2 LIMIT 1;
+
+-- name: GetValueSchemasByEnvironmentID :many
+SELECT * FROM value_schemas WHERE environment_id = $1 ORDER BY key;
+
+-- name: DeleteValueSchema :exec
+DELETE FROM value_schemas WHERE environment_id = $1 AND key = $2;
+
+-- name: CreatePromotion :one
+INSERT INTO promotions (from_environment_id, to_environment_id, keys, actor) VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetPromotionsBefore :many
+SELECT p.*, e.project_id FROM promotions p
+JOIN environment e ON e.id = p.from_environment_id
+WHERE p.id < sqlc.arg(before_id) ORDER BY p.id DESC LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: SetEnvironmentExpiry :one
+UPDATE environment SET expires_at = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2
+RETURNING *;
+
+-- name: GetExpiredEnvironments :many
+SELECT * FROM environment WHERE expires_at IS NOT NULL AND expires_at <= sqlc.arg(expires_at) AND deleted_at IS NULL ORDER BY expires_at LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: DeleteValuesByEnvironmentID :exec
+DELETE FROM environment_values WHERE environment_id = $1;
+
+-- name: DeleteValueVersionsByEnvironmentID :exec
+DELETE FROM value_versions WHERE value_id IN (SELECT id FROM environment_values WHERE environment_id = $1);
+
+-- name: DeleteRotationPoliciesByEnvironmentID :exec
+DELETE FROM rotation_policies WHERE value_id IN (SELECT id FROM environment_values WHERE environment_id = $1);
+
+-- name: DeleteValueSchemasByEnvironmentID :exec
+DELETE FROM value_schemas WHERE environment_id = $1;
+
+-- name: CreateProject :one
+INSERT INTO projects (name) VALUES ($1)
+RETURNING *;
+
+-- name: GetProject :one
+SELECT * FROM projects WHERE id = $1 LIMIT 1;
+
+-- name: GetProjectByName :one
+SELECT * FROM projects WHERE name = $1 LIMIT 1;
+
+-- name: GetAllProjects :many
+SELECT * FROM projects ORDER BY name;
+
+-- name: DeleteProject :exec
+DELETE FROM projects WHERE id = $1;
+
+-- name: UpsertProjectPermission :one
+INSERT INTO project_permissions (project_id, actor, role) VALUES ($1, $2, $3)
+ON CONFLICT (project_id, actor) DO UPDATE SET
+    role = excluded.role,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetProjectPermission :one
+SELECT * FROM project_permissions WHERE project_id = $1 AND actor = $2 LIMIT 1;
+
+-- name: GetProjectPermissions :many
+SELECT * FROM project_permissions WHERE project_id = $1 ORDER BY actor;
+
+-- name: CountProjectPermissions :one
+SELECT COUNT(*) FROM project_permissions WHERE project_id = $1;
+
+-- name: DeleteProjectPermission :exec
+DELETE FROM project_permissions WHERE project_id = $1 AND actor = $2;
+
+-- name: DeleteProjectPermissions :exec
+DELETE FROM project_permissions WHERE project_id = $1;
+
+-- name: SoftDeleteEnvironment :exec
+UPDATE environment SET deleted_at = $1 WHERE id = $2;
+
+-- name: RestoreEnvironment :one
+UPDATE environment SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1
+RETURNING *;
+
+-- name: GetDeletedEnvironment :one
+SELECT * FROM environment WHERE id = $1 AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: GetDeletedEnvironmentsByProjectID :many
+SELECT * FROM environment WHERE project_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC;
+
+-- name: GetPurgeableEnvironments :many
+SELECT * FROM environment WHERE deleted_at IS NOT NULL AND deleted_at <= sqlc.arg(deleted_at) ORDER BY deleted_at LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: SoftDeleteValue :exec
+UPDATE environment_values SET deleted_at = $1 WHERE id = $2;
+
+-- name: RestoreValue :one
+UPDATE environment_values SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1
+RETURNING *;
+
+-- name: GetDeletedValue :one
+SELECT * FROM environment_values WHERE id = $1 AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: GetDeletedValuesByProjectID :many
+SELECT v.*, e.name FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE e.project_id = $1 AND e.deleted_at IS NULL AND v.deleted_at IS NOT NULL
+ORDER BY v.deleted_at DESC;
+
+-- name: GetPurgeableValues :many
+SELECT * FROM environment_values WHERE deleted_at IS NOT NULL AND deleted_at <= sqlc.arg(deleted_at) ORDER BY deleted_at LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: DeleteWebhookDeliveriesByEnvironmentID :exec
+DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE environment_id = $1);
+
+-- name: DeleteWebhooksByEnvironmentID :exec
+DELETE FROM webhooks WHERE environment_id = $1;
+
+-- name: SetEnvironmentMetadata :one
+UPDATE environment SET description = $1, owner = $2, link = $3, tags = $4, updated_at = CURRENT_TIMESTAMP WHERE id = $5 RETURNING *;
+
+-- name: SetValueMetadata :one
+UPDATE environment_values SET description = $1, owner = $2, link = $3, tags = $4, updated_at = CURRENT_TIMESTAMP WHERE id = $5 RETURNING *;
+
+-- name: SearchEnvironments :many
+-- query is a tsquery, ie: 'stripe' & 'key':*
+SELECT * FROM environment
+WHERE to_tsvector('simple', name || ' ' || description || ' ' || tags) @@ to_tsquery('simple', sqlc.arg(query))
+    AND deleted_at IS NULL
+ORDER BY ts_rank(to_tsvector('simple', name || ' ' || description || ' ' || tags), to_tsquery('simple', sqlc.arg(query))) DESC;
+
+-- name: SearchValues :many
+SELECT v.*, e.name, e.project_id FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE to_tsvector('simple', v.key || ' ' || v.description || ' ' || v.tags) @@ to_tsquery('simple', sqlc.arg(query))
+    AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY ts_rank(to_tsvector('simple', v.key || ' ' || v.description || ' ' || v.tags), to_tsquery('simple', sqlc.arg(query))) DESC;
+
+-- name: GetValuesByProjectID :many
+SELECT v.*, e.name FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE e.project_id = $1 AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY e.name, v.key;
+
+-- name: CountCiphertexts :one
+SELECT COUNT(*) FROM (
+    SELECT id FROM environment_values
+    UNION ALL SELECT id FROM value_versions
+    UNION ALL SELECT id FROM expired_values
+) AS ciphertexts;
+
+-- name: GetValueCiphertexts :many
+SELECT id, value FROM environment_values WHERE id > sqlc.arg(after_id) ORDER BY id LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: SetValueCiphertext :exec
+-- Rewrites a value when the master key is rotated, unless it was updated
+-- since it was read
+UPDATE environment_values SET value = sqlc.arg(value) WHERE id = sqlc.arg(id) AND value = sqlc.arg(previous);
+
+-- name: GetVersionCiphertexts :many
+SELECT id, value FROM value_versions WHERE id > sqlc.arg(after_id) ORDER BY id LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: SetVersionCiphertext :exec
+UPDATE value_versions SET value = sqlc.arg(value) WHERE id = sqlc.arg(id) AND value = sqlc.arg(previous);
+
+-- name: GetExpiredValueCiphertexts :many
+SELECT id, value FROM expired_values WHERE id > sqlc.arg(after_id) ORDER BY id LIMIT CAST(sqlc.arg(limit) AS BIGINT);
+
+-- name: SetExpiredValueCiphertext :exec
+UPDATE expired_values SET value = sqlc.arg(value) WHERE id = sqlc.arg(id) AND value = sqlc.arg(previous);
+
+-- name: MarkEnvironmentE2E :one
+-- Values of the environment are encrypted by the clients from then on
+UPDATE environment SET e2e = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1
+RETURNING *;
+
+-- name: CreateEnvironmentEvent :one
+INSERT INTO environment_events (project_id, environment_id, environment_name, event, actor) VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetEnvironmentEventsByProjectID :many
+SELECT * FROM environment_events WHERE project_id = sqlc.arg(project_id) ORDER BY id DESC LIMIT CAST(sqlc.arg(limit) AS BIGINT);
@@ -6,21 +6,110 @@ package database
 
 import (
 	"context"
+	"database/sql"
 )
 
 type Querier interface {
-	CreateEnvironment(ctx context.Context, name string) (Environment, error)
+	ArchiveExpiredValue(ctx context.Context, arg ArchiveExpiredValueParams) (ExpiredValue, error)
+	CountCiphertexts(ctx context.Context) (int64, error)
+	CountProjectPermissions(ctx context.Context, projectID int64) (int64, error)
+	CreateEnvironment(ctx context.Context, arg CreateEnvironmentParams) (Environment, error)
+	CreateEnvironmentEvent(ctx context.Context, arg CreateEnvironmentEventParams) (EnvironmentEvent, error)
+	CreateProject(ctx context.Context, name string) (Project, error)
+	CreatePromotion(ctx context.Context, arg CreatePromotionParams) (Promotion, error)
 	CreateValue(ctx context.Context, arg CreateValueParams) (EnvironmentValue, error)
+	CreateValueVersion(ctx context.Context, arg CreateValueVersionParams) (ValueVersion, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
 	DeleteEnvironment(ctx context.Context, id int64) error
+	DeleteProject(ctx context.Context, id int64) error
+	DeleteProjectPermission(ctx context.Context, arg DeleteProjectPermissionParams) error
+	DeleteProjectPermissions(ctx context.Context, projectID int64) error
+	DeleteRotationPoliciesByEnvironmentID(ctx context.Context, environmentID int64) error
+	DeleteRotationPolicy(ctx context.Context, id int64) error
+	DeleteRotationPolicyByValueID(ctx context.Context, valueID int64) error
 	DeleteValue(ctx context.Context, id int64) error
+	DeleteValueSchema(ctx context.Context, arg DeleteValueSchemaParams) error
+	DeleteValueSchemasByEnvironmentID(ctx context.Context, environmentID int64) error
+	DeleteValueVersions(ctx context.Context, valueID int64) error
+	DeleteValueVersionsByEnvironmentID(ctx context.Context, environmentID int64) error
+	DeleteValuesByEnvironmentID(ctx context.Context, environmentID int64) error
+	DeleteWebhook(ctx context.Context, id int64) error
+	DeleteWebhookDeliveriesByEnvironmentID(ctx context.Context, environmentID int64) error
+	DeleteWebhookDeliveriesByWebhookID(ctx context.Context, webhookID int64) error
+	DeleteWebhooksByEnvironmentID(ctx context.Context, environmentID int64) error
+	GetActiveWebhooksByEnvironmentID(ctx context.Context, environmentID int64) ([]Webhook, error)
 	GetAllEnvironments(ctx context.Context) ([]Environment, error)
+	GetAllProjects(ctx context.Context) ([]Project, error)
 	GetAllValues(ctx context.Context) ([]EnvironmentValue, error)
+	GetDeletedEnvironment(ctx context.Context, id int64) (Environment, error)
+	GetDeletedEnvironmentsByProjectID(ctx context.Context, projectID int64) ([]Environment, error)
+	GetDeletedValue(ctx context.Context, id int64) (EnvironmentValue, error)
+	GetDeletedValuesByProjectID(ctx context.Context, projectID int64) ([]GetDeletedValuesByProjectIDRow, error)
+	GetDueRotationPolicies(ctx context.Context, arg GetDueRotationPoliciesParams) ([]RotationPolicy, error)
+	GetDueWebhookDeliveries(ctx context.Context, arg GetDueWebhookDeliveriesParams) ([]WebhookDelivery, error)
 	GetEnvironment(ctx context.Context, id int64) (Environment, error)
-	GetEnvironmentByName(ctx context.Context, name string) (Environment, error)
+	GetEnvironmentByName(ctx context.Context, arg GetEnvironmentByNameParams) (Environment, error)
+	GetEnvironmentEventsByProjectID(ctx context.Context, arg GetEnvironmentEventsByProjectIDParams) ([]EnvironmentEvent, error)
+	GetEnvironmentsByProjectID(ctx context.Context, projectID int64) ([]Environment, error)
+	GetExpiredEnvironments(ctx context.Context, arg GetExpiredEnvironmentsParams) ([]Environment, error)
+	GetExpiredValueCiphertexts(ctx context.Context, arg GetExpiredValueCiphertextsParams) ([]GetExpiredValueCiphertextsRow, error)
+	GetExpiredValues(ctx context.Context, arg GetExpiredValuesParams) ([]EnvironmentValue, error)
+	GetExpiringValues(ctx context.Context, expiresAt sql.NullTime) ([]GetExpiringValuesRow, error)
+	GetProject(ctx context.Context, id int64) (Project, error)
+	GetProjectByName(ctx context.Context, name string) (Project, error)
+	GetProjectPermission(ctx context.Context, arg GetProjectPermissionParams) (ProjectPermission, error)
+	GetProjectPermissions(ctx context.Context, projectID int64) ([]ProjectPermission, error)
+	GetPromotionsBefore(ctx context.Context, arg GetPromotionsBeforeParams) ([]GetPromotionsBeforeRow, error)
+	GetPurgeableEnvironments(ctx context.Context, arg GetPurgeableEnvironmentsParams) ([]Environment, error)
+	GetPurgeableValues(ctx context.Context, arg GetPurgeableValuesParams) ([]EnvironmentValue, error)
+	GetRotationPoliciesByEnvironmentID(ctx context.Context, environmentID int64) ([]GetRotationPoliciesByEnvironmentIDRow, error)
+	GetRotationPolicy(ctx context.Context, id int64) (RotationPolicy, error)
+	GetRotationPolicyByValueID(ctx context.Context, valueID int64) (RotationPolicy, error)
 	GetValue(ctx context.Context, id int64) (EnvironmentValue, error)
 	GetValueByKey(ctx context.Context, arg GetValueByKeyParams) (EnvironmentValue, error)
+	GetValueCiphertexts(ctx context.Context, arg GetValueCiphertextsParams) ([]GetValueCiphertextsRow, error)
+	GetValueSchemaByKey(ctx context.Context, arg GetValueSchemaByKeyParams) (ValueSchema, error)
+	GetValueSchemasByEnvironmentID(ctx context.Context, environmentID int64) ([]ValueSchema, error)
+	GetValueVersions(ctx context.Context, valueID int64) ([]ValueVersion, error)
 	GetValuesByEnvironmentID(ctx context.Context, environmentID int64) ([]EnvironmentValue, error)
+	GetValuesByProjectID(ctx context.Context, projectID int64) ([]GetValuesByProjectIDRow, error)
+	GetVersionCiphertexts(ctx context.Context, arg GetVersionCiphertextsParams) ([]GetVersionCiphertextsRow, error)
+	GetWebhook(ctx context.Context, id int64) (Webhook, error)
+	GetWebhookDeliveriesByWebhookID(ctx context.Context, arg GetWebhookDeliveriesByWebhookIDParams) ([]WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhooksByEnvironmentID(ctx context.Context, environmentID int64) ([]Webhook, error)
+	// Environments of a page in creation order, ids are increasing
+	ListEnvironmentsByCreation(ctx context.Context, arg ListEnvironmentsByCreationParams) ([]ListEnvironmentsByCreationRow, error)
+	ListEnvironmentsByCreationDesc(ctx context.Context, arg ListEnvironmentsByCreationDescParams) ([]ListEnvironmentsByCreationDescRow, error)
+	// Environments of a page ordered by name, joined with their values
+	ListEnvironmentsByName(ctx context.Context, arg ListEnvironmentsByNameParams) ([]ListEnvironmentsByNameRow, error)
+	ListEnvironmentsByNameDesc(ctx context.Context, arg ListEnvironmentsByNameDescParams) ([]ListEnvironmentsByNameDescRow, error)
+	// Values of the environment are encrypted by the clients from then on
+	MarkEnvironmentE2E(ctx context.Context, id int64) (Environment, error)
+	MarkRotationFailed(ctx context.Context, arg MarkRotationFailedParams) (RotationPolicy, error)
+	MarkRotationSucceeded(ctx context.Context, arg MarkRotationSucceededParams) (RotationPolicy, error)
+	RenameValue(ctx context.Context, arg RenameValueParams) (EnvironmentValue, error)
+	RestoreEnvironment(ctx context.Context, id int64) (Environment, error)
+	RestoreValue(ctx context.Context, id int64) (EnvironmentValue, error)
+	SearchEnvironments(ctx context.Context, query string) ([]Environment, error)
+	SearchValues(ctx context.Context, query string) ([]SearchValuesRow, error)
+	SetEnvironmentExpiry(ctx context.Context, arg SetEnvironmentExpiryParams) (Environment, error)
+	SetEnvironmentMetadata(ctx context.Context, arg SetEnvironmentMetadataParams) (Environment, error)
+	SetExpiredValueCiphertext(ctx context.Context, arg SetExpiredValueCiphertextParams) error
+	// Rewrites a value when the master key is rotated, unless it was updated
+	// since it was read
+	SetValueCiphertext(ctx context.Context, arg SetValueCiphertextParams) error
+	SetValueExpiry(ctx context.Context, arg SetValueExpiryParams) (EnvironmentValue, error)
+	SetValueMetadata(ctx context.Context, arg SetValueMetadataParams) (EnvironmentValue, error)
+	SetVersionCiphertext(ctx context.Context, arg SetVersionCiphertextParams) error
+	SoftDeleteEnvironment(ctx context.Context, arg SoftDeleteEnvironmentParams) error
+	SoftDeleteValue(ctx context.Context, arg SoftDeleteValueParams) error
 	UpdateValue(ctx context.Context, arg UpdateValueParams) (EnvironmentValue, error)
+	UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error)
+	UpsertProjectPermission(ctx context.Context, arg UpsertProjectPermissionParams) (ProjectPermission, error)
+	UpsertRotationPolicy(ctx context.Context, arg UpsertRotationPolicyParams) (RotationPolicy, error)
+	UpsertValueSchema(ctx context.Context, arg UpsertValueSchemaParams) (ValueSchema, error)
 }
 
 var _ Querier = (*Queries)(nil)
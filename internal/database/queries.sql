@@ -1,37 +1,414 @@
 -- name: CreateEnvironment :one
-INSERT INTO environment (name) VALUES (?)
+INSERT INTO environment (project_id, name) VALUES (?, ?)
 RETURNING *;
 
 -- name: GetEnvironment :one
-SELECT * FROM environment WHERE id = ? LIMIT 1;
+SELECT * FROM environment WHERE id = ? AND deleted_at IS NULL LIMIT 1;
 
 -- name: GetAllEnvironments :many
-SELECT * FROM environment;
+SELECT * FROM environment WHERE deleted_at IS NULL;
 
 -- name: DeleteEnvironment :exec
 DELETE FROM environment WHERE id = ?;
 
 -- name: GetEnvironmentByName :one
-SELECT * FROM environment WHERE name = ? LIMIT 1;
+SELECT * FROM environment WHERE project_id = ? AND name = ? AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetEnvironmentsByProjectID :many
+SELECT * FROM environment WHERE project_id = ? AND deleted_at IS NULL;
+
+-- name: ListEnvironmentsByName :many
+-- Environments of a page ordered by name, joined with their values
+SELECT e.*, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(sqlc.arg(with_values) AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = sqlc.arg(project_id) AND deleted_at IS NULL AND name LIKE sqlc.arg(name_prefix) ESCAPE '\'
+        AND name > sqlc.arg(after_name)
+    ORDER BY name
+    LIMIT sqlc.arg(limit)
+)
+ORDER BY e.name, v.key;
+
+-- name: ListEnvironmentsByNameDesc :many
+SELECT e.*, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(sqlc.arg(with_values) AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = sqlc.arg(project_id) AND deleted_at IS NULL AND name LIKE sqlc.arg(name_prefix) ESCAPE '\'
+        AND (CAST(sqlc.arg(first_page) AS BOOLEAN) OR name < sqlc.arg(before_name))
+    ORDER BY name DESC
+    LIMIT sqlc.arg(limit)
+)
+ORDER BY e.name DESC, v.key;
+
+-- name: ListEnvironmentsByCreation :many
+-- Environments of a page in creation order, ids are increasing
+SELECT e.*, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(sqlc.arg(with_values) AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = sqlc.arg(project_id) AND deleted_at IS NULL AND name LIKE sqlc.arg(name_prefix) ESCAPE '\'
+        AND id > sqlc.arg(after_id)
+    ORDER BY id
+    LIMIT sqlc.arg(limit)
+)
+ORDER BY e.id, v.key;
+
+-- name: ListEnvironmentsByCreationDesc :many
+SELECT e.*, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(sqlc.arg(with_values) AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = sqlc.arg(project_id) AND deleted_at IS NULL AND name LIKE sqlc.arg(name_prefix) ESCAPE '\'
+        AND id < sqlc.arg(before_id)
+    ORDER BY id DESC
+    LIMIT sqlc.arg(limit)
+)
+ORDER BY e.id DESC, v.key;
 
 -- name: CreateValue :one
 INSERT INTO environment_values (environment_id, key, value) VALUES (?, ?, ?)
 RETURNING *;
 
 -- name: GetValue :one
-SELECT * FROM environment_values WHERE id = ? LIMIT 1;
+SELECT * FROM environment_values WHERE id = ? AND deleted_at IS NULL LIMIT 1;
 
 -- name: GetAllValues :many
-SELECT * FROM environment_values;
+SELECT * FROM environment_values WHERE deleted_at IS NULL;
 
 -- name: DeleteValue :exec
 DELETE FROM environment_values WHERE id = ?;
 
 -- name: GetValuesByEnvironmentID :many
-SELECT * FROM environment_values WHERE environment_id = ?;
+SELECT * FROM environment_values WHERE environment_id = ? AND deleted_at IS NULL;
 
 -- name: GetValueByKey :one
-SELECT * FROM environment_values WHERE environment_id = ? AND key = ? LIMIT 1;
+SELECT * FROM environment_values WHERE environment_id = ? AND key = ? AND deleted_at IS NULL LIMIT 1;
 
 -- name: UpdateValue :one
-UPDATE environment_values SET value = ? WHERE id = ? RETURNING *;
\ No newline at end of file
+UPDATE environment_values SET value = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING *;
+
+-- name: RenameValue :one
+UPDATE environment_values SET key = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING *;
+
+-- name: SetValueExpiry :one
+UPDATE environment_values SET expires_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING *;
+
+-- name: GetExpiredValues :many
+SELECT v.* FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE v.expires_at IS NOT NULL AND v.expires_at <= ? AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY v.expires_at LIMIT ?;
+
+-- name: GetExpiringValues :many
+SELECT v.id, v.environment_id, e.project_id, e.name, v.key, v.expires_at FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE v.expires_at IS NOT NULL AND v.expires_at <= ? AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY v.expires_at;
+
+-- name: ArchiveExpiredValue :one
+INSERT INTO expired_values (value_id, environment_id, key, value, version, expired_at) VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: CreateValueVersion :one
+INSERT INTO value_versions (value_id, version, value) VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetValueVersions :many
+SELECT * FROM value_versions WHERE value_id = ? ORDER BY version DESC;
+
+-- name: DeleteValueVersions :exec
+DELETE FROM value_versions WHERE value_id = ?;
+
+-- name: CreateWebhook :one
+INSERT INTO webhooks (environment_id, url, secret) VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks WHERE id = ? LIMIT 1;
+
+-- name: GetWebhooksByEnvironmentID :many
+SELECT * FROM webhooks WHERE environment_id = ?;
+
+-- name: GetActiveWebhooksByEnvironmentID :many
+SELECT * FROM webhooks WHERE environment_id = ? AND active = 1;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks WHERE id = ?;
+
+-- name: DeleteWebhookDeliveriesByWebhookID :exec
+DELETE FROM webhook_deliveries WHERE webhook_id = ?;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at) VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries WHERE id = ? LIMIT 1;
+
+-- name: GetWebhookDeliveriesByWebhookID :many
+SELECT * FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?;
+
+-- name: GetDueWebhookDeliveries :many
+SELECT * FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?;
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, response_code = ?, delivered_at = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? RETURNING *;
+
+
+-- name: UpsertRotationPolicy :one
+INSERT INTO rotation_policies (value_id, rotator, params, interval_seconds, next_rotation_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (value_id) DO UPDATE SET
+    rotator = excluded.rotator,
+    params = excluded.params,
+    interval_seconds = excluded.interval_seconds,
+    next_rotation_at = excluded.next_rotation_at,
+    failures = 0,
+    last_error = '',
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetRotationPolicy :one
+SELECT * FROM rotation_policies WHERE id = ? LIMIT 1;
+
+-- name: GetRotationPolicyByValueID :one
+SELECT * FROM rotation_policies WHERE value_id = ? LIMIT 1;
+
+-- name: GetRotationPoliciesByEnvironmentID :many
+SELECT p.*, v.key FROM rotation_policies p
+JOIN environment_values v ON v.id = p.value_id
+WHERE v.environment_id = ?;
+
+-- name: GetDueRotationPolicies :many
+SELECT p.* FROM rotation_policies p
+JOIN environment_values v ON v.id = p.value_id
+JOIN environment e ON e.id = v.environment_id
+WHERE p.next_rotation_at <= ? AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY p.next_rotation_at LIMIT ?;
+
+-- name: MarkRotationSucceeded :one
+UPDATE rotation_policies
+SET next_rotation_at = ?, last_rotated_at = ?, failures = 0, last_error = '', updated_at = CURRENT_TIMESTAMP
+WHERE id = ? RETURNING *;
+
+-- name: MarkRotationFailed :one
+UPDATE rotation_policies
+SET next_rotation_at = ?, failures = failures + 1, last_error = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? RETURNING *;
+
+-- name: DeleteRotationPolicy :exec
+DELETE FROM rotation_policies WHERE id = ?;
+
+-- name: DeleteRotationPolicyByValueID :exec
+DELETE FROM rotation_policies WHERE value_id = ?;
+
+-- name: UpsertValueSchema :one
+INSERT INTO value_schemas (environment_id, key, type, pattern, enum, min, max, required) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (environment_id, key) DO UPDATE SET
+    type = excluded.type,
+    required = excluded.required,
+    pattern = excluded.pattern,
+    enum = excluded.enum,
+    min = excluded.min,
+    max = excluded.max,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetValueSchemaByKey :one
+SELECT * FROM value_schemas WHERE environment_id = ? AND key = ? LIMIT 1;
+
+-- name: GetValueSchemasByEnvironmentID :many
+SELECT * FROM value_schemas WHERE environment_id = ? ORDER BY key;
+
+-- name: DeleteValueSchema :exec
+DELETE FROM value_schemas WHERE environment_id = ? AND key = ?;
+
+-- name: CreatePromotion :one
+INSERT INTO promotions (from_environment_id, to_environment_id, keys, actor) VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetPromotionsBefore :many
+SELECT p.*, e.project_id FROM promotions p
+JOIN environment e ON e.id = p.from_environment_id
+WHERE p.id < sqlc.arg(before_id) ORDER BY p.id DESC LIMIT sqlc.arg(limit);
+
+-- name: SetEnvironmentExpiry :one
+UPDATE environment SET expires_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+RETURNING *;
+
+-- name: GetExpiredEnvironments :many
+SELECT * FROM environment WHERE expires_at IS NOT NULL AND expires_at <= ? AND deleted_at IS NULL ORDER BY expires_at LIMIT ?;
+
+-- name: DeleteValuesByEnvironmentID :exec
+DELETE FROM environment_values WHERE environment_id = ?;
+
+-- name: DeleteValueVersionsByEnvironmentID :exec
+DELETE FROM value_versions WHERE value_id IN (SELECT id FROM environment_values WHERE environment_id = ?);
+
+-- name: DeleteRotationPoliciesByEnvironmentID :exec
+DELETE FROM rotation_policies WHERE value_id IN (SELECT id FROM environment_values WHERE environment_id = ?);
+
+-- name: DeleteValueSchemasByEnvironmentID :exec
+DELETE FROM value_schemas WHERE environment_id = ?;
+
+-- name: CreateProject :one
+INSERT INTO projects (name) VALUES (?)
+RETURNING *;
+
+-- name: GetProject :one
+SELECT * FROM projects WHERE id = ? LIMIT 1;
+
+-- name: GetProjectByName :one
+SELECT * FROM projects WHERE name = ? LIMIT 1;
+
+-- name: GetAllProjects :many
+SELECT * FROM projects ORDER BY name;
+
+-- name: DeleteProject :exec
+DELETE FROM projects WHERE id = ?;
+
+-- name: UpsertProjectPermission :one
+INSERT INTO project_permissions (project_id, actor, role) VALUES (?, ?, ?)
+ON CONFLICT (project_id, actor) DO UPDATE SET
+    role = excluded.role,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetProjectPermission :one
+SELECT * FROM project_permissions WHERE project_id = ? AND actor = ? LIMIT 1;
+
+-- name: GetProjectPermissions :many
+SELECT * FROM project_permissions WHERE project_id = ? ORDER BY actor;
+
+-- name: CountProjectPermissions :one
+SELECT COUNT(*) FROM project_permissions WHERE project_id = ?;
+
+-- name: DeleteProjectPermission :exec
+DELETE FROM project_permissions WHERE project_id = ? AND actor = ?;
+
+-- name: DeleteProjectPermissions :exec
+DELETE FROM project_permissions WHERE project_id = ?;
+
+-- name: SoftDeleteEnvironment :exec
+UPDATE environment SET deleted_at = ? WHERE id = ?;
+
+-- name: RestoreEnvironment :one
+UPDATE environment SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+RETURNING *;
+
+-- name: GetDeletedEnvironment :one
+SELECT * FROM environment WHERE id = ? AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: GetDeletedEnvironmentsByProjectID :many
+SELECT * FROM environment WHERE project_id = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC;
+
+-- name: GetPurgeableEnvironments :many
+SELECT * FROM environment WHERE deleted_at IS NOT NULL AND deleted_at <= ? ORDER BY deleted_at LIMIT ?;
+
+-- name: SoftDeleteValue :exec
+UPDATE environment_values SET deleted_at = ? WHERE id = ?;
+
+-- name: RestoreValue :one
+UPDATE environment_values SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+RETURNING *;
+
+-- name: GetDeletedValue :one
+SELECT * FROM environment_values WHERE id = ? AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: GetDeletedValuesByProjectID :many
+SELECT v.*, e.name FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE e.project_id = ? AND e.deleted_at IS NULL AND v.deleted_at IS NOT NULL
+ORDER BY v.deleted_at DESC;
+
+-- name: GetPurgeableValues :many
+SELECT * FROM environment_values WHERE deleted_at IS NOT NULL AND deleted_at <= ? ORDER BY deleted_at LIMIT ?;
+
+-- name: DeleteWebhookDeliveriesByEnvironmentID :exec
+DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE environment_id = ?);
+
+-- name: DeleteWebhooksByEnvironmentID :exec
+DELETE FROM webhooks WHERE environment_id = ?;
+
+-- name: SetEnvironmentMetadata :one
+UPDATE environment SET description = ?, owner = ?, link = ?, tags = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING *;
+
+-- name: SetValueMetadata :one
+UPDATE environment_values SET description = ?, owner = ?, link = ?, tags = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING *;
+
+-- name: SearchEnvironments :many
+SELECT e.* FROM environment_search
+JOIN environment e ON e.id = environment_search.rowid
+WHERE environment_search MATCH sqlc.arg(query) AND e.deleted_at IS NULL
+ORDER BY rank;
+
+-- name: SearchValues :many
+SELECT v.*, e.name, e.project_id FROM value_search
+JOIN environment_values v ON v.id = value_search.rowid
+JOIN environment e ON e.id = v.environment_id
+WHERE value_search MATCH sqlc.arg(query) AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY rank;
+
+-- name: GetValuesByProjectID :many
+SELECT v.*, e.name FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE e.project_id = ? AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY e.name, v.key;
+
+-- name: CountCiphertexts :one
+SELECT COUNT(*) FROM (
+    SELECT id FROM environment_values
+    UNION ALL SELECT id FROM value_versions
+    UNION ALL SELECT id FROM expired_values
+) AS ciphertexts;
+
+-- name: GetValueCiphertexts :many
+SELECT id, value FROM environment_values WHERE id > sqlc.arg(after_id) ORDER BY id LIMIT sqlc.arg(limit);
+
+-- name: SetValueCiphertext :exec
+-- Rewrites a value when the master key is rotated, unless it was updated
+-- since it was read
+UPDATE environment_values SET value = sqlc.arg(value) WHERE id = sqlc.arg(id) AND value = sqlc.arg(previous);
+
+-- name: GetVersionCiphertexts :many
+SELECT id, value FROM value_versions WHERE id > sqlc.arg(after_id) ORDER BY id LIMIT sqlc.arg(limit);
+
+-- name: SetVersionCiphertext :exec
+UPDATE value_versions SET value = sqlc.arg(value) WHERE id = sqlc.arg(id) AND value = sqlc.arg(previous);
+
+-- name: GetExpiredValueCiphertexts :many
+SELECT id, value FROM expired_values WHERE id > sqlc.arg(after_id) ORDER BY id LIMIT sqlc.arg(limit);
+
+-- name: SetExpiredValueCiphertext :exec
+UPDATE expired_values SET value = sqlc.arg(value) WHERE id = sqlc.arg(id) AND value = sqlc.arg(previous);
+
+-- name: MarkEnvironmentE2E :one
+-- Values of the environment are encrypted by the clients from then on
+UPDATE environment SET e2e = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+RETURNING *;
+
+-- name: CreateEnvironmentEvent :one
+INSERT INTO environment_events (project_id, environment_id, environment_name, event, actor) VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetEnvironmentEventsByProjectID :many
+SELECT * FROM environment_events WHERE project_id = ? ORDER BY id DESC LIMIT ?;
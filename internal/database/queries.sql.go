@@ -7,38 +7,2423 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
+const archiveExpiredValue = `-- name: ArchiveExpiredValue :one
+INSERT INTO expired_values (value_id, environment_id, key, value, version, expired_at) VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id, value_id, environment_id, "key", value, version, expired_at, archived_at
+`
+
+type ArchiveExpiredValueParams struct {
+	ValueID       int64     `db:"value_id" json:"value_id"`
+	EnvironmentID int64     `db:"environment_id" json:"environment_id"`
+	Key           string    `db:"key" json:"key"`
+	Value         string    `db:"value" json:"value"`
+	Version       int64     `db:"version" json:"version"`
+	ExpiredAt     time.Time `db:"expired_at" json:"expired_at"`
+}
+
+func (q *Queries) ArchiveExpiredValue(ctx context.Context, arg ArchiveExpiredValueParams) (ExpiredValue, error) {
+	row := q.db.QueryRowContext(ctx, archiveExpiredValue, arg.ValueID, arg.EnvironmentID, arg.Key, arg.Value, arg.Version, arg.ExpiredAt)
+	var i ExpiredValue
+	err := row.Scan(
+		&i.ID,
+		&i.ValueID,
+		&i.EnvironmentID,
+		&i.Key,
+		&i.Value,
+		&i.Version,
+		&i.ExpiredAt,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
+const countCiphertexts = `-- name: CountCiphertexts :one
+SELECT COUNT(*) FROM (
+    SELECT id FROM environment_values
+    UNION ALL SELECT id FROM value_versions
+    UNION ALL SELECT id FROM expired_values
+) AS ciphertexts
+`
+
+func (q *Queries) CountCiphertexts(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countCiphertexts)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countProjectPermissions = `-- name: CountProjectPermissions :one
+SELECT COUNT(*) FROM project_permissions WHERE project_id = ?
+`
+
+func (q *Queries) CountProjectPermissions(ctx context.Context, projectID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countProjectPermissions, projectID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createEnvironment = `-- name: CreateEnvironment :one
-INSERT INTO environment (name) VALUES (?)
+INSERT INTO environment (project_id, name) VALUES (?, ?)
+RETURNING id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e
+`
+
+type CreateEnvironmentParams struct {
+	ProjectID int64  `db:"project_id" json:"project_id"`
+	Name      string `db:"name" json:"name"`
+}
+
+func (q *Queries) CreateEnvironment(ctx context.Context, arg CreateEnvironmentParams) (Environment, error) {
+	row := q.db.QueryRowContext(ctx, createEnvironment, arg.ProjectID, arg.Name)
+	var i Environment
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+		&i.E2e,
+	)
+	return i, err
+}
+
+const createEnvironmentEvent = `-- name: CreateEnvironmentEvent :one
+INSERT INTO environment_events (project_id, environment_id, environment_name, event, actor) VALUES (?, ?, ?, ?, ?)
+RETURNING id, project_id, environment_id, environment_name, event, actor, created_at
+`
+
+type CreateEnvironmentEventParams struct {
+	ProjectID       int64  `db:"project_id" json:"project_id"`
+	EnvironmentID   int64  `db:"environment_id" json:"environment_id"`
+	EnvironmentName string `db:"environment_name" json:"environment_name"`
+	Event           string `db:"event" json:"event"`
+	Actor           string `db:"actor" json:"actor"`
+}
+
+func (q *Queries) CreateEnvironmentEvent(ctx context.Context, arg CreateEnvironmentEventParams) (EnvironmentEvent, error) {
+	row := q.db.QueryRowContext(ctx, createEnvironmentEvent, arg.ProjectID, arg.EnvironmentID, arg.EnvironmentName, arg.Event, arg.Actor)
+	var i EnvironmentEvent
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.EnvironmentID,
+		&i.EnvironmentName,
+		&i.Event,
+		&i.Actor,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createProject = `-- name: CreateProject :one
+INSERT INTO projects (name) VALUES (?)
 RETURNING id, name, created_at, updated_at
 `
 
-func (q *Queries) CreateEnvironment(ctx context.Context, name string) (Environment, error) {
-	row := q.db.QueryRowContext(ctx, createEnvironment, name)
+func (q *Queries) CreateProject(ctx context.Context, name string) (Project, error) {
+	row := q.db.QueryRowContext(ctx, createProject, name)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createPromotion = `-- name: CreatePromotion :one
+INSERT INTO promotions (from_environment_id, to_environment_id, keys, actor) VALUES (?, ?, ?, ?)
+RETURNING id, from_environment_id, to_environment_id, keys, actor, created_at
+`
+
+type CreatePromotionParams struct {
+	FromEnvironmentID int64  `db:"from_environment_id" json:"from_environment_id"`
+	ToEnvironmentID   int64  `db:"to_environment_id" json:"to_environment_id"`
+	Keys              string `db:"keys" json:"keys"`
+	Actor             string `db:"actor" json:"actor"`
+}
+
+func (q *Queries) CreatePromotion(ctx context.Context, arg CreatePromotionParams) (Promotion, error) {
+	row := q.db.QueryRowContext(ctx, createPromotion, arg.FromEnvironmentID, arg.ToEnvironmentID, arg.Keys, arg.Actor)
+	var i Promotion
+	err := row.Scan(
+		&i.ID,
+		&i.FromEnvironmentID,
+		&i.ToEnvironmentID,
+		&i.Keys,
+		&i.Actor,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createValue = `-- name: CreateValue :one
+INSERT INTO environment_values (environment_id, key, value) VALUES (?, ?, ?)
+RETURNING id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags
+`
+
+type CreateValueParams struct {
+	EnvironmentID int64  `db:"environment_id" json:"environment_id"`
+	Key           string `db:"key" json:"key"`
+	Value         string `db:"value" json:"value"`
+}
+
+func (q *Queries) CreateValue(ctx context.Context, arg CreateValueParams) (EnvironmentValue, error) {
+	row := q.db.QueryRowContext(ctx, createValue, arg.EnvironmentID, arg.Key, arg.Value)
+	var i EnvironmentValue
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Key,
+		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+	)
+	return i, err
+}
+
+const createValueVersion = `-- name: CreateValueVersion :one
+INSERT INTO value_versions (value_id, version, value) VALUES (?, ?, ?)
+RETURNING id, value_id, version, value, created_at
+`
+
+type CreateValueVersionParams struct {
+	ValueID int64  `db:"value_id" json:"value_id"`
+	Version int64  `db:"version" json:"version"`
+	Value   string `db:"value" json:"value"`
+}
+
+func (q *Queries) CreateValueVersion(ctx context.Context, arg CreateValueVersionParams) (ValueVersion, error) {
+	row := q.db.QueryRowContext(ctx, createValueVersion, arg.ValueID, arg.Version, arg.Value)
+	var i ValueVersion
+	err := row.Scan(
+		&i.ID,
+		&i.ValueID,
+		&i.Version,
+		&i.Value,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (environment_id, url, secret) VALUES (?, ?, ?)
+RETURNING id, environment_id, url, secret, active, created_at, updated_at
+`
+
+type CreateWebhookParams struct {
+	EnvironmentID int64  `db:"environment_id" json:"environment_id"`
+	Url           string `db:"url" json:"url"`
+	Secret        string `db:"secret" json:"secret"`
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, createWebhook, arg.EnvironmentID, arg.Url, arg.Secret)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Url,
+		&i.Secret,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at) VALUES (?, ?, ?, ?)
+RETURNING id, webhook_id, event, payload, status, attempts, next_attempt_at, last_error, response_code, delivered_at, created_at, updated_at
+`
+
+type CreateWebhookDeliveryParams struct {
+	WebhookID     int64     `db:"webhook_id" json:"webhook_id"`
+	Event         string    `db:"event" json:"event"`
+	Payload       string    `db:"payload" json:"payload"`
+	NextAttemptAt time.Time `db:"next_attempt_at" json:"next_attempt_at"`
+}
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, createWebhookDelivery, arg.WebhookID, arg.Event, arg.Payload, arg.NextAttemptAt)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.Event,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.ResponseCode,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteEnvironment = `-- name: DeleteEnvironment :exec
+DELETE FROM environment WHERE id = ?
+`
+
+func (q *Queries) DeleteEnvironment(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteEnvironment, id)
+	return err
+}
+
+const deleteProject = `-- name: DeleteProject :exec
+DELETE FROM projects WHERE id = ?
+`
+
+func (q *Queries) DeleteProject(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteProject, id)
+	return err
+}
+
+const deleteProjectPermission = `-- name: DeleteProjectPermission :exec
+DELETE FROM project_permissions WHERE project_id = ? AND actor = ?
+`
+
+type DeleteProjectPermissionParams struct {
+	ProjectID int64  `db:"project_id" json:"project_id"`
+	Actor     string `db:"actor" json:"actor"`
+}
+
+func (q *Queries) DeleteProjectPermission(ctx context.Context, arg DeleteProjectPermissionParams) error {
+	_, err := q.db.ExecContext(ctx, deleteProjectPermission, arg.ProjectID, arg.Actor)
+	return err
+}
+
+const deleteProjectPermissions = `-- name: DeleteProjectPermissions :exec
+DELETE FROM project_permissions WHERE project_id = ?
+`
+
+func (q *Queries) DeleteProjectPermissions(ctx context.Context, projectID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteProjectPermissions, projectID)
+	return err
+}
+
+const deleteRotationPoliciesByEnvironmentID = `-- name: DeleteRotationPoliciesByEnvironmentID :exec
+DELETE FROM rotation_policies WHERE value_id IN (SELECT id FROM environment_values WHERE environment_id = ?)
+`
+
+func (q *Queries) DeleteRotationPoliciesByEnvironmentID(ctx context.Context, environmentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteRotationPoliciesByEnvironmentID, environmentID)
+	return err
+}
+
+const deleteRotationPolicy = `-- name: DeleteRotationPolicy :exec
+DELETE FROM rotation_policies WHERE id = ?
+`
+
+func (q *Queries) DeleteRotationPolicy(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteRotationPolicy, id)
+	return err
+}
+
+const deleteRotationPolicyByValueID = `-- name: DeleteRotationPolicyByValueID :exec
+DELETE FROM rotation_policies WHERE value_id = ?
+`
+
+func (q *Queries) DeleteRotationPolicyByValueID(ctx context.Context, valueID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteRotationPolicyByValueID, valueID)
+	return err
+}
+
+const deleteValue = `-- name: DeleteValue :exec
+DELETE FROM environment_values WHERE id = ?
+`
+
+func (q *Queries) DeleteValue(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteValue, id)
+	return err
+}
+
+const deleteValueSchema = `-- name: DeleteValueSchema :exec
+DELETE FROM value_schemas WHERE environment_id = ? AND key = ?
+`
+
+type DeleteValueSchemaParams struct {
+	EnvironmentID int64  `db:"environment_id" json:"environment_id"`
+	Key           string `db:"key" json:"key"`
+}
+
+func (q *Queries) DeleteValueSchema(ctx context.Context, arg DeleteValueSchemaParams) error {
+	_, err := q.db.ExecContext(ctx, deleteValueSchema, arg.EnvironmentID, arg.Key)
+	return err
+}
+
+const deleteValueSchemasByEnvironmentID = `-- name: DeleteValueSchemasByEnvironmentID :exec
+DELETE FROM value_schemas WHERE environment_id = ?
+`
+
+func (q *Queries) DeleteValueSchemasByEnvironmentID(ctx context.Context, environmentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteValueSchemasByEnvironmentID, environmentID)
+	return err
+}
+
+const deleteValueVersions = `-- name: DeleteValueVersions :exec
+DELETE FROM value_versions WHERE value_id = ?
+`
+
+func (q *Queries) DeleteValueVersions(ctx context.Context, valueID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteValueVersions, valueID)
+	return err
+}
+
+const deleteValueVersionsByEnvironmentID = `-- name: DeleteValueVersionsByEnvironmentID :exec
+DELETE FROM value_versions WHERE value_id IN (SELECT id FROM environment_values WHERE environment_id = ?)
+`
+
+func (q *Queries) DeleteValueVersionsByEnvironmentID(ctx context.Context, environmentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteValueVersionsByEnvironmentID, environmentID)
+	return err
+}
+
+const deleteValuesByEnvironmentID = `-- name: DeleteValuesByEnvironmentID :exec
+DELETE FROM environment_values WHERE environment_id = ?
+`
+
+func (q *Queries) DeleteValuesByEnvironmentID(ctx context.Context, environmentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteValuesByEnvironmentID, environmentID)
+	return err
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :exec
+DELETE FROM webhooks WHERE id = ?
+`
+
+func (q *Queries) DeleteWebhook(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteWebhook, id)
+	return err
+}
+
+const deleteWebhookDeliveriesByEnvironmentID = `-- name: DeleteWebhookDeliveriesByEnvironmentID :exec
+DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE environment_id = ?)
+`
+
+func (q *Queries) DeleteWebhookDeliveriesByEnvironmentID(ctx context.Context, environmentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteWebhookDeliveriesByEnvironmentID, environmentID)
+	return err
+}
+
+const deleteWebhookDeliveriesByWebhookID = `-- name: DeleteWebhookDeliveriesByWebhookID :exec
+DELETE FROM webhook_deliveries WHERE webhook_id = ?
+`
+
+func (q *Queries) DeleteWebhookDeliveriesByWebhookID(ctx context.Context, webhookID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteWebhookDeliveriesByWebhookID, webhookID)
+	return err
+}
+
+const deleteWebhooksByEnvironmentID = `-- name: DeleteWebhooksByEnvironmentID :exec
+DELETE FROM webhooks WHERE environment_id = ?
+`
+
+func (q *Queries) DeleteWebhooksByEnvironmentID(ctx context.Context, environmentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteWebhooksByEnvironmentID, environmentID)
+	return err
+}
+
+const getActiveWebhooksByEnvironmentID = `-- name: GetActiveWebhooksByEnvironmentID :many
+SELECT id, environment_id, url, secret, active, created_at, updated_at FROM webhooks WHERE environment_id = ? AND active = 1
+`
+
+func (q *Queries) GetActiveWebhooksByEnvironmentID(ctx context.Context, environmentID int64) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveWebhooksByEnvironmentID, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.Url,
+			&i.Secret,
+			&i.Active,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllEnvironments = `-- name: GetAllEnvironments :many
+SELECT id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e FROM environment WHERE deleted_at IS NULL
+`
+
+func (q *Queries) GetAllEnvironments(ctx context.Context) ([]Environment, error) {
+	rows, err := q.db.QueryContext(ctx, getAllEnvironments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Environment
+	for rows.Next() {
+		var i Environment
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.E2e,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllProjects = `-- name: GetAllProjects :many
+SELECT id, name, created_at, updated_at FROM projects ORDER BY name
+`
+
+func (q *Queries) GetAllProjects(ctx context.Context) ([]Project, error) {
+	rows, err := q.db.QueryContext(ctx, getAllProjects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Project
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllValues = `-- name: GetAllValues :many
+SELECT id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags FROM environment_values WHERE deleted_at IS NULL
+`
+
+func (q *Queries) GetAllValues(ctx context.Context) ([]EnvironmentValue, error) {
+	rows, err := q.db.QueryContext(ctx, getAllValues)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EnvironmentValue
+	for rows.Next() {
+		var i EnvironmentValue
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.Key,
+			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.ExpiresAt,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedEnvironment = `-- name: GetDeletedEnvironment :one
+SELECT id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e FROM environment WHERE id = ? AND deleted_at IS NOT NULL LIMIT 1
+`
+
+func (q *Queries) GetDeletedEnvironment(ctx context.Context, id int64) (Environment, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedEnvironment, id)
+	var i Environment
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+		&i.E2e,
+	)
+	return i, err
+}
+
+const getDeletedEnvironmentsByProjectID = `-- name: GetDeletedEnvironmentsByProjectID :many
+SELECT id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e FROM environment WHERE project_id = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC
+`
+
+func (q *Queries) GetDeletedEnvironmentsByProjectID(ctx context.Context, projectID int64) ([]Environment, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedEnvironmentsByProjectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Environment
+	for rows.Next() {
+		var i Environment
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.E2e,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedValue = `-- name: GetDeletedValue :one
+SELECT id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags FROM environment_values WHERE id = ? AND deleted_at IS NOT NULL LIMIT 1
+`
+
+func (q *Queries) GetDeletedValue(ctx context.Context, id int64) (EnvironmentValue, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedValue, id)
+	var i EnvironmentValue
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Key,
+		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+	)
+	return i, err
+}
+
+const getDeletedValuesByProjectID = `-- name: GetDeletedValuesByProjectID :many
+SELECT v.id, v.environment_id, v."key", v.value, v.created_at, v.updated_at, v.version, v.expires_at, v.deleted_at, v.description, v.owner, v.link, v.tags, e.name FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE e.project_id = ? AND e.deleted_at IS NULL AND v.deleted_at IS NOT NULL
+ORDER BY v.deleted_at DESC
+`
+
+type GetDeletedValuesByProjectIDRow struct {
+	ID            int64        `db:"id" json:"id"`
+	EnvironmentID int64        `db:"environment_id" json:"environment_id"`
+	Key           string       `db:"key" json:"key"`
+	Value         string       `db:"value" json:"value"`
+	CreatedAt     time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time    `db:"updated_at" json:"updated_at"`
+	Version       int64        `db:"version" json:"version"`
+	ExpiresAt     sql.NullTime `db:"expires_at" json:"expires_at"`
+	DeletedAt     sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	Description   string       `db:"description" json:"description"`
+	Owner         string       `db:"owner" json:"owner"`
+	Link          string       `db:"link" json:"link"`
+	Tags          string       `db:"tags" json:"tags"`
+	Name          string       `db:"name" json:"name"`
+}
+
+func (q *Queries) GetDeletedValuesByProjectID(ctx context.Context, projectID int64) ([]GetDeletedValuesByProjectIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedValuesByProjectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDeletedValuesByProjectIDRow
+	for rows.Next() {
+		var i GetDeletedValuesByProjectIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.Key,
+			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.ExpiresAt,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDueRotationPolicies = `-- name: GetDueRotationPolicies :many
+SELECT p.id, p.value_id, p.rotator, p.params, p.interval_seconds, p.next_rotation_at, p.last_rotated_at, p.failures, p.last_error, p.created_at, p.updated_at FROM rotation_policies p
+JOIN environment_values v ON v.id = p.value_id
+JOIN environment e ON e.id = v.environment_id
+WHERE p.next_rotation_at <= ? AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY p.next_rotation_at LIMIT ?
+`
+
+type GetDueRotationPoliciesParams struct {
+	NextRotationAt time.Time `db:"next_rotation_at" json:"next_rotation_at"`
+	Limit          int64     `db:"limit" json:"limit"`
+}
+
+func (q *Queries) GetDueRotationPolicies(ctx context.Context, arg GetDueRotationPoliciesParams) ([]RotationPolicy, error) {
+	rows, err := q.db.QueryContext(ctx, getDueRotationPolicies, arg.NextRotationAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RotationPolicy
+	for rows.Next() {
+		var i RotationPolicy
+		if err := rows.Scan(
+			&i.ID,
+			&i.ValueID,
+			&i.Rotator,
+			&i.Params,
+			&i.IntervalSeconds,
+			&i.NextRotationAt,
+			&i.LastRotatedAt,
+			&i.Failures,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDueWebhookDeliveries = `-- name: GetDueWebhookDeliveries :many
+SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at, last_error, response_code, delivered_at, created_at, updated_at FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?
+`
+
+type GetDueWebhookDeliveriesParams struct {
+	NextAttemptAt time.Time `db:"next_attempt_at" json:"next_attempt_at"`
+	Limit         int64     `db:"limit" json:"limit"`
+}
+
+func (q *Queries) GetDueWebhookDeliveries(ctx context.Context, arg GetDueWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, getDueWebhookDeliveries, arg.NextAttemptAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.Event,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.ResponseCode,
+			&i.DeliveredAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEnvironment = `-- name: GetEnvironment :one
+SELECT id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e FROM environment WHERE id = ? AND deleted_at IS NULL LIMIT 1
+`
+
+func (q *Queries) GetEnvironment(ctx context.Context, id int64) (Environment, error) {
+	row := q.db.QueryRowContext(ctx, getEnvironment, id)
+	var i Environment
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+		&i.E2e,
+	)
+	return i, err
+}
+
+const getEnvironmentByName = `-- name: GetEnvironmentByName :one
+SELECT id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e FROM environment WHERE project_id = ? AND name = ? AND deleted_at IS NULL LIMIT 1
+`
+
+type GetEnvironmentByNameParams struct {
+	ProjectID int64  `db:"project_id" json:"project_id"`
+	Name      string `db:"name" json:"name"`
+}
+
+func (q *Queries) GetEnvironmentByName(ctx context.Context, arg GetEnvironmentByNameParams) (Environment, error) {
+	row := q.db.QueryRowContext(ctx, getEnvironmentByName, arg.ProjectID, arg.Name)
+	var i Environment
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+		&i.E2e,
+	)
+	return i, err
+}
+
+const getEnvironmentEventsByProjectID = `-- name: GetEnvironmentEventsByProjectID :many
+SELECT id, project_id, environment_id, environment_name, event, actor, created_at FROM environment_events WHERE project_id = ? ORDER BY id DESC LIMIT ?
+`
+
+type GetEnvironmentEventsByProjectIDParams struct {
+	ProjectID int64 `db:"project_id" json:"project_id"`
+	Limit     int64 `db:"limit" json:"limit"`
+}
+
+func (q *Queries) GetEnvironmentEventsByProjectID(ctx context.Context, arg GetEnvironmentEventsByProjectIDParams) ([]EnvironmentEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getEnvironmentEventsByProjectID, arg.ProjectID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EnvironmentEvent
+	for rows.Next() {
+		var i EnvironmentEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.EnvironmentID,
+			&i.EnvironmentName,
+			&i.Event,
+			&i.Actor,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEnvironmentsByProjectID = `-- name: GetEnvironmentsByProjectID :many
+SELECT id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e FROM environment WHERE project_id = ? AND deleted_at IS NULL
+`
+
+func (q *Queries) GetEnvironmentsByProjectID(ctx context.Context, projectID int64) ([]Environment, error) {
+	rows, err := q.db.QueryContext(ctx, getEnvironmentsByProjectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Environment
+	for rows.Next() {
+		var i Environment
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.E2e,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiredEnvironments = `-- name: GetExpiredEnvironments :many
+SELECT id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e FROM environment WHERE expires_at IS NOT NULL AND expires_at <= ? AND deleted_at IS NULL ORDER BY expires_at LIMIT ?
+`
+
+type GetExpiredEnvironmentsParams struct {
+	ExpiresAt sql.NullTime `db:"expires_at" json:"expires_at"`
+	Limit     int64        `db:"limit" json:"limit"`
+}
+
+func (q *Queries) GetExpiredEnvironments(ctx context.Context, arg GetExpiredEnvironmentsParams) ([]Environment, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredEnvironments, arg.ExpiresAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Environment
+	for rows.Next() {
+		var i Environment
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.E2e,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiredValueCiphertexts = `-- name: GetExpiredValueCiphertexts :many
+SELECT id, value FROM expired_values WHERE id > ? ORDER BY id LIMIT ?
+`
+
+type GetExpiredValueCiphertextsParams struct {
+	AfterID int64 `db:"after_id" json:"after_id"`
+	Limit   int64 `db:"limit" json:"limit"`
+}
+
+type GetExpiredValueCiphertextsRow struct {
+	ID    int64  `db:"id" json:"id"`
+	Value string `db:"value" json:"value"`
+}
+
+func (q *Queries) GetExpiredValueCiphertexts(ctx context.Context, arg GetExpiredValueCiphertextsParams) ([]GetExpiredValueCiphertextsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredValueCiphertexts, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExpiredValueCiphertextsRow
+	for rows.Next() {
+		var i GetExpiredValueCiphertextsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Value,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiredValues = `-- name: GetExpiredValues :many
+SELECT v.id, v.environment_id, v."key", v.value, v.created_at, v.updated_at, v.version, v.expires_at, v.deleted_at, v.description, v.owner, v.link, v.tags FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE v.expires_at IS NOT NULL AND v.expires_at <= ? AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY v.expires_at LIMIT ?
+`
+
+type GetExpiredValuesParams struct {
+	ExpiresAt sql.NullTime `db:"expires_at" json:"expires_at"`
+	Limit     int64        `db:"limit" json:"limit"`
+}
+
+func (q *Queries) GetExpiredValues(ctx context.Context, arg GetExpiredValuesParams) ([]EnvironmentValue, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredValues, arg.ExpiresAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EnvironmentValue
+	for rows.Next() {
+		var i EnvironmentValue
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.Key,
+			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.ExpiresAt,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiringValues = `-- name: GetExpiringValues :many
+SELECT v.id, v.environment_id, e.project_id, e.name, v.key, v.expires_at FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE v.expires_at IS NOT NULL AND v.expires_at <= ? AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY v.expires_at
+`
+
+type GetExpiringValuesRow struct {
+	ID            int64        `db:"id" json:"id"`
+	EnvironmentID int64        `db:"environment_id" json:"environment_id"`
+	ProjectID     int64        `db:"project_id" json:"project_id"`
+	Name          string       `db:"name" json:"name"`
+	Key           string       `db:"key" json:"key"`
+	ExpiresAt     sql.NullTime `db:"expires_at" json:"expires_at"`
+}
+
+func (q *Queries) GetExpiringValues(ctx context.Context, expiresAt sql.NullTime) ([]GetExpiringValuesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiringValues, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExpiringValuesRow
+	for rows.Next() {
+		var i GetExpiringValuesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.ProjectID,
+			&i.Name,
+			&i.Key,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProject = `-- name: GetProject :one
+SELECT id, name, created_at, updated_at FROM projects WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetProject(ctx context.Context, id int64) (Project, error) {
+	row := q.db.QueryRowContext(ctx, getProject, id)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getProjectByName = `-- name: GetProjectByName :one
+SELECT id, name, created_at, updated_at FROM projects WHERE name = ? LIMIT 1
+`
+
+func (q *Queries) GetProjectByName(ctx context.Context, name string) (Project, error) {
+	row := q.db.QueryRowContext(ctx, getProjectByName, name)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getProjectPermission = `-- name: GetProjectPermission :one
+SELECT id, project_id, actor, role, created_at, updated_at FROM project_permissions WHERE project_id = ? AND actor = ? LIMIT 1
+`
+
+type GetProjectPermissionParams struct {
+	ProjectID int64  `db:"project_id" json:"project_id"`
+	Actor     string `db:"actor" json:"actor"`
+}
+
+func (q *Queries) GetProjectPermission(ctx context.Context, arg GetProjectPermissionParams) (ProjectPermission, error) {
+	row := q.db.QueryRowContext(ctx, getProjectPermission, arg.ProjectID, arg.Actor)
+	var i ProjectPermission
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Actor,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getProjectPermissions = `-- name: GetProjectPermissions :many
+SELECT id, project_id, actor, role, created_at, updated_at FROM project_permissions WHERE project_id = ? ORDER BY actor
+`
+
+func (q *Queries) GetProjectPermissions(ctx context.Context, projectID int64) ([]ProjectPermission, error) {
+	rows, err := q.db.QueryContext(ctx, getProjectPermissions, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ProjectPermission
+	for rows.Next() {
+		var i ProjectPermission
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Actor,
+			&i.Role,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPromotionsBefore = `-- name: GetPromotionsBefore :many
+SELECT p.id, p.from_environment_id, p.to_environment_id, p.keys, p.actor, p.created_at, e.project_id FROM promotions p
+JOIN environment e ON e.id = p.from_environment_id
+WHERE p.id < ? ORDER BY p.id DESC LIMIT ?
+`
+
+type GetPromotionsBeforeParams struct {
+	BeforeID int64 `db:"before_id" json:"before_id"`
+	Limit    int64 `db:"limit" json:"limit"`
+}
+
+type GetPromotionsBeforeRow struct {
+	ID                int64     `db:"id" json:"id"`
+	FromEnvironmentID int64     `db:"from_environment_id" json:"from_environment_id"`
+	ToEnvironmentID   int64     `db:"to_environment_id" json:"to_environment_id"`
+	Keys              string    `db:"keys" json:"keys"`
+	Actor             string    `db:"actor" json:"actor"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+	ProjectID         int64     `db:"project_id" json:"project_id"`
+}
+
+func (q *Queries) GetPromotionsBefore(ctx context.Context, arg GetPromotionsBeforeParams) ([]GetPromotionsBeforeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPromotionsBefore, arg.BeforeID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPromotionsBeforeRow
+	for rows.Next() {
+		var i GetPromotionsBeforeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromEnvironmentID,
+			&i.ToEnvironmentID,
+			&i.Keys,
+			&i.Actor,
+			&i.CreatedAt,
+			&i.ProjectID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPurgeableEnvironments = `-- name: GetPurgeableEnvironments :many
+SELECT id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e FROM environment WHERE deleted_at IS NOT NULL AND deleted_at <= ? ORDER BY deleted_at LIMIT ?
+`
+
+type GetPurgeableEnvironmentsParams struct {
+	DeletedAt sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	Limit     int64        `db:"limit" json:"limit"`
+}
+
+func (q *Queries) GetPurgeableEnvironments(ctx context.Context, arg GetPurgeableEnvironmentsParams) ([]Environment, error) {
+	rows, err := q.db.QueryContext(ctx, getPurgeableEnvironments, arg.DeletedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Environment
+	for rows.Next() {
+		var i Environment
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.E2e,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPurgeableValues = `-- name: GetPurgeableValues :many
+SELECT id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags FROM environment_values WHERE deleted_at IS NOT NULL AND deleted_at <= ? ORDER BY deleted_at LIMIT ?
+`
+
+type GetPurgeableValuesParams struct {
+	DeletedAt sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	Limit     int64        `db:"limit" json:"limit"`
+}
+
+func (q *Queries) GetPurgeableValues(ctx context.Context, arg GetPurgeableValuesParams) ([]EnvironmentValue, error) {
+	rows, err := q.db.QueryContext(ctx, getPurgeableValues, arg.DeletedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EnvironmentValue
+	for rows.Next() {
+		var i EnvironmentValue
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.Key,
+			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.ExpiresAt,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRotationPoliciesByEnvironmentID = `-- name: GetRotationPoliciesByEnvironmentID :many
+SELECT p.id, p.value_id, p.rotator, p.params, p.interval_seconds, p.next_rotation_at, p.last_rotated_at, p.failures, p.last_error, p.created_at, p.updated_at, v.key FROM rotation_policies p
+JOIN environment_values v ON v.id = p.value_id
+WHERE v.environment_id = ?
+`
+
+type GetRotationPoliciesByEnvironmentIDRow struct {
+	ID              int64        `db:"id" json:"id"`
+	ValueID         int64        `db:"value_id" json:"value_id"`
+	Rotator         string       `db:"rotator" json:"rotator"`
+	Params          string       `db:"params" json:"params"`
+	IntervalSeconds int64        `db:"interval_seconds" json:"interval_seconds"`
+	NextRotationAt  time.Time    `db:"next_rotation_at" json:"next_rotation_at"`
+	LastRotatedAt   sql.NullTime `db:"last_rotated_at" json:"last_rotated_at"`
+	Failures        int64        `db:"failures" json:"failures"`
+	LastError       string       `db:"last_error" json:"last_error"`
+	CreatedAt       time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time    `db:"updated_at" json:"updated_at"`
+	Key             string       `db:"key" json:"key"`
+}
+
+func (q *Queries) GetRotationPoliciesByEnvironmentID(ctx context.Context, environmentID int64) ([]GetRotationPoliciesByEnvironmentIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRotationPoliciesByEnvironmentID, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRotationPoliciesByEnvironmentIDRow
+	for rows.Next() {
+		var i GetRotationPoliciesByEnvironmentIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ValueID,
+			&i.Rotator,
+			&i.Params,
+			&i.IntervalSeconds,
+			&i.NextRotationAt,
+			&i.LastRotatedAt,
+			&i.Failures,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Key,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRotationPolicy = `-- name: GetRotationPolicy :one
+SELECT id, value_id, rotator, params, interval_seconds, next_rotation_at, last_rotated_at, failures, last_error, created_at, updated_at FROM rotation_policies WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetRotationPolicy(ctx context.Context, id int64) (RotationPolicy, error) {
+	row := q.db.QueryRowContext(ctx, getRotationPolicy, id)
+	var i RotationPolicy
+	err := row.Scan(
+		&i.ID,
+		&i.ValueID,
+		&i.Rotator,
+		&i.Params,
+		&i.IntervalSeconds,
+		&i.NextRotationAt,
+		&i.LastRotatedAt,
+		&i.Failures,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getRotationPolicyByValueID = `-- name: GetRotationPolicyByValueID :one
+SELECT id, value_id, rotator, params, interval_seconds, next_rotation_at, last_rotated_at, failures, last_error, created_at, updated_at FROM rotation_policies WHERE value_id = ? LIMIT 1
+`
+
+func (q *Queries) GetRotationPolicyByValueID(ctx context.Context, valueID int64) (RotationPolicy, error) {
+	row := q.db.QueryRowContext(ctx, getRotationPolicyByValueID, valueID)
+	var i RotationPolicy
+	err := row.Scan(
+		&i.ID,
+		&i.ValueID,
+		&i.Rotator,
+		&i.Params,
+		&i.IntervalSeconds,
+		&i.NextRotationAt,
+		&i.LastRotatedAt,
+		&i.Failures,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getValue = `-- name: GetValue :one
+SELECT id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags FROM environment_values WHERE id = ? AND deleted_at IS NULL LIMIT 1
+`
+
+func (q *Queries) GetValue(ctx context.Context, id int64) (EnvironmentValue, error) {
+	row := q.db.QueryRowContext(ctx, getValue, id)
+	var i EnvironmentValue
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Key,
+		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+	)
+	return i, err
+}
+
+const getValueByKey = `-- name: GetValueByKey :one
+SELECT id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags FROM environment_values WHERE environment_id = ? AND key = ? AND deleted_at IS NULL LIMIT 1
+`
+
+type GetValueByKeyParams struct {
+	EnvironmentID int64  `db:"environment_id" json:"environment_id"`
+	Key           string `db:"key" json:"key"`
+}
+
+func (q *Queries) GetValueByKey(ctx context.Context, arg GetValueByKeyParams) (EnvironmentValue, error) {
+	row := q.db.QueryRowContext(ctx, getValueByKey, arg.EnvironmentID, arg.Key)
+	var i EnvironmentValue
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Key,
+		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+	)
+	return i, err
+}
+
+const getValueCiphertexts = `-- name: GetValueCiphertexts :many
+SELECT id, value FROM environment_values WHERE id > ? ORDER BY id LIMIT ?
+`
+
+type GetValueCiphertextsParams struct {
+	AfterID int64 `db:"after_id" json:"after_id"`
+	Limit   int64 `db:"limit" json:"limit"`
+}
+
+type GetValueCiphertextsRow struct {
+	ID    int64  `db:"id" json:"id"`
+	Value string `db:"value" json:"value"`
+}
+
+func (q *Queries) GetValueCiphertexts(ctx context.Context, arg GetValueCiphertextsParams) ([]GetValueCiphertextsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getValueCiphertexts, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetValueCiphertextsRow
+	for rows.Next() {
+		var i GetValueCiphertextsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Value,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getValueSchemaByKey = `-- name: GetValueSchemaByKey :one
+SELECT id, environment_id, "key", type, pattern, enum, min, max, created_at, updated_at, required FROM value_schemas WHERE environment_id = ? AND key = ? LIMIT 1
+`
+
+type GetValueSchemaByKeyParams struct {
+	EnvironmentID int64  `db:"environment_id" json:"environment_id"`
+	Key           string `db:"key" json:"key"`
+}
+
+func (q *Queries) GetValueSchemaByKey(ctx context.Context, arg GetValueSchemaByKeyParams) (ValueSchema, error) {
+	row := q.db.QueryRowContext(ctx, getValueSchemaByKey, arg.EnvironmentID, arg.Key)
+	var i ValueSchema
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Key,
+		&i.Type,
+		&i.Pattern,
+		&i.Enum,
+		&i.Min,
+		&i.Max,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Required,
+	)
+	return i, err
+}
+
+const getValueSchemasByEnvironmentID = `-- name: GetValueSchemasByEnvironmentID :many
+SELECT id, environment_id, "key", type, pattern, enum, min, max, created_at, updated_at, required FROM value_schemas WHERE environment_id = ? ORDER BY key
+`
+
+func (q *Queries) GetValueSchemasByEnvironmentID(ctx context.Context, environmentID int64) ([]ValueSchema, error) {
+	rows, err := q.db.QueryContext(ctx, getValueSchemasByEnvironmentID, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ValueSchema
+	for rows.Next() {
+		var i ValueSchema
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.Key,
+			&i.Type,
+			&i.Pattern,
+			&i.Enum,
+			&i.Min,
+			&i.Max,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Required,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getValueVersions = `-- name: GetValueVersions :many
+SELECT id, value_id, version, value, created_at FROM value_versions WHERE value_id = ? ORDER BY version DESC
+`
+
+func (q *Queries) GetValueVersions(ctx context.Context, valueID int64) ([]ValueVersion, error) {
+	rows, err := q.db.QueryContext(ctx, getValueVersions, valueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ValueVersion
+	for rows.Next() {
+		var i ValueVersion
+		if err := rows.Scan(
+			&i.ID,
+			&i.ValueID,
+			&i.Version,
+			&i.Value,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getValuesByEnvironmentID = `-- name: GetValuesByEnvironmentID :many
+SELECT id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags FROM environment_values WHERE environment_id = ? AND deleted_at IS NULL
+`
+
+func (q *Queries) GetValuesByEnvironmentID(ctx context.Context, environmentID int64) ([]EnvironmentValue, error) {
+	rows, err := q.db.QueryContext(ctx, getValuesByEnvironmentID, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EnvironmentValue
+	for rows.Next() {
+		var i EnvironmentValue
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.Key,
+			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.ExpiresAt,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getValuesByProjectID = `-- name: GetValuesByProjectID :many
+SELECT v.id, v.environment_id, v."key", v.value, v.created_at, v.updated_at, v.version, v.expires_at, v.deleted_at, v.description, v.owner, v.link, v.tags, e.name FROM environment_values v
+JOIN environment e ON e.id = v.environment_id
+WHERE e.project_id = ? AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY e.name, v.key
+`
+
+type GetValuesByProjectIDRow struct {
+	ID            int64        `db:"id" json:"id"`
+	EnvironmentID int64        `db:"environment_id" json:"environment_id"`
+	Key           string       `db:"key" json:"key"`
+	Value         string       `db:"value" json:"value"`
+	CreatedAt     time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time    `db:"updated_at" json:"updated_at"`
+	Version       int64        `db:"version" json:"version"`
+	ExpiresAt     sql.NullTime `db:"expires_at" json:"expires_at"`
+	DeletedAt     sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	Description   string       `db:"description" json:"description"`
+	Owner         string       `db:"owner" json:"owner"`
+	Link          string       `db:"link" json:"link"`
+	Tags          string       `db:"tags" json:"tags"`
+	Name          string       `db:"name" json:"name"`
+}
+
+func (q *Queries) GetValuesByProjectID(ctx context.Context, projectID int64) ([]GetValuesByProjectIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getValuesByProjectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetValuesByProjectIDRow
+	for rows.Next() {
+		var i GetValuesByProjectIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.Key,
+			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			&i.ExpiresAt,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVersionCiphertexts = `-- name: GetVersionCiphertexts :many
+SELECT id, value FROM value_versions WHERE id > ? ORDER BY id LIMIT ?
+`
+
+type GetVersionCiphertextsParams struct {
+	AfterID int64 `db:"after_id" json:"after_id"`
+	Limit   int64 `db:"limit" json:"limit"`
+}
+
+type GetVersionCiphertextsRow struct {
+	ID    int64  `db:"id" json:"id"`
+	Value string `db:"value" json:"value"`
+}
+
+func (q *Queries) GetVersionCiphertexts(ctx context.Context, arg GetVersionCiphertextsParams) ([]GetVersionCiphertextsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getVersionCiphertexts, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetVersionCiphertextsRow
+	for rows.Next() {
+		var i GetVersionCiphertextsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Value,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhook = `-- name: GetWebhook :one
+SELECT id, environment_id, url, secret, active, created_at, updated_at FROM webhooks WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetWebhook(ctx context.Context, id int64) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, getWebhook, id)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Url,
+		&i.Secret,
+		&i.Active,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getWebhookDeliveriesByWebhookID = `-- name: GetWebhookDeliveriesByWebhookID :many
+SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at, last_error, response_code, delivered_at, created_at, updated_at FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?
+`
+
+type GetWebhookDeliveriesByWebhookIDParams struct {
+	WebhookID int64 `db:"webhook_id" json:"webhook_id"`
+	Limit     int64 `db:"limit" json:"limit"`
+}
+
+func (q *Queries) GetWebhookDeliveriesByWebhookID(ctx context.Context, arg GetWebhookDeliveriesByWebhookIDParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhookDeliveriesByWebhookID, arg.WebhookID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.Event,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.ResponseCode,
+			&i.DeliveredAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookDelivery = `-- name: GetWebhookDelivery :one
+SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at, last_error, response_code, delivered_at, created_at, updated_at FROM webhook_deliveries WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookDelivery, id)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.Event,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.ResponseCode,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getWebhooksByEnvironmentID = `-- name: GetWebhooksByEnvironmentID :many
+SELECT id, environment_id, url, secret, active, created_at, updated_at FROM webhooks WHERE environment_id = ?
+`
+
+func (q *Queries) GetWebhooksByEnvironmentID(ctx context.Context, environmentID int64) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhooksByEnvironmentID, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.EnvironmentID,
+			&i.Url,
+			&i.Secret,
+			&i.Active,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEnvironmentsByCreation = `-- name: ListEnvironmentsByCreation :many
+SELECT e.id, e.name, e.created_at, e.updated_at, e.expires_at, e.project_id, e.deleted_at, e.description, e.owner, e.link, e.tags, e.e2e, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(? AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = ? AND deleted_at IS NULL AND name LIKE ? ESCAPE '\'
+        AND id > ?
+    ORDER BY id
+    LIMIT ?
+)
+ORDER BY e.id, v.key
+`
+
+type ListEnvironmentsByCreationParams struct {
+	WithValues bool   `db:"with_values" json:"with_values"`
+	ProjectID  int64  `db:"project_id" json:"project_id"`
+	NamePrefix string `db:"name_prefix" json:"name_prefix"`
+	AfterID    int64  `db:"after_id" json:"after_id"`
+	Limit      int64  `db:"limit" json:"limit"`
+}
+
+type ListEnvironmentsByCreationRow struct {
+	ID               int64          `db:"id" json:"id"`
+	Name             string         `db:"name" json:"name"`
+	CreatedAt        time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time      `db:"updated_at" json:"updated_at"`
+	ExpiresAt        sql.NullTime   `db:"expires_at" json:"expires_at"`
+	ProjectID        int64          `db:"project_id" json:"project_id"`
+	DeletedAt        sql.NullTime   `db:"deleted_at" json:"deleted_at"`
+	Description      string         `db:"description" json:"description"`
+	Owner            string         `db:"owner" json:"owner"`
+	Link             string         `db:"link" json:"link"`
+	Tags             string         `db:"tags" json:"tags"`
+	E2e              bool           `db:"e2e" json:"e2e"`
+	ValueID          sql.NullInt64  `db:"value_id" json:"value_id"`
+	ValueKey         sql.NullString `db:"value_key" json:"value_key"`
+	Value            sql.NullString `db:"value" json:"value"`
+	ValueExpiresAt   sql.NullTime   `db:"value_expires_at" json:"value_expires_at"`
+	ValueDescription sql.NullString `db:"value_description" json:"value_description"`
+	ValueOwner       sql.NullString `db:"value_owner" json:"value_owner"`
+	ValueLink        sql.NullString `db:"value_link" json:"value_link"`
+	ValueTags        sql.NullString `db:"value_tags" json:"value_tags"`
+	ValueType        sql.NullString `db:"value_type" json:"value_type"`
+}
+
+// Environments of a page in creation order, ids are increasing
+func (q *Queries) ListEnvironmentsByCreation(ctx context.Context, arg ListEnvironmentsByCreationParams) ([]ListEnvironmentsByCreationRow, error) {
+	rows, err := q.db.QueryContext(ctx, listEnvironmentsByCreation, arg.WithValues, arg.ProjectID, arg.NamePrefix, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEnvironmentsByCreationRow
+	for rows.Next() {
+		var i ListEnvironmentsByCreationRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.E2e,
+			&i.ValueID,
+			&i.ValueKey,
+			&i.Value,
+			&i.ValueExpiresAt,
+			&i.ValueDescription,
+			&i.ValueOwner,
+			&i.ValueLink,
+			&i.ValueTags,
+			&i.ValueType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEnvironmentsByCreationDesc = `-- name: ListEnvironmentsByCreationDesc :many
+SELECT e.id, e.name, e.created_at, e.updated_at, e.expires_at, e.project_id, e.deleted_at, e.description, e.owner, e.link, e.tags, e.e2e, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(? AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = ? AND deleted_at IS NULL AND name LIKE ? ESCAPE '\'
+        AND id < ?
+    ORDER BY id DESC
+    LIMIT ?
+)
+ORDER BY e.id DESC, v.key
+`
+
+type ListEnvironmentsByCreationDescParams struct {
+	WithValues bool   `db:"with_values" json:"with_values"`
+	ProjectID  int64  `db:"project_id" json:"project_id"`
+	NamePrefix string `db:"name_prefix" json:"name_prefix"`
+	BeforeID   int64  `db:"before_id" json:"before_id"`
+	Limit      int64  `db:"limit" json:"limit"`
+}
+
+type ListEnvironmentsByCreationDescRow struct {
+	ID               int64          `db:"id" json:"id"`
+	Name             string         `db:"name" json:"name"`
+	CreatedAt        time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time      `db:"updated_at" json:"updated_at"`
+	ExpiresAt        sql.NullTime   `db:"expires_at" json:"expires_at"`
+	ProjectID        int64          `db:"project_id" json:"project_id"`
+	DeletedAt        sql.NullTime   `db:"deleted_at" json:"deleted_at"`
+	Description      string         `db:"description" json:"description"`
+	Owner            string         `db:"owner" json:"owner"`
+	Link             string         `db:"link" json:"link"`
+	Tags             string         `db:"tags" json:"tags"`
+	E2e              bool           `db:"e2e" json:"e2e"`
+	ValueID          sql.NullInt64  `db:"value_id" json:"value_id"`
+	ValueKey         sql.NullString `db:"value_key" json:"value_key"`
+	Value            sql.NullString `db:"value" json:"value"`
+	ValueExpiresAt   sql.NullTime   `db:"value_expires_at" json:"value_expires_at"`
+	ValueDescription sql.NullString `db:"value_description" json:"value_description"`
+	ValueOwner       sql.NullString `db:"value_owner" json:"value_owner"`
+	ValueLink        sql.NullString `db:"value_link" json:"value_link"`
+	ValueTags        sql.NullString `db:"value_tags" json:"value_tags"`
+	ValueType        sql.NullString `db:"value_type" json:"value_type"`
+}
+
+func (q *Queries) ListEnvironmentsByCreationDesc(ctx context.Context, arg ListEnvironmentsByCreationDescParams) ([]ListEnvironmentsByCreationDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listEnvironmentsByCreationDesc, arg.WithValues, arg.ProjectID, arg.NamePrefix, arg.BeforeID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEnvironmentsByCreationDescRow
+	for rows.Next() {
+		var i ListEnvironmentsByCreationDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.E2e,
+			&i.ValueID,
+			&i.ValueKey,
+			&i.Value,
+			&i.ValueExpiresAt,
+			&i.ValueDescription,
+			&i.ValueOwner,
+			&i.ValueLink,
+			&i.ValueTags,
+			&i.ValueType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEnvironmentsByName = `-- name: ListEnvironmentsByName :many
+SELECT e.id, e.name, e.created_at, e.updated_at, e.expires_at, e.project_id, e.deleted_at, e.description, e.owner, e.link, e.tags, e.e2e, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(? AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = ? AND deleted_at IS NULL AND name LIKE ? ESCAPE '\'
+        AND name > ?
+    ORDER BY name
+    LIMIT ?
+)
+ORDER BY e.name, v.key
+`
+
+type ListEnvironmentsByNameParams struct {
+	WithValues bool   `db:"with_values" json:"with_values"`
+	ProjectID  int64  `db:"project_id" json:"project_id"`
+	NamePrefix string `db:"name_prefix" json:"name_prefix"`
+	AfterName  string `db:"after_name" json:"after_name"`
+	Limit      int64  `db:"limit" json:"limit"`
+}
+
+type ListEnvironmentsByNameRow struct {
+	ID               int64          `db:"id" json:"id"`
+	Name             string         `db:"name" json:"name"`
+	CreatedAt        time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time      `db:"updated_at" json:"updated_at"`
+	ExpiresAt        sql.NullTime   `db:"expires_at" json:"expires_at"`
+	ProjectID        int64          `db:"project_id" json:"project_id"`
+	DeletedAt        sql.NullTime   `db:"deleted_at" json:"deleted_at"`
+	Description      string         `db:"description" json:"description"`
+	Owner            string         `db:"owner" json:"owner"`
+	Link             string         `db:"link" json:"link"`
+	Tags             string         `db:"tags" json:"tags"`
+	E2e              bool           `db:"e2e" json:"e2e"`
+	ValueID          sql.NullInt64  `db:"value_id" json:"value_id"`
+	ValueKey         sql.NullString `db:"value_key" json:"value_key"`
+	Value            sql.NullString `db:"value" json:"value"`
+	ValueExpiresAt   sql.NullTime   `db:"value_expires_at" json:"value_expires_at"`
+	ValueDescription sql.NullString `db:"value_description" json:"value_description"`
+	ValueOwner       sql.NullString `db:"value_owner" json:"value_owner"`
+	ValueLink        sql.NullString `db:"value_link" json:"value_link"`
+	ValueTags        sql.NullString `db:"value_tags" json:"value_tags"`
+	ValueType        sql.NullString `db:"value_type" json:"value_type"`
+}
+
+// Environments of a page ordered by name, joined with their values
+func (q *Queries) ListEnvironmentsByName(ctx context.Context, arg ListEnvironmentsByNameParams) ([]ListEnvironmentsByNameRow, error) {
+	rows, err := q.db.QueryContext(ctx, listEnvironmentsByName, arg.WithValues, arg.ProjectID, arg.NamePrefix, arg.AfterName, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEnvironmentsByNameRow
+	for rows.Next() {
+		var i ListEnvironmentsByNameRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.E2e,
+			&i.ValueID,
+			&i.ValueKey,
+			&i.Value,
+			&i.ValueExpiresAt,
+			&i.ValueDescription,
+			&i.ValueOwner,
+			&i.ValueLink,
+			&i.ValueTags,
+			&i.ValueType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEnvironmentsByNameDesc = `-- name: ListEnvironmentsByNameDesc :many
+SELECT e.id, e.name, e.created_at, e.updated_at, e.expires_at, e.project_id, e.deleted_at, e.description, e.owner, e.link, e.tags, e.e2e, v.id AS value_id, v.key AS value_key, v.value, v.expires_at AS value_expires_at,
+    v.description AS value_description, v.owner AS value_owner, v.link AS value_link, v.tags AS value_tags,
+    s.type AS value_type
+FROM environment e
+LEFT JOIN environment_values v ON v.environment_id = e.id AND v.deleted_at IS NULL AND CAST(? AS BOOLEAN)
+LEFT JOIN value_schemas s ON s.environment_id = v.environment_id AND s.key = v.key
+WHERE e.id IN (
+    SELECT id FROM environment
+    WHERE project_id = ? AND deleted_at IS NULL AND name LIKE ? ESCAPE '\'
+        AND (CAST(? AS BOOLEAN) OR name < ?)
+    ORDER BY name DESC
+    LIMIT ?
+)
+ORDER BY e.name DESC, v.key
+`
+
+type ListEnvironmentsByNameDescParams struct {
+	WithValues bool   `db:"with_values" json:"with_values"`
+	ProjectID  int64  `db:"project_id" json:"project_id"`
+	NamePrefix string `db:"name_prefix" json:"name_prefix"`
+	FirstPage  bool   `db:"first_page" json:"first_page"`
+	BeforeName string `db:"before_name" json:"before_name"`
+	Limit      int64  `db:"limit" json:"limit"`
+}
+
+type ListEnvironmentsByNameDescRow struct {
+	ID               int64          `db:"id" json:"id"`
+	Name             string         `db:"name" json:"name"`
+	CreatedAt        time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time      `db:"updated_at" json:"updated_at"`
+	ExpiresAt        sql.NullTime   `db:"expires_at" json:"expires_at"`
+	ProjectID        int64          `db:"project_id" json:"project_id"`
+	DeletedAt        sql.NullTime   `db:"deleted_at" json:"deleted_at"`
+	Description      string         `db:"description" json:"description"`
+	Owner            string         `db:"owner" json:"owner"`
+	Link             string         `db:"link" json:"link"`
+	Tags             string         `db:"tags" json:"tags"`
+	E2e              bool           `db:"e2e" json:"e2e"`
+	ValueID          sql.NullInt64  `db:"value_id" json:"value_id"`
+	ValueKey         sql.NullString `db:"value_key" json:"value_key"`
+	Value            sql.NullString `db:"value" json:"value"`
+	ValueExpiresAt   sql.NullTime   `db:"value_expires_at" json:"value_expires_at"`
+	ValueDescription sql.NullString `db:"value_description" json:"value_description"`
+	ValueOwner       sql.NullString `db:"value_owner" json:"value_owner"`
+	ValueLink        sql.NullString `db:"value_link" json:"value_link"`
+	ValueTags        sql.NullString `db:"value_tags" json:"value_tags"`
+	ValueType        sql.NullString `db:"value_type" json:"value_type"`
+}
+
+func (q *Queries) ListEnvironmentsByNameDesc(ctx context.Context, arg ListEnvironmentsByNameDescParams) ([]ListEnvironmentsByNameDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listEnvironmentsByNameDesc, arg.WithValues, arg.ProjectID, arg.NamePrefix, arg.FirstPage, arg.BeforeName, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEnvironmentsByNameDescRow
+	for rows.Next() {
+		var i ListEnvironmentsByNameDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.E2e,
+			&i.ValueID,
+			&i.ValueKey,
+			&i.Value,
+			&i.ValueExpiresAt,
+			&i.ValueDescription,
+			&i.ValueOwner,
+			&i.ValueLink,
+			&i.ValueTags,
+			&i.ValueType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEnvironmentE2E = `-- name: MarkEnvironmentE2E :one
+UPDATE environment SET e2e = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+RETURNING id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e
+`
+
+// Values of the environment are encrypted by the clients from then on
+func (q *Queries) MarkEnvironmentE2E(ctx context.Context, id int64) (Environment, error) {
+	row := q.db.QueryRowContext(ctx, markEnvironmentE2E, id)
 	var i Environment
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+		&i.E2e,
 	)
 	return i, err
 }
 
-const createValue = `-- name: CreateValue :one
-INSERT INTO environment_values (environment_id, key, value) VALUES (?, ?, ?)
-RETURNING id, environment_id, "key", value, created_at, updated_at
+const markRotationFailed = `-- name: MarkRotationFailed :one
+UPDATE rotation_policies
+SET next_rotation_at = ?, failures = failures + 1, last_error = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? RETURNING id, value_id, rotator, params, interval_seconds, next_rotation_at, last_rotated_at, failures, last_error, created_at, updated_at
 `
 
-type CreateValueParams struct {
-	EnvironmentID int64  `db:"environment_id" json:"environment_id"`
-	Key           string `db:"key" json:"key"`
-	Value         string `db:"value" json:"value"`
+type MarkRotationFailedParams struct {
+	NextRotationAt time.Time `db:"next_rotation_at" json:"next_rotation_at"`
+	LastError      string    `db:"last_error" json:"last_error"`
+	ID             int64     `db:"id" json:"id"`
 }
 
-func (q *Queries) CreateValue(ctx context.Context, arg CreateValueParams) (EnvironmentValue, error) {
-	row := q.db.QueryRowContext(ctx, createValue, arg.EnvironmentID, arg.Key, arg.Value)
+func (q *Queries) MarkRotationFailed(ctx context.Context, arg MarkRotationFailedParams) (RotationPolicy, error) {
+	row := q.db.QueryRowContext(ctx, markRotationFailed, arg.NextRotationAt, arg.LastError, arg.ID)
+	var i RotationPolicy
+	err := row.Scan(
+		&i.ID,
+		&i.ValueID,
+		&i.Rotator,
+		&i.Params,
+		&i.IntervalSeconds,
+		&i.NextRotationAt,
+		&i.LastRotatedAt,
+		&i.Failures,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const markRotationSucceeded = `-- name: MarkRotationSucceeded :one
+UPDATE rotation_policies
+SET next_rotation_at = ?, last_rotated_at = ?, failures = 0, last_error = '', updated_at = CURRENT_TIMESTAMP
+WHERE id = ? RETURNING id, value_id, rotator, params, interval_seconds, next_rotation_at, last_rotated_at, failures, last_error, created_at, updated_at
+`
+
+type MarkRotationSucceededParams struct {
+	NextRotationAt time.Time    `db:"next_rotation_at" json:"next_rotation_at"`
+	LastRotatedAt  sql.NullTime `db:"last_rotated_at" json:"last_rotated_at"`
+	ID             int64        `db:"id" json:"id"`
+}
+
+func (q *Queries) MarkRotationSucceeded(ctx context.Context, arg MarkRotationSucceededParams) (RotationPolicy, error) {
+	row := q.db.QueryRowContext(ctx, markRotationSucceeded, arg.NextRotationAt, arg.LastRotatedAt, arg.ID)
+	var i RotationPolicy
+	err := row.Scan(
+		&i.ID,
+		&i.ValueID,
+		&i.Rotator,
+		&i.Params,
+		&i.IntervalSeconds,
+		&i.NextRotationAt,
+		&i.LastRotatedAt,
+		&i.Failures,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const renameValue = `-- name: RenameValue :one
+UPDATE environment_values SET key = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags
+`
+
+type RenameValueParams struct {
+	Key string `db:"key" json:"key"`
+	ID  int64  `db:"id" json:"id"`
+}
+
+func (q *Queries) RenameValue(ctx context.Context, arg RenameValueParams) (EnvironmentValue, error) {
+	row := q.db.QueryRowContext(ctx, renameValue, arg.Key, arg.ID)
 	var i EnvironmentValue
 	err := row.Scan(
 		&i.ID,
@@ -47,34 +2432,77 @@ func (q *Queries) CreateValue(ctx context.Context, arg CreateValueParams) (Envir
 		&i.Value,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
 	)
 	return i, err
 }
 
-const deleteEnvironment = `-- name: DeleteEnvironment :exec
-DELETE FROM environment WHERE id = ?
+const restoreEnvironment = `-- name: RestoreEnvironment :one
+UPDATE environment SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+RETURNING id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e
 `
 
-func (q *Queries) DeleteEnvironment(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteEnvironment, id)
-	return err
+func (q *Queries) RestoreEnvironment(ctx context.Context, id int64) (Environment, error) {
+	row := q.db.QueryRowContext(ctx, restoreEnvironment, id)
+	var i Environment
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+		&i.E2e,
+	)
+	return i, err
 }
 
-const deleteValue = `-- name: DeleteValue :exec
-DELETE FROM environment_values WHERE id = ?
+const restoreValue = `-- name: RestoreValue :one
+UPDATE environment_values SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+RETURNING id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags
 `
 
-func (q *Queries) DeleteValue(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteValue, id)
-	return err
+func (q *Queries) RestoreValue(ctx context.Context, id int64) (EnvironmentValue, error) {
+	row := q.db.QueryRowContext(ctx, restoreValue, id)
+	var i EnvironmentValue
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Key,
+		&i.Value,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+	)
+	return i, err
 }
 
-const getAllEnvironments = `-- name: GetAllEnvironments :many
-SELECT id, name, created_at, updated_at FROM environment
+const searchEnvironments = `-- name: SearchEnvironments :many
+SELECT e.id, e.name, e.created_at, e.updated_at, e.expires_at, e.project_id, e.deleted_at, e.description, e.owner, e.link, e.tags, e.e2e FROM environment_search
+JOIN environment e ON e.id = environment_search.rowid
+WHERE environment_search MATCH ? AND e.deleted_at IS NULL
+ORDER BY rank
 `
 
-func (q *Queries) GetAllEnvironments(ctx context.Context) ([]Environment, error) {
-	rows, err := q.db.QueryContext(ctx, getAllEnvironments)
+func (q *Queries) SearchEnvironments(ctx context.Context, query string) ([]Environment, error) {
+	rows, err := q.db.QueryContext(ctx, searchEnvironments, query)
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +2515,14 @@ func (q *Queries) GetAllEnvironments(ctx context.Context) ([]Environment, error)
 			&i.Name,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.E2e,
 		); err != nil {
 			return nil, err
 		}
@@ -101,19 +2537,41 @@ func (q *Queries) GetAllEnvironments(ctx context.Context) ([]Environment, error)
 	return items, nil
 }
 
-const getAllValues = `-- name: GetAllValues :many
-SELECT id, environment_id, "key", value, created_at, updated_at FROM environment_values
+const searchValues = `-- name: SearchValues :many
+SELECT v.id, v.environment_id, v."key", v.value, v.created_at, v.updated_at, v.version, v.expires_at, v.deleted_at, v.description, v.owner, v.link, v.tags, e.name, e.project_id FROM value_search
+JOIN environment_values v ON v.id = value_search.rowid
+JOIN environment e ON e.id = v.environment_id
+WHERE value_search MATCH ? AND v.deleted_at IS NULL AND e.deleted_at IS NULL
+ORDER BY rank
 `
 
-func (q *Queries) GetAllValues(ctx context.Context) ([]EnvironmentValue, error) {
-	rows, err := q.db.QueryContext(ctx, getAllValues)
+type SearchValuesRow struct {
+	ID            int64        `db:"id" json:"id"`
+	EnvironmentID int64        `db:"environment_id" json:"environment_id"`
+	Key           string       `db:"key" json:"key"`
+	Value         string       `db:"value" json:"value"`
+	CreatedAt     time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time    `db:"updated_at" json:"updated_at"`
+	Version       int64        `db:"version" json:"version"`
+	ExpiresAt     sql.NullTime `db:"expires_at" json:"expires_at"`
+	DeletedAt     sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	Description   string       `db:"description" json:"description"`
+	Owner         string       `db:"owner" json:"owner"`
+	Link          string       `db:"link" json:"link"`
+	Tags          string       `db:"tags" json:"tags"`
+	Name          string       `db:"name" json:"name"`
+	ProjectID     int64        `db:"project_id" json:"project_id"`
+}
+
+func (q *Queries) SearchValues(ctx context.Context, query string) ([]SearchValuesRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchValues, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []EnvironmentValue
+	var items []SearchValuesRow
 	for rows.Next() {
-		var i EnvironmentValue
+		var i SearchValuesRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.EnvironmentID,
@@ -121,6 +2579,15 @@ func (q *Queries) GetAllValues(ctx context.Context) ([]EnvironmentValue, error)
 			&i.Value,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Version,
+			&i.ExpiresAt,
+			&i.DeletedAt,
+			&i.Description,
+			&i.Owner,
+			&i.Link,
+			&i.Tags,
+			&i.Name,
+			&i.ProjectID,
 		); err != nil {
 			return nil, err
 		}
@@ -135,44 +2602,111 @@ func (q *Queries) GetAllValues(ctx context.Context) ([]EnvironmentValue, error)
 	return items, nil
 }
 
-const getEnvironment = `-- name: GetEnvironment :one
-SELECT id, name, created_at, updated_at FROM environment WHERE id = ? LIMIT 1
+const setEnvironmentExpiry = `-- name: SetEnvironmentExpiry :one
+UPDATE environment SET expires_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+RETURNING id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e
 `
 
-func (q *Queries) GetEnvironment(ctx context.Context, id int64) (Environment, error) {
-	row := q.db.QueryRowContext(ctx, getEnvironment, id)
+type SetEnvironmentExpiryParams struct {
+	ExpiresAt sql.NullTime `db:"expires_at" json:"expires_at"`
+	ID        int64        `db:"id" json:"id"`
+}
+
+func (q *Queries) SetEnvironmentExpiry(ctx context.Context, arg SetEnvironmentExpiryParams) (Environment, error) {
+	row := q.db.QueryRowContext(ctx, setEnvironmentExpiry, arg.ExpiresAt, arg.ID)
 	var i Environment
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+		&i.E2e,
 	)
 	return i, err
 }
 
-const getEnvironmentByName = `-- name: GetEnvironmentByName :one
-SELECT id, name, created_at, updated_at FROM environment WHERE name = ? LIMIT 1
+const setEnvironmentMetadata = `-- name: SetEnvironmentMetadata :one
+UPDATE environment SET description = ?, owner = ?, link = ?, tags = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING id, name, created_at, updated_at, expires_at, project_id, deleted_at, description, owner, link, tags, e2e
 `
 
-func (q *Queries) GetEnvironmentByName(ctx context.Context, name string) (Environment, error) {
-	row := q.db.QueryRowContext(ctx, getEnvironmentByName, name)
+type SetEnvironmentMetadataParams struct {
+	Description string `db:"description" json:"description"`
+	Owner       string `db:"owner" json:"owner"`
+	Link        string `db:"link" json:"link"`
+	Tags        string `db:"tags" json:"tags"`
+	ID          int64  `db:"id" json:"id"`
+}
+
+func (q *Queries) SetEnvironmentMetadata(ctx context.Context, arg SetEnvironmentMetadataParams) (Environment, error) {
+	row := q.db.QueryRowContext(ctx, setEnvironmentMetadata, arg.Description, arg.Owner, arg.Link, arg.Tags, arg.ID)
 	var i Environment
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+		&i.E2e,
 	)
 	return i, err
 }
 
-const getValue = `-- name: GetValue :one
-SELECT id, environment_id, "key", value, created_at, updated_at FROM environment_values WHERE id = ? LIMIT 1
+const setExpiredValueCiphertext = `-- name: SetExpiredValueCiphertext :exec
+UPDATE expired_values SET value = ? WHERE id = ? AND value = ?
 `
 
-func (q *Queries) GetValue(ctx context.Context, id int64) (EnvironmentValue, error) {
-	row := q.db.QueryRowContext(ctx, getValue, id)
+type SetExpiredValueCiphertextParams struct {
+	Value    string `db:"value" json:"value"`
+	ID       int64  `db:"id" json:"id"`
+	Previous string `db:"previous" json:"previous"`
+}
+
+func (q *Queries) SetExpiredValueCiphertext(ctx context.Context, arg SetExpiredValueCiphertextParams) error {
+	_, err := q.db.ExecContext(ctx, setExpiredValueCiphertext, arg.Value, arg.ID, arg.Previous)
+	return err
+}
+
+const setValueCiphertext = `-- name: SetValueCiphertext :exec
+UPDATE environment_values SET value = ? WHERE id = ? AND value = ?
+`
+
+type SetValueCiphertextParams struct {
+	Value    string `db:"value" json:"value"`
+	ID       int64  `db:"id" json:"id"`
+	Previous string `db:"previous" json:"previous"`
+}
+
+// Rewrites a value when the master key is rotated, unless it was updated
+// since it was read
+func (q *Queries) SetValueCiphertext(ctx context.Context, arg SetValueCiphertextParams) error {
+	_, err := q.db.ExecContext(ctx, setValueCiphertext, arg.Value, arg.ID, arg.Previous)
+	return err
+}
+
+const setValueExpiry = `-- name: SetValueExpiry :one
+UPDATE environment_values SET expires_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags
+`
+
+type SetValueExpiryParams struct {
+	ExpiresAt sql.NullTime `db:"expires_at" json:"expires_at"`
+	ID        int64        `db:"id" json:"id"`
+}
+
+func (q *Queries) SetValueExpiry(ctx context.Context, arg SetValueExpiryParams) (EnvironmentValue, error) {
+	row := q.db.QueryRowContext(ctx, setValueExpiry, arg.ExpiresAt, arg.ID)
 	var i EnvironmentValue
 	err := row.Scan(
 		&i.ID,
@@ -181,21 +2715,31 @@ func (q *Queries) GetValue(ctx context.Context, id int64) (EnvironmentValue, err
 		&i.Value,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
 	)
 	return i, err
 }
 
-const getValueByKey = `-- name: GetValueByKey :one
-SELECT id, environment_id, "key", value, created_at, updated_at FROM environment_values WHERE environment_id = ? AND key = ? LIMIT 1
+const setValueMetadata = `-- name: SetValueMetadata :one
+UPDATE environment_values SET description = ?, owner = ?, link = ?, tags = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags
 `
 
-type GetValueByKeyParams struct {
-	EnvironmentID int64  `db:"environment_id" json:"environment_id"`
-	Key           string `db:"key" json:"key"`
+type SetValueMetadataParams struct {
+	Description string `db:"description" json:"description"`
+	Owner       string `db:"owner" json:"owner"`
+	Link        string `db:"link" json:"link"`
+	Tags        string `db:"tags" json:"tags"`
+	ID          int64  `db:"id" json:"id"`
 }
 
-func (q *Queries) GetValueByKey(ctx context.Context, arg GetValueByKeyParams) (EnvironmentValue, error) {
-	row := q.db.QueryRowContext(ctx, getValueByKey, arg.EnvironmentID, arg.Key)
+func (q *Queries) SetValueMetadata(ctx context.Context, arg SetValueMetadataParams) (EnvironmentValue, error) {
+	row := q.db.QueryRowContext(ctx, setValueMetadata, arg.Description, arg.Owner, arg.Link, arg.Tags, arg.ID)
 	var i EnvironmentValue
 	err := row.Scan(
 		&i.ID,
@@ -204,46 +2748,62 @@ func (q *Queries) GetValueByKey(ctx context.Context, arg GetValueByKeyParams) (E
 		&i.Value,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
 	)
 	return i, err
 }
 
-const getValuesByEnvironmentID = `-- name: GetValuesByEnvironmentID :many
-SELECT id, environment_id, "key", value, created_at, updated_at FROM environment_values WHERE environment_id = ?
+const setVersionCiphertext = `-- name: SetVersionCiphertext :exec
+UPDATE value_versions SET value = ? WHERE id = ? AND value = ?
 `
 
-func (q *Queries) GetValuesByEnvironmentID(ctx context.Context, environmentID int64) ([]EnvironmentValue, error) {
-	rows, err := q.db.QueryContext(ctx, getValuesByEnvironmentID, environmentID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []EnvironmentValue
-	for rows.Next() {
-		var i EnvironmentValue
-		if err := rows.Scan(
-			&i.ID,
-			&i.EnvironmentID,
-			&i.Key,
-			&i.Value,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type SetVersionCiphertextParams struct {
+	Value    string `db:"value" json:"value"`
+	ID       int64  `db:"id" json:"id"`
+	Previous string `db:"previous" json:"previous"`
+}
+
+func (q *Queries) SetVersionCiphertext(ctx context.Context, arg SetVersionCiphertextParams) error {
+	_, err := q.db.ExecContext(ctx, setVersionCiphertext, arg.Value, arg.ID, arg.Previous)
+	return err
+}
+
+const softDeleteEnvironment = `-- name: SoftDeleteEnvironment :exec
+UPDATE environment SET deleted_at = ? WHERE id = ?
+`
+
+type SoftDeleteEnvironmentParams struct {
+	DeletedAt sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	ID        int64        `db:"id" json:"id"`
+}
+
+func (q *Queries) SoftDeleteEnvironment(ctx context.Context, arg SoftDeleteEnvironmentParams) error {
+	_, err := q.db.ExecContext(ctx, softDeleteEnvironment, arg.DeletedAt, arg.ID)
+	return err
+}
+
+const softDeleteValue = `-- name: SoftDeleteValue :exec
+UPDATE environment_values SET deleted_at = ? WHERE id = ?
+`
+
+type SoftDeleteValueParams struct {
+	DeletedAt sql.NullTime `db:"deleted_at" json:"deleted_at"`
+	ID        int64        `db:"id" json:"id"`
+}
+
+func (q *Queries) SoftDeleteValue(ctx context.Context, arg SoftDeleteValueParams) error {
+	_, err := q.db.ExecContext(ctx, softDeleteValue, arg.DeletedAt, arg.ID)
+	return err
 }
 
 const updateValue = `-- name: UpdateValue :one
-UPDATE environment_values SET value = ? WHERE id = ? RETURNING id, environment_id, "key", value, created_at, updated_at
+UPDATE environment_values SET value = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ? RETURNING id, environment_id, "key", value, created_at, updated_at, version, expires_at, deleted_at, description, owner, link, tags
 `
 
 type UpdateValueParams struct {
@@ -261,6 +2821,160 @@ func (q *Queries) UpdateValue(ctx context.Context, arg UpdateValueParams) (Envir
 		&i.Value,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Version,
+		&i.ExpiresAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.Owner,
+		&i.Link,
+		&i.Tags,
+	)
+	return i, err
+}
+
+const updateWebhookDelivery = `-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, response_code = ?, delivered_at = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ? RETURNING id, webhook_id, event, payload, status, attempts, next_attempt_at, last_error, response_code, delivered_at, created_at, updated_at
+`
+
+type UpdateWebhookDeliveryParams struct {
+	Status        string       `db:"status" json:"status"`
+	Attempts      int64        `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time    `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     string       `db:"last_error" json:"last_error"`
+	ResponseCode  int64        `db:"response_code" json:"response_code"`
+	DeliveredAt   sql.NullTime `db:"delivered_at" json:"delivered_at"`
+	ID            int64        `db:"id" json:"id"`
+}
+
+func (q *Queries) UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, updateWebhookDelivery, arg.Status, arg.Attempts, arg.NextAttemptAt, arg.LastError, arg.ResponseCode, arg.DeliveredAt, arg.ID)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.Event,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.ResponseCode,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertProjectPermission = `-- name: UpsertProjectPermission :one
+INSERT INTO project_permissions (project_id, actor, role) VALUES (?, ?, ?)
+ON CONFLICT (project_id, actor) DO UPDATE SET
+    role = excluded.role,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING id, project_id, actor, role, created_at, updated_at
+`
+
+type UpsertProjectPermissionParams struct {
+	ProjectID int64  `db:"project_id" json:"project_id"`
+	Actor     string `db:"actor" json:"actor"`
+	Role      string `db:"role" json:"role"`
+}
+
+func (q *Queries) UpsertProjectPermission(ctx context.Context, arg UpsertProjectPermissionParams) (ProjectPermission, error) {
+	row := q.db.QueryRowContext(ctx, upsertProjectPermission, arg.ProjectID, arg.Actor, arg.Role)
+	var i ProjectPermission
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Actor,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertRotationPolicy = `-- name: UpsertRotationPolicy :one
+INSERT INTO rotation_policies (value_id, rotator, params, interval_seconds, next_rotation_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (value_id) DO UPDATE SET
+    rotator = excluded.rotator,
+    params = excluded.params,
+    interval_seconds = excluded.interval_seconds,
+    next_rotation_at = excluded.next_rotation_at,
+    failures = 0,
+    last_error = '',
+    updated_at = CURRENT_TIMESTAMP
+RETURNING id, value_id, rotator, params, interval_seconds, next_rotation_at, last_rotated_at, failures, last_error, created_at, updated_at
+`
+
+type UpsertRotationPolicyParams struct {
+	ValueID         int64     `db:"value_id" json:"value_id"`
+	Rotator         string    `db:"rotator" json:"rotator"`
+	Params          string    `db:"params" json:"params"`
+	IntervalSeconds int64     `db:"interval_seconds" json:"interval_seconds"`
+	NextRotationAt  time.Time `db:"next_rotation_at" json:"next_rotation_at"`
+}
+
+func (q *Queries) UpsertRotationPolicy(ctx context.Context, arg UpsertRotationPolicyParams) (RotationPolicy, error) {
+	row := q.db.QueryRowContext(ctx, upsertRotationPolicy, arg.ValueID, arg.Rotator, arg.Params, arg.IntervalSeconds, arg.NextRotationAt)
+	var i RotationPolicy
+	err := row.Scan(
+		&i.ID,
+		&i.ValueID,
+		&i.Rotator,
+		&i.Params,
+		&i.IntervalSeconds,
+		&i.NextRotationAt,
+		&i.LastRotatedAt,
+		&i.Failures,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertValueSchema = `-- name: UpsertValueSchema :one
+INSERT INTO value_schemas (environment_id, key, type, pattern, enum, min, max, required) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (environment_id, key) DO UPDATE SET
+    type = excluded.type,
+    required = excluded.required,
+    pattern = excluded.pattern,
+    enum = excluded.enum,
+    min = excluded.min,
+    max = excluded.max,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING id, environment_id, "key", type, pattern, enum, min, max, created_at, updated_at, required
+`
+
+type UpsertValueSchemaParams struct {
+	EnvironmentID int64           `db:"environment_id" json:"environment_id"`
+	Key           string          `db:"key" json:"key"`
+	Type          string          `db:"type" json:"type"`
+	Pattern       string          `db:"pattern" json:"pattern"`
+	Enum          string          `db:"enum" json:"enum"`
+	Min           sql.NullFloat64 `db:"min" json:"min"`
+	Max           sql.NullFloat64 `db:"max" json:"max"`
+	Required      bool            `db:"required" json:"required"`
+}
+
+func (q *Queries) UpsertValueSchema(ctx context.Context, arg UpsertValueSchemaParams) (ValueSchema, error) {
+	row := q.db.QueryRowContext(ctx, upsertValueSchema, arg.EnvironmentID, arg.Key, arg.Type, arg.Pattern, arg.Enum, arg.Min, arg.Max, arg.Required)
+	var i ValueSchema
+	err := row.Scan(
+		&i.ID,
+		&i.EnvironmentID,
+		&i.Key,
+		&i.Type,
+		&i.Pattern,
+		&i.Enum,
+		&i.Min,
+		&i.Max,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Required,
 	)
 	return i, err
 }
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DB is a connection pool together with the queries of its driver, the
+// queries of each driver are generated from their own dialect
+type DB struct {
+	*sql.DB
+	newQuerier func(db DBTX) Querier
+}
+
+// NewDB wraps db, newQuerier binds the queries of its driver to a connection
+// or a transaction
+func NewDB(db *sql.DB, newQuerier func(db DBTX) Querier) *DB {
+	return &DB{DB: db, newQuerier: newQuerier}
+}
+
+// NewSQLiteDB wraps a SQLite database
+func NewSQLiteDB(db *sql.DB) *DB {
+	return NewDB(db, func(db DBTX) Querier {
+		return New(db)
+	})
+}
+
+// Wrap returns db with its queries wrapped by wrap, in and outside of
+// transactions, ie: to encrypt values
+func (db *DB) Wrap(wrap func(q Querier) Querier) *DB {
+	newQuerier := db.newQuerier
+	return NewDB(db.DB, func(db DBTX) Querier {
+		return wrap(newQuerier(db))
+	})
+}
+
+// Querier returns the queries of the driver outside of a transaction
+func (db *DB) Querier() Querier {
+	return db.newQuerier(db.DB)
+}
+
+// InTx runs fn with queries bound to a transaction on db. The transaction is
+// committed when fn succeeds and rolled back otherwise.
+func InTx(ctx context.Context, db *DB, fn func(q Querier) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(db.newQuerier(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
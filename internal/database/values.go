@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rodrwan/secretly/internal/schema"
+)
+
+// SetValue creates or updates the value of a key in an environment and
+// records the resulting version. It reports whether the key was created.
+// The value must satisfy the schema of the key, if there is one.
+func SetValue(ctx context.Context, q Querier, environmentID int64, key, value string) (EnvironmentValue, bool, error) {
+	if err := ValidateValue(ctx, q, environmentID, key, value); err != nil {
+		return EnvironmentValue{}, false, err
+	}
+
+	existing, err := q.GetValueByKey(ctx, GetValueByKeyParams{
+		EnvironmentID: environmentID,
+		Key:           key,
+	})
+
+	var (
+		stored  EnvironmentValue
+		created bool
+	)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		stored, err = q.CreateValue(ctx, CreateValueParams{
+			EnvironmentID: environmentID,
+			Key:           key,
+			Value:         value,
+		})
+		created = true
+	case err == nil:
+		stored, err = q.UpdateValue(ctx, UpdateValueParams{
+			ID:    existing.ID,
+			Value: value,
+		})
+	}
+	if err != nil {
+		return EnvironmentValue{}, false, err
+	}
+
+	if _, err := q.CreateValueVersion(ctx, CreateValueVersionParams{
+		ValueID: stored.ID,
+		Version: stored.Version,
+		Value:   stored.Value,
+	}); err != nil {
+		return EnvironmentValue{}, false, err
+	}
+
+	return stored, created, nil
+}
+
+// ValidateValue checks a value against the schema of its key. Keys without
+// a schema accept any value. Invalid values return a *schema.ValidationError.
+func ValidateValue(ctx context.Context, q Querier, environmentID int64, key, value string) error {
+	valueSchema, err := q.GetValueSchemaByKey(ctx, GetValueSchemaByKeyParams{
+		EnvironmentID: environmentID,
+		Key:           key,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get schema of %s: %w", key, err)
+	}
+
+	rule, err := valueSchema.Rule()
+	if err != nil {
+		return err
+	}
+
+	return rule.Validate(key, value)
+}
+
+// Rule returns the validation rule stored in a value schema
+func (s ValueSchema) Rule() (schema.Rule, error) {
+	rule := schema.Rule{
+		Type:     s.Type,
+		Pattern:  s.Pattern,
+		Required: s.Required,
+	}
+	if err := json.Unmarshal([]byte(s.Enum), &rule.Enum); err != nil {
+		return schema.Rule{}, fmt.Errorf("invalid enum of %s: %w", s.Key, err)
+	}
+	if s.Min.Valid {
+		rule.Min = &s.Min.Float64
+	}
+	if s.Max.Valid {
+		rule.Max = &s.Max.Float64
+	}
+	return rule, nil
+}
@@ -0,0 +1,253 @@
+// Package e2e encrypts values end-to-end, for recipients whose X25519 public
+// keys are known, so only the holders of their identities can decrypt them.
+// The server stores the ciphertext as any other value.
+//
+// Like age, each value is sealed with a random file key, which is wrapped
+// for every recipient with a key agreed between an ephemeral key and the
+// recipient's key:
+//
+//	secretly-e2e:v1:<base64 of count, count stanzas and the sealed value>
+//
+// A stanza is the ephemeral public key and the wrapped file key. The header,
+// the count and the stanzas, is authenticated with the value.
+package e2e
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+)
+
+// Prefix starts every encrypted value
+const Prefix = "secretly-e2e:v1:"
+
+const (
+	identityPrefix  = "SECRETLY-E2E-KEY-"
+	recipientPrefix = "secretly-e2e-pub-"
+	// wrapInfo binds the keys derived to wrap file keys to this format
+	wrapInfo = "secretly-e2e v1 wrap"
+	keySize  = 32
+	// stanzaSize is an ephemeral public key and a sealed file key
+	stanzaSize = keySize + 12 + encryption.KeySize + 16
+	// maxRecipients fit the count of the header
+	maxRecipients = 255
+)
+
+// ErrNoIdentity is returned when a value isn't encrypted for any of the
+// identities decrypting it
+var ErrNoIdentity = errors.New("the value isn't encrypted for any of the identities")
+
+var encoding = base64.RawURLEncoding
+
+// Identity is the X25519 private key of a recipient, it decrypts values
+type Identity struct {
+	key *ecdh.PrivateKey
+}
+
+// Recipient is the X25519 public key values are encrypted for
+type Recipient struct {
+	key *ecdh.PublicKey
+}
+
+// GenerateIdentity creates a random identity
+func GenerateIdentity() (*Identity, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{key: key}, nil
+}
+
+// ParseIdentity decodes an identity encoded by String,
+// SECRETLY-E2E-KEY-<base64>
+func ParseIdentity(s string) (*Identity, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(s), identityPrefix)
+	if !ok {
+		return nil, fmt.Errorf("an identity must start with %s", identityPrefix)
+	}
+	b, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	return &Identity{key: key}, nil
+}
+
+func (i *Identity) String() string {
+	return identityPrefix + encoding.EncodeToString(i.key.Bytes())
+}
+
+// Recipient returns the public key values are encrypted with for i
+func (i *Identity) Recipient() *Recipient {
+	return &Recipient{key: i.key.PublicKey()}
+}
+
+// ParseRecipient decodes a recipient encoded by String,
+// secretly-e2e-pub-<base64>
+func ParseRecipient(s string) (*Recipient, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(s), recipientPrefix)
+	if !ok {
+		return nil, fmt.Errorf("a recipient must start with %s", recipientPrefix)
+	}
+	b, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	key, err := ecdh.X25519().NewPublicKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	return &Recipient{key: key}, nil
+}
+
+func (r *Recipient) String() string {
+	return recipientPrefix + encoding.EncodeToString(r.key.Bytes())
+}
+
+// Equal reports whether r and other are the same public key
+func (r *Recipient) Equal(other *Recipient) bool {
+	return r.key.Equal(other.key)
+}
+
+// IsCiphertext reports whether value was encrypted by Encrypt
+func IsCiphertext(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Encrypt encrypts value for recipients, any of their identities decrypts it
+func Encrypt(value string, recipients ...*Recipient) (string, error) {
+	if len(recipients) == 0 {
+		return "", errors.New("there are no recipients to encrypt for")
+	}
+	if len(recipients) > maxRecipients {
+		return "", fmt.Errorf("there can't be more than %d recipients", maxRecipients)
+	}
+
+	fileKey := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return "", err
+	}
+	defer clear(fileKey)
+
+	header := []byte{byte(len(recipients))}
+	for _, recipient := range recipients {
+		stanza, err := wrap(fileKey, recipient)
+		if err != nil {
+			return "", err
+		}
+		header = append(header, stanza...)
+	}
+
+	cipher, err := encryption.NewCipher(fileKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := cipher.Seal([]byte(value), header)
+	if err != nil {
+		return "", err
+	}
+	return Prefix + encoding.EncodeToString(append(header, sealed...)), nil
+}
+
+// Decrypt decrypts a value encrypted by Encrypt with any of identities
+func Decrypt(value string, identities ...*Identity) (string, error) {
+	encoded, ok := strings.CutPrefix(value, Prefix)
+	if !ok {
+		return "", errors.New("the value isn't encrypted end-to-end")
+	}
+	data, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	if len(data) < 1 {
+		return "", errors.New("invalid encrypted value: no header")
+	}
+	count := int(data[0])
+	headerSize := 1 + count*stanzaSize
+	if count == 0 || len(data) < headerSize {
+		return "", errors.New("invalid encrypted value: truncated header")
+	}
+	header, sealed := data[:headerSize], data[headerSize:]
+
+	for _, identity := range identities {
+		for i := range count {
+			stanza := header[1+i*stanzaSize : 1+(i+1)*stanzaSize]
+			fileKey, err := unwrap(stanza, identity)
+			if err != nil {
+				continue
+			}
+			cipher, err := encryption.NewCipher(fileKey)
+			clear(fileKey)
+			if err != nil {
+				return "", err
+			}
+			plaintext, err := cipher.Open(sealed, header)
+			if err != nil {
+				return "", err
+			}
+			return string(plaintext), nil
+		}
+	}
+	return "", ErrNoIdentity
+}
+
+// wrap seals fileKey for recipient, the stanza starts with the ephemeral
+// public key the wrapping key was agreed with
+func wrap(fileKey []byte, recipient *Recipient) ([]byte, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient.key)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := wrapCipher(shared, ephemeral.PublicKey(), recipient.key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := cipher.Seal(fileKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephemeral.PublicKey().Bytes(), sealed...), nil
+}
+
+// unwrap opens the file key of a stanza sealed for identity
+func unwrap(stanza []byte, identity *Identity) ([]byte, error) {
+	ephemeral, err := ecdh.X25519().NewPublicKey(stanza[:keySize])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := identity.key.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := wrapCipher(shared, ephemeral, identity.key.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	return cipher.Open(stanza[keySize:], nil)
+}
+
+// wrapCipher derives the key wrapping a file key from the secret shared
+// by the ephemeral key and the recipient's
+func wrapCipher(shared []byte, ephemeral, recipient *ecdh.PublicKey) (*encryption.Cipher, error) {
+	salt := bytes.Join([][]byte{ephemeral.Bytes(), recipient.Bytes()}, nil)
+	key, err := hkdf.Key(sha256.New, shared, salt, wrapInfo, encryption.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return encryption.NewCipher(key)
+}
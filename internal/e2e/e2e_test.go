@@ -0,0 +1,130 @@
+package e2e
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+)
+
+func newIdentity(t *testing.T) *Identity {
+	t.Helper()
+	identity, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	identity := newIdentity(t)
+	fileKey := make([]byte, encryption.KeySize)
+	for i := range fileKey {
+		fileKey[i] = byte(i)
+	}
+
+	stanza, err := wrap(fileKey, identity.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stanza) != stanzaSize {
+		t.Fatalf("got a stanza of %d bytes, want %d", len(stanza), stanzaSize)
+	}
+	got, err := unwrap(stanza, identity)
+	if err != nil {
+		t.Fatalf("unwrap: %v", err)
+	}
+	if string(got) != string(fileKey) {
+		t.Errorf("unwrap = %x, want %x", got, fileKey)
+	}
+
+	if _, err := unwrap(stanza, newIdentity(t)); err == nil {
+		t.Error("a stanza was unwrapped by another identity")
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	alice, bob := newIdentity(t), newIdentity(t)
+	encrypted, err := Encrypt("s3cret", alice.Recipient(), bob.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsCiphertext(encrypted) || strings.Contains(encrypted, "s3cret") {
+		t.Fatalf("Encrypt = %q", encrypted)
+	}
+
+	for name, identity := range map[string]*Identity{"alice": alice, "bob": bob} {
+		if got, err := Decrypt(encrypted, identity); err != nil || got != "s3cret" {
+			t.Errorf("Decrypt with %s = %q, %v", name, got, err)
+		}
+	}
+	// Any of the identities decrypts it
+	if got, err := Decrypt(encrypted, newIdentity(t), bob); err != nil || got != "s3cret" {
+		t.Errorf("Decrypt with another identity and bob = %q, %v", got, err)
+	}
+}
+
+func TestDecryptFailures(t *testing.T) {
+	alice := newIdentity(t)
+	encrypted, err := Encrypt("s3cret", alice.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Decrypt(encrypted, newIdentity(t)); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("Decrypt with the wrong key: got %v, want ErrNoIdentity", err)
+	}
+	if _, err := Decrypt(encrypted); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("Decrypt without identities: got %v, want ErrNoIdentity", err)
+	}
+
+	data, err := encoding.DecodeString(strings.TrimPrefix(encrypted, Prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := func(i int) string {
+		data := append([]byte(nil), data...)
+		data[i] ^= 1
+		return Prefix + encoding.EncodeToString(data)
+	}
+	invalid := map[string]string{
+		"plaintext":        "s3cret",
+		"not base64":       Prefix + "!!!",
+		"empty":            Prefix,
+		"no recipients":    Prefix + encoding.EncodeToString([]byte{0}),
+		"truncated header": Prefix + encoding.EncodeToString(data[:stanzaSize]),
+		"tampered stanza":  tampered(1 + keySize + 1),
+		"tampered value":   tampered(len(data) - 1),
+	}
+	for name, value := range invalid {
+		if got, err := Decrypt(value, alice); err == nil {
+			t.Errorf("%s: Decrypt = %q, want an error", name, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	identity := newIdentity(t)
+	parsed, err := ParseIdentity(identity.String())
+	if err != nil {
+		t.Fatalf("ParseIdentity: %v", err)
+	}
+	if !parsed.Recipient().Equal(identity.Recipient()) {
+		t.Error("a parsed identity has another recipient")
+	}
+	recipient, err := ParseRecipient(identity.Recipient().String())
+	if err != nil {
+		t.Fatalf("ParseRecipient: %v", err)
+	}
+	if !recipient.Equal(identity.Recipient()) {
+		t.Error("a parsed recipient differs")
+	}
+
+	if _, err := ParseIdentity(identity.Recipient().String()); err == nil {
+		t.Error("a recipient was parsed as an identity")
+	}
+	if _, err := ParseRecipient(identity.String()); err == nil {
+		t.Error("an identity was parsed as a recipient")
+	}
+}
@@ -0,0 +1,85 @@
+// Package encryption seals data with AES-256-GCM
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the size of a key in bytes
+const KeySize = 32
+
+// ErrDecrypt is returned when data can't be opened, because the key is
+// wrong or the data was modified
+var ErrDecrypt = errors.New("failed to decrypt, wrong key or corrupted data")
+
+// Cipher seals and opens data with a key
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher for a key of KeySize bytes
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("the key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts plaintext with a random nonce, which is prepended to the
+// result. The same additionalData is required to open it.
+func (c *Cipher) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts data sealed by Seal
+func (c *Cipher) Open(sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < c.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// ParseKey decodes a key of KeySize bytes encoded as hex or base64, ie: the
+// output of openssl rand -hex 32
+func ParseKey(s string) ([]byte, error) {
+	if key, err := ParseBytes(s); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("the key must be %d bytes encoded as hex or base64", KeySize)
+}
+
+// ParseBytes decodes bytes encoded as hex or base64, ie: a salt
+func ParseBytes(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(s); err == nil {
+		return b, nil
+	}
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return nil, errors.New("must be encoded as hex or base64")
+}
@@ -0,0 +1,134 @@
+package expiry
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/webhook"
+)
+
+// Actions taken on expired values
+const (
+	// ActionDelete removes expired values and their history
+	ActionDelete = "delete"
+	// ActionArchive moves the last version of expired values to expired_values
+	ActionArchive = "archive"
+)
+
+const defaultBatchSize = 100
+
+// Reaper removes values whose expiration has passed
+type Reaper struct {
+	db        database.Querier
+	webhooks  *webhook.Dispatcher
+	action    string
+	interval  time.Duration
+	batchSize int64
+	now       func() time.Time
+	// paused skips the runs while it returns true, nil never pauses
+	paused func() bool
+}
+
+// NewReaper creates a new reaper. action must be ActionDelete or ActionArchive.
+func NewReaper(db database.Querier, dispatcher *webhook.Dispatcher, action string, interval time.Duration) (*Reaper, error) {
+	if action != ActionDelete && action != ActionArchive {
+		return nil, fmt.Errorf("unknown expiry action %q", action)
+	}
+
+	return &Reaper{
+		db:        db,
+		webhooks:  dispatcher,
+		action:    action,
+		interval:  interval,
+		batchSize: defaultBatchSize,
+		now:       func() time.Time { return time.Now().UTC() },
+	}, nil
+}
+
+// Run reaps expired values until ctx is cancelled
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Reap(ctx); err != nil {
+			log.Printf("expiry: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PauseWhile skips the runs while paused returns true, ie: while the master
+// key is sealed and expired values can't be read
+func (r *Reaper) PauseWhile(paused func() bool) {
+	r.paused = paused
+}
+
+// Reap removes every expired value and returns how many were reaped, none
+// while the reaper is paused
+func (r *Reaper) Reap(ctx context.Context) (int, error) {
+	if r.paused != nil && r.paused() {
+		return 0, nil
+	}
+
+	reaped := 0
+	for {
+		values, err := r.db.GetExpiredValues(ctx, database.GetExpiredValuesParams{
+			ExpiresAt: sql.NullTime{Time: r.now(), Valid: true},
+			Limit:     r.batchSize,
+		})
+		if err != nil {
+			return reaped, fmt.Errorf("failed to get expired values: %w", err)
+		}
+
+		for _, value := range values {
+			if err := r.reap(ctx, value); err != nil {
+				return reaped, fmt.Errorf("failed to reap value %d: %w", value.ID, err)
+			}
+			reaped++
+		}
+
+		if int64(len(values)) < r.batchSize {
+			return reaped, nil
+		}
+	}
+}
+
+func (r *Reaper) reap(ctx context.Context, value database.EnvironmentValue) error {
+	if r.action == ActionArchive {
+		if _, err := r.db.ArchiveExpiredValue(ctx, database.ArchiveExpiredValueParams{
+			ValueID:       value.ID,
+			EnvironmentID: value.EnvironmentID,
+			Key:           value.Key,
+			Value:         value.Value,
+			Version:       value.Version,
+			ExpiredAt:     value.ExpiresAt.Time,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := database.PurgeValue(ctx, r.db, value.ID); err != nil {
+		return err
+	}
+
+	// Values of a deleted environment have no one left to notify
+	env, err := r.db.GetEnvironment(ctx, value.EnvironmentID)
+	if err != nil {
+		return nil
+	}
+	if err := r.webhooks.Publish(ctx, webhook.NewEvent(webhook.EventValueExpired, env.ID, env.Name, value.Key)); err != nil {
+		log.Printf("expiry: failed to publish %s: %v", webhook.EventValueExpired, err)
+	}
+
+	return nil
+}
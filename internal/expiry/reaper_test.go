@@ -0,0 +1,81 @@
+package expiry
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/database/dbtest"
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/keyring"
+)
+
+func TestReapWhileSealed(t *testing.T) {
+	ctx := context.Background()
+	key := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	shares, check, err := keyring.SplitKey(key, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := keyring.NewShamirProvider("1", 2, check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unseal := func() {
+		t.Helper()
+		for _, share := range shares {
+			if _, err := provider.Unseal(share); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	keys, err := keyring.New(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := dbtest.SQLite(t).Wrap(keys.Querier).Querier()
+
+	unseal()
+	env, err := q.CreateEnvironment(ctx, database.CreateEnvironmentParams{ProjectID: 1, Name: "production"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _, err := database.SetValue(ctx, q, env.ID, "TOKEN", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	if _, err := q.SetValueExpiry(ctx, database.SetValueExpiryParams{
+		ID:        value.ID,
+		ExpiresAt: sql.NullTime{Time: now.Add(-time.Minute), Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	provider.Seal()
+
+	r, err := NewReaper(q, nil, ActionArchive, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.now = func() time.Time { return now }
+	r.PauseWhile(func() bool { return provider.Status().Sealed })
+
+	if reaped, err := r.Reap(ctx); reaped != 0 || err != nil {
+		t.Errorf("Reap while sealed = %d, %v, want nothing", reaped, err)
+	}
+
+	unseal()
+	if reaped, err := r.Reap(ctx); reaped != 1 || err != nil {
+		t.Errorf("Reap = %d, %v, want the expired value", reaped, err)
+	}
+	if _, err := q.GetValue(ctx, value.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("the expired value is still there: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package generator
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Generator types
+const (
+	TypePassword = "password"
+	TypeHex      = "hex"
+	TypeBase64   = "base64"
+	TypeRSA      = "rsa"
+	TypeEd25519  = "ed25519"
+	TypeUUID     = "uuid"
+	// TypeX509SelfSigned generates a self-signed certificate followed by its
+	// ECDSA P-256 private key, both PEM encoded
+	TypeX509SelfSigned = "x509-self-signed"
+)
+
+const (
+	defaultPasswordLength = 32
+	defaultTokenBytes     = 32
+	defaultRSABits        = 2048
+	defaultCertDays       = 365
+	defaultCommonName     = "localhost"
+	minRSABits            = 2048
+	maxLength             = 4096
+)
+
+// Character sets that can be combined with "+" (ie: alnum+symbols)
+var charsets = map[string]string{
+	"lower":   "abcdefghijklmnopqrstuvwxyz",
+	"upper":   "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"digits":  "0123456789",
+	"numeric": "0123456789",
+	"alpha":   "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"alnum":   "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	"symbols": "!#$%&*+-.:=?@^_~",
+}
+
+// Spec describes the value to generate
+type Spec struct {
+	Type string `json:"type"`
+	// Length is the number of characters of a password or the number of
+	// random bytes of a hex/base64 token
+	Length int `json:"length,omitempty"`
+	// Charset of a password, ie: alnum, alnum+symbols
+	Charset string `json:"charset,omitempty"`
+	// Bits is the size of RSA keys
+	Bits int `json:"bits,omitempty"`
+	// CommonName of a self-signed certificate, also added as a DNS name
+	CommonName string `json:"common_name,omitempty"`
+	// DNSNames are additional names of a self-signed certificate
+	DNSNames []string `json:"dns_names,omitempty"`
+	// Days a self-signed certificate is valid for
+	Days int `json:"days,omitempty"`
+}
+
+// Types returns the supported generator types
+func Types() []string {
+	return []string{TypePassword, TypeUUID, TypeHex, TypeBase64, TypeEd25519, TypeRSA, TypeX509SelfSigned}
+}
+
+// Generate produces a new random value using crypto/rand
+func Generate(spec Spec) (string, error) {
+	switch spec.Type {
+	case TypePassword:
+		return password(spec.Length, spec.Charset)
+	case TypeHex:
+		b, err := randomBytes(spec.Length)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(b), nil
+	case TypeBase64:
+		b, err := randomBytes(spec.Length)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	case TypeRSA:
+		return rsaKey(spec.Bits)
+	case TypeEd25519:
+		return ed25519Key()
+	case TypeUUID:
+		return uuid()
+	case TypeX509SelfSigned:
+		return selfSignedCertificate(spec.CommonName, spec.DNSNames, spec.Days)
+	default:
+		return "", fmt.Errorf("unknown generator type %q", spec.Type)
+	}
+}
+
+func password(length int, charset string) (string, error) {
+	if length == 0 {
+		length = defaultPasswordLength
+	}
+	if length < 0 || length > maxLength {
+		return "", fmt.Errorf("invalid password length %d", length)
+	}
+	if charset == "" {
+		charset = "alnum"
+	}
+
+	var alphabet strings.Builder
+	for _, name := range strings.Split(charset, "+") {
+		chars, ok := charsets[name]
+		if !ok {
+			return "", fmt.Errorf("unknown charset %q", name)
+		}
+		alphabet.WriteString(chars)
+	}
+
+	chars := []rune(alphabet.String())
+	max := big.NewInt(int64(len(chars)))
+	out := make([]rune, length)
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out[i] = chars[n.Int64()]
+	}
+
+	return string(out), nil
+}
+
+func randomBytes(length int) ([]byte, error) {
+	if length == 0 {
+		length = defaultTokenBytes
+	}
+	if length < 0 || length > maxLength {
+		return nil, fmt.Errorf("invalid length %d", length)
+	}
+
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// rsaKey returns a PEM encoded PKCS#8 RSA private key
+func rsaKey(bits int) (string, error) {
+	if bits == 0 {
+		bits = defaultRSABits
+	}
+	if bits < minRSABits || bits > 8192 {
+		return "", fmt.Errorf("invalid rsa key size %d", bits)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return "", err
+	}
+	return encodePrivateKey(key)
+}
+
+// ed25519Key returns a PEM encoded PKCS#8 Ed25519 private key
+func ed25519Key() (string, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return encodePrivateKey(key)
+}
+
+func encodePrivateKey(key any) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// uuid returns a random (version 4) UUID
+func uuid() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// selfSignedCertificate returns a PEM encoded self-signed certificate
+// followed by its PKCS#8 ECDSA P-256 private key
+func selfSignedCertificate(commonName string, dnsNames []string, days int) (string, error) {
+	if commonName == "" {
+		commonName = defaultCommonName
+	}
+	if days == 0 {
+		days = defaultCertDays
+	}
+	if days < 0 || days > 3650 {
+		return "", fmt.Errorf("invalid certificate validity %d days", days)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              append([]string{commonName}, dnsNames...),
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.AddDate(0, 0, days),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", err
+	}
+
+	privateKey, err := encodePrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})) + privateKey, nil
+}
@@ -0,0 +1,190 @@
+package janitor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/store"
+	"github.com/rodrwan/secretly/internal/webhook"
+)
+
+const defaultBatchSize = 20
+
+// Actor is the actor of the events the janitor records
+const Actor = "janitor"
+
+// Janitor deletes expired ephemeral environments, the SQL store moves them
+// to the trash, and purges whatever stayed in the trash longer than the
+// retention
+type Janitor struct {
+	db *database.DB
+	// store keeps the environments, ie: the git storage
+	store     store.Store
+	webhooks  *webhook.Dispatcher
+	interval  time.Duration
+	retention time.Duration
+	batchSize int64
+	now       func() time.Time
+}
+
+// NewJanitor creates a janitor of the environments of s, the trash and the
+// audit log are in db
+func NewJanitor(db *database.DB, s store.Store, dispatcher *webhook.Dispatcher, interval, retention time.Duration) *Janitor {
+	return &Janitor{
+		db:        db,
+		store:     s,
+		webhooks:  dispatcher,
+		interval:  interval,
+		retention: retention,
+		batchSize: defaultBatchSize,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run cleans up until ctx is cancelled
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.Sweep(ctx); err != nil {
+			log.Printf("janitor: %v", err)
+		}
+		if _, err := j.Purge(ctx); err != nil {
+			log.Printf("janitor: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep deletes every expired environment and returns how many were deleted
+func (j *Janitor) Sweep(ctx context.Context) (int, error) {
+	deleted := 0
+	for {
+		envs, err := j.store.GetExpiredEnvironments(ctx, j.now(), j.batchSize)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to get expired environments: %w", err)
+		}
+
+		// An environment that fails is retried on the next sweep, without
+		// holding back the others
+		failed := 0
+		for _, env := range envs {
+			err := j.expire(ctx, env)
+			if errors.Is(err, store.ErrReadOnly) {
+				// A read-only store is swept by the server writing to it
+				return deleted, nil
+			}
+			if err != nil {
+				log.Printf("janitor: failed to delete environment %s: %v", env.Name, err)
+				failed++
+				continue
+			}
+			deleted++
+		}
+
+		// The failed environments come back in the next batch, stop when
+		// there is nothing else left
+		if int64(len(envs)) < j.batchSize || failed == len(envs) {
+			return deleted, nil
+		}
+	}
+}
+
+func (j *Janitor) expire(ctx context.Context, env store.Environment) error {
+	values, err := j.store.GetValues(ctx, env.ID)
+	if err != nil {
+		return err
+	}
+
+	// The event is recorded in the transaction of the SQL store, with the
+	// deletion
+	if err := j.store.InTx(ctx, func(s store.Store) error {
+		if err := s.DeleteEnvironment(ctx, env.ID); err != nil {
+			return err
+		}
+		q := j.db.Querier()
+		if sqlStore, ok := s.(*store.SQL); ok {
+			q = sqlStore.Querier()
+		}
+		return database.RecordEnvironmentEvent(ctx, q, env.ProjectID, env.ID, env.Name, database.EventEnvironmentExpired, Actor)
+	}); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for _, value := range values {
+		keys = append(keys, value.Key)
+	}
+
+	log.Printf("janitor: deleted expired environment %s", env.Name)
+	if err := j.webhooks.Publish(ctx, webhook.NewEvent(webhook.EventEnvironmentExpired, env.ID, env.Name, keys...)); err != nil {
+		log.Printf("janitor: failed to publish %s: %v", webhook.EventEnvironmentExpired, err)
+	}
+
+	return nil
+}
+
+// Purge permanently deletes the environments and values that stayed in the
+// trash longer than the retention and returns how many were purged
+func (j *Janitor) Purge(ctx context.Context) (int, error) {
+	queries := j.db.Querier()
+	deletedBefore := sql.NullTime{Time: j.now().Add(-j.retention), Valid: true}
+
+	purged := 0
+	for {
+		envs, err := queries.GetPurgeableEnvironments(ctx, database.GetPurgeableEnvironmentsParams{
+			DeletedAt: deletedBefore,
+			Limit:     j.batchSize,
+		})
+		if err != nil {
+			return purged, fmt.Errorf("failed to get purgeable environments: %w", err)
+		}
+
+		for _, env := range envs {
+			if err := database.InTx(ctx, j.db, func(q database.Querier) error {
+				return database.PurgeEnvironment(ctx, q, env.ID)
+			}); err != nil {
+				return purged, fmt.Errorf("failed to purge environment %s: %w", env.Name, err)
+			}
+			purged++
+		}
+
+		if int64(len(envs)) < j.batchSize {
+			break
+		}
+	}
+
+	for {
+		values, err := queries.GetPurgeableValues(ctx, database.GetPurgeableValuesParams{
+			DeletedAt: deletedBefore,
+			Limit:     j.batchSize,
+		})
+		if err != nil {
+			return purged, fmt.Errorf("failed to get purgeable values: %w", err)
+		}
+
+		for _, value := range values {
+			if err := database.InTx(ctx, j.db, func(q database.Querier) error {
+				return database.PurgeValue(ctx, q, value.ID)
+			}); err != nil {
+				return purged, fmt.Errorf("failed to purge value %d: %w", value.ID, err)
+			}
+			purged++
+		}
+
+		if int64(len(values)) < j.batchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package janitor
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/database/dbtest"
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/store"
+)
+
+func TestSweepSQL(t *testing.T) {
+	db := dbtest.SQLite(t)
+	testSweep(t, db, store.NewSQL(db))
+}
+
+func TestSweepGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	key := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := encryption.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := store.NewGit(context.Background(), t.TempDir(), cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSweep(t, dbtest.SQLite(t), g)
+}
+
+// testSweep checks the janitor deletes the expired environments of s, and
+// only those, in batches
+func testSweep(t *testing.T, db *database.DB, s store.Store) {
+	ctx := context.Background()
+	project, err := db.Querier().CreateProject(ctx, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expired := now.Add(-time.Minute)
+	later := now.Add(time.Hour)
+	envs := map[string]*time.Time{
+		"pr-1":    &expired,
+		"pr-2":    &expired,
+		"pr-3":    &expired,
+		"pr-4":    &later,
+		"staging": nil,
+	}
+	for name, expiresAt := range envs {
+		env, err := s.CreateEnvironment(ctx, project.ID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.SetValue(ctx, env.ID, "TOKEN", "s3cret"); err != nil {
+			t.Fatal(err)
+		}
+		if expiresAt != nil {
+			if _, err := s.SetEnvironmentExpiry(ctx, env.ID, expiresAt); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	j := NewJanitor(db, s, nil, time.Minute, time.Hour)
+	j.batchSize = 2
+	j.now = func() time.Time { return now }
+
+	deleted, err := j.Sweep(ctx)
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("Sweep deleted %d environments, want 3", deleted)
+	}
+	for name, expiresAt := range envs {
+		_, err := s.GetEnvironmentByName(ctx, project.ID, name)
+		if gone := errors.Is(err, store.ErrNotFound); gone != (expiresAt == &expired) {
+			t.Errorf("%s: GetEnvironmentByName = %v after the sweep", name, err)
+		}
+	}
+
+	events, err := db.Querier().GetEnvironmentEventsByProjectID(ctx, database.GetEnvironmentEventsByProjectIDParams{
+		ProjectID: project.ID,
+		Limit:     10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	for _, event := range events {
+		if event.Event != database.EventEnvironmentExpired || event.Actor != Actor {
+			t.Errorf("event = %+v", event)
+		}
+	}
+
+	if deleted, err := j.Sweep(ctx); err != nil || deleted != 0 {
+		t.Errorf("second Sweep = %d, %v, want nothing to delete", deleted, err)
+	}
+}
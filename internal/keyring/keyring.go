@@ -0,0 +1,145 @@
+// Package keyring encrypts values at rest with envelope encryption. Each
+// value is sealed with its own data key, which is wrapped by a master key
+// of a KeyProvider.
+// Every ciphertext records the ID of its master key, so the master key can
+// be rotated by re-wrapping the data keys without touching the values.
+package keyring
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+)
+
+// prefix starts every ciphertext, values without it are stored in plaintext
+const prefix = "secretly:v1:"
+
+// ErrUnknownKey is returned when a ciphertext was wrapped by a master key
+// that isn't in the keyring
+var ErrUnknownKey = errors.New("unknown master key")
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Keyring holds the providers of the master keys by ID. The active one wraps
+// new data keys, the others are decrypt-only until every ciphertext is
+// re-wrapped.
+type Keyring struct {
+	active    KeyProvider
+	providers map[string]KeyProvider
+}
+
+// New creates a keyring whose active key is the one of active
+func New(active KeyProvider, decryptOnly ...KeyProvider) (*Keyring, error) {
+	k := &Keyring{active: active, providers: make(map[string]KeyProvider, len(decryptOnly)+1)}
+	for _, provider := range append([]KeyProvider{active}, decryptOnly...) {
+		id := provider.ID()
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid master key ID %q, must only have letters, digits, '_', '.' and '-'", id)
+		}
+		if _, ok := k.providers[id]; ok {
+			return nil, fmt.Errorf("duplicate master key %q", id)
+		}
+		k.providers[id] = provider
+	}
+	return k, nil
+}
+
+// ActiveKeyID is the ID of the master key wrapping new data keys
+func (k *Keyring) ActiveKeyID() string {
+	return k.active.ID()
+}
+
+// Encrypt seals a value with a new data key wrapped by the active key
+func (k *Keyring) Encrypt(ctx context.Context, value string) (string, error) {
+	dataKey := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	cipher, err := encryption.NewCipher(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := cipher.Seal([]byte(value), nil)
+	if err != nil {
+		return "", err
+	}
+	return k.wrap(ctx, dataKey, sealed)
+}
+
+// Decrypt opens a value sealed by Encrypt, values stored before encryption
+// was enabled are returned as they are
+func (k *Keyring) Decrypt(ctx context.Context, value string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+	_, dataKey, sealed, err := k.unwrap(ctx, value)
+	if err != nil {
+		return "", err
+	}
+	cipher, err := encryption.NewCipher(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := cipher.Open(sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap wraps the data key of a value with the active key, plaintext values
+// are encrypted. It reports whether the value changed.
+func (k *Keyring) Rewrap(ctx context.Context, value string) (string, bool, error) {
+	if !strings.HasPrefix(value, prefix) {
+		encrypted, err := k.Encrypt(ctx, value)
+		return encrypted, err == nil, err
+	}
+	id, dataKey, sealed, err := k.unwrap(ctx, value)
+	if err != nil || id == k.active.ID() {
+		return value, false, err
+	}
+	rewrapped, err := k.wrap(ctx, dataKey, sealed)
+	return rewrapped, err == nil, err
+}
+
+// wrap formats a ciphertext as secretly:v1:<key id>:<wrapped data key>:<sealed value>
+func (k *Keyring) wrap(ctx context.Context, dataKey, sealed []byte) (string, error) {
+	wrapped, err := k.active.Wrap(ctx, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap the data key: %w", err)
+	}
+	return prefix + wrapped.KeyID + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped.Ciphertext) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (k *Keyring) unwrap(ctx context.Context, value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, encryption.ErrDecrypt
+	}
+	id := parts[0]
+	provider, ok := k.providers[id]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, encryption.ErrDecrypt
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, encryption.ErrDecrypt
+	}
+	dataKey, err := provider.Unwrap(ctx, WrappedKey{KeyID: id, Ciphertext: ciphertext})
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return id, dataKey, sealed, nil
+}
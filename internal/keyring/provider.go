@@ -0,0 +1,137 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/kms"
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrProviderMismatch is returned when a provider is asked to unwrap a data
+// key wrapped by another master key
+var ErrProviderMismatch = errors.New("the data key was wrapped by another master key")
+
+// WrappedKey is a data key encrypted by the master key KeyID
+type WrappedKey struct {
+	KeyID      string
+	Ciphertext []byte
+}
+
+// KeyProvider wraps data keys with a master key it holds, locally or in a
+// KMS. The master key never leaves the provider.
+type KeyProvider interface {
+	// ID identifies the master key, it is recorded with every data key
+	ID() string
+	// Wrap encrypts a data key with the master key
+	Wrap(ctx context.Context, dataKey []byte) (WrappedKey, error)
+	// Unwrap decrypts a data key wrapped by the master key, it returns
+	// ErrProviderMismatch for keys of another master key
+	Unwrap(ctx context.Context, wrapped WrappedKey) ([]byte, error)
+}
+
+// localProvider holds its master key in memory
+type localProvider struct {
+	id     string
+	cipher *encryption.Cipher
+}
+
+// NewKeyProvider creates a provider of a master key of encryption.KeySize bytes
+func NewKeyProvider(id string, key []byte) (KeyProvider, error) {
+	cipher, err := encryption.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key %q: %w", id, err)
+	}
+	return localProvider{id: id, cipher: cipher}, nil
+}
+
+// NewKeyFileProvider creates a provider of the master key in a file, encoded
+// as hex or base64
+func NewKeyFileProvider(id, path string) (KeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key %q: %w", id, err)
+	}
+	key, err := encryption.ParseKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key file %s: %w", path, err)
+	}
+	return NewKeyProvider(id, key)
+}
+
+// Parameters of argon2id, the recommended ones of RFC 9106 for a server
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+// minSaltSize is the size of the shortest salt of a passphrase
+const minSaltSize = 16
+
+// NewPassphraseProvider creates a provider of a master key derived from a
+// passphrase with argon2id. The salt must be random and kept with the
+// passphrase, the same pair always derives the same key.
+func NewPassphraseProvider(id, passphrase string, salt []byte) (KeyProvider, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("the passphrase of master key %q is empty", id)
+	}
+	if len(salt) < minSaltSize {
+		return nil, fmt.Errorf("the salt of master key %q must be at least %d bytes", id, minSaltSize)
+	}
+	key := argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, encryption.KeySize)
+	return NewKeyProvider(id, key)
+}
+
+func (p localProvider) ID() string {
+	return p.id
+}
+
+func (p localProvider) Wrap(ctx context.Context, dataKey []byte) (WrappedKey, error) {
+	ciphertext, err := p.cipher.Seal(dataKey, []byte(prefix+p.id))
+	if err != nil {
+		return WrappedKey{}, err
+	}
+	return WrappedKey{KeyID: p.id, Ciphertext: ciphertext}, nil
+}
+
+func (p localProvider) Unwrap(ctx context.Context, wrapped WrappedKey) ([]byte, error) {
+	if wrapped.KeyID != p.id {
+		return nil, fmt.Errorf("%w: %q, not %q", ErrProviderMismatch, wrapped.KeyID, p.id)
+	}
+	return p.cipher.Open(wrapped.Ciphertext, []byte(prefix+p.id))
+}
+
+// kmsProvider wraps data keys with a master key of a KMS
+type kmsProvider struct {
+	id     string
+	name   string
+	client *kms.Client
+}
+
+// NewKMSProvider creates a provider of the master key name of a KMS
+func NewKMSProvider(id, name string, client *kms.Client) KeyProvider {
+	return kmsProvider{id: id, name: name, client: client}
+}
+
+func (p kmsProvider) ID() string {
+	return p.id
+}
+
+func (p kmsProvider) Wrap(ctx context.Context, dataKey []byte) (WrappedKey, error) {
+	ciphertext, err := p.client.Wrap(ctx, p.name, dataKey)
+	if err != nil {
+		return WrappedKey{}, err
+	}
+	return WrappedKey{KeyID: p.id, Ciphertext: ciphertext}, nil
+}
+
+func (p kmsProvider) Unwrap(ctx context.Context, wrapped WrappedKey) ([]byte, error) {
+	if wrapped.KeyID != p.id {
+		return nil, fmt.Errorf("%w: %q, not %q", ErrProviderMismatch, wrapped.KeyID, p.id)
+	}
+	return p.client.Unwrap(ctx, p.name, wrapped.Ciphertext)
+}
@@ -0,0 +1,253 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/kms"
+)
+
+func randomBytes(t *testing.T, size int) []byte {
+	t.Helper()
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func keyFileProvider(t *testing.T, id string) KeyProvider {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), id+".key")
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(randomBytes(t, encryption.KeySize))+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewKeyFileProvider(id, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func passphraseProvider(t *testing.T, id, passphrase string, salt []byte) KeyProvider {
+	t.Helper()
+	provider, err := NewPassphraseProvider(id, passphrase, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// kmsClient returns a client of a KMS server holding the master keys of names
+func kmsClient(t *testing.T, names ...string) *kms.Client {
+	t.Helper()
+	keys := make(map[string][]byte, len(names))
+	for _, name := range names {
+		keys[name] = randomBytes(t, encryption.KeySize)
+	}
+	server, err := kms.NewServer(keys, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := http.NewServeMux()
+	server.RegisterRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	return kms.NewClient(ts.URL, "token")
+}
+
+func TestProviderRoundTrip(t *testing.T) {
+	salt := randomBytes(t, minSaltSize)
+	providers := map[string]KeyProvider{
+		"key file":   keyFileProvider(t, "file"),
+		"passphrase": passphraseProvider(t, "passphrase", "correct horse battery staple", salt),
+		"kms":        NewKMSProvider("kms", "master", kmsClient(t, "master")),
+	}
+
+	for name, provider := range providers {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			dataKey := randomBytes(t, encryption.KeySize)
+
+			wrapped, err := provider.Wrap(ctx, dataKey)
+			if err != nil {
+				t.Fatalf("Wrap: %v", err)
+			}
+			if wrapped.KeyID != provider.ID() {
+				t.Errorf("KeyID = %q, want %q", wrapped.KeyID, provider.ID())
+			}
+			if bytes.Contains(wrapped.Ciphertext, dataKey) {
+				t.Error("the wrapped key contains the data key")
+			}
+
+			unwrapped, err := provider.Unwrap(ctx, wrapped)
+			if err != nil {
+				t.Fatalf("Unwrap: %v", err)
+			}
+			if !bytes.Equal(unwrapped, dataKey) {
+				t.Error("Unwrap returned another data key")
+			}
+		})
+	}
+}
+
+func TestProviderMismatch(t *testing.T) {
+	ctx := context.Background()
+	client := kmsClient(t, "master")
+	providers := []KeyProvider{
+		keyFileProvider(t, "file"),
+		passphraseProvider(t, "passphrase", "correct horse battery staple", randomBytes(t, minSaltSize)),
+		NewKMSProvider("kms", "master", client),
+	}
+
+	for _, wrapping := range providers {
+		wrapped, err := wrapping.Wrap(ctx, randomBytes(t, encryption.KeySize))
+		if err != nil {
+			t.Fatalf("%s: Wrap: %v", wrapping.ID(), err)
+		}
+		for _, unwrapping := range providers {
+			if unwrapping == wrapping {
+				continue
+			}
+			if _, err := unwrapping.Unwrap(ctx, wrapped); !errors.Is(err, ErrProviderMismatch) {
+				t.Errorf("%s unwrapping a key of %s: got %v, want ErrProviderMismatch", unwrapping.ID(), wrapping.ID(), err)
+			}
+		}
+	}
+}
+
+func TestPassphraseProvider(t *testing.T) {
+	ctx := context.Background()
+	salt := randomBytes(t, minSaltSize)
+	provider := passphraseProvider(t, "master", "correct horse battery staple", salt)
+	dataKey := randomBytes(t, encryption.KeySize)
+	wrapped, err := provider.Wrap(ctx, dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("same passphrase and salt", func(t *testing.T) {
+		unwrapped, err := passphraseProvider(t, "master", "correct horse battery staple", salt).Unwrap(ctx, wrapped)
+		if err != nil {
+			t.Fatalf("Unwrap: %v", err)
+		}
+		if !bytes.Equal(unwrapped, dataKey) {
+			t.Error("Unwrap returned another data key")
+		}
+	})
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		_, err := passphraseProvider(t, "master", "incorrect horse", salt).Unwrap(ctx, wrapped)
+		if !errors.Is(err, encryption.ErrDecrypt) {
+			t.Errorf("got %v, want ErrDecrypt", err)
+		}
+	})
+
+	t.Run("wrong salt", func(t *testing.T) {
+		_, err := passphraseProvider(t, "master", "correct horse battery staple", randomBytes(t, minSaltSize)).Unwrap(ctx, wrapped)
+		if !errors.Is(err, encryption.ErrDecrypt) {
+			t.Errorf("got %v, want ErrDecrypt", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := NewPassphraseProvider("master", "", salt); err == nil {
+			t.Error("an empty passphrase was accepted")
+		}
+		if _, err := NewPassphraseProvider("master", "correct horse battery staple", salt[:minSaltSize-1]); err == nil {
+			t.Error("a short salt was accepted")
+		}
+	})
+}
+
+func TestKeyFileProvider(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := NewKeyFileProvider("master", filepath.Join(dir, "missing.key")); err == nil {
+		t.Error("a missing file was accepted")
+	}
+
+	short := filepath.Join(dir, "short.key")
+	if err := os.WriteFile(short, []byte(hex.EncodeToString(randomBytes(t, 16))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewKeyFileProvider("master", short); err == nil {
+		t.Error("a key of 16 bytes was accepted")
+	}
+}
+
+func TestKMSProvider(t *testing.T) {
+	ctx := context.Background()
+	client := kmsClient(t, "master", "other")
+	dataKey := randomBytes(t, encryption.KeySize)
+	wrapped, err := NewKMSProvider("kms", "master", client).Wrap(ctx, dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("another master key", func(t *testing.T) {
+		// Same ID, so the provider can't tell, the KMS refuses it
+		if _, err := NewKMSProvider("kms", "other", client).Unwrap(ctx, wrapped); err == nil {
+			t.Error("a key wrapped by master was unwrapped by other")
+		}
+	})
+
+	t.Run("unknown master key", func(t *testing.T) {
+		_, err := NewKMSProvider("kms", "missing", client).Wrap(ctx, dataKey)
+		if err == nil || !strings.Contains(err.Error(), "unknown key missing") {
+			t.Errorf("got %v, want an unknown key error", err)
+		}
+	})
+}
+
+func TestKeyringUnknownKey(t *testing.T) {
+	ctx := context.Background()
+	old := keyFileProvider(t, "old")
+	current := keyFileProvider(t, "current")
+
+	rotated, err := New(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := rotated.Encrypt(ctx, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("decrypt-only key", func(t *testing.T) {
+		k, err := New(current, old)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plaintext, err := k.Decrypt(ctx, value)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if plaintext != "s3cret" {
+			t.Errorf("Decrypt = %q, want %q", plaintext, "s3cret")
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		k, err := New(current)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := k.Decrypt(ctx, value); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("got %v, want ErrUnknownKey", err)
+		}
+		if _, _, err := k.Rewrap(ctx, value); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("Rewrap: got %v, want ErrUnknownKey", err)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package keyring
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rodrwan/secretly/internal/database"
+)
+
+// Querier wraps q so values are encrypted before they are stored and
+// decrypted when they are read. The ciphertext queries are left as they are
+// to re-wrap values.
+func (k *Keyring) Querier(q database.Querier) database.Querier {
+	return querier{Querier: q, keyring: k}
+}
+
+type querier struct {
+	database.Querier
+	keyring *Keyring
+}
+
+var _ database.Querier = querier{}
+
+func (q querier) encrypt(ctx context.Context, value *string) error {
+	encrypted, err := q.keyring.Encrypt(ctx, *value)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt value: %w", err)
+	}
+	*value = encrypted
+	return nil
+}
+
+func (q querier) decrypt(ctx context.Context, value *string) error {
+	decrypted, err := q.keyring.Decrypt(ctx, *value)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	*value = decrypted
+	return nil
+}
+
+// decryptOne decrypts the value of a row returned with err
+func decryptOne[T any](ctx context.Context, q querier, item T, err error, value func(*T) *string) (T, error) {
+	if err != nil {
+		return item, err
+	}
+	if err := q.decrypt(ctx, value(&item)); err != nil {
+		var zero T
+		return zero, err
+	}
+	return item, nil
+}
+
+// decryptAll decrypts the values of rows returned with err
+func decryptAll[T any](ctx context.Context, q querier, items []T, err error, value func(*T) *string) ([]T, error) {
+	if err != nil {
+		return items, err
+	}
+	for i := range items {
+		if err := q.decrypt(ctx, value(&items[i])); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// decryptAllNull decrypts the values of rows of a left join, rows without a
+// value are left as they are
+func decryptAllNull[T any](ctx context.Context, q querier, items []T, err error, value func(*T) (*string, bool)) ([]T, error) {
+	if err != nil {
+		return items, err
+	}
+	for i := range items {
+		if v, ok := value(&items[i]); ok {
+			if err := q.decrypt(ctx, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return items, nil
+}
+
+func environmentValue(v *database.EnvironmentValue) *string { return &v.Value }
+
+func (q querier) ArchiveExpiredValue(ctx context.Context, arg database.ArchiveExpiredValueParams) (database.ExpiredValue, error) {
+	if err := q.encrypt(ctx, &arg.Value); err != nil {
+		return database.ExpiredValue{}, err
+	}
+	i, err := q.Querier.ArchiveExpiredValue(ctx, arg)
+	return decryptOne(ctx, q, i, err, func(v *database.ExpiredValue) *string { return &v.Value })
+}
+
+func (q querier) CreateValue(ctx context.Context, arg database.CreateValueParams) (database.EnvironmentValue, error) {
+	if err := q.encrypt(ctx, &arg.Value); err != nil {
+		return database.EnvironmentValue{}, err
+	}
+	i, err := q.Querier.CreateValue(ctx, arg)
+	return decryptOne(ctx, q, i, err, environmentValue)
+}
+
+func (q querier) CreateValueVersion(ctx context.Context, arg database.CreateValueVersionParams) (database.ValueVersion, error) {
+	if err := q.encrypt(ctx, &arg.Value); err != nil {
+		return database.ValueVersion{}, err
+	}
+	i, err := q.Querier.CreateValueVersion(ctx, arg)
+	return decryptOne(ctx, q, i, err, func(v *database.ValueVersion) *string { return &v.Value })
+}
+
+func (q querier) GetAllValues(ctx context.Context) ([]database.EnvironmentValue, error) {
+	items, err := q.Querier.GetAllValues(ctx)
+	return decryptAll(ctx, q, items, err, environmentValue)
+}
+
+func (q querier) GetDeletedValue(ctx context.Context, id int64) (database.EnvironmentValue, error) {
+	i, err := q.Querier.GetDeletedValue(ctx, id)
+	return decryptOne(ctx, q, i, err, environmentValue)
+}
+
+func (q querier) GetDeletedValuesByProjectID(ctx context.Context, projectID int64) ([]database.GetDeletedValuesByProjectIDRow, error) {
+	items, err := q.Querier.GetDeletedValuesByProjectID(ctx, projectID)
+	return decryptAll(ctx, q, items, err, func(v *database.GetDeletedValuesByProjectIDRow) *string { return &v.Value })
+}
+
+func (q querier) GetExpiredValues(ctx context.Context, arg database.GetExpiredValuesParams) ([]database.EnvironmentValue, error) {
+	items, err := q.Querier.GetExpiredValues(ctx, arg)
+	return decryptAll(ctx, q, items, err, environmentValue)
+}
+
+func (q querier) GetPurgeableValues(ctx context.Context, arg database.GetPurgeableValuesParams) ([]database.EnvironmentValue, error) {
+	items, err := q.Querier.GetPurgeableValues(ctx, arg)
+	return decryptAll(ctx, q, items, err, environmentValue)
+}
+
+func (q querier) GetValue(ctx context.Context, id int64) (database.EnvironmentValue, error) {
+	i, err := q.Querier.GetValue(ctx, id)
+	return decryptOne(ctx, q, i, err, environmentValue)
+}
+
+func (q querier) GetValueByKey(ctx context.Context, arg database.GetValueByKeyParams) (database.EnvironmentValue, error) {
+	i, err := q.Querier.GetValueByKey(ctx, arg)
+	return decryptOne(ctx, q, i, err, environmentValue)
+}
+
+func (q querier) GetValueVersions(ctx context.Context, valueID int64) ([]database.ValueVersion, error) {
+	items, err := q.Querier.GetValueVersions(ctx, valueID)
+	return decryptAll(ctx, q, items, err, func(v *database.ValueVersion) *string { return &v.Value })
+}
+
+func (q querier) GetValuesByEnvironmentID(ctx context.Context, environmentID int64) ([]database.EnvironmentValue, error) {
+	items, err := q.Querier.GetValuesByEnvironmentID(ctx, environmentID)
+	return decryptAll(ctx, q, items, err, environmentValue)
+}
+
+func (q querier) GetValuesByProjectID(ctx context.Context, projectID int64) ([]database.GetValuesByProjectIDRow, error) {
+	items, err := q.Querier.GetValuesByProjectID(ctx, projectID)
+	return decryptAll(ctx, q, items, err, func(v *database.GetValuesByProjectIDRow) *string { return &v.Value })
+}
+
+func (q querier) ListEnvironmentsByCreation(ctx context.Context, arg database.ListEnvironmentsByCreationParams) ([]database.ListEnvironmentsByCreationRow, error) {
+	items, err := q.Querier.ListEnvironmentsByCreation(ctx, arg)
+	return decryptAllNull(ctx, q, items, err, func(v *database.ListEnvironmentsByCreationRow) (*string, bool) {
+		return &v.Value.String, v.Value.Valid
+	})
+}
+
+func (q querier) ListEnvironmentsByCreationDesc(ctx context.Context, arg database.ListEnvironmentsByCreationDescParams) ([]database.ListEnvironmentsByCreationDescRow, error) {
+	items, err := q.Querier.ListEnvironmentsByCreationDesc(ctx, arg)
+	return decryptAllNull(ctx, q, items, err, func(v *database.ListEnvironmentsByCreationDescRow) (*string, bool) {
+		return &v.Value.String, v.Value.Valid
+	})
+}
+
+func (q querier) ListEnvironmentsByName(ctx context.Context, arg database.ListEnvironmentsByNameParams) ([]database.ListEnvironmentsByNameRow, error) {
+	items, err := q.Querier.ListEnvironmentsByName(ctx, arg)
+	return decryptAllNull(ctx, q, items, err, func(v *database.ListEnvironmentsByNameRow) (*string, bool) {
+		return &v.Value.String, v.Value.Valid
+	})
+}
+
+func (q querier) ListEnvironmentsByNameDesc(ctx context.Context, arg database.ListEnvironmentsByNameDescParams) ([]database.ListEnvironmentsByNameDescRow, error) {
+	items, err := q.Querier.ListEnvironmentsByNameDesc(ctx, arg)
+	return decryptAllNull(ctx, q, items, err, func(v *database.ListEnvironmentsByNameDescRow) (*string, bool) {
+		return &v.Value.String, v.Value.Valid
+	})
+}
+
+func (q querier) RenameValue(ctx context.Context, arg database.RenameValueParams) (database.EnvironmentValue, error) {
+	i, err := q.Querier.RenameValue(ctx, arg)
+	return decryptOne(ctx, q, i, err, environmentValue)
+}
+
+func (q querier) RestoreValue(ctx context.Context, id int64) (database.EnvironmentValue, error) {
+	i, err := q.Querier.RestoreValue(ctx, id)
+	return decryptOne(ctx, q, i, err, environmentValue)
+}
+
+func (q querier) SearchValues(ctx context.Context, query string) ([]database.SearchValuesRow, error) {
+	items, err := q.Querier.SearchValues(ctx, query)
+	return decryptAll(ctx, q, items, err, func(v *database.SearchValuesRow) *string { return &v.Value })
+}
+
+func (q querier) SetValueExpiry(ctx context.Context, arg database.SetValueExpiryParams) (database.EnvironmentValue, error) {
+	i, err := q.Querier.SetValueExpiry(ctx, arg)
+	return decryptOne(ctx, q, i, err, environmentValue)
+}
+
+func (q querier) SetValueMetadata(ctx context.Context, arg database.SetValueMetadataParams) (database.EnvironmentValue, error) {
+	i, err := q.Querier.SetValueMetadata(ctx, arg)
+	return decryptOne(ctx, q, i, err, environmentValue)
+}
+
+func (q querier) UpdateValue(ctx context.Context, arg database.UpdateValueParams) (database.EnvironmentValue, error) {
+	if err := q.encrypt(ctx, &arg.Value); err != nil {
+		return database.EnvironmentValue{}, err
+	}
+	i, err := q.Querier.UpdateValue(ctx, arg)
+	return decryptOne(ctx, q, i, err, environmentValue)
+}
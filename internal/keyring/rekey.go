@@ -0,0 +1,211 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+)
+
+// ErrRekeyRunning is returned when a rekey is started while one runs
+var ErrRekeyRunning = errors.New("a rekey is already running")
+
+// Progress of a rekey
+type Progress struct {
+	// KeyID is the master key values are re-wrapped with
+	KeyID   string `json:"key_id"`
+	Running bool   `json:"running"`
+	// Total is the number of values, versions and archived values to scan
+	Total     int64      `json:"total"`
+	Scanned   int64      `json:"scanned"`
+	Rewrapped int64      `json:"rewrapped"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// FinishedAt is set once every value is wrapped by KeyID, or on Error
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ciphertext is a stored value as it is in the database
+type ciphertext struct {
+	id    int64
+	value string
+}
+
+// table is a column of values re-wrapped in batches
+type table struct {
+	name string
+	get  func(ctx context.Context, q database.Querier, afterID, limit int64) ([]ciphertext, error)
+	set  func(ctx context.Context, q database.Querier, id int64, value, previous string) error
+}
+
+var tables = []table{
+	{
+		name: "values",
+		get: func(ctx context.Context, q database.Querier, afterID, limit int64) ([]ciphertext, error) {
+			rows, err := q.GetValueCiphertexts(ctx, database.GetValueCiphertextsParams{AfterID: afterID, Limit: limit})
+			return convert(rows, err, func(r database.GetValueCiphertextsRow) ciphertext { return ciphertext{r.ID, r.Value} })
+		},
+		set: func(ctx context.Context, q database.Querier, id int64, value, previous string) error {
+			return q.SetValueCiphertext(ctx, database.SetValueCiphertextParams{ID: id, Value: value, Previous: previous})
+		},
+	},
+	{
+		name: "versions",
+		get: func(ctx context.Context, q database.Querier, afterID, limit int64) ([]ciphertext, error) {
+			rows, err := q.GetVersionCiphertexts(ctx, database.GetVersionCiphertextsParams{AfterID: afterID, Limit: limit})
+			return convert(rows, err, func(r database.GetVersionCiphertextsRow) ciphertext { return ciphertext{r.ID, r.Value} })
+		},
+		set: func(ctx context.Context, q database.Querier, id int64, value, previous string) error {
+			return q.SetVersionCiphertext(ctx, database.SetVersionCiphertextParams{ID: id, Value: value, Previous: previous})
+		},
+	},
+	{
+		name: "expired values",
+		get: func(ctx context.Context, q database.Querier, afterID, limit int64) ([]ciphertext, error) {
+			rows, err := q.GetExpiredValueCiphertexts(ctx, database.GetExpiredValueCiphertextsParams{AfterID: afterID, Limit: limit})
+			return convert(rows, err, func(r database.GetExpiredValueCiphertextsRow) ciphertext { return ciphertext{r.ID, r.Value} })
+		},
+		set: func(ctx context.Context, q database.Querier, id int64, value, previous string) error {
+			return q.SetExpiredValueCiphertext(ctx, database.SetExpiredValueCiphertextParams{ID: id, Value: value, Previous: previous})
+		},
+	},
+}
+
+func convert[T any](rows []T, err error, fn func(T) ciphertext) ([]ciphertext, error) {
+	if err != nil {
+		return nil, err
+	}
+	converted := make([]ciphertext, 0, len(rows))
+	for _, row := range rows {
+		converted = append(converted, fn(row))
+	}
+	return converted, nil
+}
+
+// Rekeyer re-wraps every value with the active key of a keyring, in batches
+// so the server keeps serving requests. Values stored in plaintext are
+// encrypted. Once it finishes, the other keys can be removed.
+type Rekeyer struct {
+	db        *database.DB
+	keyring   *Keyring
+	batchSize int64
+
+	mu       sync.Mutex
+	progress Progress
+}
+
+// NewRekeyer creates a new rekeyer of the values of db
+func NewRekeyer(db *database.DB, keyring *Keyring, batchSize int) *Rekeyer {
+	return &Rekeyer{db: db, keyring: keyring, batchSize: int64(batchSize)}
+}
+
+// Progress returns the progress of the running or last rekey
+func (r *Rekeyer) Progress() Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress
+}
+
+// Start rekeys in the background until it is done or ctx is cancelled
+func (r *Rekeyer) Start(ctx context.Context) error {
+	if err := r.begin(); err != nil {
+		return err
+	}
+	go func() {
+		if err := r.run(ctx); err != nil {
+			log.Printf("rekey: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Run rekeys and returns once it is done
+func (r *Rekeyer) Run(ctx context.Context) error {
+	if err := r.begin(); err != nil {
+		return err
+	}
+	return r.run(ctx)
+}
+
+func (r *Rekeyer) begin() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.progress.Running {
+		return ErrRekeyRunning
+	}
+	now := time.Now().UTC()
+	r.progress = Progress{KeyID: r.keyring.ActiveKeyID(), Running: true, StartedAt: &now}
+	return nil
+}
+
+func (r *Rekeyer) run(ctx context.Context) (err error) {
+	defer func() {
+		now := time.Now().UTC()
+		r.update(func(p *Progress) {
+			p.Running = false
+			p.FinishedAt = &now
+			if err != nil {
+				p.Error = err.Error()
+			}
+		})
+	}()
+
+	total, err := r.db.Querier().CountCiphertexts(ctx)
+	if err != nil {
+		return err
+	}
+	r.update(func(p *Progress) { p.Total = total })
+
+	for _, t := range tables {
+		var afterID int64
+		for {
+			var scanned, rewrapped int64
+			err := database.InTx(ctx, r.db, func(q database.Querier) error {
+				rows, err := t.get(ctx, q, afterID, r.batchSize)
+				if err != nil {
+					return err
+				}
+				for _, row := range rows {
+					value, changed, err := r.keyring.Rewrap(ctx, row.value)
+					if err != nil {
+						return err
+					}
+					if changed {
+						if err := t.set(ctx, q, row.id, value, row.value); err != nil {
+							return err
+						}
+						rewrapped++
+					}
+					afterID = row.id
+				}
+				scanned = int64(len(rows))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if scanned == 0 {
+				break
+			}
+
+			progress := r.update(func(p *Progress) {
+				p.Scanned += scanned
+				p.Rewrapped += rewrapped
+			})
+			log.Printf("rekey: %s, scanned %d of %d, re-wrapped %d with key %s",
+				t.name, progress.Scanned, progress.Total, progress.Rewrapped, progress.KeyID)
+		}
+	}
+	return nil
+}
+
+// update changes the progress and returns it
+func (r *Rekeyer) update(fn func(p *Progress)) Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.progress)
+	return r.progress
+}
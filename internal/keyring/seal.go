@@ -0,0 +1,164 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/shamir"
+)
+
+// ErrSealed is returned when a data key is wrapped or unwrapped by a sealed
+// master key
+var ErrSealed = errors.New("the master key is sealed")
+
+// ErrShareMismatch is returned when the shares given to unseal don't recover
+// the master key, the shares given so far are discarded
+var ErrShareMismatch = errors.New("the shares don't recover the master key")
+
+// SealStatus is the state of a sealed master key
+type SealStatus struct {
+	Sealed    bool `json:"sealed"`
+	Threshold int  `json:"threshold"`
+	// Progress is the number of shares given towards the threshold
+	Progress int `json:"progress"`
+}
+
+// ShamirProvider is a master key split in shares with Shamir's secret
+// sharing. It starts sealed, without the key, until threshold shares are
+// given to Unseal. Seal forgets the key again.
+type ShamirProvider struct {
+	id        string
+	threshold int
+	check     []byte
+
+	mu       sync.RWMutex
+	shares   [][]byte
+	provider KeyProvider
+}
+
+// NewShamirProvider creates a sealed provider of a master key recovered from
+// threshold shares. check is the KeyCheck of the master key, it verifies the
+// shares recovered it.
+func NewShamirProvider(id string, threshold int, check []byte) (*ShamirProvider, error) {
+	if threshold < 2 {
+		return nil, fmt.Errorf("the threshold of master key %q must be at least 2", id)
+	}
+	if len(check) != sha256.Size {
+		return nil, fmt.Errorf("the key check of master key %q must be %d bytes", id, sha256.Size)
+	}
+	return &ShamirProvider{id: id, threshold: threshold, check: check}, nil
+}
+
+// KeyCheck identifies a master key without revealing it
+func KeyCheck(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("secretly unseal check"))
+	return mac.Sum(nil)
+}
+
+// SplitKey splits a master key in parts shares, threshold of them unseal
+// it. It returns the shares and the KeyCheck of the key.
+func SplitKey(key []byte, parts, threshold int) ([][]byte, []byte, error) {
+	if len(key) != encryption.KeySize {
+		return nil, nil, fmt.Errorf("the key must be %d bytes, got %d", encryption.KeySize, len(key))
+	}
+	shares, err := shamir.Split(key, parts, threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shares, KeyCheck(key), nil
+}
+
+func (p *ShamirProvider) ID() string {
+	return p.id
+}
+
+// Status returns whether the key is sealed and how many shares were given
+func (p *ShamirProvider) Status() SealStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return SealStatus{Sealed: p.provider == nil, Threshold: p.threshold, Progress: len(p.shares)}
+}
+
+// Unseal gives a share of the master key, the key is recovered once
+// threshold shares are given. Sharing a share twice is an error.
+func (p *ShamirProvider) Unseal(share []byte) (SealStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := func() SealStatus {
+		return SealStatus{Sealed: p.provider == nil, Threshold: p.threshold, Progress: len(p.shares)}
+	}
+	if p.provider != nil {
+		return status(), nil
+	}
+	if len(share) != encryption.KeySize+shamir.ShareOverhead {
+		return status(), fmt.Errorf("a share must be %d bytes", encryption.KeySize+shamir.ShareOverhead)
+	}
+	for _, given := range p.shares {
+		if bytes.Equal(given, share) {
+			return status(), errors.New("the share was already given")
+		}
+	}
+
+	p.shares = append(p.shares, bytes.Clone(share))
+	if len(p.shares) < p.threshold {
+		return status(), nil
+	}
+
+	key, err := shamir.Combine(p.shares)
+	p.reset()
+	if err != nil {
+		return status(), err
+	}
+	if !hmac.Equal(KeyCheck(key), p.check) {
+		clear(key)
+		return status(), ErrShareMismatch
+	}
+	provider, err := NewKeyProvider(p.id, key)
+	clear(key)
+	if err != nil {
+		return status(), err
+	}
+	p.provider = provider
+	return status(), nil
+}
+
+// Seal forgets the master key and the shares given so far
+func (p *ShamirProvider) Seal() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reset()
+	p.provider = nil
+}
+
+func (p *ShamirProvider) reset() {
+	for _, share := range p.shares {
+		clear(share)
+	}
+	p.shares = nil
+}
+
+func (p *ShamirProvider) Wrap(ctx context.Context, dataKey []byte) (WrappedKey, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.provider == nil {
+		return WrappedKey{}, ErrSealed
+	}
+	return p.provider.Wrap(ctx, dataKey)
+}
+
+func (p *ShamirProvider) Unwrap(ctx context.Context, wrapped WrappedKey) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.provider == nil {
+		return nil, ErrSealed
+	}
+	return p.provider.Unwrap(ctx, wrapped)
+}
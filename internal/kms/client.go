@@ -0,0 +1,78 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls a KMS server
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client of the server at baseURL, ie: http://localhost:9100
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Wrap encrypts a data key with the master key name
+func (c *Client) Wrap(ctx context.Context, name string, plaintext []byte) ([]byte, error) {
+	var resp WrapResponse
+	if err := c.post(ctx, name, "wrap", WrapRequest{Plaintext: plaintext}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Ciphertext, nil
+}
+
+// Unwrap decrypts a data key encrypted with the master key name
+func (c *Client) Unwrap(ctx context.Context, name string, ciphertext []byte) ([]byte, error) {
+	var resp UnwrapResponse
+	if err := c.post(ctx, name, "unwrap", UnwrapRequest{Ciphertext: ciphertext}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+func (c *Client) post(ctx context.Context, name, action string, request, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/keys/%s/%s", c.baseURL, url.PathEscape(name), action)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("kms: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			return fmt.Errorf("kms: failed to %s with key %s: %s", action, name, resp.Status)
+		}
+		return fmt.Errorf("kms: failed to %s with key %s: %s", action, name, errResp.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
@@ -0,0 +1,134 @@
+// Package kms is a local stand-in for a key management service. It keeps
+// named master keys and wraps data keys with them over HTTP, so the server
+// never holds a master key, like with a cloud KMS.
+package kms
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+)
+
+// WrapRequest is the body of POST /v1/keys/{name}/wrap
+type WrapRequest struct {
+	Plaintext []byte `json:"plaintext"`
+}
+
+// WrapResponse is the response of POST /v1/keys/{name}/wrap
+type WrapResponse struct {
+	Key        string `json:"key"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// UnwrapRequest is the body of POST /v1/keys/{name}/unwrap
+type UnwrapRequest struct {
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// UnwrapResponse is the response of POST /v1/keys/{name}/unwrap
+type UnwrapResponse struct {
+	Plaintext []byte `json:"plaintext"`
+}
+
+// ErrorResponse is the body of every failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server wraps and unwraps data keys with its master keys
+type Server struct {
+	keys  map[string]*encryption.Cipher
+	token string
+}
+
+// NewServer creates a server of master keys by name. Requests must have the
+// bearer token, unless it is empty.
+func NewServer(keys map[string][]byte, token string) (*Server, error) {
+	s := &Server{keys: make(map[string]*encryption.Cipher, len(keys)), token: token}
+	for name, key := range keys {
+		cipher, err := encryption.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		s.keys[name] = cipher
+	}
+	return s, nil
+}
+
+// RegisterRoutes registers the routes of the server in router
+func (s *Server) RegisterRoutes(router *http.ServeMux) {
+	// Encrypt a data key with a master key
+	router.HandleFunc("POST /v1/keys/{name}/wrap", s.call(s.wrap))
+	// Decrypt a data key encrypted with a master key
+	router.HandleFunc("POST /v1/keys/{name}/unwrap", s.call(s.unwrap))
+}
+
+type handlerFunc func(cipher *encryption.Cipher, name string, r *http.Request) (any, int, error)
+
+func (s *Server) call(handler handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "invalid token"})
+				return
+			}
+		}
+
+		name := r.PathValue("name")
+		cipher, ok := s.keys[name]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "unknown key " + name})
+			return
+		}
+
+		resp, code, err := handler(cipher, name, r)
+		if err != nil {
+			log.Printf("kms: %s %s: %v", r.Method, r.URL.Path, err)
+			writeJSON(w, code, ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, code, resp)
+	}
+}
+
+func (s *Server) wrap(cipher *encryption.Cipher, name string, r *http.Request) (any, int, error) {
+	var request WrapRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if len(request.Plaintext) == 0 {
+		return nil, http.StatusBadRequest, errors.New("plaintext is required")
+	}
+
+	// The name is authenticated, a ciphertext only unwraps with its key
+	ciphertext, err := cipher.Seal(request.Plaintext, []byte(name))
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return WrapResponse{Key: name, Ciphertext: ciphertext}, http.StatusOK, nil
+}
+
+func (s *Server) unwrap(cipher *encryption.Cipher, name string, r *http.Request) (any, int, error) {
+	var request UnwrapRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	plaintext, err := cipher.Open(request.Ciphertext, []byte(name))
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return UnwrapResponse{Plaintext: plaintext}, http.StatusOK, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,104 @@
+package rotation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rodrwan/secretly/internal/generator"
+)
+
+// Request contains what a Rotator needs to produce the next value of a key
+type Request struct {
+	EnvironmentID   int64
+	EnvironmentName string
+	Key             string
+	// Current is the value being replaced
+	Current string
+	// Params are the rotator specific parameters stored with the policy
+	Params json.RawMessage
+}
+
+// Rotator produces a new value for a key. Implementations can also update
+// the secret in the system that consumes it (ie: a database password)
+// before returning the new value.
+type Rotator interface {
+	Rotate(ctx context.Context, req Request) (string, error)
+}
+
+// RotatorFunc adapts a function to the Rotator interface
+type RotatorFunc func(ctx context.Context, req Request) (string, error)
+
+// Rotate calls f(ctx, req)
+func (f RotatorFunc) Rotate(ctx context.Context, req Request) (string, error) {
+	return f(ctx, req)
+}
+
+// Registry holds the rotators available to rotation policies
+type Registry struct {
+	mu       sync.RWMutex
+	rotators map[string]Rotator
+}
+
+// NewRegistry creates a registry with the built-in rotators: password, hex,
+// base64, rsa and ed25519
+func NewRegistry() *Registry {
+	r := &Registry{rotators: make(map[string]Rotator)}
+	for _, t := range []string{
+		generator.TypePassword,
+		generator.TypeHex,
+		generator.TypeBase64,
+		generator.TypeRSA,
+		generator.TypeEd25519,
+	} {
+		r.Register(t, generatorRotator(t))
+	}
+	return r
+}
+
+// Register adds or replaces a rotator
+func (r *Registry) Register(name string, rotator Rotator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rotators[name] = rotator
+}
+
+// Get returns the rotator registered under name
+func (r *Registry) Get(name string) (Rotator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rotator, ok := r.rotators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rotator %q", name)
+	}
+	return rotator, nil
+}
+
+// Names returns the registered rotator names
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.rotators))
+	for name := range r.rotators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// generatorRotator returns a rotator that replaces the value with a freshly
+// generated one. Params are decoded into a generator.Spec.
+func generatorRotator(generatorType string) Rotator {
+	return RotatorFunc(func(ctx context.Context, req Request) (string, error) {
+		var spec generator.Spec
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &spec); err != nil {
+				return "", fmt.Errorf("invalid params: %w", err)
+			}
+		}
+		spec.Type = generatorType
+		return generator.Generate(spec)
+	})
+}
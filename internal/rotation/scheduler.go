@@ -0,0 +1,190 @@
+package rotation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/webhook"
+)
+
+const defaultBatchSize = 50
+
+// Scheduler rotates the values whose policy is due. Failed rotations are
+// retried with exponential backoff, capped at the policy interval, and
+// reported through the policy and a webhook event.
+type Scheduler struct {
+	// sqlDB stores rotated values in a transaction, db is its querier
+	sqlDB      *database.DB
+	db         database.Querier
+	registry   *Registry
+	webhooks   *webhook.Dispatcher
+	interval   time.Duration
+	retryDelay time.Duration
+	batchSize  int64
+	now        func() time.Time
+	// paused skips the runs while it returns true, nil never pauses
+	paused func() bool
+}
+
+// NewScheduler creates a new rotation scheduler
+func NewScheduler(db *database.DB, registry *Registry, dispatcher *webhook.Dispatcher, interval, retryDelay time.Duration) *Scheduler {
+	return &Scheduler{
+		sqlDB:      db,
+		db:         db.Querier(),
+		registry:   registry,
+		webhooks:   dispatcher,
+		interval:   interval,
+		retryDelay: retryDelay,
+		batchSize:  defaultBatchSize,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Registry returns the rotators available to the scheduler
+func (s *Scheduler) Registry() *Registry {
+	return s.registry
+}
+
+// Run rotates due values until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RotateDue(ctx); err != nil {
+			log.Printf("rotation: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PauseWhile skips the runs while paused returns true, ie: while the master
+// key is sealed, values can't be encrypted and every rotation would fail
+func (s *Scheduler) PauseWhile(paused func() bool) {
+	s.paused = paused
+}
+
+// RotateDue rotates every value whose next rotation is due, unless the
+// scheduler is paused
+func (s *Scheduler) RotateDue(ctx context.Context) error {
+	if s.paused != nil && s.paused() {
+		return nil
+	}
+
+	policies, err := s.db.GetDueRotationPolicies(ctx, database.GetDueRotationPoliciesParams{
+		NextRotationAt: s.now(),
+		Limit:          s.batchSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get due policies: %w", err)
+	}
+
+	for _, policy := range policies {
+		if _, err := s.Rotate(ctx, policy); err != nil {
+			log.Printf("rotation: policy %d: %v", policy.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Rotate rotates the value of a policy now, storing the result as a new
+// version. On failure the policy is rescheduled for a retry.
+func (s *Scheduler) Rotate(ctx context.Context, policy database.RotationPolicy) (database.EnvironmentValue, error) {
+	value, err := s.db.GetValue(ctx, policy.ValueID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The value was deleted, its policy has nothing left to rotate
+		return database.EnvironmentValue{}, s.db.DeleteRotationPolicy(ctx, policy.ID)
+	}
+	if err != nil {
+		return database.EnvironmentValue{}, fmt.Errorf("failed to get value: %w", err)
+	}
+
+	env, err := s.db.GetEnvironment(ctx, value.EnvironmentID)
+	if err != nil {
+		return database.EnvironmentValue{}, fmt.Errorf("failed to get environment: %w", err)
+	}
+
+	rotated, err := s.rotate(ctx, policy, env, value)
+	if err != nil {
+		if _, markErr := s.db.MarkRotationFailed(ctx, database.MarkRotationFailedParams{
+			ID:             policy.ID,
+			NextRotationAt: s.now().Add(s.backoff(policy)),
+			LastError:      err.Error(),
+		}); markErr != nil {
+			return database.EnvironmentValue{}, fmt.Errorf("failed to record rotation failure: %w", markErr)
+		}
+		s.publish(ctx, webhook.NewEvent(webhook.EventValueRotationFailed, env.ID, env.Name, value.Key))
+		return database.EnvironmentValue{}, err
+	}
+
+	now := s.now()
+	if _, err := s.db.MarkRotationSucceeded(ctx, database.MarkRotationSucceededParams{
+		ID:             policy.ID,
+		NextRotationAt: now.Add(time.Duration(policy.IntervalSeconds) * time.Second),
+		LastRotatedAt:  sql.NullTime{Time: now, Valid: true},
+	}); err != nil {
+		return database.EnvironmentValue{}, fmt.Errorf("failed to reschedule rotation: %w", err)
+	}
+
+	s.publish(ctx, webhook.NewEvent(webhook.EventValueRotated, env.ID, env.Name, value.Key))
+
+	return rotated, nil
+}
+
+func (s *Scheduler) rotate(ctx context.Context, policy database.RotationPolicy, env database.Environment, value database.EnvironmentValue) (database.EnvironmentValue, error) {
+	rotator, err := s.registry.Get(policy.Rotator)
+	if err != nil {
+		return database.EnvironmentValue{}, err
+	}
+
+	next, err := rotator.Rotate(ctx, Request{
+		EnvironmentID:   env.ID,
+		EnvironmentName: env.Name,
+		Key:             value.Key,
+		Current:         value.Value,
+		Params:          json.RawMessage(policy.Params),
+	})
+	if err != nil {
+		return database.EnvironmentValue{}, fmt.Errorf("rotator %s failed: %w", policy.Rotator, err)
+	}
+
+	// The value and its version are stored together
+	var stored database.EnvironmentValue
+	err = database.InTx(ctx, s.sqlDB, func(q database.Querier) error {
+		stored, _, err = database.SetValue(ctx, q, env.ID, value.Key, next)
+		return err
+	})
+	if err != nil {
+		return database.EnvironmentValue{}, fmt.Errorf("failed to store rotated value: %w", err)
+	}
+
+	return stored, nil
+}
+
+// backoff returns the delay before a failed policy is retried
+func (s *Scheduler) backoff(policy database.RotationPolicy) time.Duration {
+	limit := time.Duration(policy.IntervalSeconds) * time.Second
+	delay := s.retryDelay
+	for i := int64(0); i < policy.Failures && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+func (s *Scheduler) publish(ctx context.Context, event webhook.Event) {
+	if err := s.webhooks.Publish(ctx, event); err != nil {
+		log.Printf("rotation: failed to publish %s: %v", event.Type, err)
+	}
+}
@@ -0,0 +1,106 @@
+package rotation
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/database/dbtest"
+	"github.com/rodrwan/secretly/internal/encryption"
+	"github.com/rodrwan/secretly/internal/keyring"
+)
+
+// sealedProvider returns a sealed master key split in shares, and a
+// function unsealing it
+func sealedProvider(t *testing.T) (*keyring.ShamirProvider, func()) {
+	t.Helper()
+	key := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	shares, check, err := keyring.SplitKey(key, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := keyring.NewShamirProvider("1", 2, check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider, func() {
+		t.Helper()
+		for _, share := range shares {
+			if _, err := provider.Unseal(share); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestRotateDueWhileSealed(t *testing.T) {
+	ctx := context.Background()
+	plain := dbtest.SQLite(t)
+	provider, unseal := sealedProvider(t)
+	keys, err := keyring.New(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := plain.Wrap(keys.Querier)
+
+	// A value stored before encryption was enabled can be read while
+	// sealed, its rotated value can't be encrypted
+	q := plain.Querier()
+	env, err := q.CreateEnvironment(ctx, database.CreateEnvironmentParams{ProjectID: 1, Name: "production"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _, err := database.SetValue(ctx, q, env.ID, "TOKEN", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	policy, err := q.UpsertRotationPolicy(ctx, database.UpsertRotationPolicyParams{
+		ValueID:         value.ID,
+		Rotator:         "counter",
+		Params:          "{}",
+		IntervalSeconds: 3600,
+		NextRotationAt:  now.Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotations := 0
+	registry := NewRegistry()
+	registry.Register("counter", RotatorFunc(func(ctx context.Context, req Request) (string, error) {
+		rotations++
+		return "rotated", nil
+	}))
+	s := NewScheduler(db, registry, nil, time.Minute, time.Minute)
+	s.now = func() time.Time { return now }
+	s.PauseWhile(func() bool { return provider.Status().Sealed })
+
+	if err := s.RotateDue(ctx); err != nil {
+		t.Fatalf("RotateDue while sealed: %v", err)
+	}
+	got, err := q.GetRotationPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotations != 0 || got.Failures != 0 || got.LastError != "" {
+		t.Errorf("rotated %d times while sealed, policy %+v", rotations, got)
+	}
+
+	unseal()
+	if err := s.RotateDue(ctx); err != nil {
+		t.Fatalf("RotateDue: %v", err)
+	}
+	rotated, err := db.Querier().GetValue(ctx, value.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotations != 1 || rotated.Version != 2 || rotated.Value != "rotated" {
+		t.Errorf("once unsealed: %d rotations, %s version %d", rotations, rotated.Value, rotated.Version)
+	}
+}
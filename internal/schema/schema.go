@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Value types
+const (
+	TypeString   = "string"
+	TypeInt      = "int"
+	TypeBool     = "bool"
+	TypeURL      = "url"
+	TypeDuration = "duration"
+	TypeJSON     = "json"
+	TypePEM      = "pem"
+)
+
+// Types returns the supported value types
+func Types() []string {
+	return []string{TypeString, TypeInt, TypeBool, TypeURL, TypeDuration, TypeJSON, TypePEM}
+}
+
+// Rule describes the type and constraints of a key
+type Rule struct {
+	Type string `json:"type"`
+	// Pattern is a regular expression the value must match
+	Pattern string `json:"pattern,omitempty"`
+	// Enum lists the allowed values
+	Enum []string `json:"enum,omitempty"`
+	// Min and Max bound the value of an int, the seconds of a duration or
+	// the length of any other type
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// Required keys must be set for the environment to be complete
+	Required bool `json:"required,omitempty"`
+}
+
+// ValidationError is returned when a value doesn't satisfy its rule
+type ValidationError struct {
+	Key    string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid value for %s: %s", e.Key, e.Reason)
+}
+
+// IsValidationError reports whether err is a *ValidationError
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
+
+// Check verifies the rule itself is well formed
+func (r Rule) Check() error {
+	if !slices.Contains(Types(), r.Type) {
+		return fmt.Errorf("unknown type %q, must be one of %s", r.Type, strings.Join(Types(), ", "))
+	}
+	if r.Pattern != "" {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return errors.New("min must not be greater than max")
+	}
+	if r.Type == TypeBool && (r.Min != nil || r.Max != nil) {
+		return errors.New("min and max don't apply to bool")
+	}
+	return nil
+}
+
+// Validate checks value against the rule
+func (r Rule) Validate(key, value string) error {
+	invalid := func(format string, args ...any) error {
+		return &ValidationError{Key: key, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	// measure is what min and max are compared against
+	measure := float64(utf8.RuneCountInString(value))
+	measureName := "length"
+
+	switch r.Type {
+	case TypeString, "":
+	case TypeInt:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return invalid("%q is not an int", value)
+		}
+		measure, measureName = float64(i), "value"
+	case TypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return invalid("%q is not a bool", value)
+		}
+	case TypeURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" && u.Opaque == "" && u.Path == "" {
+			return invalid("%q is not an absolute url", value)
+		}
+	case TypeDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return invalid("%q is not a duration", value)
+		}
+		measure, measureName = d.Seconds(), "seconds"
+	case TypeJSON:
+		if !json.Valid([]byte(value)) {
+			return invalid("not valid json")
+		}
+	case TypePEM:
+		if block, _ := pem.Decode([]byte(value)); block == nil {
+			return invalid("not a pem encoded block")
+		}
+	default:
+		return invalid("unknown type %q", r.Type)
+	}
+
+	if len(r.Enum) > 0 && !slices.Contains(r.Enum, value) {
+		return invalid("%q is not one of %s", value, strings.Join(r.Enum, ", "))
+	}
+
+	if r.Pattern != "" {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return invalid("invalid pattern: %v", err)
+		}
+		if !re.MatchString(value) {
+			return invalid("does not match %s", r.Pattern)
+		}
+	}
+
+	if r.Min != nil && measure < *r.Min {
+		return invalid("%s must be at least %v", measureName, *r.Min)
+	}
+	if r.Max != nil && measure > *r.Max {
+		return invalid("%s must be at most %v", measureName, *r.Max)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+// Package shamir splits a secret in shares with Shamir's secret sharing, any
+// threshold of them recover it and fewer reveal nothing about it. Each byte
+// of the secret is the constant term of a random polynomial over GF(2^8),
+// a share is the value of the polynomials at a point, appended to it.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ShareOverhead is the number of bytes a share adds to the secret, the point
+// it was evaluated at
+const ShareOverhead = 1
+
+// Split splits secret in parts shares, threshold of them recover it
+func Split(secret []byte, parts, threshold int) ([][]byte, error) {
+	switch {
+	case len(secret) == 0:
+		return nil, errors.New("the secret is empty")
+	case threshold < 2:
+		return nil, errors.New("the threshold must be at least 2")
+	case parts < threshold:
+		return nil, errors.New("there must be at least as many parts as the threshold")
+	case parts > 255:
+		return nil, errors.New("there can't be more than 255 parts")
+	}
+
+	// Random distinct points, 0 would be the secret itself
+	xs, err := points(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([][]byte, parts)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+ShareOverhead)
+		shares[i][len(secret)] = xs[i]
+	}
+
+	coefficients := make([]byte, threshold)
+	for b, value := range secret {
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		coefficients[0] = value
+		for i, x := range xs {
+			shares[i][b] = evaluate(coefficients, x)
+		}
+	}
+	return shares, nil
+}
+
+// Combine recovers a secret from at least threshold of its shares. Fewer
+// shares, or shares of another secret, recover another secret.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least 2 shares are required")
+	}
+	size := len(shares[0])
+	if size <= ShareOverhead {
+		return nil, errors.New("the shares are too short")
+	}
+
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("the shares must have the same length")
+		}
+		x := share[size-ShareOverhead]
+		if x == 0 || seen[x] {
+			return nil, fmt.Errorf("share %d is invalid or duplicated", i+1)
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-ShareOverhead)
+	ys := make([]byte, len(shares))
+	for b := range secret {
+		for i, share := range shares {
+			ys[i] = share[b]
+		}
+		secret[b] = interpolate(xs, ys)
+	}
+	return secret, nil
+}
+
+// points returns n distinct random non-zero points
+func points(n int) ([]byte, error) {
+	all := make([]byte, 255)
+	for i := range all {
+		all[i] = byte(i + 1)
+	}
+	// Fisher-Yates shuffle of the first n
+	for i := 0; i < n; i++ {
+		var r [1]byte
+		if _, err := rand.Read(r[:]); err != nil {
+			return nil, err
+		}
+		j := i + int(r[0])%(len(all)-i)
+		all[i], all[j] = all[j], all[i]
+	}
+	return all[:n], nil
+}
+
+// evaluate evaluates the polynomial of coefficients at x with Horner's method
+func evaluate(coefficients []byte, x byte) byte {
+	var result byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = add(mul(result, x), coefficients[i])
+	}
+	return result
+}
+
+// interpolate is the value at 0 of the Lagrange polynomial through the
+// points (xs[i], ys[i])
+func interpolate(xs, ys []byte) byte {
+	var result byte
+	for i := range xs {
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			// x_j / (x_j - x_i), subtraction is addition in GF(2^8)
+			basis = mul(basis, div(xs[j], add(xs[j], xs[i])))
+		}
+		result = add(result, mul(ys[i], basis))
+	}
+	return result
+}
+
+// Arithmetic in GF(2^8) with the polynomial of AES, x^8 + x^4 + x^3 + x + 1
+
+func add(a, b byte) byte {
+	return a ^ b
+}
+
+// mul multiplies without branching on the operands, in constant time
+func mul(a, b byte) byte {
+	var product byte
+	for i := 0; i < 8; i++ {
+		product ^= -(b & 1) & a
+		a = a<<1 ^ -(a>>7)&0x1b
+		b >>= 1
+	}
+	return product
+}
+
+// inverse is a^254, the multiplicative inverse of a non-zero a
+func inverse(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 254; i++ {
+		result = mul(result, a)
+	}
+	return result
+}
+
+func div(a, b byte) byte {
+	return mul(a, inverse(b))
+}
@@ -0,0 +1,130 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"testing"
+)
+
+func randomSecret(t *testing.T, size int) []byte {
+	t.Helper()
+	secret := make([]byte, size)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
+
+func TestSplitCombine(t *testing.T) {
+	tests := []struct{ parts, threshold int }{
+		{2, 2},
+		{3, 2},
+		{5, 3},
+		{10, 10},
+		{32, 17},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d of %d", tt.threshold, tt.parts), func(t *testing.T) {
+			secret := randomSecret(t, 32)
+			shares, err := Split(secret, tt.parts, tt.threshold)
+			if err != nil {
+				t.Fatalf("Split: %v", err)
+			}
+			if len(shares) != tt.parts {
+				t.Fatalf("got %d shares, want %d", len(shares), tt.parts)
+			}
+			for i, share := range shares {
+				if len(share) != len(secret)+ShareOverhead {
+					t.Fatalf("share %d is %d bytes, want %d", i, len(share), len(secret)+ShareOverhead)
+				}
+			}
+
+			// Any threshold shares recover the secret, ie: the first and the last ones
+			for _, subset := range [][][]byte{shares[:tt.threshold], shares[tt.parts-tt.threshold:], shares} {
+				got, err := Combine(subset)
+				if err != nil {
+					t.Fatalf("Combine of %d shares: %v", len(subset), err)
+				}
+				if !bytes.Equal(got, secret) {
+					t.Errorf("Combine of %d shares recovered another secret", len(subset))
+				}
+			}
+
+			// Fewer shares recover another secret
+			if tt.threshold > 2 {
+				got, err := Combine(shares[:tt.threshold-1])
+				if err != nil {
+					t.Fatalf("Combine of %d shares: %v", tt.threshold-1, err)
+				}
+				if bytes.Equal(got, secret) {
+					t.Error("fewer shares than the threshold recovered the secret")
+				}
+			}
+		})
+	}
+}
+
+func TestSplitInvalid(t *testing.T) {
+	secret := randomSecret(t, 16)
+	tests := []struct {
+		name             string
+		secret           []byte
+		parts, threshold int
+	}{
+		{"empty secret", nil, 3, 2},
+		{"threshold of 1", secret, 3, 1},
+		{"fewer parts than the threshold", secret, 2, 3},
+		{"too many parts", secret, 256, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Split(tt.secret, tt.parts, tt.threshold); err == nil {
+				t.Error("Split succeeded")
+			}
+		})
+	}
+}
+
+func TestCombineInvalid(t *testing.T) {
+	shares, err := Split(randomSecret(t, 16), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero := bytes.Clone(shares[1])
+	zero[len(zero)-1] = 0
+
+	tests := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{"one share", shares[:1]},
+		{"duplicated share", [][]byte{shares[0], shares[0]}},
+		{"duplicated index", [][]byte{shares[0], append(bytes.Clone(shares[1][:16]), shares[0][16])}},
+		{"zero index", [][]byte{shares[0], zero}},
+		{"different lengths", [][]byte{shares[0], shares[1][1:]}},
+		{"too short", [][]byte{{1}, {2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Combine(tt.shares); err == nil {
+				t.Error("Combine succeeded")
+			}
+		})
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if got := mul(byte(a), inverse(byte(a))); got != 1 {
+			t.Fatalf("%d * inverse(%d) = %d, want 1", a, a, got)
+		}
+		if got := div(mul(byte(a), 0x53), 0x53); got != byte(a) {
+			t.Fatalf("%d * 0x53 / 0x53 = %d", a, got)
+		}
+	}
+	// The example of FIPS-197, {57} * {83} = {c1}
+	if got := mul(0x57, 0x83); got != 0xc1 {
+		t.Errorf("mul(0x57, 0x83) = %#x, want 0xc1", got)
+	}
+}
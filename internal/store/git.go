@@ -0,0 +1,948 @@
+package store
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/mail"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+)
+
+var _ Store = (*Git)(nil)
+
+// DefaultGitAuthor is the author of the commits of changes without an author
+const DefaultGitAuthor = "Secretly <secretly@localhost>"
+
+// gitFileExt is the extension of environment files
+const gitFileExt = ".env.enc"
+
+// Git keeps each environment as an encrypted file in a git working tree,
+// <project id>/<name>.env.enc, and commits every change. The versions of a
+// value come from the history of its file.
+type Git struct {
+	dir      string
+	cipher   *encryption.Cipher
+	readOnly bool
+	// author of the commits made without WithAuthor, and their committer
+	author string
+
+	// mu serializes the changes, reads wait for the files being written
+	mu sync.Mutex
+}
+
+// GitOption configures a Git store
+type GitOption func(*Git)
+
+// GitReadOnly serves the environments of a checked out repository without
+// ever writing to it, writes return ErrReadOnly
+func GitReadOnly(readOnly bool) GitOption {
+	return func(g *Git) {
+		g.readOnly = readOnly
+	}
+}
+
+// GitAuthor sets the author of changes without WithAuthor, ie:
+// "Secretly <secretly@example.com>"
+func GitAuthor(author string) GitOption {
+	return func(g *Git) {
+		g.author = author
+	}
+}
+
+// NewGit keeps environments in the git working tree dir, encrypted with
+// cipher. Unless it is read-only, a repository is initialized in dir when it
+// isn't the top level of one.
+func NewGit(ctx context.Context, dir string, cipher *encryption.Cipher, opts ...GitOption) (*Git, error) {
+	g := &Git{dir: dir, cipher: cipher, author: DefaultGitAuthor}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if _, err := mail.ParseAddress(g.author); err != nil {
+		return nil, fmt.Errorf("invalid git author %q, must be like %s", g.author, DefaultGitAuthor)
+	}
+
+	if g.readOnly {
+		if _, err := g.git(ctx, "rev-parse", "--is-inside-work-tree"); err != nil {
+			return nil, fmt.Errorf("%s is not a git working tree: %w", dir, err)
+		}
+		return g, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if !g.isTopLevel(ctx) {
+		if _, err := g.git(ctx, "init", "--quiet"); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// isTopLevel reports whether dir is the top level of a working tree, the
+// store never commits to a repository it is nested in
+func (g *Git) isTopLevel(ctx context.Context) bool {
+	out, err := g.git(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return false
+	}
+	top, err := filepath.EvalSymlinks(strings.TrimSpace(string(out)))
+	if err != nil {
+		return false
+	}
+	dir, err := filepath.Abs(g.dir)
+	if err == nil {
+		dir, err = filepath.EvalSymlinks(dir)
+	}
+	return err == nil && dir == top
+}
+
+// git runs a git command in the working tree
+func (g *Git) git(ctx context.Context, args ...string) ([]byte, error) {
+	committer, _ := mail.ParseAddress(g.author)
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", g.dir}, args...)...)
+	cmd.Env = os.Environ()
+	if committer != nil {
+		cmd.Env = append(cmd.Env,
+			"GIT_COMMITTER_NAME="+committer.Name,
+			"GIT_COMMITTER_EMAIL="+committer.Address,
+		)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// view runs fn with the files of the working tree
+func (g *Git) view(fn func(t *gitTx) error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return fn(g.newTx())
+}
+
+// update runs fn and commits its changes when it succeeds
+func (g *Git) update(ctx context.Context, fn func(t *gitTx) error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t := g.newTx()
+	if err := fn(t); err != nil {
+		return err
+	}
+	return g.commit(ctx, t)
+}
+
+func (g *Git) newTx() *gitTx {
+	return &gitTx{g: g, changes: make(map[string][]byte)}
+}
+
+// commit writes the files changed by t and commits them in a single commit.
+// When it fails, the files are restored so the working tree keeps matching
+// the last commit.
+func (g *Git) commit(ctx context.Context, t *gitTx) (err error) {
+	if len(t.changes) == 0 {
+		return nil
+	}
+
+	var (
+		paths    []string
+		previous []gitFile
+	)
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, g.restore(ctx, previous))
+		}
+	}()
+
+	for _, p := range slices.Sorted(maps.Keys(t.changes)) {
+		file := filepath.Join(g.dir, filepath.FromSlash(p))
+		old, readErr := os.ReadFile(file)
+		if readErr != nil && !errors.Is(readErr, fs.ErrNotExist) {
+			return readErr
+		}
+		existed := readErr == nil
+		data := t.changes[p]
+		if data == nil && !existed {
+			// Created and removed by the same transaction
+			continue
+		}
+		previous = append(previous, gitFile{path: p, data: old, existed: existed})
+
+		if data == nil {
+			if err := os.Remove(file); err != nil {
+				return err
+			}
+		} else {
+			if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+				return err
+			}
+			if err := os.WriteFile(file, data, 0o600); err != nil {
+				return err
+			}
+		}
+		paths = append(paths, p)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	if _, err := g.git(ctx, append([]string{"add", "--all", "--"}, paths...)...); err != nil {
+		return err
+	}
+
+	subject := t.messages[0]
+	if len(t.messages) > 1 {
+		subject = fmt.Sprintf("%s and %d more changes", subject, len(t.messages)-1)
+	}
+	args := []string{"commit", "--quiet", "--author", g.commitAuthor(ctx), "-m", subject}
+	if len(t.messages) > 1 {
+		args = append(args, "-m", strings.Join(t.messages, "\n"))
+	}
+	_, err = g.git(ctx, args...)
+	return err
+}
+
+// gitFile is the content of a file before a commit changed it
+type gitFile struct {
+	path    string
+	data    []byte
+	existed bool
+}
+
+// restore puts back the files of a failed commit and unstages them
+func (g *Git) restore(ctx context.Context, files []gitFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	var errs []error
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		file := filepath.Join(g.dir, filepath.FromSlash(f.path))
+		if f.existed {
+			errs = append(errs, os.WriteFile(file, f.data, 0o600))
+		} else if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		paths = append(paths, f.path)
+	}
+
+	// The request may be why the commit failed, the index is reset anyway
+	_, err := g.git(context.WithoutCancel(ctx), append([]string{"reset", "--quiet", "--"}, paths...)...)
+	errs = append(errs, err)
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to restore the working tree: %w", err)
+	}
+	return nil
+}
+
+// gitAuthorName is the name of an author without an email
+var gitAuthorName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 ._-]{0,127}$`)
+
+// commitAuthor is the author of ctx as a git author, actors without an email
+// get an empty one. Authors that are neither an address nor a plain name
+// fall back to the author of the store.
+func (g *Git) commitAuthor(ctx context.Context) string {
+	author := Author(ctx)
+	if author == "" {
+		return g.author
+	}
+	if gitAuthorName.MatchString(author) {
+		return author + " <>"
+	}
+	addr, err := mail.ParseAddress(author)
+	if err != nil || strings.ContainsAny(addr.Name+addr.Address, "<>\n\r") {
+		return g.author
+	}
+	return cmp.Or(addr.Name, addr.Address) + " <" + addr.Address + ">"
+}
+
+func (g *Git) GetEnvironment(ctx context.Context, id int64) (env Environment, err error) {
+	err = g.view(func(t *gitTx) error {
+		env, err = t.GetEnvironment(ctx, id)
+		return err
+	})
+	return env, err
+}
+
+func (g *Git) GetEnvironmentByName(ctx context.Context, projectID int64, name string) (env Environment, err error) {
+	err = g.view(func(t *gitTx) error {
+		env, err = t.GetEnvironmentByName(ctx, projectID, name)
+		return err
+	})
+	return env, err
+}
+
+func (g *Git) ListEnvironments(ctx context.Context, opts ListOptions) (envs []Environment, err error) {
+	err = g.view(func(t *gitTx) error {
+		envs, err = t.ListEnvironments(ctx, opts)
+		return err
+	})
+	return envs, err
+}
+
+func (g *Git) CreateEnvironment(ctx context.Context, projectID int64, name string) (env Environment, err error) {
+	err = g.update(ctx, func(t *gitTx) error {
+		env, err = t.CreateEnvironment(ctx, projectID, name)
+		return err
+	})
+	return env, err
+}
+
+func (g *Git) SetEnvironmentExpiry(ctx context.Context, id int64, expiresAt *time.Time) (env Environment, err error) {
+	err = g.update(ctx, func(t *gitTx) error {
+		env, err = t.SetEnvironmentExpiry(ctx, id, expiresAt)
+		return err
+	})
+	return env, err
+}
+
+func (g *Git) MarkEnvironmentE2E(ctx context.Context, id int64) (env Environment, err error) {
+	err = g.update(ctx, func(t *gitTx) error {
+		env, err = t.MarkEnvironmentE2E(ctx, id)
+		return err
+	})
+	return env, err
+}
+
+func (g *Git) SetEnvironmentMetadata(ctx context.Context, id int64, m Metadata) (env Environment, err error) {
+	err = g.update(ctx, func(t *gitTx) error {
+		env, err = t.SetEnvironmentMetadata(ctx, id, m)
+		return err
+	})
+	return env, err
+}
+
+func (g *Git) DeleteEnvironment(ctx context.Context, id int64) error {
+	return g.update(ctx, func(t *gitTx) error {
+		return t.DeleteEnvironment(ctx, id)
+	})
+}
+
+func (g *Git) GetExpiredEnvironments(ctx context.Context, now time.Time, limit int64) (envs []Environment, err error) {
+	err = g.view(func(t *gitTx) error {
+		envs, err = t.GetExpiredEnvironments(ctx, now, limit)
+		return err
+	})
+	return envs, err
+}
+
+func (g *Git) GetValues(ctx context.Context, environmentID int64) (values []Value, err error) {
+	err = g.view(func(t *gitTx) error {
+		values, err = t.GetValues(ctx, environmentID)
+		return err
+	})
+	return values, err
+}
+
+func (g *Git) GetValue(ctx context.Context, environmentID int64, key string) (value Value, err error) {
+	err = g.view(func(t *gitTx) error {
+		value, err = t.GetValue(ctx, environmentID, key)
+		return err
+	})
+	return value, err
+}
+
+func (g *Git) SetValue(ctx context.Context, environmentID int64, key, value string) (stored Value, created bool, err error) {
+	err = g.update(ctx, func(t *gitTx) error {
+		stored, created, err = t.SetValue(ctx, environmentID, key, value)
+		return err
+	})
+	return stored, created, err
+}
+
+func (g *Git) SetValueExpiry(ctx context.Context, environmentID int64, key string, expiresAt *time.Time) (value Value, err error) {
+	err = g.update(ctx, func(t *gitTx) error {
+		value, err = t.SetValueExpiry(ctx, environmentID, key, expiresAt)
+		return err
+	})
+	return value, err
+}
+
+func (g *Git) SetValueMetadata(ctx context.Context, environmentID int64, key string, m Metadata) (value Value, err error) {
+	err = g.update(ctx, func(t *gitTx) error {
+		value, err = t.SetValueMetadata(ctx, environmentID, key, m)
+		return err
+	})
+	return value, err
+}
+
+func (g *Git) RenameValue(ctx context.Context, environmentID int64, key, newKey string) (value Value, err error) {
+	err = g.update(ctx, func(t *gitTx) error {
+		value, err = t.RenameValue(ctx, environmentID, key, newKey)
+		return err
+	})
+	return value, err
+}
+
+func (g *Git) DeleteValue(ctx context.Context, environmentID int64, key string) error {
+	return g.update(ctx, func(t *gitTx) error {
+		return t.DeleteValue(ctx, environmentID, key)
+	})
+}
+
+func (g *Git) GetVersions(ctx context.Context, environmentID int64, key string) (versions []Version, err error) {
+	err = g.view(func(t *gitTx) error {
+		versions, err = t.GetVersions(ctx, environmentID, key)
+		return err
+	})
+	return versions, err
+}
+
+// InTx commits all the changes of fn at once
+func (g *Git) InTx(ctx context.Context, fn func(s Store) error) error {
+	return g.update(ctx, func(t *gitTx) error {
+		return fn(t)
+	})
+}
+
+// gitEnvironment is the content of an environment file
+type gitEnvironment struct {
+	ID        int64      `json:"id"`
+	ProjectID int64      `json:"project_id"`
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	E2E       bool       `json:"e2e,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	gitMetadata
+	Values []gitValue `json:"values"`
+}
+
+type gitValue struct {
+	ID        int64      `json:"id"`
+	Key       string     `json:"key"`
+	Value     string     `json:"value"`
+	Version   int64      `json:"version"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	gitMetadata
+}
+
+// gitMetadata is the Metadata of an environment or a value in its file
+type gitMetadata struct {
+	Description string   `json:"description,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	Link        string   `json:"link,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// path of the environment file, relative to the working tree
+func (env gitEnvironment) path() string {
+	return path.Join(strconv.FormatInt(env.ProjectID, 10), url.PathEscape(env.Name)+gitFileExt)
+}
+
+func (env gitEnvironment) value(key string) (int, bool) {
+	i := slices.IndexFunc(env.Values, func(v gitValue) bool { return v.Key == key })
+	return i, i >= 0
+}
+
+func (env gitEnvironment) toEnvironment() Environment {
+	return Environment{
+		ID:        env.ID,
+		ProjectID: env.ProjectID,
+		Name:      env.Name,
+		ExpiresAt: env.ExpiresAt,
+		E2E:       env.E2E,
+		CreatedAt: env.CreatedAt,
+		UpdatedAt: env.UpdatedAt,
+		Metadata:  Metadata(env.gitMetadata),
+	}
+}
+
+func (v gitValue) toValue(environmentID int64) Value {
+	return Value{
+		ID:            v.ID,
+		EnvironmentID: environmentID,
+		Key:           v.Key,
+		Value:         v.Value,
+		Version:       v.Version,
+		ExpiresAt:     v.ExpiresAt,
+		CreatedAt:     v.CreatedAt,
+		UpdatedAt:     v.UpdatedAt,
+		Metadata:      Metadata(v.gitMetadata),
+	}
+}
+
+// gitTx reads the environment files of a Git store and keeps its changes
+// until they are committed
+type gitTx struct {
+	g *Git
+	// changes by path, nil removes the file
+	changes map[string][]byte
+	// messages describe the changes, one per change
+	messages []string
+}
+
+// decode opens the content of the file at p
+func (t *gitTx) decode(p string, data []byte) (gitEnvironment, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return gitEnvironment{}, fmt.Errorf("%s: %w", p, err)
+	}
+	// The path is authenticated, a file can't be passed off as another environment
+	plaintext, err := t.g.cipher.Open(sealed, []byte(p))
+	if err != nil {
+		return gitEnvironment{}, fmt.Errorf("%s: %w", p, err)
+	}
+
+	var env gitEnvironment
+	if err := json.Unmarshal(plaintext, &env); err != nil {
+		return gitEnvironment{}, fmt.Errorf("%s: %w", p, err)
+	}
+	return env, nil
+}
+
+func (t *gitTx) read(p string) (gitEnvironment, error) {
+	data, changed := t.changes[p]
+	if !changed {
+		var err error
+		data, err = os.ReadFile(filepath.Join(t.g.dir, filepath.FromSlash(p)))
+		if errors.Is(err, fs.ErrNotExist) {
+			data = nil
+		} else if err != nil {
+			return gitEnvironment{}, err
+		}
+	}
+	if data == nil {
+		return gitEnvironment{}, fmt.Errorf("environment %s: %w", p, ErrNotFound)
+	}
+	return t.decode(p, data)
+}
+
+// environments reads every environment file, ordered by ID
+func (t *gitTx) environments() ([]gitEnvironment, error) {
+	paths := make(map[string]bool)
+	err := filepath.WalkDir(t.g.dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), gitFileExt) {
+			rel, err := filepath.Rel(t.g.dir, file)
+			if err != nil {
+				return err
+			}
+			paths[filepath.ToSlash(rel)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for p, data := range t.changes {
+		paths[p] = data != nil
+	}
+
+	envs := make([]gitEnvironment, 0, len(paths))
+	for p, exists := range paths {
+		if !exists {
+			continue
+		}
+		env, err := t.read(p)
+		if err != nil {
+			return nil, err
+		}
+		envs = append(envs, env)
+	}
+	slices.SortFunc(envs, func(a, b gitEnvironment) int { return cmp.Compare(a.ID, b.ID) })
+	return envs, nil
+}
+
+func (t *gitTx) environment(id int64) (gitEnvironment, error) {
+	envs, err := t.environments()
+	if err != nil {
+		return gitEnvironment{}, err
+	}
+	i := slices.IndexFunc(envs, func(env gitEnvironment) bool { return env.ID == id })
+	if i < 0 {
+		return gitEnvironment{}, fmt.Errorf("environment %d: %w", id, ErrNotFound)
+	}
+	return envs[i], nil
+}
+
+func (t *gitTx) write(env gitEnvironment, message string) error {
+	if t.g.readOnly {
+		return ErrReadOnly
+	}
+
+	plaintext, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	p := env.path()
+	sealed, err := t.g.cipher.Seal(plaintext, []byte(p))
+	if err != nil {
+		return err
+	}
+
+	t.changes[p] = []byte(base64.StdEncoding.EncodeToString(sealed) + "\n")
+	t.messages = append(t.messages, message)
+	return nil
+}
+
+func (t *gitTx) remove(env gitEnvironment, message string) error {
+	if t.g.readOnly {
+		return ErrReadOnly
+	}
+	t.changes[env.path()] = nil
+	t.messages = append(t.messages, message)
+	return nil
+}
+
+// nextID is greater than the IDs of every environment and value, so a
+// value keeps its ID across environments
+func (t *gitTx) nextID() (int64, error) {
+	envs, err := t.environments()
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	for _, env := range envs {
+		id = max(id, env.ID)
+		for _, value := range env.Values {
+			id = max(id, value.ID)
+		}
+	}
+	return id + 1, nil
+}
+
+func (t *gitTx) GetEnvironment(ctx context.Context, id int64) (Environment, error) {
+	env, err := t.environment(id)
+	if err != nil {
+		return Environment{}, err
+	}
+	return env.toEnvironment(), nil
+}
+
+func (t *gitTx) GetEnvironmentByName(ctx context.Context, projectID int64, name string) (Environment, error) {
+	env, err := t.read(gitEnvironment{ProjectID: projectID, Name: name}.path())
+	if err != nil {
+		return Environment{}, err
+	}
+	return env.toEnvironment(), nil
+}
+
+func (t *gitTx) ListEnvironments(ctx context.Context, opts ListOptions) ([]Environment, error) {
+	files, err := t.environments()
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]Environment, 0, len(files))
+	for _, file := range files {
+		env := file.toEnvironment()
+		if opts.WithValues {
+			for _, value := range file.Values {
+				env.Values = append(env.Values, value.toValue(env.ID))
+			}
+			slices.SortFunc(env.Values, func(a, b Value) int { return strings.Compare(a.Key, b.Key) })
+		}
+		all = append(all, env)
+	}
+	return listPage(all, opts)
+}
+
+func (t *gitTx) CreateEnvironment(ctx context.Context, projectID int64, name string) (Environment, error) {
+	if _, err := t.GetEnvironmentByName(ctx, projectID, name); err == nil {
+		return Environment{}, fmt.Errorf("environment %s: %w", name, ErrExists)
+	}
+
+	id, err := t.nextID()
+	if err != nil {
+		return Environment{}, err
+	}
+
+	now := time.Now().UTC()
+	env := gitEnvironment{
+		ID:        id,
+		ProjectID: projectID,
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Values:    make([]gitValue, 0),
+	}
+	if err := t.write(env, "Create environment "+name); err != nil {
+		return Environment{}, err
+	}
+	return env.toEnvironment(), nil
+}
+
+func (t *gitTx) SetEnvironmentExpiry(ctx context.Context, id int64, expiresAt *time.Time) (Environment, error) {
+	env, err := t.environment(id)
+	if err != nil {
+		return Environment{}, err
+	}
+
+	env.ExpiresAt = expiresAt
+	env.UpdatedAt = time.Now().UTC()
+	if err := t.write(env, "Set the expiration of environment "+env.Name); err != nil {
+		return Environment{}, err
+	}
+	return env.toEnvironment(), nil
+}
+
+func (t *gitTx) MarkEnvironmentE2E(ctx context.Context, id int64) (Environment, error) {
+	env, err := t.environment(id)
+	if err != nil {
+		return Environment{}, err
+	}
+
+	env.E2E = true
+	env.UpdatedAt = time.Now().UTC()
+	if err := t.write(env, "Encrypt environment "+env.Name+" end-to-end"); err != nil {
+		return Environment{}, err
+	}
+	return env.toEnvironment(), nil
+}
+
+func (t *gitTx) SetEnvironmentMetadata(ctx context.Context, id int64, m Metadata) (Environment, error) {
+	env, err := t.environment(id)
+	if err != nil {
+		return Environment{}, err
+	}
+
+	env.gitMetadata = gitMetadata(m)
+	env.UpdatedAt = time.Now().UTC()
+	if err := t.write(env, "Set the metadata of environment "+env.Name); err != nil {
+		return Environment{}, err
+	}
+	return env.toEnvironment(), nil
+}
+
+func (t *gitTx) DeleteEnvironment(ctx context.Context, id int64) error {
+	env, err := t.environment(id)
+	if err != nil {
+		return err
+	}
+	return t.remove(env, "Delete environment "+env.Name)
+}
+
+func (t *gitTx) GetExpiredEnvironments(ctx context.Context, now time.Time, limit int64) ([]Environment, error) {
+	files, err := t.environments()
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]Environment, 0, len(files))
+	for _, file := range files {
+		all = append(all, file.toEnvironment())
+	}
+	return expired(all, now, limit), nil
+}
+
+func (t *gitTx) GetValues(ctx context.Context, environmentID int64) ([]Value, error) {
+	env, err := t.environment(environmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]Value, 0, len(env.Values))
+	for _, value := range env.Values {
+		values = append(values, value.toValue(env.ID))
+	}
+	return values, nil
+}
+
+func (t *gitTx) GetValue(ctx context.Context, environmentID int64, key string) (Value, error) {
+	env, err := t.environment(environmentID)
+	if err != nil {
+		return Value{}, err
+	}
+	i, ok := env.value(key)
+	if !ok {
+		return Value{}, fmt.Errorf("value %s: %w", key, ErrNotFound)
+	}
+	return env.Values[i].toValue(env.ID), nil
+}
+
+func (t *gitTx) SetValue(ctx context.Context, environmentID int64, key, value string) (Value, bool, error) {
+	env, err := t.environment(environmentID)
+	if err != nil {
+		return Value{}, false, err
+	}
+
+	now := time.Now().UTC()
+	i, exists := env.value(key)
+	if exists {
+		env.Values[i].Value = value
+		env.Values[i].Version++
+		env.Values[i].UpdatedAt = now
+	} else {
+		id, err := t.nextID()
+		if err != nil {
+			return Value{}, false, err
+		}
+		env.Values = append(env.Values, gitValue{
+			ID:        id,
+			Key:       key,
+			Value:     value,
+			Version:   1,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		i = len(env.Values) - 1
+	}
+	env.UpdatedAt = now
+
+	if err := t.write(env, fmt.Sprintf("Set %s in %s", key, env.Name)); err != nil {
+		return Value{}, false, err
+	}
+	return env.Values[i].toValue(env.ID), !exists, nil
+}
+
+func (t *gitTx) SetValueExpiry(ctx context.Context, environmentID int64, key string, expiresAt *time.Time) (Value, error) {
+	env, err := t.environment(environmentID)
+	if err != nil {
+		return Value{}, err
+	}
+	i, ok := env.value(key)
+	if !ok {
+		return Value{}, fmt.Errorf("value %s: %w", key, ErrNotFound)
+	}
+
+	env.Values[i].ExpiresAt = expiresAt
+	if err := t.write(env, fmt.Sprintf("Set the expiration of %s in %s", key, env.Name)); err != nil {
+		return Value{}, err
+	}
+	return env.Values[i].toValue(env.ID), nil
+}
+
+func (t *gitTx) SetValueMetadata(ctx context.Context, environmentID int64, key string, m Metadata) (Value, error) {
+	env, err := t.environment(environmentID)
+	if err != nil {
+		return Value{}, err
+	}
+	i, ok := env.value(key)
+	if !ok {
+		return Value{}, fmt.Errorf("value %s: %w", key, ErrNotFound)
+	}
+
+	env.Values[i].gitMetadata = gitMetadata(m)
+	env.Values[i].UpdatedAt = time.Now().UTC()
+	if err := t.write(env, fmt.Sprintf("Set the metadata of %s in %s", key, env.Name)); err != nil {
+		return Value{}, err
+	}
+	return env.Values[i].toValue(env.ID), nil
+}
+
+// RenameValue keeps the ID of the value, so GetVersions still finds its
+// versions under the old key
+func (t *gitTx) RenameValue(ctx context.Context, environmentID int64, key, newKey string) (Value, error) {
+	env, err := t.environment(environmentID)
+	if err != nil {
+		return Value{}, err
+	}
+	i, ok := env.value(key)
+	if !ok {
+		return Value{}, fmt.Errorf("value %s: %w", key, ErrNotFound)
+	}
+	if _, ok := env.value(newKey); ok {
+		return Value{}, fmt.Errorf("value %s: %w", newKey, ErrExists)
+	}
+
+	now := time.Now().UTC()
+	env.Values[i].Key = newKey
+	env.Values[i].UpdatedAt = now
+	env.UpdatedAt = now
+	if err := t.write(env, fmt.Sprintf("Rename %s to %s in %s", key, newKey, env.Name)); err != nil {
+		return Value{}, err
+	}
+	return env.Values[i].toValue(env.ID), nil
+}
+
+func (t *gitTx) DeleteValue(ctx context.Context, environmentID int64, key string) error {
+	env, err := t.environment(environmentID)
+	if err != nil {
+		return err
+	}
+	i, ok := env.value(key)
+	if !ok {
+		return fmt.Errorf("value %s: %w", key, ErrNotFound)
+	}
+
+	env.Values = slices.Delete(env.Values, i, i+1)
+	env.UpdatedAt = time.Now().UTC()
+	return t.write(env, fmt.Sprintf("Delete %s from %s", key, env.Name))
+}
+
+// GetVersions reads the value from every commit of its environment file,
+// by ID so the versions of a renamed value are kept. A value deleted and set
+// again is a new value, with its own versions.
+func (t *gitTx) GetVersions(ctx context.Context, environmentID int64, key string) ([]Version, error) {
+	env, err := t.environment(environmentID)
+	if err != nil {
+		return nil, err
+	}
+	i, ok := env.value(key)
+	if !ok {
+		return nil, fmt.Errorf("value %s: %w", key, ErrNotFound)
+	}
+	current := env.Values[i]
+
+	// The current version may not be committed yet inside a transaction
+	versions := []Version{{Version: current.Version, Value: current.Value, CreatedAt: current.UpdatedAt}}
+
+	p := env.path()
+	out, err := t.g.git(ctx, "log", "--format=%H", "--", p)
+	if err != nil {
+		// A repository without commits has no history yet
+		return versions, nil
+	}
+	for _, commit := range strings.Fields(string(out)) {
+		data, err := t.g.git(ctx, "show", commit+":./"+p)
+		if err != nil {
+			// The commit deleted the file
+			continue
+		}
+		old, err := t.decode(p, data)
+		if err != nil {
+			return nil, err
+		}
+		j := slices.IndexFunc(old.Values, func(v gitValue) bool { return v.ID == current.ID })
+		if j < 0 {
+			continue
+		}
+		version := old.Values[j]
+		if slices.ContainsFunc(versions, func(v Version) bool { return v.Version == version.Version }) {
+			continue
+		}
+		versions = append(versions, Version{Version: version.Version, Value: version.Value, CreatedAt: version.UpdatedAt})
+	}
+
+	slices.SortFunc(versions, func(a, b Version) int { return cmp.Compare(b.Version, a.Version) })
+	return versions, nil
+}
+
+// InTx runs fn directly, the changes of t are committed together already
+func (t *gitTx) InTx(ctx context.Context, fn func(s Store) error) error {
+	return fn(t)
+}
@@ -0,0 +1,266 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rodrwan/secretly/internal/encryption"
+)
+
+// breakCommits makes the commits fail with a pre-commit hook, after the
+// files are written and added
+func (g *Git) breakCommits(t *testing.T) func() {
+	t.Helper()
+	hook := filepath.Join(g.dir, ".git", "hooks", "pre-commit")
+	if err := os.MkdirAll(filepath.Dir(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		if err := os.Remove(hook); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// gitRepository is a local repository the Git stores of a test are opened on
+type gitRepository struct {
+	dir    string
+	cipher *encryption.Cipher
+}
+
+func newGitRepository(t *testing.T) *gitRepository {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	key := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := encryption.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &gitRepository{dir: t.TempDir(), cipher: cipher}
+}
+
+func (r *gitRepository) open(t *testing.T, opts ...GitOption) *Git {
+	t.Helper()
+	g, err := NewGit(context.Background(), r.dir, r.cipher, opts...)
+	if err != nil {
+		t.Fatalf("NewGit: %v", err)
+	}
+	return g
+}
+
+// log returns the author and subject of the commits, the latest first
+func (r *gitRepository) log(t *testing.T) []string {
+	t.Helper()
+	out, err := exec.Command("git", "-C", r.dir, "log", "--format=%an <%ae>: %s").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n")
+}
+
+func TestGitCommits(t *testing.T) {
+	ctx := context.Background()
+	repo := newGitRepository(t)
+	g := repo.open(t, GitAuthor("Secretly <secretly@example.com>"))
+
+	env, err := g.CreateEnvironment(ctx, 1, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := WithAuthor(ctx, "Alice <alice@example.com>")
+	if _, _, err := g.SetValue(alice, env.ID, "API_KEY", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.RenameValue(WithAuthor(ctx, "bob"), env.ID, "API_KEY", "TOKEN"); err != nil {
+		t.Fatal(err)
+	}
+	// A transaction is a single commit
+	err = g.InTx(alice, func(s Store) error {
+		if _, _, err := s.SetValue(alice, env.ID, "A", "1"); err != nil {
+			return err
+		}
+		_, _, err := s.SetValue(alice, env.ID, "B", "2")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"Alice <alice@example.com>: Set A in staging and 1 more changes",
+		"bob <>: Rename API_KEY to TOKEN in staging",
+		"Alice <alice@example.com>: Set API_KEY in staging",
+		"Secretly <secretly@example.com>: Create environment staging",
+	}
+	if got := repo.log(t); !slices.Equal(got, want) {
+		t.Errorf("git log = %q, want %q", got, want)
+	}
+
+	// The files of the working tree are encrypted
+	err = filepath.WalkDir(repo.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(data, []byte("s3cret")) || bytes.Contains(data, []byte("TOKEN")) {
+			t.Errorf("%s isn't encrypted", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitCommitAuthor(t *testing.T) {
+	ctx := context.Background()
+	g := newGitRepository(t).open(t, GitAuthor("Secretly <secretly@example.com>"))
+
+	tests := map[string]string{
+		"":                            "Secretly <secretly@example.com>",
+		"bob":                         "bob <>",
+		"ci-bot.prod":                 "ci-bot.prod <>",
+		"Alice <alice@example.com>":   "Alice <alice@example.com>",
+		"alice@example.com":           "alice@example.com <alice@example.com>",
+		"Mallory <":                   "Secretly <secretly@example.com>",
+		"<evil>":                      "Secretly <secretly@example.com>",
+		"Mallory\n<mallory@evil.com>": "Secretly <secretly@example.com>",
+		"Mallory <a@b.c> <d@e.f>":     "Secretly <secretly@example.com>",
+		"--amend":                     "Secretly <secretly@example.com>",
+	}
+	for author, want := range tests {
+		if got := g.commitAuthor(WithAuthor(ctx, author)); got != want {
+			t.Errorf("commitAuthor(%q) = %q, want %q", author, got, want)
+		}
+	}
+
+	// Malformed authors don't fail the commit
+	env, err := g.CreateEnvironment(WithAuthor(ctx, "Mallory <"), 1, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.SetValue(WithAuthor(ctx, "Mallory\n<x@y.z>"), env.ID, "A", "1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitReopen(t *testing.T) {
+	ctx := context.Background()
+	repo := newGitRepository(t)
+	g := repo.open(t)
+
+	env, err := g.CreateEnvironment(ctx, 1, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"v1", "v2"} {
+		if _, _, err := g.SetValue(ctx, env.ID, "API_KEY", value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := g.RenameValue(ctx, env.ID, "API_KEY", "TOKEN"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := repo.open(t)
+	got, err := reopened.GetEnvironmentByName(ctx, 1, "staging")
+	if err != nil || got.ID != env.ID {
+		t.Fatalf("GetEnvironmentByName after reopening = %+v, %v", got, err)
+	}
+	versions, err := reopened.GetVersions(ctx, env.ID, "TOKEN")
+	if err != nil {
+		t.Fatalf("GetVersions after reopening: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Value != "v2" || versions[1].Value != "v1" {
+		t.Errorf("GetVersions after reopening = %+v", versions)
+	}
+
+	// IDs keep growing, they aren't reused by another store
+	other, err := reopened.CreateEnvironment(ctx, 1, "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.ID == env.ID {
+		t.Error("a reopened store reused an environment ID")
+	}
+
+	t.Run("another key", func(t *testing.T) {
+		other := newGitRepository(t)
+		other.dir = repo.dir
+		if _, err := other.open(t).GetEnvironment(ctx, env.ID); !errors.Is(err, encryption.ErrDecrypt) {
+			t.Errorf("got %v, want ErrDecrypt", err)
+		}
+	})
+}
+
+func TestGitReadOnly(t *testing.T) {
+	ctx := context.Background()
+	repo := newGitRepository(t)
+	env, err := repo.open(t).CreateEnvironment(ctx, 1, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits := repo.log(t)
+
+	g := repo.open(t, GitReadOnly(true))
+	if _, err := g.GetEnvironment(ctx, env.ID); err != nil {
+		t.Errorf("GetEnvironment: %v", err)
+	}
+
+	writes := map[string]func() error{
+		"CreateEnvironment": func() error {
+			_, err := g.CreateEnvironment(ctx, 1, "production")
+			return err
+		},
+		"SetValue": func() error {
+			_, _, err := g.SetValue(ctx, env.ID, "API_KEY", "s3cret")
+			return err
+		},
+		"SetEnvironmentMetadata": func() error {
+			_, err := g.SetEnvironmentMetadata(ctx, env.ID, Metadata{Owner: "alice"})
+			return err
+		},
+		"DeleteEnvironment": func() error {
+			return g.DeleteEnvironment(ctx, env.ID)
+		},
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: got %v, want ErrReadOnly", name, err)
+		}
+	}
+	if got := repo.log(t); !slices.Equal(got, commits) {
+		t.Errorf("a read-only store committed: %q", got)
+	}
+
+	t.Run("not a repository", func(t *testing.T) {
+		if _, err := NewGit(ctx, t.TempDir(), repo.cipher, GitReadOnly(true)); err == nil {
+			t.Error("a read-only store was opened on a directory that isn't a repository")
+		}
+	})
+}
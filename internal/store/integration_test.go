@@ -0,0 +1,22 @@
+//go:build integration
+
+package store
+
+import (
+	"testing"
+
+	"github.com/rodrwan/secretly/internal/database/dbtest"
+)
+
+// TestIntegration runs the SQL store against every database, PostgreSQL
+// needs DATABASE_URL
+func TestIntegration(t *testing.T) {
+	for _, driver := range dbtest.Drivers {
+		t.Run(driver.Name, func(t *testing.T) {
+			testStore(t, func(t *testing.T) (Store, func(t *testing.T) int64) {
+				db := driver.Open(t)
+				return NewSQL(db), sqlProjects(db)
+			})
+		})
+	}
+}
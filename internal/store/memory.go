@@ -0,0 +1,452 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ Store = (*Memory)(nil)
+
+// Memory keeps environments in memory, ie: for tests. Values have no
+// schemas and deleted environments and values are gone for good.
+type Memory struct {
+	mu    sync.Mutex
+	state *memoryState
+}
+
+func NewMemory() *Memory {
+	return &Memory{state: newMemoryState()}
+}
+
+func (m *Memory) GetEnvironment(ctx context.Context, id int64) (Environment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.GetEnvironment(ctx, id)
+}
+
+func (m *Memory) GetEnvironmentByName(ctx context.Context, projectID int64, name string) (Environment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.GetEnvironmentByName(ctx, projectID, name)
+}
+
+func (m *Memory) ListEnvironments(ctx context.Context, opts ListOptions) ([]Environment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.ListEnvironments(ctx, opts)
+}
+
+func (m *Memory) CreateEnvironment(ctx context.Context, projectID int64, name string) (Environment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.CreateEnvironment(ctx, projectID, name)
+}
+
+func (m *Memory) SetEnvironmentExpiry(ctx context.Context, id int64, expiresAt *time.Time) (Environment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.SetEnvironmentExpiry(ctx, id, expiresAt)
+}
+
+func (m *Memory) MarkEnvironmentE2E(ctx context.Context, id int64) (Environment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.MarkEnvironmentE2E(ctx, id)
+}
+
+func (m *Memory) SetEnvironmentMetadata(ctx context.Context, id int64, metadata Metadata) (Environment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.SetEnvironmentMetadata(ctx, id, metadata)
+}
+
+func (m *Memory) DeleteEnvironment(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.DeleteEnvironment(ctx, id)
+}
+
+func (m *Memory) GetExpiredEnvironments(ctx context.Context, now time.Time, limit int64) ([]Environment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.GetExpiredEnvironments(ctx, now, limit)
+}
+
+func (m *Memory) GetValues(ctx context.Context, environmentID int64) ([]Value, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.GetValues(ctx, environmentID)
+}
+
+func (m *Memory) GetValue(ctx context.Context, environmentID int64, key string) (Value, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.GetValue(ctx, environmentID, key)
+}
+
+func (m *Memory) SetValue(ctx context.Context, environmentID int64, key, value string) (Value, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.SetValue(ctx, environmentID, key, value)
+}
+
+func (m *Memory) SetValueExpiry(ctx context.Context, environmentID int64, key string, expiresAt *time.Time) (Value, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.SetValueExpiry(ctx, environmentID, key, expiresAt)
+}
+
+func (m *Memory) SetValueMetadata(ctx context.Context, environmentID int64, key string, metadata Metadata) (Value, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.SetValueMetadata(ctx, environmentID, key, metadata)
+}
+
+func (m *Memory) RenameValue(ctx context.Context, environmentID int64, key, newKey string) (Value, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.RenameValue(ctx, environmentID, key, newKey)
+}
+
+func (m *Memory) DeleteValue(ctx context.Context, environmentID int64, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.DeleteValue(ctx, environmentID, key)
+}
+
+func (m *Memory) GetVersions(ctx context.Context, environmentID int64, key string) ([]Version, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.GetVersions(ctx, environmentID, key)
+}
+
+// InTx runs fn on a copy of the store, which replaces it when fn succeeds.
+// Other calls wait until fn returns.
+func (m *Memory) InTx(ctx context.Context, fn func(s Store) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := m.state.clone()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	m.state = tx
+	return nil
+}
+
+// memoryState is the data of a Memory store, it isn't safe for concurrent use
+type memoryState struct {
+	nextID       int64
+	environments map[int64]Environment
+	// values by environment and key
+	values   map[int64]map[string]Value
+	versions map[int64][]Version
+}
+
+func newMemoryState() *memoryState {
+	return &memoryState{
+		nextID:       1,
+		environments: make(map[int64]Environment),
+		values:       make(map[int64]map[string]Value),
+		versions:     make(map[int64][]Version),
+	}
+}
+
+func (s *memoryState) clone() *memoryState {
+	c := &memoryState{
+		nextID:       s.nextID,
+		environments: maps.Clone(s.environments),
+		values:       make(map[int64]map[string]Value, len(s.values)),
+		versions:     make(map[int64][]Version, len(s.versions)),
+	}
+	for envID, values := range s.values {
+		c.values[envID] = maps.Clone(values)
+	}
+	for valueID, versions := range s.versions {
+		c.versions[valueID] = slices.Clone(versions)
+	}
+	return c
+}
+
+func (s *memoryState) id() int64 {
+	id := s.nextID
+	s.nextID++
+	return id
+}
+
+func (s *memoryState) GetEnvironment(ctx context.Context, id int64) (Environment, error) {
+	env, ok := s.environments[id]
+	if !ok {
+		return Environment{}, fmt.Errorf("environment %d: %w", id, ErrNotFound)
+	}
+	return env, nil
+}
+
+func (s *memoryState) GetEnvironmentByName(ctx context.Context, projectID int64, name string) (Environment, error) {
+	for _, env := range s.environments {
+		if env.ProjectID == projectID && env.Name == name {
+			return env, nil
+		}
+	}
+	return Environment{}, fmt.Errorf("environment %s: %w", name, ErrNotFound)
+}
+
+func (s *memoryState) ListEnvironments(ctx context.Context, opts ListOptions) ([]Environment, error) {
+	envs, err := listPage(slices.Collect(maps.Values(s.environments)), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.WithValues {
+		for i, env := range envs {
+			envs[i].Values, _ = s.GetValues(ctx, env.ID)
+			slices.SortFunc(envs[i].Values, func(a, b Value) int { return strings.Compare(a.Key, b.Key) })
+		}
+	}
+	return envs, nil
+}
+
+// listPage selects the page of opts from all the environments of a store,
+// for stores that can't do it in their queries
+func listPage(all []Environment, opts ListOptions) ([]Environment, error) {
+	var (
+		compare func(a, b Environment) int
+		after   func(env Environment) bool
+	)
+	switch opts.Sort {
+	case SortByName:
+		compare = func(a, b Environment) int { return strings.Compare(a.Name, b.Name) }
+		after = func(env Environment) bool { return env.Name > opts.AfterName }
+	case SortByNameDesc:
+		compare = func(a, b Environment) int { return strings.Compare(b.Name, a.Name) }
+		after = func(env Environment) bool { return env.Name < opts.AfterName }
+	case SortByCreation:
+		compare = func(a, b Environment) int { return cmp.Compare(a.ID, b.ID) }
+		after = func(env Environment) bool { return env.ID > opts.AfterID }
+	case SortByCreationDesc:
+		compare = func(a, b Environment) int { return cmp.Compare(b.ID, a.ID) }
+		after = func(env Environment) bool { return env.ID < opts.AfterID }
+	default:
+		return nil, fmt.Errorf("unknown sort %q", opts.Sort)
+	}
+
+	envs := make([]Environment, 0)
+	for _, env := range all {
+		if env.ProjectID != opts.ProjectID || !strings.HasPrefix(env.Name, opts.NamePrefix) {
+			continue
+		}
+		if opts.AfterID != 0 && !after(env) {
+			continue
+		}
+		envs = append(envs, env)
+	}
+	slices.SortFunc(envs, compare)
+	if opts.Limit > 0 && int64(len(envs)) > opts.Limit {
+		envs = envs[:opts.Limit]
+	}
+	return envs, nil
+}
+
+func (s *memoryState) CreateEnvironment(ctx context.Context, projectID int64, name string) (Environment, error) {
+	if _, err := s.GetEnvironmentByName(ctx, projectID, name); err == nil {
+		return Environment{}, fmt.Errorf("environment %s: %w", name, ErrExists)
+	}
+
+	now := time.Now().UTC()
+	env := Environment{
+		ID:        s.id(),
+		ProjectID: projectID,
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.environments[env.ID] = env
+	return env, nil
+}
+
+func (s *memoryState) SetEnvironmentExpiry(ctx context.Context, id int64, expiresAt *time.Time) (Environment, error) {
+	env, err := s.GetEnvironment(ctx, id)
+	if err != nil {
+		return Environment{}, err
+	}
+	env.ExpiresAt = expiresAt
+	env.UpdatedAt = time.Now().UTC()
+	s.environments[id] = env
+	return env, nil
+}
+
+func (s *memoryState) MarkEnvironmentE2E(ctx context.Context, id int64) (Environment, error) {
+	env, err := s.GetEnvironment(ctx, id)
+	if err != nil {
+		return Environment{}, err
+	}
+	env.E2E = true
+	env.UpdatedAt = time.Now().UTC()
+	s.environments[id] = env
+	return env, nil
+}
+
+func (s *memoryState) SetEnvironmentMetadata(ctx context.Context, id int64, metadata Metadata) (Environment, error) {
+	env, err := s.GetEnvironment(ctx, id)
+	if err != nil {
+		return Environment{}, err
+	}
+	env.Metadata = metadata
+	env.Metadata.Tags = slices.Clone(metadata.Tags)
+	env.UpdatedAt = time.Now().UTC()
+	s.environments[id] = env
+	return env, nil
+}
+
+func (s *memoryState) DeleteEnvironment(ctx context.Context, id int64) error {
+	if _, err := s.GetEnvironment(ctx, id); err != nil {
+		return err
+	}
+	for _, value := range s.values[id] {
+		delete(s.versions, value.ID)
+	}
+	delete(s.values, id)
+	delete(s.environments, id)
+	return nil
+}
+
+func (s *memoryState) GetExpiredEnvironments(ctx context.Context, now time.Time, limit int64) ([]Environment, error) {
+	return expired(slices.Collect(maps.Values(s.environments)), now, limit), nil
+}
+
+// expired selects the environments expired at now from all the environments
+// of a store, the earliest expired first
+func expired(all []Environment, now time.Time, limit int64) []Environment {
+	envs := slices.DeleteFunc(all, func(env Environment) bool {
+		return env.ExpiresAt == nil || env.ExpiresAt.After(now)
+	})
+	slices.SortFunc(envs, func(a, b Environment) int {
+		return cmp.Or(a.ExpiresAt.Compare(*b.ExpiresAt), cmp.Compare(a.ID, b.ID))
+	})
+	if limit > 0 && int64(len(envs)) > limit {
+		envs = envs[:limit]
+	}
+	return envs
+}
+
+func (s *memoryState) GetValues(ctx context.Context, environmentID int64) ([]Value, error) {
+	values := slices.Collect(maps.Values(s.values[environmentID]))
+	slices.SortFunc(values, func(a, b Value) int { return cmp.Compare(a.ID, b.ID) })
+	if values == nil {
+		values = make([]Value, 0)
+	}
+	return values, nil
+}
+
+func (s *memoryState) GetValue(ctx context.Context, environmentID int64, key string) (Value, error) {
+	value, ok := s.values[environmentID][key]
+	if !ok {
+		return Value{}, fmt.Errorf("value %s: %w", key, ErrNotFound)
+	}
+	return value, nil
+}
+
+func (s *memoryState) SetValue(ctx context.Context, environmentID int64, key, value string) (Value, bool, error) {
+	if _, err := s.GetEnvironment(ctx, environmentID); err != nil {
+		return Value{}, false, err
+	}
+
+	now := time.Now().UTC()
+	stored, ok := s.values[environmentID][key]
+	if ok {
+		stored.Value = value
+		stored.Version++
+		stored.UpdatedAt = now
+	} else {
+		stored = Value{
+			ID:            s.id(),
+			EnvironmentID: environmentID,
+			Key:           key,
+			Value:         value,
+			Version:       1,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+	}
+
+	if s.values[environmentID] == nil {
+		s.values[environmentID] = make(map[string]Value)
+	}
+	s.values[environmentID][key] = stored
+	s.versions[stored.ID] = append(s.versions[stored.ID], Version{
+		Version:   stored.Version,
+		Value:     stored.Value,
+		CreatedAt: now,
+	})
+	return stored, !ok, nil
+}
+
+func (s *memoryState) SetValueExpiry(ctx context.Context, environmentID int64, key string, expiresAt *time.Time) (Value, error) {
+	value, err := s.GetValue(ctx, environmentID, key)
+	if err != nil {
+		return Value{}, err
+	}
+	value.ExpiresAt = expiresAt
+	s.values[environmentID][key] = value
+	return value, nil
+}
+
+func (s *memoryState) SetValueMetadata(ctx context.Context, environmentID int64, key string, metadata Metadata) (Value, error) {
+	value, err := s.GetValue(ctx, environmentID, key)
+	if err != nil {
+		return Value{}, err
+	}
+	value.Metadata = metadata
+	value.Metadata.Tags = slices.Clone(metadata.Tags)
+	value.UpdatedAt = time.Now().UTC()
+	s.values[environmentID][key] = value
+	return value, nil
+}
+
+// RenameValue keeps the ID of the value, its versions are kept by ID
+func (s *memoryState) RenameValue(ctx context.Context, environmentID int64, key, newKey string) (Value, error) {
+	value, err := s.GetValue(ctx, environmentID, key)
+	if err != nil {
+		return Value{}, err
+	}
+	if _, err := s.GetValue(ctx, environmentID, newKey); err == nil {
+		return Value{}, fmt.Errorf("value %s: %w", newKey, ErrExists)
+	}
+
+	value.Key = newKey
+	value.UpdatedAt = time.Now().UTC()
+	delete(s.values[environmentID], key)
+	s.values[environmentID][newKey] = value
+	return value, nil
+}
+
+func (s *memoryState) DeleteValue(ctx context.Context, environmentID int64, key string) error {
+	value, err := s.GetValue(ctx, environmentID, key)
+	if err != nil {
+		return err
+	}
+	delete(s.versions, value.ID)
+	delete(s.values[environmentID], key)
+	return nil
+}
+
+func (s *memoryState) GetVersions(ctx context.Context, environmentID int64, key string) ([]Version, error) {
+	value, err := s.GetValue(ctx, environmentID, key)
+	if err != nil {
+		return nil, err
+	}
+	versions := slices.Clone(s.versions[value.ID])
+	slices.Reverse(versions)
+	return versions, nil
+}
+
+// InTx runs fn directly, the state is already a copy inside a transaction
+func (s *memoryState) InTx(ctx context.Context, fn func(s Store) error) error {
+	return fn(s)
+}
@@ -0,0 +1,411 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+)
+
+var _ Store = (*SQL)(nil)
+
+// SQL stores environments in a database through its generated queries, so
+// it works with every driver of database.DB. Deleted environments and values
+// go to the trash, like the rest of the server expects.
+type SQL struct {
+	q database.Querier
+	// db starts transactions, it is nil inside one or when the queries
+	// can't start them
+	db *database.DB
+}
+
+// NewSQL stores environments in db
+func NewSQL(db *database.DB) *SQL {
+	return &SQL{q: db.Querier(), db: db}
+}
+
+// NewSQLQuerier stores environments through q, without transactions of its
+// own. InTx runs directly against q.
+func NewSQLQuerier(q database.Querier) *SQL {
+	return &SQL{q: q}
+}
+
+// Querier returns the queries of the store, bound to its transaction inside
+// InTx, so the features built on the SQL schema write in the same one
+func (s *SQL) Querier() database.Querier {
+	return s.q
+}
+
+// notFound keeps sql.ErrNoRows in the chain for callers that still check it
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	return err
+}
+
+func (s *SQL) GetEnvironment(ctx context.Context, id int64) (Environment, error) {
+	env, err := s.q.GetEnvironment(ctx, id)
+	if err != nil {
+		return Environment{}, notFound(err)
+	}
+	return fromDBEnvironment(env), nil
+}
+
+func (s *SQL) GetEnvironmentByName(ctx context.Context, projectID int64, name string) (Environment, error) {
+	env, err := s.q.GetEnvironmentByName(ctx, database.GetEnvironmentByNameParams{
+		ProjectID: projectID,
+		Name:      name,
+	})
+	if err != nil {
+		return Environment{}, notFound(err)
+	}
+	return fromDBEnvironment(env), nil
+}
+
+// ListEnvironments gets the page with database.ListEnvironments, a single
+// query whatever the number of environments
+func (s *SQL) ListEnvironments(ctx context.Context, opts ListOptions) ([]Environment, error) {
+	rows, err := database.ListEnvironments(ctx, s.q, database.ListEnvironmentsParams(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows of the same environment are consecutive, one per value
+	envs := make([]Environment, 0)
+	for i := 0; i < len(rows); {
+		row := rows[i]
+		env := Environment{
+			ID:        row.ID,
+			ProjectID: row.ProjectID,
+			Name:      row.Name,
+			ExpiresAt: nullTime(row.ExpiresAt),
+			E2E:       row.E2e,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Metadata:  metadata(row.Description, row.Owner, row.Link, row.Tags),
+		}
+		for ; i < len(rows) && rows[i].ID == env.ID; i++ {
+			row := rows[i]
+			if !row.ValueID.Valid {
+				continue
+			}
+			env.Values = append(env.Values, Value{
+				ID:            row.ValueID.Int64,
+				EnvironmentID: env.ID,
+				Key:           row.ValueKey.String,
+				Value:         row.Value.String,
+				ExpiresAt:     nullTime(row.ValueExpiresAt),
+				Type:          row.ValueType.String,
+				Metadata:      metadata(row.ValueDescription.String, row.ValueOwner.String, row.ValueLink.String, row.ValueTags.String),
+			})
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+func (s *SQL) CreateEnvironment(ctx context.Context, projectID int64, name string) (Environment, error) {
+	env, err := s.q.CreateEnvironment(ctx, database.CreateEnvironmentParams{
+		ProjectID: projectID,
+		Name:      name,
+	})
+	if database.IsUniqueViolation(err) {
+		return Environment{}, fmt.Errorf("environment %s: %w", name, ErrExists)
+	}
+	if err != nil {
+		return Environment{}, err
+	}
+	return fromDBEnvironment(env), nil
+}
+
+func (s *SQL) SetEnvironmentExpiry(ctx context.Context, id int64, expiresAt *time.Time) (Environment, error) {
+	env, err := s.q.SetEnvironmentExpiry(ctx, database.SetEnvironmentExpiryParams{
+		ID:        id,
+		ExpiresAt: toNullTime(expiresAt),
+	})
+	if err != nil {
+		return Environment{}, notFound(err)
+	}
+	return fromDBEnvironment(env), nil
+}
+
+func (s *SQL) MarkEnvironmentE2E(ctx context.Context, id int64) (Environment, error) {
+	env, err := s.q.MarkEnvironmentE2E(ctx, id)
+	if err != nil {
+		return Environment{}, notFound(err)
+	}
+	return fromDBEnvironment(env), nil
+}
+
+func (s *SQL) SetEnvironmentMetadata(ctx context.Context, id int64, m Metadata) (Environment, error) {
+	if _, err := s.q.GetEnvironment(ctx, id); err != nil {
+		return Environment{}, notFound(err)
+	}
+	tags, err := encodeTags(m.Tags)
+	if err != nil {
+		return Environment{}, err
+	}
+	env, err := s.q.SetEnvironmentMetadata(ctx, database.SetEnvironmentMetadataParams{
+		ID:          id,
+		Description: m.Description,
+		Owner:       m.Owner,
+		Link:        m.Link,
+		Tags:        tags,
+	})
+	if err != nil {
+		return Environment{}, err
+	}
+	return fromDBEnvironment(env), nil
+}
+
+// DeleteEnvironment moves the environment to the trash, its values are
+// restored with it
+func (s *SQL) DeleteEnvironment(ctx context.Context, id int64) error {
+	if _, err := s.q.GetEnvironment(ctx, id); err != nil {
+		return notFound(err)
+	}
+	return s.q.SoftDeleteEnvironment(ctx, database.SoftDeleteEnvironmentParams{
+		ID:        id,
+		DeletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+}
+
+func (s *SQL) GetExpiredEnvironments(ctx context.Context, now time.Time, limit int64) ([]Environment, error) {
+	envsFromDB, err := s.q.GetExpiredEnvironments(ctx, database.GetExpiredEnvironmentsParams{
+		ExpiresAt: sql.NullTime{Time: now.UTC(), Valid: true},
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	envs := make([]Environment, 0, len(envsFromDB))
+	for _, env := range envsFromDB {
+		envs = append(envs, fromDBEnvironment(env))
+	}
+	return envs, nil
+}
+
+// GetValues gets the values of an environment with the type of their schemas
+func (s *SQL) GetValues(ctx context.Context, environmentID int64) ([]Value, error) {
+	valuesFromDB, err := s.q.GetValuesByEnvironmentID(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, err := s.q.GetValueSchemasByEnvironmentID(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string, len(schemas))
+	for _, schema := range schemas {
+		types[schema.Key] = schema.Type
+	}
+
+	values := make([]Value, 0, len(valuesFromDB))
+	for _, value := range valuesFromDB {
+		v := fromDBValue(value)
+		v.Type = types[v.Key]
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (s *SQL) GetValue(ctx context.Context, environmentID int64, key string) (Value, error) {
+	value, err := s.q.GetValueByKey(ctx, database.GetValueByKeyParams{
+		EnvironmentID: environmentID,
+		Key:           key,
+	})
+	if err != nil {
+		return Value{}, notFound(err)
+	}
+	return fromDBValue(value), nil
+}
+
+// SetValue validates the value against the schema of its key, see
+// database.SetValue
+func (s *SQL) SetValue(ctx context.Context, environmentID int64, key, value string) (Value, bool, error) {
+	stored, created, err := database.SetValue(ctx, s.q, environmentID, key, value)
+	if err != nil {
+		return Value{}, false, err
+	}
+	return fromDBValue(stored), created, nil
+}
+
+func (s *SQL) SetValueExpiry(ctx context.Context, environmentID int64, key string, expiresAt *time.Time) (Value, error) {
+	value, err := s.GetValue(ctx, environmentID, key)
+	if err != nil {
+		return Value{}, err
+	}
+
+	stored, err := s.q.SetValueExpiry(ctx, database.SetValueExpiryParams{
+		ID:        value.ID,
+		ExpiresAt: toNullTime(expiresAt),
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	return fromDBValue(stored), nil
+}
+
+func (s *SQL) SetValueMetadata(ctx context.Context, environmentID int64, key string, m Metadata) (Value, error) {
+	value, err := s.GetValue(ctx, environmentID, key)
+	if err != nil {
+		return Value{}, err
+	}
+	tags, err := encodeTags(m.Tags)
+	if err != nil {
+		return Value{}, err
+	}
+	stored, err := s.q.SetValueMetadata(ctx, database.SetValueMetadataParams{
+		ID:          value.ID,
+		Description: m.Description,
+		Owner:       m.Owner,
+		Link:        m.Link,
+		Tags:        tags,
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	return fromDBValue(stored), nil
+}
+
+// RenameValue keeps the ID of the value, so its versions and rotation
+// policy follow it
+func (s *SQL) RenameValue(ctx context.Context, environmentID int64, key, newKey string) (Value, error) {
+	value, err := s.GetValue(ctx, environmentID, key)
+	if err != nil {
+		return Value{}, err
+	}
+	_, err = s.GetValue(ctx, environmentID, newKey)
+	switch {
+	case err == nil:
+		return Value{}, fmt.Errorf("value %s: %w", newKey, ErrExists)
+	case !errors.Is(err, ErrNotFound):
+		return Value{}, err
+	}
+
+	renamed, err := s.q.RenameValue(ctx, database.RenameValueParams{
+		ID:  value.ID,
+		Key: newKey,
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	return fromDBValue(renamed), nil
+}
+
+// DeleteValue moves the value to the trash and removes its rotation policy,
+// so it isn't rotated while deleted
+func (s *SQL) DeleteValue(ctx context.Context, environmentID int64, key string) error {
+	value, err := s.GetValue(ctx, environmentID, key)
+	if err != nil {
+		return err
+	}
+	if err := s.q.DeleteRotationPolicyByValueID(ctx, value.ID); err != nil {
+		return err
+	}
+	return s.q.SoftDeleteValue(ctx, database.SoftDeleteValueParams{
+		ID:        value.ID,
+		DeletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+}
+
+func (s *SQL) GetVersions(ctx context.Context, environmentID int64, key string) ([]Version, error) {
+	value, err := s.GetValue(ctx, environmentID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	versionsFromDB, err := s.q.GetValueVersions(ctx, value.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]Version, 0, len(versionsFromDB))
+	for _, version := range versionsFromDB {
+		versions = append(versions, Version{
+			Version:   version.Version,
+			Value:     version.Value,
+			CreatedAt: version.CreatedAt,
+		})
+	}
+	return versions, nil
+}
+
+func (s *SQL) InTx(ctx context.Context, fn func(s Store) error) error {
+	if s.db == nil {
+		return fn(s)
+	}
+	return database.InTx(ctx, s.db, func(q database.Querier) error {
+		return fn(&SQL{q: q})
+	})
+}
+
+func fromDBEnvironment(env database.Environment) Environment {
+	return Environment{
+		ID:        env.ID,
+		ProjectID: env.ProjectID,
+		Name:      env.Name,
+		ExpiresAt: nullTime(env.ExpiresAt),
+		E2E:       env.E2e,
+		CreatedAt: env.CreatedAt,
+		UpdatedAt: env.UpdatedAt,
+		Metadata:  metadata(env.Description, env.Owner, env.Link, env.Tags),
+	}
+}
+
+func fromDBValue(value database.EnvironmentValue) Value {
+	return Value{
+		ID:            value.ID,
+		EnvironmentID: value.EnvironmentID,
+		Key:           value.Key,
+		Value:         value.Value,
+		Version:       value.Version,
+		ExpiresAt:     nullTime(value.ExpiresAt),
+		CreatedAt:     value.CreatedAt,
+		UpdatedAt:     value.UpdatedAt,
+		Metadata:      metadata(value.Description, value.Owner, value.Link, value.Tags),
+	}
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// encodeTags encodes tags as they are stored, a JSON array
+func encodeTags(tags []string) (string, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	b, err := json.Marshal(tags)
+	return string(b), err
+}
+
+// metadata decodes the tags, stored as a JSON array
+func metadata(description, owner, link, tags string) Metadata {
+	m := Metadata{
+		Description: description,
+		Owner:       owner,
+		Link:        link,
+	}
+	if err := json.Unmarshal([]byte(tags), &m.Tags); err != nil || len(m.Tags) == 0 {
+		m.Tags = nil
+	}
+	return m
+}
@@ -0,0 +1,148 @@
+// Package store keeps environments, their values and the versions of each
+// value behind a Store, so handlers don't depend on how they are persisted.
+//
+// Features built on the SQL schema, ie: projects, value schemas, the trash,
+// webhooks and rotation, still use database.Querier directly.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when an environment or a value doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrExists is returned when an environment is created with the name of
+	// another one of its project
+	ErrExists = errors.New("already exists")
+	// ErrReadOnly is returned by the writes of a read-only store
+	ErrReadOnly = errors.New("the store is read-only")
+)
+
+// Orders of ListOptions
+const (
+	SortByName         = "name"
+	SortByNameDesc     = "-name"
+	SortByCreation     = "created_at"
+	SortByCreationDesc = "-created_at"
+)
+
+type Environment struct {
+	ID        int64
+	ProjectID int64
+	Name      string
+	// ExpiresAt is set for ephemeral environments
+	ExpiresAt *time.Time
+	// E2E is set when the values are encrypted by the clients, the store
+	// only has their ciphertext
+	E2E       bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Metadata
+	// Values is only set by ListEnvironments with WithValues
+	Values []Value
+}
+
+type Value struct {
+	ID            int64
+	EnvironmentID int64
+	Key           string
+	Value         string
+	// Version increases on every change of the value
+	Version   int64
+	ExpiresAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Type is the type of the key's schema, empty when it has none or the
+	// store doesn't support schemas
+	Type string
+	Metadata
+}
+
+// Version is a past or current value of a key
+type Version struct {
+	Version   int64
+	Value     string
+	CreatedAt time.Time
+}
+
+type Metadata struct {
+	Description string
+	Owner       string
+	Link        string
+	Tags        []string
+}
+
+// ListOptions selects a page of the environments of a project
+type ListOptions struct {
+	ProjectID int64
+	// NamePrefix keeps the environments whose name starts with it
+	NamePrefix string
+	// Sort is one of the SortBy orders
+	Sort string
+	// AfterName and AfterID identify the last environment of the previous
+	// page, AfterID is 0 for the first page
+	AfterName string
+	AfterID   int64
+	// Limit is the size of the page, 0 gets every environment
+	Limit int64
+	// WithValues also gets the values of each environment
+	WithValues bool
+}
+
+// Store persists environments, values and their versions
+type Store interface {
+	GetEnvironment(ctx context.Context, id int64) (Environment, error)
+	GetEnvironmentByName(ctx context.Context, projectID int64, name string) (Environment, error)
+	ListEnvironments(ctx context.Context, opts ListOptions) ([]Environment, error)
+	CreateEnvironment(ctx context.Context, projectID int64, name string) (Environment, error)
+	// SetEnvironmentExpiry makes an environment ephemeral, nil never expires
+	SetEnvironmentExpiry(ctx context.Context, id int64, expiresAt *time.Time) (Environment, error)
+	// MarkEnvironmentE2E marks the values of an environment as encrypted by
+	// the clients, it can't be undone
+	MarkEnvironmentE2E(ctx context.Context, id int64) (Environment, error)
+	// SetEnvironmentMetadata replaces the metadata of an environment
+	SetEnvironmentMetadata(ctx context.Context, id int64, m Metadata) (Environment, error)
+	// DeleteEnvironment deletes an environment with its values
+	DeleteEnvironment(ctx context.Context, id int64) error
+	// GetExpiredEnvironments gets up to limit ephemeral environments that
+	// expired at now, the earliest expired first
+	GetExpiredEnvironments(ctx context.Context, now time.Time, limit int64) ([]Environment, error)
+
+	GetValues(ctx context.Context, environmentID int64) ([]Value, error)
+	GetValue(ctx context.Context, environmentID int64, key string) (Value, error)
+	// SetValue creates or updates the value of a key and records the
+	// resulting version. It reports whether the key was created.
+	SetValue(ctx context.Context, environmentID int64, key, value string) (Value, bool, error)
+	// SetValueExpiry sets when a value expires, nil never expires
+	SetValueExpiry(ctx context.Context, environmentID int64, key string, expiresAt *time.Time) (Value, error)
+	// SetValueMetadata replaces the metadata of a value
+	SetValueMetadata(ctx context.Context, environmentID int64, key string, m Metadata) (Value, error)
+	// RenameValue moves the value of key to newKey, with its versions. It
+	// returns ErrExists when newKey is already set.
+	RenameValue(ctx context.Context, environmentID int64, key, newKey string) (Value, error)
+	DeleteValue(ctx context.Context, environmentID int64, key string) error
+
+	// GetVersions gets the versions of a key, the latest first
+	GetVersions(ctx context.Context, environmentID int64, key string) ([]Version, error)
+
+	// InTx runs fn with a store whose changes are applied when fn succeeds
+	// and discarded otherwise
+	InTx(ctx context.Context, fn func(s Store) error) error
+}
+
+type authorKey struct{}
+
+// WithAuthor attributes the changes made with ctx to author, stores that keep
+// a history, like Git, record it
+func WithAuthor(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, authorKey{}, author)
+}
+
+// Author returns who makes the changes of ctx, empty when unknown
+func Author(ctx context.Context) string {
+	author, _ := ctx.Value(authorKey{}).(string)
+	return author
+}
@@ -0,0 +1,632 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+	"github.com/rodrwan/secretly/internal/database/dbtest"
+	"github.com/rodrwan/secretly/internal/encryption"
+)
+
+// newStoreFunc creates an empty store, and a function creating the projects
+// its environments belong to
+type newStoreFunc func(t *testing.T) (Store, func(t *testing.T) int64)
+
+func TestMemory(t *testing.T) {
+	testStore(t, func(t *testing.T) (Store, func(t *testing.T) int64) {
+		return NewMemory(), counter()
+	})
+}
+
+func TestSQL(t *testing.T) {
+	testStore(t, func(t *testing.T) (Store, func(t *testing.T) int64) {
+		db := dbtest.SQLite(t)
+		return NewSQL(db), sqlProjects(db)
+	})
+}
+
+func TestGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	testStore(t, func(t *testing.T) (Store, func(t *testing.T) int64) {
+		return newGit(t), counter()
+	})
+}
+
+// counter creates projects for stores that don't keep them
+func counter() func(t *testing.T) int64 {
+	var id int64
+	return func(t *testing.T) int64 {
+		id++
+		return id
+	}
+}
+
+// sqlProjects creates projects in db
+func sqlProjects(db *database.DB) func(t *testing.T) int64 {
+	var n int
+	return func(t *testing.T) int64 {
+		t.Helper()
+		n++
+		project, err := db.Querier().CreateProject(context.Background(), fmt.Sprintf("project-%d", n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return project.ID
+	}
+}
+
+func newGit(t *testing.T, opts ...GitOption) *Git {
+	t.Helper()
+	key := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := encryption.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGit(context.Background(), t.TempDir(), cipher, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// testStore runs the behaviors every Store must have against the stores of
+// newStore
+func testStore(t *testing.T, newStore newStoreFunc) {
+	t.Run("environments", func(t *testing.T) { testEnvironments(t, newStore) })
+	t.Run("list", func(t *testing.T) { testListEnvironments(t, newStore) })
+	t.Run("values", func(t *testing.T) { testValues(t, newStore) })
+	t.Run("rename", func(t *testing.T) { testRenameValue(t, newStore) })
+	t.Run("metadata", func(t *testing.T) { testMetadata(t, newStore) })
+	t.Run("expiry", func(t *testing.T) { testExpiry(t, newStore) })
+	t.Run("expired", func(t *testing.T) { testExpiredEnvironments(t, newStore) })
+	t.Run("transactions", func(t *testing.T) { testTransactions(t, newStore) })
+	t.Run("failed commits", func(t *testing.T) { testFailedCommits(t, newStore) })
+}
+
+func mustCreate(t *testing.T, s Store, projectID int64, name string) Environment {
+	t.Helper()
+	env, err := s.CreateEnvironment(context.Background(), projectID, name)
+	if err != nil {
+		t.Fatalf("CreateEnvironment(%s): %v", name, err)
+	}
+	return env
+}
+
+func mustSet(t *testing.T, s Store, environmentID int64, key, value string) Value {
+	t.Helper()
+	stored, _, err := s.SetValue(context.Background(), environmentID, key, value)
+	if err != nil {
+		t.Fatalf("SetValue(%s): %v", key, err)
+	}
+	return stored
+}
+
+func testEnvironments(t *testing.T, newStore newStoreFunc) {
+	ctx := context.Background()
+	s, newProject := newStore(t)
+	projectID, otherID := newProject(t), newProject(t)
+
+	env := mustCreate(t, s, projectID, "staging")
+	if env.ID == 0 || env.ProjectID != projectID || env.Name != "staging" {
+		t.Errorf("CreateEnvironment = %+v", env)
+	}
+	if env.ExpiresAt != nil || env.E2E {
+		t.Errorf("a new environment is ephemeral or E2E: %+v", env)
+	}
+
+	got, err := s.GetEnvironment(ctx, env.ID)
+	if err != nil || got.ID != env.ID || got.Name != "staging" {
+		t.Errorf("GetEnvironment = %+v, %v", got, err)
+	}
+	got, err = s.GetEnvironmentByName(ctx, projectID, "staging")
+	if err != nil || got.ID != env.ID {
+		t.Errorf("GetEnvironmentByName = %+v, %v", got, err)
+	}
+
+	if _, err := s.CreateEnvironment(ctx, projectID, "staging"); !errors.Is(err, ErrExists) {
+		t.Errorf("creating a duplicate: got %v, want ErrExists", err)
+	}
+	other := mustCreate(t, s, otherID, "staging")
+	if other.ID == env.ID {
+		t.Error("environments of two projects have the same ID")
+	}
+
+	if _, err := s.GetEnvironment(ctx, env.ID+other.ID+100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetEnvironment of a missing environment: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetEnvironmentByName(ctx, projectID, "production"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetEnvironmentByName of a missing environment: got %v, want ErrNotFound", err)
+	}
+
+	e2e, err := s.MarkEnvironmentE2E(ctx, env.ID)
+	if err != nil || !e2e.E2E {
+		t.Errorf("MarkEnvironmentE2E = %+v, %v", e2e, err)
+	}
+	if got, _ := s.GetEnvironment(ctx, env.ID); !got.E2E {
+		t.Error("the environment isn't E2E after MarkEnvironmentE2E")
+	}
+
+	if err := s.DeleteEnvironment(ctx, env.ID); err != nil {
+		t.Fatalf("DeleteEnvironment: %v", err)
+	}
+	if _, err := s.GetEnvironment(ctx, env.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetEnvironment of a deleted environment: got %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteEnvironment(ctx, env.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting twice: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetEnvironment(ctx, other.ID); err != nil {
+		t.Errorf("deleting an environment deleted another one: %v", err)
+	}
+
+	// The name of a deleted environment is free again
+	recreated := mustCreate(t, s, projectID, "staging")
+	if recreated.ID == env.ID {
+		t.Error("a recreated environment has the ID of the deleted one")
+	}
+}
+
+func names(envs []Environment) []string {
+	names := make([]string, 0, len(envs))
+	for _, env := range envs {
+		names = append(names, env.Name)
+	}
+	return names
+}
+
+func testListEnvironments(t *testing.T, newStore newStoreFunc) {
+	ctx := context.Background()
+	s, newProject := newStore(t)
+	projectID, otherID := newProject(t), newProject(t)
+
+	// Created out of order, so the orders by name and by creation differ
+	for _, name := range []string{"web", "api", "worker", "app"} {
+		mustCreate(t, s, projectID, name)
+	}
+	mustCreate(t, s, otherID, "admin")
+
+	list := func(opts ListOptions) []Environment {
+		t.Helper()
+		opts.ProjectID = projectID
+		envs, err := s.ListEnvironments(ctx, opts)
+		if err != nil {
+			t.Fatalf("ListEnvironments(%+v): %v", opts, err)
+		}
+		return envs
+	}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"by name", ListOptions{Sort: SortByName}, []string{"api", "app", "web", "worker"}},
+		{"by name desc", ListOptions{Sort: SortByNameDesc}, []string{"worker", "web", "app", "api"}},
+		{"by creation", ListOptions{Sort: SortByCreation}, []string{"web", "api", "worker", "app"}},
+		{"by creation desc", ListOptions{Sort: SortByCreationDesc}, []string{"app", "worker", "api", "web"}},
+		{"prefix", ListOptions{Sort: SortByName, NamePrefix: "w"}, []string{"web", "worker"}},
+		{"limit", ListOptions{Sort: SortByName, Limit: 2}, []string{"api", "app"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(list(tt.opts)); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		for _, sort := range []string{SortByName, SortByNameDesc, SortByCreation, SortByCreationDesc} {
+			var got []string
+			opts := ListOptions{Sort: sort, Limit: 3}
+			for {
+				page := list(opts)
+				got = append(got, names(page)...)
+				if len(page) < int(opts.Limit) {
+					break
+				}
+				last := page[len(page)-1]
+				opts.AfterName, opts.AfterID = last.Name, last.ID
+			}
+			if want := names(list(ListOptions{Sort: sort})); !slices.Equal(got, want) {
+				t.Errorf("%s: pages %v, want %v", sort, got, want)
+			}
+		}
+	})
+
+	t.Run("with values", func(t *testing.T) {
+		api, err := s.GetEnvironmentByName(ctx, projectID, "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustSet(t, s, api.ID, "PORT", "8080")
+		mustSet(t, s, api.ID, "HOST", "localhost")
+
+		envs := list(ListOptions{Sort: SortByName, WithValues: true})
+		if len(envs) != 4 {
+			t.Fatalf("got %d environments, want 4", len(envs))
+		}
+		var keys []string
+		for _, value := range envs[0].Values {
+			keys = append(keys, value.Key+"="+value.Value)
+		}
+		if want := []string{"HOST=localhost", "PORT=8080"}; !slices.Equal(keys, want) {
+			t.Errorf("values of api = %v, want %v", keys, want)
+		}
+		if len(envs[1].Values) != 0 {
+			t.Errorf("app has values %+v", envs[1].Values)
+		}
+
+		if envs := list(ListOptions{Sort: SortByName}); len(envs[0].Values) != 0 {
+			t.Error("got values without WithValues")
+		}
+	})
+
+	t.Run("unknown sort", func(t *testing.T) {
+		if _, err := s.ListEnvironments(ctx, ListOptions{ProjectID: projectID, Sort: "size"}); err == nil {
+			t.Error("an unknown sort was accepted")
+		}
+	})
+}
+
+func testValues(t *testing.T, newStore newStoreFunc) {
+	ctx := context.Background()
+	s, newProject := newStore(t)
+	env := mustCreate(t, s, newProject(t), "staging")
+
+	values, err := s.GetValues(ctx, env.ID)
+	if err != nil || values == nil || len(values) != 0 {
+		t.Errorf("GetValues of a new environment = %#v, %v", values, err)
+	}
+
+	created, ok, err := s.SetValue(ctx, env.ID, "API_KEY", "v1")
+	if err != nil || !ok {
+		t.Fatalf("SetValue = %v, %v", ok, err)
+	}
+	if created.EnvironmentID != env.ID || created.Key != "API_KEY" || created.Value != "v1" || created.Version != 1 {
+		t.Errorf("SetValue = %+v", created)
+	}
+
+	updated, ok, err := s.SetValue(ctx, env.ID, "API_KEY", "v2")
+	if err != nil || ok {
+		t.Fatalf("SetValue of an existing key = %v, %v", ok, err)
+	}
+	if updated.ID != created.ID || updated.Value != "v2" || updated.Version != 2 {
+		t.Errorf("SetValue of an existing key = %+v", updated)
+	}
+
+	got, err := s.GetValue(ctx, env.ID, "API_KEY")
+	if err != nil || got.Value != "v2" || got.Version != 2 {
+		t.Errorf("GetValue = %+v, %v", got, err)
+	}
+	if _, err := s.GetValue(ctx, env.ID, "MISSING"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetValue of a missing key: got %v, want ErrNotFound", err)
+	}
+
+	mustSet(t, s, env.ID, "DEBUG", "true")
+	values, err = s.GetValues(ctx, env.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, value := range values {
+		keys = append(keys, value.Key+"="+value.Value)
+	}
+	slices.Sort(keys)
+	if want := []string{"API_KEY=v2", "DEBUG=true"}; !slices.Equal(keys, want) {
+		t.Errorf("GetValues = %v, want %v", keys, want)
+	}
+
+	mustSet(t, s, env.ID, "API_KEY", "v3")
+	versions, err := s.GetVersions(ctx, env.ID, "API_KEY")
+	if err != nil {
+		t.Fatalf("GetVersions: %v", err)
+	}
+	var history []string
+	for _, version := range versions {
+		history = append(history, fmt.Sprintf("%d=%s", version.Version, version.Value))
+	}
+	if want := []string{"3=v3", "2=v2", "1=v1"}; !slices.Equal(history, want) {
+		t.Errorf("GetVersions = %v, want %v", history, want)
+	}
+	if _, err := s.GetVersions(ctx, env.ID, "MISSING"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetVersions of a missing key: got %v, want ErrNotFound", err)
+	}
+
+	if err := s.DeleteValue(ctx, env.ID, "API_KEY"); err != nil {
+		t.Fatalf("DeleteValue: %v", err)
+	}
+	if _, err := s.GetValue(ctx, env.ID, "API_KEY"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetValue of a deleted key: got %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteValue(ctx, env.ID, "API_KEY"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting twice: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetValue(ctx, env.ID, "DEBUG"); err != nil {
+		t.Errorf("deleting a value deleted another one: %v", err)
+	}
+
+	// A key set again after its deletion starts a new history
+	again, ok, err := s.SetValue(ctx, env.ID, "API_KEY", "v4")
+	if err != nil || !ok || again.Version != 1 {
+		t.Errorf("SetValue of a deleted key = %+v, %v, %v", again, ok, err)
+	}
+}
+
+func testRenameValue(t *testing.T, newStore newStoreFunc) {
+	ctx := context.Background()
+	s, newProject := newStore(t)
+	env := mustCreate(t, s, newProject(t), "staging")
+	original := mustSet(t, s, env.ID, "API_KEY", "v1")
+	mustSet(t, s, env.ID, "API_KEY", "v2")
+	mustSet(t, s, env.ID, "DEBUG", "true")
+
+	renamed, err := s.RenameValue(ctx, env.ID, "API_KEY", "TOKEN")
+	if err != nil {
+		t.Fatalf("RenameValue: %v", err)
+	}
+	if renamed.ID != original.ID || renamed.Key != "TOKEN" || renamed.Value != "v2" || renamed.Version != 2 {
+		t.Errorf("RenameValue = %+v", renamed)
+	}
+	if _, err := s.GetValue(ctx, env.ID, "API_KEY"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetValue of the old key: got %v, want ErrNotFound", err)
+	}
+
+	// The versions follow the value
+	versions, err := s.GetVersions(ctx, env.ID, "TOKEN")
+	if err != nil {
+		t.Fatalf("GetVersions: %v", err)
+	}
+	var history []string
+	for _, version := range versions {
+		history = append(history, fmt.Sprintf("%d=%s", version.Version, version.Value))
+	}
+	if want := []string{"2=v2", "1=v1"}; !slices.Equal(history, want) {
+		t.Errorf("GetVersions of the renamed value = %v, want %v", history, want)
+	}
+
+	if _, err := s.RenameValue(ctx, env.ID, "TOKEN", "DEBUG"); !errors.Is(err, ErrExists) {
+		t.Errorf("renaming to a set key: got %v, want ErrExists", err)
+	}
+	if got, _ := s.GetValue(ctx, env.ID, "DEBUG"); got.Value != "true" {
+		t.Errorf("a failed rename changed DEBUG to %q", got.Value)
+	}
+	if _, err := s.RenameValue(ctx, env.ID, "MISSING", "OTHER"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("renaming a missing key: got %v, want ErrNotFound", err)
+	}
+}
+
+func testMetadata(t *testing.T, newStore newStoreFunc) {
+	ctx := context.Background()
+	s, newProject := newStore(t)
+	env := mustCreate(t, s, newProject(t), "staging")
+	mustSet(t, s, env.ID, "API_KEY", "secret")
+	m := Metadata{
+		Description: "Payments API",
+		Owner:       "payments@example.com",
+		Link:        "https://example.com/runbook",
+		Tags:        []string{"critical", "pci"},
+	}
+
+	equal := func(got Metadata) bool {
+		return got.Description == m.Description && got.Owner == m.Owner && got.Link == m.Link && slices.Equal(got.Tags, m.Tags)
+	}
+
+	if got, err := s.SetEnvironmentMetadata(ctx, env.ID, m); err != nil || !equal(got.Metadata) {
+		t.Errorf("SetEnvironmentMetadata = %+v, %v", got.Metadata, err)
+	}
+	if got, _ := s.GetEnvironment(ctx, env.ID); !equal(got.Metadata) {
+		t.Errorf("GetEnvironment after SetEnvironmentMetadata: Metadata = %+v", got.Metadata)
+	}
+	if _, err := s.SetEnvironmentMetadata(ctx, env.ID+100, m); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetEnvironmentMetadata of a missing environment: got %v, want ErrNotFound", err)
+	}
+
+	if got, err := s.SetValueMetadata(ctx, env.ID, "API_KEY", m); err != nil || !equal(got.Metadata) || got.Value != "secret" {
+		t.Errorf("SetValueMetadata = %+v, %v", got, err)
+	}
+	if got, _ := s.GetValue(ctx, env.ID, "API_KEY"); !equal(got.Metadata) || got.Version != 1 {
+		t.Errorf("GetValue after SetValueMetadata = %+v", got)
+	}
+	if _, err := s.SetValueMetadata(ctx, env.ID, "MISSING", m); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetValueMetadata of a missing key: got %v, want ErrNotFound", err)
+	}
+
+	if got, err := s.SetEnvironmentMetadata(ctx, env.ID, Metadata{}); err != nil || got.Description != "" || len(got.Tags) != 0 {
+		t.Errorf("clearing the metadata = %+v, %v", got.Metadata, err)
+	}
+}
+
+func testExpiry(t *testing.T, newStore newStoreFunc) {
+	ctx := context.Background()
+	s, newProject := newStore(t)
+	env := mustCreate(t, s, newProject(t), "preview")
+	mustSet(t, s, env.ID, "TOKEN", "secret")
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	got, err := s.SetEnvironmentExpiry(ctx, env.ID, &expiresAt)
+	if err != nil || got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("SetEnvironmentExpiry = %+v, %v", got.ExpiresAt, err)
+	}
+	if got, _ := s.GetEnvironment(ctx, env.ID); got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("GetEnvironment after SetEnvironmentExpiry: ExpiresAt = %v, want %v", got.ExpiresAt, expiresAt)
+	}
+	if got, err := s.SetEnvironmentExpiry(ctx, env.ID, nil); err != nil || got.ExpiresAt != nil {
+		t.Errorf("SetEnvironmentExpiry(nil) = %+v, %v", got.ExpiresAt, err)
+	}
+	if _, err := s.SetEnvironmentExpiry(ctx, env.ID+100, &expiresAt); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetEnvironmentExpiry of a missing environment: got %v, want ErrNotFound", err)
+	}
+
+	value, err := s.SetValueExpiry(ctx, env.ID, "TOKEN", &expiresAt)
+	if err != nil || value.ExpiresAt == nil || !value.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("SetValueExpiry = %+v, %v", value.ExpiresAt, err)
+	}
+	if got, _ := s.GetValue(ctx, env.ID, "TOKEN"); got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) || got.Value != "secret" {
+		t.Errorf("GetValue after SetValueExpiry = %+v", got)
+	}
+	if got, err := s.SetValueExpiry(ctx, env.ID, "TOKEN", nil); err != nil || got.ExpiresAt != nil {
+		t.Errorf("SetValueExpiry(nil) = %+v, %v", got.ExpiresAt, err)
+	}
+	if _, err := s.SetValueExpiry(ctx, env.ID, "MISSING", &expiresAt); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetValueExpiry of a missing key: got %v, want ErrNotFound", err)
+	}
+}
+
+func testTransactions(t *testing.T, newStore newStoreFunc) {
+	ctx := context.Background()
+	s, newProject := newStore(t)
+	projectID := newProject(t)
+	env := mustCreate(t, s, projectID, "staging")
+
+	err := s.InTx(ctx, func(tx Store) error {
+		mustSet(t, tx, env.ID, "A", "1")
+		mustSet(t, tx, env.ID, "B", "2")
+		// Reads inside the transaction see its changes
+		if _, err := tx.GetValue(ctx, env.ID, "A"); err != nil {
+			t.Errorf("GetValue inside the transaction: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("InTx: %v", err)
+	}
+	if values, _ := s.GetValues(ctx, env.ID); len(values) != 2 {
+		t.Errorf("got %d values after the transaction, want 2", len(values))
+	}
+
+	errRollback := errors.New("rollback")
+	err = s.InTx(ctx, func(tx Store) error {
+		mustSet(t, tx, env.ID, "A", "changed")
+		if err := tx.DeleteValue(ctx, env.ID, "B"); err != nil {
+			t.Errorf("DeleteValue inside the transaction: %v", err)
+		}
+		mustCreate(t, tx, projectID, "production")
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("InTx = %v, want the error of fn", err)
+	}
+	if got, _ := s.GetValue(ctx, env.ID, "A"); got.Value != "1" || got.Version != 1 {
+		t.Errorf("A = %+v after a rolled back transaction", got)
+	}
+	if _, err := s.GetValue(ctx, env.ID, "B"); err != nil {
+		t.Errorf("B was deleted by a rolled back transaction: %v", err)
+	}
+	if _, err := s.GetEnvironmentByName(ctx, projectID, "production"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("an environment was created by a rolled back transaction: %v", err)
+	}
+}
+
+func testExpiredEnvironments(t *testing.T, newStore newStoreFunc) {
+	ctx := context.Background()
+	s, newProject := newStore(t)
+	projectID := newProject(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	expiry := map[string]time.Time{
+		"pr-2":   now.Add(-time.Hour),
+		"pr-1":   now.Add(-2 * time.Hour),
+		"pr-3":   now,
+		"future": now.Add(time.Hour),
+	}
+	for _, name := range []string{"pr-2", "pr-1", "pr-3", "future", "staging"} {
+		env := mustCreate(t, s, projectID, name)
+		if expiresAt, ok := expiry[name]; ok {
+			if _, err := s.SetEnvironmentExpiry(ctx, env.ID, &expiresAt); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	envs, err := s.GetExpiredEnvironments(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("GetExpiredEnvironments: %v", err)
+	}
+	// The oldest expiration first
+	if got, want := names(envs), []string{"pr-1", "pr-2", "pr-3"}; !slices.Equal(got, want) {
+		t.Errorf("GetExpiredEnvironments = %v, want %v", got, want)
+	}
+
+	envs, err = s.GetExpiredEnvironments(ctx, now, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(envs), []string{"pr-1", "pr-2"}; !slices.Equal(got, want) {
+		t.Errorf("GetExpiredEnvironments with a limit = %v, want %v", got, want)
+	}
+}
+
+// commitBreaker is implemented by the stores whose commits can be made to
+// fail in tests, until the returned function repairs them
+type commitBreaker interface {
+	breakCommits(t *testing.T) func()
+}
+
+func testFailedCommits(t *testing.T, newStore newStoreFunc) {
+	ctx := context.Background()
+	s, newProject := newStore(t)
+	breaker, ok := s.(commitBreaker)
+	if !ok {
+		t.Skip("the commits of the store can't fail")
+	}
+	projectID := newProject(t)
+	env := mustCreate(t, s, projectID, "staging")
+	mustSet(t, s, env.ID, "A", "1")
+	mustSet(t, s, env.ID, "B", "2")
+
+	repair := breaker.breakCommits(t)
+	if _, _, err := s.SetValue(ctx, env.ID, "A", "changed"); err == nil {
+		t.Error("SetValue succeeded with a failing commit")
+	}
+	if _, err := s.CreateEnvironment(ctx, projectID, "production"); err == nil {
+		t.Error("CreateEnvironment succeeded with a failing commit")
+	}
+	if err := s.DeleteEnvironment(ctx, env.ID); err == nil {
+		t.Error("DeleteEnvironment succeeded with a failing commit")
+	}
+	err := s.InTx(ctx, func(tx Store) error {
+		mustSet(t, tx, env.ID, "C", "3")
+		return tx.DeleteValue(ctx, env.ID, "B")
+	})
+	if err == nil {
+		t.Error("InTx succeeded with a failing commit")
+	}
+
+	// Reads see the last successful commit
+	if got, _ := s.GetValue(ctx, env.ID, "A"); got.Value != "1" || got.Version != 1 {
+		t.Errorf("A = %+v after a failed commit", got)
+	}
+	if _, err := s.GetValue(ctx, env.ID, "B"); err != nil {
+		t.Errorf("B was deleted by a failed commit: %v", err)
+	}
+	if _, err := s.GetValue(ctx, env.ID, "C"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("C was set by a failed commit: %v", err)
+	}
+	if _, err := s.GetEnvironmentByName(ctx, projectID, "production"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("an environment was created by a failed commit: %v", err)
+	}
+
+	// Nothing of the failed commits is left for the next one
+	repair()
+	mustSet(t, s, env.ID, "D", "4")
+	values, err := s.GetValues(ctx, env.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(values); got != 3 {
+		t.Errorf("got %d values after repairing, want A, B and D", got)
+	}
+}
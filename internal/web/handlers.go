@@ -15,11 +15,11 @@ var staticFiles embed.FS
 
 // Handler maneja las rutas web
 type Handler struct {
-	queries *database.Queries
+	queries database.Querier
 }
 
 // NewHandler crea una nueva instancia del manejador web
-func NewHandler(queries *database.Queries) *Handler {
+func NewHandler(queries database.Querier) *Handler {
 	return &Handler{
 		queries: queries,
 	}
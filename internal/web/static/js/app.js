@@ -22,18 +22,100 @@ function showToast(message, type = "success") {
   }, 3000);
 }
 
+// Project whose environments are shown, remembered across visits
+let currentProject = localStorage.getItem("project") || "default";
+
+function projectURL(path = "") {
+  return `/api/v1/projects/${encodeURIComponent(currentProject)}${path}`;
+}
+
+// Function to load the projects into the project switcher
+async function loadProjects() {
+  try {
+    const response = await fetch("/api/v1/projects");
+    const projects = await response.json();
+
+    const select = document.getElementById("project-select");
+    select.innerHTML = "";
+
+    const names = (projects?.data || []).map((project) => project.name);
+    if (!names.includes(currentProject)) {
+      currentProject = "default";
+    }
+
+    names.forEach((name) => {
+      const option = document.createElement("option");
+      option.value = name;
+      option.textContent = name;
+      option.selected = name === currentProject;
+      select.appendChild(option);
+    });
+  } catch (error) {
+    console.error("Error loading projects:", error);
+    showToast("Error loading projects", "error");
+  }
+}
+
+// Function to show the environments of another project
+function switchProject(name) {
+  currentProject = name;
+  localStorage.setItem("project", name);
+  loadEnvironments();
+}
+
+// Function to create a project and switch to it
+async function addNewProject() {
+  const name = prompt("Name of the new project");
+  if (name === null || !name.trim()) {
+    return;
+  }
+
+  try {
+    const response = await fetch("/api/v1/projects", {
+      method: "POST",
+      headers: {
+        "Content-Type": "application/json",
+      },
+      body: JSON.stringify({ name: name.trim() }),
+    });
+
+    const result = await response.json();
+    if (response.ok && !result.error) {
+      showToast("Project created successfully");
+      currentProject = name.trim();
+      localStorage.setItem("project", currentProject);
+      await loadProjects();
+      loadEnvironments();
+    } else {
+      throw new Error(result.error || "Error creating project");
+    }
+  } catch (error) {
+    console.error("Error creating project:", error);
+    showToast("Error creating project", "error");
+  }
+}
+
 // Function to load environments and their variables
 async function loadEnvironments() {
   try {
-    const response = await fetch("/api/v1/env");
-    const environments = await response.json();
-
     const container = document.getElementById("environments-container");
     container.innerHTML = "";
 
-    environments?.data?.forEach((env) => {
-      addEnvironmentToContainer(env);
-    });
+    // Environments are listed a page at a time, follow next_cursor until the last one
+    let cursor = "";
+    do {
+      const query = cursor ? `?cursor=${encodeURIComponent(cursor)}` : "";
+      const response = await fetch(projectURL(`/env${query}`));
+      const environments = await response.json();
+      if (environments?.error) {
+        throw new Error(environments.error);
+      }
+
+      environments?.data?.forEach((env) => {
+        addEnvironmentToContainer(env);
+      });
+      cursor = environments?.next_cursor || "";
+    } while (cursor);
   } catch (error) {
     console.error("Error loading environments:", error);
     showToast("Error loading environments", "error");
@@ -52,11 +134,16 @@ function addEnvironmentToContainer(env = null) {
   if (env) {
     nameInput.value = env.name;
     nameInput.dataset.id = env.id;
+    showMetadata(clone.querySelector(".environment-meta"), env);
 
     // Add existing variables
     env.values.forEach((value) => {
       addVariableToContainer(variablesContainer, value);
     });
+
+    if (env.e2e) {
+      showReadOnly(clone.querySelector(".environment-item"));
+    }
   }
 
   // Add animation class
@@ -66,6 +153,23 @@ function addEnvironmentToContainer(env = null) {
   container.appendChild(clone);
 }
 
+// Function to show an end-to-end encrypted environment read-only, its values
+// are ciphertext that only its clients can decrypt
+function showReadOnly(environmentItem) {
+  environmentItem.querySelectorAll("input").forEach((input) => {
+    input.readOnly = true;
+  });
+  environmentItem.querySelectorAll("button").forEach((button) => {
+    button.classList.add("hidden");
+  });
+
+  const badge = document.createElement("span");
+  badge.className = "text-sm text-code-yellow";
+  badge.title = "Values are encrypted by the clients, the server can't read them";
+  badge.innerHTML = '<i class="fas fa-lock mr-2"></i>End-to-end encrypted';
+  environmentItem.querySelector(".environment-name").after(badge);
+}
+
 // Function to add a new environment
 function addNewEnvironment() {
   addEnvironmentToContainer();
@@ -103,6 +207,44 @@ function removeEnvironment(button) {
   }, 300);
 }
 
+// Function to duplicate an environment into a new one
+async function duplicateEnvironment(button) {
+  const item = button.closest(".environment-item");
+  const nameInput = item.querySelector(".environment-name");
+  const environmentId = nameInput.dataset.id;
+
+  if (!environmentId) {
+    showToast("Save the environment before duplicating it", "error");
+    return;
+  }
+
+  const name = prompt("Name of the new environment", `${nameInput.value}-copy`);
+  if (name === null || !name.trim()) {
+    return;
+  }
+
+  try {
+    const response = await fetch(`/api/v1/env/${environmentId}/clone`, {
+      method: "POST",
+      headers: {
+        "Content-Type": "application/json",
+      },
+      body: JSON.stringify({ name: name.trim() }),
+    });
+
+    const result = await response.json();
+    if (response.ok && !result.error) {
+      showToast("Environment duplicated successfully");
+      loadEnvironments(); // Reload to ensure synchronization
+    } else {
+      throw new Error(result.error || "Error duplicating environment");
+    }
+  } catch (error) {
+    console.error("Error duplicating environment:", error);
+    showToast("Error duplicating environment", "error");
+  }
+}
+
 // Function to add a new variable to an environment
 function addVariableToContainer(container, value = null) {
   const template = document.getElementById("variable-template");
@@ -115,7 +257,8 @@ function addVariableToContainer(container, value = null) {
     keyInput.value = value.key;
     valueInput.value = value.value;
     keyInput.dataset.id = value.id;
-    removeButton.dataset.id = value.id;
+    removeButton.dataset.key = value.key;
+    showMetadata(clone.querySelector(".variable-meta"), value);
   }
 
   // Add animation class
@@ -147,10 +290,14 @@ function removeVariable(button) {
   // Wait for animation to complete before removing
   setTimeout(async () => {
     item.remove();
+    // Variables that were never saved only exist in the page
+    const valueKey = valueInput.dataset.key;
+    if (!valueKey) {
+      return;
+    }
     try {
       const environmentId = nameInput.dataset.id;
-      const valueId = valueInput.dataset.id;
-      const url = `/api/v1/env/${environmentId}/value/${valueId}`;
+      const url = `/api/v1/env/${environmentId}/value/${encodeURIComponent(valueKey)}`;
 
       const response = await fetch(url, {
         method: "DELETE",
@@ -172,6 +319,198 @@ function removeVariable(button) {
   }, 300);
 }
 
+// Function to show the description, owner, tags and link of an environment or
+// variable, which are kept in the element to edit them later
+function showMetadata(element, item) {
+  const metadata = {
+    description: item.description || "",
+    owner: item.owner || "",
+    tags: item.tags || [],
+    link: item.link || "",
+  };
+  element.dataset.metadata = JSON.stringify(metadata);
+  element.innerHTML = "";
+
+  const parts = [
+    metadata.owner && `@${metadata.owner}`,
+    metadata.tags.map((tag) => `#${tag}`).join(" "),
+    metadata.description,
+  ].filter(Boolean);
+  element.appendChild(document.createTextNode(parts.join(" · ")));
+
+  if (metadata.link) {
+    const link = document.createElement("a");
+    link.href = metadata.link;
+    link.target = "_blank";
+    link.rel = "noopener noreferrer";
+    link.className = "ml-2 text-code-accent hover:underline";
+    link.textContent = "docs";
+    element.appendChild(link);
+  }
+}
+
+// Function to ask for the metadata, null when cancelled
+function promptMetadata(current) {
+  const description = prompt("Description", current.description);
+  if (description === null) {
+    return null;
+  }
+  const owner = prompt("Owner", current.owner);
+  if (owner === null) {
+    return null;
+  }
+  const tags = prompt("Tags, separated by commas", current.tags.join(", "));
+  if (tags === null) {
+    return null;
+  }
+  const link = prompt("Link", current.link);
+  if (link === null) {
+    return null;
+  }
+
+  return {
+    description,
+    owner,
+    tags: tags.split(",").map((tag) => tag.trim()).filter(Boolean),
+    link,
+  };
+}
+
+async function saveMetadata(url, metadata) {
+  try {
+    const response = await fetch(url, {
+      method: "PUT",
+      headers: {
+        "Content-Type": "application/json",
+      },
+      body: JSON.stringify(metadata),
+    });
+
+    const result = await response.json();
+    if (response.ok && !result.error) {
+      showToast("Metadata saved successfully");
+      loadEnvironments(); // Reload to ensure synchronization
+    } else {
+      throw new Error(result.error || "Error saving metadata");
+    }
+  } catch (error) {
+    console.error("Error saving metadata:", error);
+    showToast("Error saving metadata", "error");
+  }
+}
+
+// Function to edit the metadata of an environment
+function editEnvironmentMetadata(button) {
+  const item = button.closest(".environment-item");
+  const environmentId = item.querySelector(".environment-name").dataset.id;
+  if (!environmentId) {
+    showToast("Save the environment before editing its metadata", "error");
+    return;
+  }
+
+  const current = JSON.parse(
+    item.querySelector(".environment-meta").dataset.metadata || "null"
+  );
+  const metadata = promptMetadata(
+    current || { description: "", owner: "", tags: [], link: "" }
+  );
+  if (metadata) {
+    saveMetadata(`/api/v1/env/${environmentId}/metadata`, metadata);
+  }
+}
+
+// Function to edit the metadata of a variable
+function editVariableMetadata(button) {
+  const item = button.closest(".variable-item");
+  const environmentId = button
+    .closest(".environment-item")
+    .querySelector(".environment-name").dataset.id;
+  const valueKey = item.querySelector(".remove-button").dataset.key;
+  if (!environmentId || !valueKey) {
+    showToast("Save the variable before editing its metadata", "error");
+    return;
+  }
+
+  const current = JSON.parse(
+    item.querySelector(".variable-meta").dataset.metadata || "null"
+  );
+  const metadata = promptMetadata(
+    current || { description: "", owner: "", tags: [], link: "" }
+  );
+  if (metadata) {
+    saveMetadata(
+      `/api/v1/env/${environmentId}/value/${encodeURIComponent(valueKey)}/metadata`,
+      metadata
+    );
+  }
+}
+
+// Generator types supported by the server
+const GENERATOR_TYPES = [
+  "password",
+  "uuid",
+  "hex",
+  "base64",
+  "ed25519",
+  "rsa",
+  "x509-self-signed",
+];
+
+// Function to regenerate a variable server-side
+async function regenerateVariable(button) {
+  const item = button.closest(".variable-item");
+  const environmentItem = button.closest(".environment-item");
+  const environmentId =
+    environmentItem.querySelector(".environment-name").dataset.id;
+  const key = item.querySelector(".variable-key").value.trim();
+
+  if (!environmentId || !key) {
+    showToast("Save the environment before regenerating values", "error");
+    return;
+  }
+
+  const type = prompt(
+    `Generator (${GENERATOR_TYPES.join(", ")})`,
+    "password"
+  );
+  if (type === null) {
+    return;
+  }
+  if (!GENERATOR_TYPES.includes(type.trim())) {
+    showToast(`Unknown generator ${type}`, "error");
+    return;
+  }
+
+  const generate = { type: type.trim() };
+  if (generate.type === "password") {
+    generate.length = 32;
+    generate.charset = "alnum+symbols";
+  }
+
+  try {
+    const response = await fetch(`/api/v1/env/${environmentId}`, {
+      method: "PUT",
+      headers: {
+        "Content-Type": "application/json",
+      },
+      body: JSON.stringify({
+        values: [{ key: key, generate: generate }],
+      }),
+    });
+
+    const result = await response.json();
+    if (response.ok && !result.error) {
+      showToast("Value regenerated successfully");
+      loadEnvironments(); // Reload to ensure synchronization
+    } else {
+      throw new Error(result.error || "Error regenerating value");
+    }
+  } catch (error) {
+    console.error("Error regenerating value:", error);
+    showToast("Error regenerating value", "error");
+  }
+}
+
 // Function to save an environment and its variables
 async function saveEnvironment(button) {
   const environmentItem = button.closest(".environment-item");
@@ -196,7 +535,9 @@ async function saveEnvironment(button) {
   try {
     const environmentId = nameInput.dataset.id;
     const method = environmentId ? "PUT" : "POST";
-    const url = environmentId ? `/api/v1/env/${environmentId}` : "/api/v1/env";
+    const url = environmentId
+      ? `/api/v1/env/${environmentId}`
+      : projectURL("/env");
 
     const response = await fetch(url, {
       method: method,
@@ -255,5 +596,96 @@ function togglePasswordVisibility(button) {
   }
 }
 
-// Load environments on startup
-document.addEventListener("DOMContentLoaded", loadEnvironments);
+// Load projects and environments on startup
+// Function to search environments and keys across projects while typing
+let searchTimeout;
+function searchSecrets(query) {
+  clearTimeout(searchTimeout);
+  searchTimeout = setTimeout(async () => {
+    const results = document.getElementById("search-results");
+    if (!query.trim()) {
+      results.classList.add("hidden");
+      return;
+    }
+
+    try {
+      const response = await fetch(
+        `/api/v1/search?q=${encodeURIComponent(query.trim())}&limit=20`
+      );
+      const result = await response.json();
+      if (!response.ok || result.error) {
+        throw new Error(result.error || "Error searching");
+      }
+      showSearchResults(result.data || []);
+    } catch (error) {
+      console.error("Error searching:", error);
+      showToast("Error searching", "error");
+    }
+  }, 250);
+}
+
+function showSearchResults(matches) {
+  const results = document.getElementById("search-results");
+  results.innerHTML = "";
+
+  if (matches.length === 0) {
+    const empty = document.createElement("p");
+    empty.className = "px-4 py-2 text-sm text-gray-500";
+    empty.textContent = "No results";
+    results.appendChild(empty);
+  }
+
+  matches.forEach((match) => {
+    const item = document.createElement("button");
+    item.type = "button";
+    item.className =
+      "block w-full text-left px-4 py-2 hover:bg-gray-800 transition-colors duration-200";
+
+    const title = document.createElement("span");
+    title.className = "text-code-accent";
+    title.textContent = match.key || match.environment_name;
+    item.appendChild(title);
+
+    const context = document.createElement("span");
+    context.className = "ml-2 text-xs text-gray-500";
+    context.textContent = [
+      `${match.project} / ${match.environment_name}`,
+      (match.tags || []).map((tag) => `#${tag}`).join(" "),
+      match.description,
+    ]
+      .filter(Boolean)
+      .join(" · ");
+    item.appendChild(context);
+
+    item.onclick = () => openSearchResult(match);
+    results.appendChild(item);
+  });
+
+  results.classList.remove("hidden");
+}
+
+// Function to show the environment of a search result
+async function openSearchResult(match) {
+  document.getElementById("search-results").classList.add("hidden");
+
+  if (match.project !== currentProject) {
+    currentProject = match.project;
+    localStorage.setItem("project", currentProject);
+    await loadProjects();
+  }
+  await loadEnvironments();
+
+  const nameInput = document.querySelector(
+    `.environment-name[data-id="${match.environment_id}"]`
+  );
+  if (nameInput) {
+    nameInput
+      .closest(".environment-item")
+      .scrollIntoView({ behavior: "smooth", block: "start" });
+  }
+}
+
+document.addEventListener("DOMContentLoaded", async () => {
+  await loadProjects();
+  loadEnvironments();
+});
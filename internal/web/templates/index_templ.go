@@ -41,7 +41,7 @@ func Index() templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"bg-code-bg border border-gray-800 rounded-lg p-6 shadow-lg\"><div class=\"flex justify-between items-center mb-6\"><div><h1 class=\"text-2xl font-bold text-code-accent\">Environment Variables</h1><p class=\"text-sm text-code-fg mt-1\">Manage your environment variables securely</p></div><div class=\"flex space-x-4\"><button type=\"button\" class=\"bg-code-accent hover:bg-blue-600 text-white px-4 py-2 rounded-md flex items-center transition-colors duration-200\" onclick=\"addNewEnvironment()\"><i class=\"fas fa-plus mr-2\"></i> Add Environment</button></div></div><div id=\"environments-container\" class=\"space-y-6\"><!-- Environments will be loaded dynamically here --></div></div><!-- Template for new environment --> <template id=\"environment-template\"><div class=\"environment-item bg-gray-800 rounded-lg p-4 border border-gray-700\"><div class=\"flex justify-between items-center mb-4\"><input type=\"text\" class=\"environment-name w-64 px-3 py-2 bg-gray-900 border border-gray-700 rounded-md text-code-fg placeholder-gray-500 focus:outline-none focus:border-code-accent\" placeholder=\"Environment name\"><div class=\"flex space-x-2\"><button type=\"button\" class=\"bg-code-accent hover:bg-blue-600 text-white px-4 py-2 rounded-md flex items-center transition-colors duration-200\" onclick=\"addNewVariable(this)\"><i class=\"fas fa-plus mr-2\"></i> Add Variable</button> <button type=\"button\" class=\"bg-code-green hover:bg-green-600 text-white px-4 py-2 rounded-md flex items-center transition-colors duration-200\" onclick=\"saveEnvironment(this)\"><i class=\"fas fa-save mr-2\"></i> Save</button> <button type=\"button\" class=\"text-code-red hover:text-red-400 transition-colors duration-200\" onclick=\"removeEnvironment(this)\"><i class=\"fas fa-trash\"></i></button></div></div><div class=\"variables-container space-y-4\"><!-- Variables will be added here --></div></div></template><!-- Template for new variable --> <template id=\"variable-template\"><div class=\"variable-item flex items-center space-x-4 p-4 bg-gray-900 rounded-md border border-gray-700\"><div class=\"flex-1\"><input type=\"text\" class=\"variable-key w-full px-3 py-2 bg-gray-800 border border-gray-700 rounded-md text-code-fg placeholder-gray-500 focus:outline-none focus:border-code-accent\" placeholder=\"Variable name\"></div><div class=\"flex-1 relative\"><input type=\"password\" class=\"variable-value w-full px-3 py-2 pr-10 bg-gray-800 border border-gray-700 rounded-md text-code-fg placeholder-gray-500 focus:outline-none focus:border-code-accent\" placeholder=\"Value\"> <button type=\"button\" class=\"absolute right-2 top-1/2 transform -translate-y-1/2 text-gray-400 hover:text-code-fg transition-colors duration-200 toggle-password\" onclick=\"togglePasswordVisibility(this)\"><i class=\"fas fa-eye\"></i></button></div><button type=\"button\" class=\"remove-button text-code-red hover:text-red-400 transition-colors duration-200\" onclick=\"removeVariable(this)\"><i class=\"fas fa-trash\"></i></button></div></template><!-- Toast notification --> <div id=\"toast\" class=\"fixed bottom-4 right-4 bg-gray-800 text-white px-6 py-3 rounded-md shadow-lg transform translate-y-full opacity-0 transition-all duration-300\"><div class=\"flex items-center\"><i class=\"fas fa-check-circle text-code-green mr-2\"></i> <span id=\"toast-message\"></span></div></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"bg-code-bg border border-gray-800 rounded-lg p-6 shadow-lg\"><div class=\"flex justify-between items-center mb-6\"><div><h1 class=\"text-2xl font-bold text-code-accent\">Environment Variables</h1><p class=\"text-sm text-code-fg mt-1\">Manage your environment variables securely</p></div><div class=\"flex space-x-4\"><select id=\"project-select\" class=\"px-3 py-2 bg-gray-900 border border-gray-700 rounded-md text-code-fg focus:outline-none focus:border-code-accent\" title=\"Project\" onchange=\"switchProject(this.value)\"></select> <button type=\"button\" class=\"bg-gray-700 hover:bg-gray-600 text-white px-4 py-2 rounded-md flex items-center transition-colors duration-200\" onclick=\"addNewProject()\"><i class=\"fas fa-folder-plus mr-2\"></i> New Project</button> <button type=\"button\" class=\"bg-code-accent hover:bg-blue-600 text-white px-4 py-2 rounded-md flex items-center transition-colors duration-200\" onclick=\"addNewEnvironment()\"><i class=\"fas fa-plus mr-2\"></i> Add Environment</button></div></div><div class=\"relative mb-6\"><i class=\"fas fa-search absolute left-3 top-1/2 transform -translate-y-1/2 text-gray-500\"></i> <input type=\"search\" id=\"search-input\" class=\"w-full pl-10 pr-3 py-2 bg-gray-900 border border-gray-700 rounded-md text-code-fg placeholder-gray-500 focus:outline-none focus:border-code-accent\" placeholder=\"Search keys, descriptions and tags\" oninput=\"searchSecrets(this.value)\"><div id=\"search-results\" class=\"hidden absolute z-10 w-full mt-1 bg-gray-900 border border-gray-700 rounded-md shadow-lg max-h-80 overflow-y-auto\"></div></div><div id=\"environments-container\" class=\"space-y-6\"><!-- Environments will be loaded dynamically here --></div></div><!-- Template for new environment --> <template id=\"environment-template\"><div class=\"environment-item bg-gray-800 rounded-lg p-4 border border-gray-700\"><div class=\"flex justify-between items-center mb-4\"><input type=\"text\" class=\"environment-name w-64 px-3 py-2 bg-gray-900 border border-gray-700 rounded-md text-code-fg placeholder-gray-500 focus:outline-none focus:border-code-accent\" placeholder=\"Environment name\"><div class=\"flex space-x-2\"><button type=\"button\" class=\"bg-code-accent hover:bg-blue-600 text-white px-4 py-2 rounded-md flex items-center transition-colors duration-200\" onclick=\"addNewVariable(this)\"><i class=\"fas fa-plus mr-2\"></i> Add Variable</button> <button type=\"button\" class=\"bg-code-green hover:bg-green-600 text-white px-4 py-2 rounded-md flex items-center transition-colors duration-200\" onclick=\"saveEnvironment(this)\"><i class=\"fas fa-save mr-2\"></i> Save</button> <button type=\"button\" class=\"text-code-yellow hover:text-yellow-400 transition-colors duration-200\" title=\"Duplicate\" onclick=\"duplicateEnvironment(this)\"><i class=\"fas fa-copy\"></i></button> <button type=\"button\" class=\"text-code-purple hover:text-purple-400 transition-colors duration-200\" title=\"Edit metadata\" onclick=\"editEnvironmentMetadata(this)\"><i class=\"fas fa-tag\"></i></button> <button type=\"button\" class=\"text-code-red hover:text-red-400 transition-colors duration-200\" onclick=\"removeEnvironment(this)\"><i class=\"fas fa-trash\"></i></button></div></div><p class=\"environment-meta text-sm text-gray-500 -mt-2 mb-4\"></p><div class=\"variables-container space-y-4\"><!-- Variables will be added here --></div></div></template><!-- Template for new variable --> <template id=\"variable-template\"><div class=\"variable-item flex items-center space-x-4 p-4 bg-gray-900 rounded-md border border-gray-700\"><div class=\"flex-1\"><input type=\"text\" class=\"variable-key w-full px-3 py-2 bg-gray-800 border border-gray-700 rounded-md text-code-fg placeholder-gray-500 focus:outline-none focus:border-code-accent\" placeholder=\"Variable name\"><p class=\"variable-meta text-xs text-gray-500 mt-1\"></p></div><div class=\"flex-1 relative\"><input type=\"password\" class=\"variable-value w-full px-3 py-2 pr-10 bg-gray-800 border border-gray-700 rounded-md text-code-fg placeholder-gray-500 focus:outline-none focus:border-code-accent\" placeholder=\"Value\"> <button type=\"button\" class=\"absolute right-2 top-1/2 transform -translate-y-1/2 text-gray-400 hover:text-code-fg transition-colors duration-200 toggle-password\" onclick=\"togglePasswordVisibility(this)\"><i class=\"fas fa-eye\"></i></button></div><button type=\"button\" class=\"regenerate-button text-code-yellow hover:text-yellow-400 transition-colors duration-200\" title=\"Regenerate value\" onclick=\"regenerateVariable(this)\"><i class=\"fas fa-sync-alt\"></i></button> <button type=\"button\" class=\"text-code-purple hover:text-purple-400 transition-colors duration-200\" title=\"Edit metadata\" onclick=\"editVariableMetadata(this)\"><i class=\"fas fa-tag\"></i></button> <button type=\"button\" class=\"remove-button text-code-red hover:text-red-400 transition-colors duration-200\" onclick=\"removeVariable(this)\"><i class=\"fas fa-trash\"></i></button></div></template><!-- Toast notification --> <div id=\"toast\" class=\"fixed bottom-4 right-4 bg-gray-800 text-white px-6 py-3 rounded-md shadow-lg transform translate-y-full opacity-0 transition-all duration-300\"><div class=\"flex items-center\"><i class=\"fas fa-check-circle text-code-green mr-2\"></i> <span id=\"toast-message\"></span></div></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
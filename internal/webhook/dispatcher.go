@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+)
+
+// Delivery statuses stored in webhook_deliveries
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+// SignatureHeader carries the HMAC-SHA256 signature of the request body
+const SignatureHeader = "X-Secretly-Signature"
+
+// Dispatcher enqueues events for every active webhook of an environment.
+// Deliveries are persisted and sent later by a Worker.
+type Dispatcher struct {
+	db database.Querier
+}
+
+// NewDispatcher creates a new dispatcher
+func NewDispatcher(db database.Querier) *Dispatcher {
+	return &Dispatcher{db: db}
+}
+
+// Publish stores a pending delivery for each subscription of the event's
+// environment. A nil dispatcher discards the event.
+func (d *Dispatcher) Publish(ctx context.Context, event Event) error {
+	if d == nil {
+		return nil
+	}
+
+	webhooks, err := d.db.GetActiveWebhooksByEnvironmentID(ctx, event.Environment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	for _, webhook := range webhooks {
+		_, err := d.db.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			WebhookID:     webhook.ID,
+			Event:         event.Type,
+			Payload:       string(payload),
+			NextAttemptAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enqueue delivery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Sign returns the signature header value for body using secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of body
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// GenerateSecret returns a random secret for signing payloads
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
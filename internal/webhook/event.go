@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"time"
+)
+
+// Event types published when secrets change
+const (
+	EventEnvironmentCreated  = "environment.created"
+	EventEnvironmentUpdated  = "environment.updated"
+	EventEnvironmentDeleted  = "environment.deleted"
+	EventEnvironmentExpired  = "environment.expired"
+	EventEnvironmentRestored = "environment.restored"
+	EventValueDeleted        = "value.deleted"
+	EventValueRestored       = "value.restored"
+	EventValueRenamed        = "value.renamed"
+	EventValueRotated        = "value.rotated"
+	EventValueRotationFailed = "value.rotation_failed"
+	EventValueExpired        = "value.expired"
+)
+
+// Event describes a change to an environment. It only carries key names,
+// values are never sent to subscribers.
+type Event struct {
+	Type        string      `json:"event"`
+	Environment Environment `json:"environment"`
+	Keys        []string    `json:"keys"`
+	OccurredAt  time.Time   `json:"occurred_at"`
+}
+
+// Environment identifies the environment an event refers to
+type Environment struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// NewEvent creates a new event for the given environment and keys
+func NewEvent(eventType string, environmentID int64, environmentName string, keys ...string) Event {
+	if keys == nil {
+		keys = []string{}
+	}
+
+	return Event{
+		Type: eventType,
+		Environment: Environment{
+			ID:   environmentID,
+			Name: environmentName,
+		},
+		Keys:       keys,
+		OccurredAt: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rodrwan/secretly/internal/database"
+)
+
+const (
+	defaultBatchSize  = 50
+	defaultBaseDelay  = 10 * time.Second
+	defaultMaxDelay   = time.Hour
+	maxErrorBodyBytes = 512
+)
+
+// Worker delivers pending webhook deliveries, retrying failures with
+// exponential backoff until MaxAttempts is reached, after which the delivery
+// is dead-lettered.
+type Worker struct {
+	db          database.Querier
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int64
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	batchSize   int64
+	now         func() time.Time
+}
+
+// NewWorker creates a new delivery worker
+func NewWorker(db database.Querier, interval time.Duration, maxAttempts int) *Worker {
+	return &Worker{
+		db:          db,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		interval:    interval,
+		maxAttempts: int64(maxAttempts),
+		baseDelay:   defaultBaseDelay,
+		maxDelay:    defaultMaxDelay,
+		batchSize:   defaultBatchSize,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.ProcessDue(ctx); err != nil {
+			log.Printf("webhook: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue sends every delivery whose next attempt is due
+func (w *Worker) ProcessDue(ctx context.Context) error {
+	deliveries, err := w.db.GetDueWebhookDeliveries(ctx, database.GetDueWebhookDeliveriesParams{
+		NextAttemptAt: w.now(),
+		Limit:         w.batchSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get due deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		if err := w.deliver(ctx, delivery); err != nil {
+			log.Printf("webhook: delivery %d: %v", delivery.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) deliver(ctx context.Context, delivery database.WebhookDelivery) error {
+	webhook, err := w.db.GetWebhook(ctx, delivery.WebhookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Nothing can send the delivery anymore, dead-letter it so it
+		// doesn't take a slot of every batch
+		_, err = w.db.UpdateWebhookDelivery(ctx, database.UpdateWebhookDeliveryParams{
+			ID:            delivery.ID,
+			Status:        StatusDead,
+			Attempts:      delivery.Attempts,
+			NextAttemptAt: delivery.NextAttemptAt,
+			LastError:     "the webhook was deleted",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update delivery: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	params := database.UpdateWebhookDeliveryParams{
+		ID:            delivery.ID,
+		Status:        StatusPending,
+		Attempts:      delivery.Attempts + 1,
+		NextAttemptAt: delivery.NextAttemptAt,
+	}
+
+	code, sendErr := w.send(ctx, webhook, delivery)
+	params.ResponseCode = int64(code)
+
+	switch {
+	case sendErr == nil:
+		params.Status = StatusSucceeded
+		params.DeliveredAt = sql.NullTime{Time: w.now(), Valid: true}
+	case params.Attempts >= w.maxAttempts:
+		params.Status = StatusDead
+		params.LastError = sendErr.Error()
+	default:
+		params.LastError = sendErr.Error()
+		params.NextAttemptAt = w.now().Add(w.backoff(params.Attempts))
+	}
+
+	if _, err := w.db.UpdateWebhookDelivery(ctx, params); err != nil {
+		return fmt.Errorf("failed to update delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (w *Worker) send(ctx context.Context, webhook database.Webhook, delivery database.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "secretly-webhook")
+	req.Header.Set("X-Secretly-Event", delivery.Event)
+	req.Header.Set("X-Secretly-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return resp.StatusCode, fmt.Errorf("unexpected status %s: %s", resp.Status, msg)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the given attempt is retried
+func (w *Worker) backoff(attempts int64) time.Duration {
+	delay := w.baseDelay
+	for i := int64(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= w.maxDelay {
+			return w.maxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,259 @@
+package secretly
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tagName        = "secretly"
+	defaultTagName = "default"
+)
+
+// DecodeError lists every key that couldn't be decoded
+type DecodeError struct {
+	// Missing keys are required but not set
+	Missing []string
+	// Invalid keys are set but couldn't be parsed
+	Invalid map[string]error
+}
+
+func (e *DecodeError) Error() string {
+	var problems []string
+	if len(e.Missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(e.Missing, ", "))
+	}
+	for _, key := range slices.Sorted(maps.Keys(e.Invalid)) {
+		problems = append(problems, fmt.Sprintf("invalid %s: %v", key, e.Invalid[key]))
+	}
+	return "failed to decode environment: " + strings.Join(problems, "; ")
+}
+
+// Decode populates the struct pointed to by dst with the values of an environment.
+//
+// Fields are mapped with the secretly tag, ie:
+//
+//	type Config struct {
+//		DatabaseURL string        `secretly:"DATABASE_URL,required"`
+//		Port        int           `secretly:"PORT" default:"8080"`
+//		Timeout     time.Duration `secretly:"TIMEOUT" default:"5s"`
+//		Hosts       []string      `secretly:"HOSTS"`
+//		Redis       struct {
+//			Addr string `secretly:"ADDR"`
+//		} `secretly:"REDIS"` // reads REDIS_ADDR
+//	}
+//
+// Supported types are strings, bools, ints, uints, floats, durations,
+// comma separated slices of those, pointers and any type implementing
+// encoding.TextUnmarshaler. Nested structs are decoded recursively, using
+// their tag name, if any, as a prefix. A nil pointer to a nested struct is
+// only allocated when one of its keys is set. Every missing or unparsable
+// key is reported in a single *DecodeError.
+func (c *Client) Decode(ctx context.Context, environmentName string, dst any) error {
+	if err := checkDestination(dst); err != nil {
+		return err
+	}
+
+	environment, err := c.getEnvironment(ctx, environmentName)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]string, len(environment.Values))
+	for _, value := range environment.Values {
+		values[value.Key] = value.Value
+	}
+
+	return DecodeValues(values, dst)
+}
+
+// DecodeValues populates the struct pointed to by dst from a map of values,
+// following the same rules as Decode
+func DecodeValues(values map[string]string, dst any) error {
+	if err := checkDestination(dst); err != nil {
+		return err
+	}
+
+	decodeErr := &DecodeError{Invalid: map[string]error{}}
+	decodeStruct(values, reflect.ValueOf(dst).Elem(), "", decodeErr)
+	if len(decodeErr.Missing) > 0 || len(decodeErr.Invalid) > 0 {
+		return decodeErr
+	}
+	return nil
+}
+
+func checkDestination(dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("decode destination must be a non-nil pointer to a struct")
+	}
+	return nil
+}
+
+// decodeStruct decodes the fields of rv and returns how many of their keys
+// are set in values
+func decodeStruct(values map[string]string, rv reflect.Value, prefix string, decodeErr *DecodeError) int {
+	set := 0
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get(tagName)
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		required := opts == "required"
+
+		fv := rv.Field(i)
+
+		// Structs that don't decode from text hold more fields
+		nestedPrefix := prefix
+		if name != "" {
+			nestedPrefix = prefix + name + "_"
+		}
+		if isNestedStruct(fv.Type()) {
+			set += decodeStruct(values, fv, nestedPrefix, decodeErr)
+			continue
+		}
+		if fv.Kind() == reflect.Pointer && isNestedStruct(fv.Type().Elem()) {
+			set += decodeNestedPointer(values, fv, nestedPrefix, decodeErr)
+			continue
+		}
+
+		if name == "" {
+			continue
+		}
+		key := prefix + name
+
+		raw, ok := values[key]
+		if ok {
+			set++
+		} else {
+			raw, ok = field.Tag.Lookup(defaultTagName)
+		}
+		if !ok {
+			if required {
+				decodeErr.Missing = append(decodeErr.Missing, key)
+			}
+			continue
+		}
+
+		if err := setField(fv, raw); err != nil {
+			decodeErr.Invalid[key] = err
+		}
+	}
+	return set
+}
+
+// decodeNestedPointer decodes a pointer to a nested struct. A nil pointer is
+// an optional group of keys: it stays nil, and its required keys aren't
+// missing, unless one of its keys is set.
+func decodeNestedPointer(values map[string]string, fv reflect.Value, prefix string, decodeErr *DecodeError) int {
+	if !fv.IsNil() {
+		return decodeStruct(values, fv.Elem(), prefix, decodeErr)
+	}
+
+	nested := reflect.New(fv.Type().Elem())
+	nestedErr := &DecodeError{Invalid: map[string]error{}}
+	set := decodeStruct(values, nested.Elem(), prefix, nestedErr)
+	if set == 0 {
+		return 0
+	}
+	decodeErr.Missing = append(decodeErr.Missing, nestedErr.Missing...)
+	maps.Copy(decodeErr.Invalid, nestedErr.Invalid)
+	fv.Set(nested)
+	return set
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func setField(fv reflect.Value, raw string) error {
+	if fv.CanAddr() {
+		if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(raw))
+		}
+	}
+
+	if fv.Type() == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Pointer:
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setField(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		// []byte holds the raw value
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			fv.SetBytes([]byte(raw))
+			return nil
+		}
+		slice := reflect.MakeSlice(fv.Type(), 0, 0)
+		if raw != "" {
+			for _, part := range strings.Split(raw, ",") {
+				elem := reflect.New(fv.Type().Elem()).Elem()
+				if err := setField(elem, strings.TrimSpace(part)); err != nil {
+					return err
+				}
+				slice = reflect.Append(slice, elem)
+			}
+		}
+		fv.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package secretly
+
+import (
+	"errors"
+	"maps"
+	"net/netip"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+type decodeRedis struct {
+	Addr string `secretly:"ADDR,required"`
+	DB   int    `secretly:"DB" default:"0"`
+}
+
+type decodeConfig struct {
+	String   string        `secretly:"STRING"`
+	Bool     bool          `secretly:"BOOL"`
+	Int      int           `secretly:"INT"`
+	Int8     int8          `secretly:"INT8"`
+	Uint     uint          `secretly:"UINT"`
+	Float    float64       `secretly:"FLOAT"`
+	Duration time.Duration `secretly:"DURATION"`
+	Strings  []string      `secretly:"STRINGS"`
+	Ints     []int         `secretly:"INTS"`
+	Bytes    []byte        `secretly:"BYTES"`
+	Pointer  *int          `secretly:"POINTER"`
+	Text     netip.Addr    `secretly:"TEXT"`
+	Time     *time.Time    `secretly:"TIME"`
+	Default  string        `secretly:"DEFAULT" default:"fallback"`
+	Redis    decodeRedis   `secretly:"REDIS"`
+	Cache    *decodeRedis  `secretly:"CACHE"`
+	Ignored  string        `secretly:"-"`
+	Untagged string
+	Map      map[string]string `secretly:"MAP"`
+	private  string            `secretly:"PRIVATE"`
+}
+
+func TestDecodeValues(t *testing.T) {
+	one := 1
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	base := map[string]string{"REDIS_ADDR": "redis:6379"}
+
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   func(c *decodeConfig)
+	}{
+		{"string", map[string]string{"STRING": "hello"}, func(c *decodeConfig) { c.String = "hello" }},
+		{"bool", map[string]string{"BOOL": "true"}, func(c *decodeConfig) { c.Bool = true }},
+		{"int", map[string]string{"INT": "-42"}, func(c *decodeConfig) { c.Int = -42 }},
+		{"int8", map[string]string{"INT8": "127"}, func(c *decodeConfig) { c.Int8 = 127 }},
+		{"uint", map[string]string{"UINT": "42"}, func(c *decodeConfig) { c.Uint = 42 }},
+		{"float", map[string]string{"FLOAT": "1.5"}, func(c *decodeConfig) { c.Float = 1.5 }},
+		{"duration", map[string]string{"DURATION": "5s"}, func(c *decodeConfig) { c.Duration = 5 * time.Second }},
+		{"strings", map[string]string{"STRINGS": "a, b,c"}, func(c *decodeConfig) { c.Strings = []string{"a", "b", "c"} }},
+		{"empty slice", map[string]string{"STRINGS": ""}, func(c *decodeConfig) { c.Strings = []string{} }},
+		{"ints", map[string]string{"INTS": "1,2"}, func(c *decodeConfig) { c.Ints = []int{1, 2} }},
+		{"bytes", map[string]string{"BYTES": "raw,value"}, func(c *decodeConfig) { c.Bytes = []byte("raw,value") }},
+		{"pointer", map[string]string{"POINTER": "1"}, func(c *decodeConfig) { c.Pointer = &one }},
+		{"text unmarshaler", map[string]string{"TEXT": "10.0.0.1"}, func(c *decodeConfig) { c.Text = netip.MustParseAddr("10.0.0.1") }},
+		{"pointer to a text unmarshaler", map[string]string{"TIME": at.Format(time.RFC3339)}, func(c *decodeConfig) { c.Time = &at }},
+		{"default overridden", map[string]string{"DEFAULT": "set"}, func(c *decodeConfig) { c.Default = "set" }},
+		{"nested", map[string]string{"REDIS_DB": "2"}, func(c *decodeConfig) { c.Redis.DB = 2 }},
+		{"nested pointer", map[string]string{"CACHE_ADDR": "cache:6379"}, func(c *decodeConfig) { c.Cache = &decodeRedis{Addr: "cache:6379"} }},
+		{"ignored fields", map[string]string{"-": "x", "Untagged": "x", "PRIVATE": "x"}, func(c *decodeConfig) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string]string{}
+			for k, v := range base {
+				values[k] = v
+			}
+			for k, v := range tt.values {
+				values[k] = v
+			}
+
+			want := decodeConfig{Default: "fallback", Redis: decodeRedis{Addr: "redis:6379"}}
+			tt.want(&want)
+			var got decodeConfig
+			if err := DecodeValues(values, &got); err != nil {
+				t.Fatalf("DecodeValues: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeValuesErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      map[string]string
+		wantMissing []string
+		wantInvalid []string
+	}{
+		{
+			name:        "missing required",
+			values:      map[string]string{},
+			wantMissing: []string{"REDIS_ADDR"},
+		},
+		{
+			name:        "missing required of a set nested pointer",
+			values:      map[string]string{"REDIS_ADDR": "redis", "CACHE_DB": "1"},
+			wantMissing: []string{"CACHE_ADDR"},
+		},
+		{
+			name: "parse errors",
+			values: map[string]string{
+				"REDIS_ADDR": "redis",
+				"BOOL":       "maybe",
+				"INT":        "forty",
+				"INT8":       "128",
+				"UINT":       "-1",
+				"FLOAT":      "x",
+				"DURATION":   "5",
+				"INTS":       "1,x",
+				"POINTER":    "x",
+				"TEXT":       "not an ip",
+				"CACHE_DB":   "x",
+			},
+			wantMissing: []string{"CACHE_ADDR"},
+			wantInvalid: []string{"BOOL", "CACHE_DB", "DURATION", "FLOAT", "INT", "INT8", "INTS", "POINTER", "TEXT", "UINT"},
+		},
+		{
+			name:        "unsupported type",
+			values:      map[string]string{"REDIS_ADDR": "redis", "MAP": "a=b"},
+			wantInvalid: []string{"MAP"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c decodeConfig
+			err := DecodeValues(tt.values, &c)
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("got %v, want a *DecodeError", err)
+			}
+			if !slices.Equal(decodeErr.Missing, tt.wantMissing) {
+				t.Errorf("missing %q, want %q", decodeErr.Missing, tt.wantMissing)
+			}
+			invalid := slices.Sorted(maps.Keys(decodeErr.Invalid))
+			if !slices.Equal(invalid, tt.wantInvalid) {
+				t.Errorf("invalid %q, want %q", invalid, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestDecodeValuesDestination(t *testing.T) {
+	var c decodeConfig
+	var nilConfig *decodeConfig
+	for name, dst := range map[string]any{
+		"struct":     c,
+		"nil":        nilConfig,
+		"not struct": new(int),
+		"untyped":    nil,
+	} {
+		if err := DecodeValues(map[string]string{}, dst); err == nil {
+			t.Errorf("%s: DecodeValues succeeded", name)
+		}
+	}
+}
+
+func TestDecodeValuesNestedPointer(t *testing.T) {
+	// A nil pointer stays nil when none of its keys are set
+	var c decodeConfig
+	if err := DecodeValues(map[string]string{"REDIS_ADDR": "redis"}, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Cache != nil {
+		t.Errorf("Cache = %+v, want nil", c.Cache)
+	}
+
+	// A pointer already set is decoded into
+	cache := &decodeRedis{Addr: "old", DB: 3}
+	c = decodeConfig{Cache: cache}
+	values := map[string]string{"REDIS_ADDR": "redis", "CACHE_ADDR": "cache", "CACHE_DB": "4"}
+	if err := DecodeValues(values, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Cache != cache || *cache != (decodeRedis{Addr: "cache", DB: 4}) {
+		t.Errorf("Cache = %+v", c.Cache)
+	}
+}
@@ -0,0 +1,126 @@
+package secretly
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rodrwan/secretly/internal/e2e"
+)
+
+// Identity is an X25519 private key, it decrypts the values of end-to-end
+// encrypted environments. Keep it secret, ie: in a file only the service
+// reads.
+type Identity = e2e.Identity
+
+// Recipient is the public key of an Identity, values are encrypted for the
+// recipients of a team so any of its identities decrypts them
+type Recipient = e2e.Recipient
+
+// GenerateIdentity creates a random identity, share its Recipient with the
+// team and keep the identity secret
+func GenerateIdentity() (*Identity, error) {
+	return e2e.GenerateIdentity()
+}
+
+// ParseIdentity decodes an identity, SECRETLY-E2E-KEY-<base64>
+func ParseIdentity(s string) (*Identity, error) {
+	return e2e.ParseIdentity(s)
+}
+
+// ParseRecipient decodes a recipient, secretly-e2e-pub-<base64>
+func ParseRecipient(s string) (*Recipient, error) {
+	return e2e.ParseRecipient(s)
+}
+
+// WithE2E encrypts the values written to end-to-end encrypted environments
+// for recipients and identity's own recipient, and decrypts the values read
+// from them with identity. The server only sees their ciphertext.
+func WithE2E(identity *Identity, recipients ...*Recipient) ClientOption {
+	return func(c *Client) {
+		c.identity = identity
+		c.recipients = append([]*Recipient{identity.Recipient()}, recipients...)
+	}
+}
+
+// RequireE2E refuses to read or write the environments named unless the
+// server reports them end-to-end encrypted, so a server that stops
+// reporting it never gets their values in plaintext. The environments the
+// client creates or sees end-to-end encrypted are required too.
+func RequireE2E(environmentNames ...string) ClientOption {
+	return func(c *Client) {
+		c.requireE2E(environmentNames...)
+	}
+}
+
+// ErrNotE2E is returned when an environment that must be end-to-end
+// encrypted isn't reported so by the server
+var ErrNotE2E = errors.New("the environment must be end-to-end encrypted but the server reports it isn't")
+
+func (c *Client) requireE2E(environmentNames ...string) {
+	c.e2eMu.Lock()
+	defer c.e2eMu.Unlock()
+	if c.e2eEnvironments == nil {
+		c.e2eEnvironments = make(map[string]bool)
+	}
+	for _, name := range environmentNames {
+		c.e2eEnvironments[name] = true
+	}
+}
+
+// checkE2E fails when env must be end-to-end encrypted and isn't, and
+// remembers it must be when it is
+func (c *Client) checkE2E(env EnvironmentResponse) error {
+	if env.E2E {
+		c.requireE2E(env.Name)
+		return nil
+	}
+	c.e2eMu.Lock()
+	defer c.e2eMu.Unlock()
+	if c.e2eEnvironments[env.Name] {
+		return fmt.Errorf("environment %s: %w", env.Name, ErrNotE2E)
+	}
+	return nil
+}
+
+// errNoIdentity is returned when the values of an end-to-end encrypted
+// environment are read or written by a client without an identity
+func errNoIdentity(environmentName string) error {
+	return fmt.Errorf("environment %s is end-to-end encrypted, create the client WithE2E", environmentName)
+}
+
+// encryptValue encrypts value for the recipients of the client when env is
+// end-to-end encrypted
+func (c *Client) encryptValue(env EnvironmentResponse, value string) (string, error) {
+	if err := c.checkE2E(env); err != nil {
+		return "", err
+	}
+	if !env.E2E {
+		return value, nil
+	}
+	if c.identity == nil {
+		return "", errNoIdentity(env.Name)
+	}
+	return e2e.Encrypt(value, c.recipients...)
+}
+
+// decryptEnvironment decrypts the values of an end-to-end encrypted
+// environment with the identity of the client
+func (c *Client) decryptEnvironment(env *EnvironmentResponse) error {
+	if err := c.checkE2E(*env); err != nil {
+		return err
+	}
+	if !env.E2E || len(env.Values) == 0 {
+		return nil
+	}
+	if c.identity == nil {
+		return errNoIdentity(env.Name)
+	}
+	for i, value := range env.Values {
+		decrypted, err := e2e.Decrypt(value.Value, c.identity)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s of %s: %w", value.Key, env.Name, err)
+		}
+		env.Values[i].Value = decrypted
+	}
+	return nil
+}
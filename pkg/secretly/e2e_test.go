@@ -0,0 +1,105 @@
+package secretly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// e2eServer serves a single environment, reported end-to-end encrypted while
+// e2e is set, and counts the values written to it
+type e2eServer struct {
+	e2e    atomic.Bool
+	values []EnvValuesResponse
+	writes atomic.Int32
+}
+
+func (s *e2eServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	env := EnvironmentResponse{ID: 1, Name: "payments", E2E: s.e2e.Load(), Values: s.values}
+	if r.Method != http.MethodGet {
+		s.writes.Add(1)
+		json.NewEncoder(w).Encode(writeResponse{Code: http.StatusOK, Data: env})
+		return
+	}
+	json.NewEncoder(w).Encode(GetEnvResponse{Code: http.StatusOK, Data: []EnvironmentResponse{env}})
+}
+
+func newE2EServer(t *testing.T, e2e bool) (*e2eServer, string) {
+	t.Helper()
+	s := &e2eServer{}
+	s.e2e.Store(e2e)
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return s, server.URL
+}
+
+func TestE2EFailsClosed(t *testing.T) {
+	ctx := context.Background()
+	identity, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("seen encrypted", func(t *testing.T) {
+		s, url := newE2EServer(t, true)
+		c := New(WithBaseURL(url), WithE2E(identity))
+		if err := c.CreateValue(ctx, "payments", "STRIPE_KEY", "sk_live"); err != nil {
+			t.Fatalf("CreateValue: %v", err)
+		}
+
+		// The server stops reporting the environment encrypted
+		s.e2e.Store(false)
+		if err := c.CreateValue(ctx, "payments", "OTHER_KEY", "sk_live"); !errors.Is(err, ErrNotE2E) {
+			t.Errorf("got %v, want ErrNotE2E", err)
+		}
+		if got := s.writes.Load(); got != 1 {
+			t.Errorf("the server got %d writes, want 1", got)
+		}
+	})
+
+	t.Run("required", func(t *testing.T) {
+		s, url := newE2EServer(t, false)
+		c := New(WithBaseURL(url), WithE2E(identity), RequireE2E("payments"))
+		if err := c.CreateValue(ctx, "payments", "STRIPE_KEY", "sk_live"); !errors.Is(err, ErrNotE2E) {
+			t.Errorf("CreateValue: got %v, want ErrNotE2E", err)
+		}
+		if _, err := c.getEnvironment(ctx, "payments"); !errors.Is(err, ErrNotE2E) {
+			t.Errorf("getEnvironment: got %v, want ErrNotE2E", err)
+		}
+		if err := c.LoadToEnvironment("payments"); !errors.Is(err, ErrNotE2E) {
+			t.Errorf("LoadToEnvironment: got %v, want ErrNotE2E", err)
+		}
+		if got := s.writes.Load(); got != 0 {
+			t.Errorf("the server got %d writes, want none", got)
+		}
+	})
+
+	t.Run("created encrypted", func(t *testing.T) {
+		s, url := newE2EServer(t, false)
+		c := New(WithBaseURL(url), WithE2E(identity))
+		if _, err := c.CreateEnvironment(ctx, "payments", CreateOptions{E2E: true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.CreateValue(ctx, "payments", "STRIPE_KEY", "sk_live"); !errors.Is(err, ErrNotE2E) {
+			t.Errorf("got %v, want ErrNotE2E", err)
+		}
+		if got := s.writes.Load(); got != 1 {
+			t.Errorf("the server got %d writes, want the creation only", got)
+		}
+	})
+
+	t.Run("plaintext environments", func(t *testing.T) {
+		s, url := newE2EServer(t, false)
+		c := New(WithBaseURL(url), WithE2E(identity))
+		if err := c.CreateValue(ctx, "payments", "PORT", "8080"); err != nil {
+			t.Errorf("CreateValue: %v", err)
+		}
+		if got := s.writes.Load(); got != 1 {
+			t.Errorf("the server got %d writes, want 1", got)
+		}
+	})
+}
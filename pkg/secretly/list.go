@@ -0,0 +1,121 @@
+package secretly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Orders of ListOptions.Sort
+const (
+	SortByName         = "name"
+	SortByNameDesc     = "-name"
+	SortByCreation     = "created_at"
+	SortByCreationDesc = "-created_at"
+)
+
+// ListOptions filters and orders the environments listed by Environments
+type ListOptions struct {
+	// Prefix keeps the environments whose name starts with it
+	Prefix string
+	// Sort is one of the SortBy orders, the server sorts by creation by default
+	Sort string
+	// Tag keeps the environments tagged with it
+	Tag string
+	// PageSize is the number of environments requested at once, the server's default when 0
+	PageSize int
+	// WithoutValues lists the environments without their values
+	WithoutValues bool
+}
+
+func (o ListOptions) params() url.Values {
+	params := url.Values{}
+	if o.Prefix != "" {
+		params.Set("prefix", o.Prefix)
+	}
+	if o.Sort != "" {
+		params.Set("sort", o.Sort)
+	}
+	if o.Tag != "" {
+		params.Set("tag", o.Tag)
+	}
+	if o.PageSize > 0 {
+		params.Set("limit", strconv.Itoa(o.PageSize))
+	}
+	if o.WithoutValues {
+		params.Set("fields", "id,name,expires_at,description,owner,tags,link")
+	}
+	return params
+}
+
+// Environments iterates over the environments of the client's project,
+// requesting the next page as the previous one is consumed. Iteration stops
+// after the first error.
+func (c *Client) Environments(ctx context.Context, opts ListOptions) iter.Seq2[EnvironmentResponse, error] {
+	return func(yield func(EnvironmentResponse, error) bool) {
+		cursor := ""
+		for {
+			page, err := c.environmentsPage(ctx, opts, cursor)
+			if err != nil {
+				yield(EnvironmentResponse{}, err)
+				return
+			}
+
+			for _, environment := range page.Data {
+				if !yield(environment, nil) {
+					return
+				}
+			}
+
+			if page.NextCursor == "" {
+				return
+			}
+			cursor = page.NextCursor
+		}
+	}
+}
+
+func (c *Client) environmentsPage(ctx context.Context, opts ListOptions, cursor string) (GetEnvResponse, error) {
+	params := opts.params()
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.envURL(params), nil)
+	if err != nil {
+		return GetEnvResponse{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return GetEnvResponse{}, fmt.Errorf("failed to get env: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return GetEnvResponse{}, fmt.Errorf("failed to get env: %s", resp.Status)
+	}
+
+	var page GetEnvResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return GetEnvResponse{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if page.Error != "" {
+		return GetEnvResponse{}, fmt.Errorf("failed to get env: %s", page.Error)
+	}
+	// Without an identity, the values of end-to-end encrypted environments
+	// are left encrypted
+	if c.identity != nil {
+		for i := range page.Data {
+			if err := c.decryptEnvironment(&page.Data[i]); err != nil {
+				return GetEnvResponse{}, err
+			}
+		}
+	}
+
+	return page, nil
+}
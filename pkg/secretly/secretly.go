@@ -1,10 +1,16 @@
 package secretly
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"slices"
+	"sync"
 	"time"
 )
 
@@ -17,6 +23,22 @@ const (
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
+	// Project the environments belong to, the server's default project when empty
+	Project string
+
+	// identity decrypts the values of end-to-end encrypted environments,
+	// which are encrypted for recipients
+	identity   *Identity
+	recipients []*Recipient
+	// e2eEnvironments are the environments that must be end-to-end
+	// encrypted, required by RequireE2E or seen encrypted by the client
+	e2eMu           sync.Mutex
+	e2eEnvironments map[string]bool
+
+	// rootCAs verify the certificate of the server, clientCert authenticates
+	// the client to it. They configure the transport of HTTPClient.
+	rootCAs    *x509.CertPool
+	clientCert *tls.Certificate
 }
 
 // ClientOption is a function that configures a Client
@@ -39,6 +61,13 @@ func WithTimeout(timeout time.Duration) ClientOption {
 	}
 }
 
+// WithProject sets the project environments are looked up in
+func WithProject(project string) ClientOption {
+	return func(c *Client) {
+		c.Project = project
+	}
+}
+
 // WithHTTPClient sets a custom HTTP client
 func WithHTTPClient(client *http.Client) ClientOption {
 	return func(c *Client) {
@@ -58,6 +87,7 @@ func New(opts ...ClientOption) *Client {
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.configureTLS()
 
 	return c
 }
@@ -106,39 +136,70 @@ type GetEnvResponse struct {
 	Message string                `json:"message"`
 	Error   string                `json:"error"`
 	Data    []EnvironmentResponse `json:"data"`
+	// NextCursor requests the next page of environments, it is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type EnvironmentResponse struct {
-	ID     int                 `json:"id"`
-	Name   string              `json:"name"`
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// E2E is set when the values are encrypted by the clients, see WithE2E
+	E2E bool `json:"e2e,omitempty"`
+	Metadata
 	Values []EnvValuesResponse `json:"values"`
 }
 
 type EnvValuesResponse struct {
-	ID    int    `json:"id"`
-	Key   string `json:"key"`
-	Value string `json:"value"`
+	ID        int        `json:"id"`
+	Key       string     `json:"key"`
+	Value     string     `json:"value"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Type is the type declared by the key's schema, empty if it has none
+	Type string `json:"type,omitempty"`
+	Metadata
 }
 
-func (c *Client) getAllEnvironments() ([]EnvironmentResponse, error) {
-	url := fmt.Sprintf("%s/api/v1/env", c.BaseURL)
+// Metadata documents an environment or a value
+type Metadata struct {
+	Description string   `json:"description,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Link        string   `json:"link,omitempty"`
+}
 
-	resp, err := c.HTTPClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get env: %w", err)
+// HasTag reports whether the environment or value is tagged with tag
+func (m Metadata) HasTag(tag string) bool {
+	return slices.Contains(m.Tags, tag)
+}
+
+// envURL returns the url of the environments endpoint with the given query
+// params and the client's project
+func (c *Client) envURL(params url.Values) string {
+	if params == nil {
+		params = url.Values{}
+	}
+	if c.Project != "" {
+		params.Set("project", c.Project)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get env: %s", resp.Status)
+	endpoint := fmt.Sprintf("%s/api/v1/env", c.BaseURL)
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
 	}
+	return endpoint
+}
 
-	var environments GetEnvResponse
-	if err := json.NewDecoder(resp.Body).Decode(&environments); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+// getAllEnvironments gets the environments of every page
+func (c *Client) getAllEnvironments() ([]EnvironmentResponse, error) {
+	environments := make([]EnvironmentResponse, 0)
+	for environment, err := range c.Environments(context.Background(), ListOptions{}) {
+		if err != nil {
+			return nil, err
+		}
+		environments = append(environments, environment)
 	}
 
-	return environments.Data, nil
+	return environments, nil
 }
 
 /*
@@ -163,9 +224,7 @@ Example respose:
 ]
 */
 func (c *Client) getEnvironmentByName(environmentName string) ([]map[string]interface{}, error) {
-	url := fmt.Sprintf("%s/api/v1/env?name=%s", c.BaseURL, environmentName)
-
-	resp, err := c.HTTPClient.Get(url)
+	resp, err := c.HTTPClient.Get(c.envURL(url.Values{"name": {environmentName}}))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get env: %w", err)
 	}
@@ -183,6 +242,40 @@ func (c *Client) getEnvironmentByName(environmentName string) ([]map[string]inte
 	return environments, nil
 }
 
+// getEnvironment retrieves a single environment by name
+func (c *Client) getEnvironment(ctx context.Context, environmentName string) (EnvironmentResponse, error) {
+	endpoint := c.envURL(url.Values{"name": {environmentName}})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return EnvironmentResponse{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return EnvironmentResponse{}, fmt.Errorf("failed to get env: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return EnvironmentResponse{}, fmt.Errorf("failed to get env: %s", resp.Status)
+	}
+
+	var environments GetEnvResponse
+	if err := json.NewDecoder(resp.Body).Decode(&environments); err != nil {
+		return EnvironmentResponse{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if environments.Error != "" || len(environments.Data) == 0 {
+		return EnvironmentResponse{}, fmt.Errorf("environment %s not found", environmentName)
+	}
+
+	environment := environments.Data[0]
+	if err := c.decryptEnvironment(&environment); err != nil {
+		return EnvironmentResponse{}, err
+	}
+	return environment, nil
+}
+
 // LoadToEnvironment loads the retrieved variables into the current process environment
 func (c *Client) LoadToEnvironment(environmentName string) error {
 	environments, err := c.getAllEnvironments()
@@ -192,6 +285,12 @@ func (c *Client) LoadToEnvironment(environmentName string) error {
 
 	for _, environment := range environments {
 		if environment.Name == environmentName {
+			if err := c.checkE2E(environment); err != nil {
+				return err
+			}
+			if environment.E2E && c.identity == nil {
+				return errNoIdentity(environmentName)
+			}
 			for _, value := range environment.Values {
 				os.Setenv(value.Key, value.Value)
 			}
@@ -0,0 +1,65 @@
+package secretly
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+
+	"github.com/rodrwan/secretly/internal/certs"
+)
+
+// LoadCABundle reads a file of PEM certificates for WithRootCAs, ie: the
+// CA of a server with a self-signed certificate
+func LoadCABundle(file string) (*x509.CertPool, error) {
+	return certs.LoadCertPool(file)
+}
+
+// WithRootCAs verifies the certificate of the server with pool instead of
+// the system's CAs
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(c *Client) {
+		c.rootCAs = pool
+	}
+}
+
+// WithClientCertificate authenticates the client to a server requiring
+// client certificates, load it with tls.LoadX509KeyPair. The server
+// identifies the client by its subject.
+func WithClientCertificate(cert tls.Certificate) ClientOption {
+	return func(c *Client) {
+		c.clientCert = &cert
+	}
+}
+
+// configureTLS sets the CAs and the client certificate of the options on the
+// transport of the HTTP client, a copy of http.DefaultTransport when it has
+// none. A custom transport that isn't an *http.Transport is left as is.
+func (c *Client) configureTLS() {
+	if c.rootCAs == nil && c.clientCert == nil {
+		return
+	}
+
+	var transport *http.Transport
+	switch t := c.HTTPClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if c.rootCAs != nil {
+		transport.TLSClientConfig.RootCAs = c.rootCAs
+	}
+	if c.clientCert != nil {
+		transport.TLSClientConfig.Certificates = []tls.Certificate{*c.clientCert}
+	}
+
+	// Don't change the client given to WithHTTPClient, it may be shared
+	client := *c.HTTPClient
+	client.Transport = transport
+	c.HTTPClient = &client
+}
@@ -0,0 +1,58 @@
+package secretly
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// String returns the raw value
+func (v EnvValuesResponse) String() string {
+	return v.Value
+}
+
+// Int parses the value as a base 10 integer
+func (v EnvValuesResponse) Int() (int64, error) {
+	i, err := strconv.ParseInt(v.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not an int: %w", v.Key, err)
+	}
+	return i, nil
+}
+
+// Bool parses the value as a boolean, ie: true, false, 1, 0
+func (v EnvValuesResponse) Bool() (bool, error) {
+	b, err := strconv.ParseBool(v.Value)
+	if err != nil {
+		return false, fmt.Errorf("%s is not a bool: %w", v.Key, err)
+	}
+	return b, nil
+}
+
+// Duration parses the value as a duration, ie: 30s, 1h
+func (v EnvValuesResponse) Duration() (time.Duration, error) {
+	d, err := time.ParseDuration(v.Value)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a duration: %w", v.Key, err)
+	}
+	return d, nil
+}
+
+// URL parses the value as a url
+func (v EnvValuesResponse) URL() (*url.URL, error) {
+	u, err := url.Parse(v.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a url: %w", v.Key, err)
+	}
+	return u, nil
+}
+
+// JSON decodes the value into dst
+func (v EnvValuesResponse) JSON(dst any) error {
+	if err := json.Unmarshal([]byte(v.Value), dst); err != nil {
+		return fmt.Errorf("%s is not valid json: %w", v.Key, err)
+	}
+	return nil
+}
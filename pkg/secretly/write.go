@@ -0,0 +1,124 @@
+package secretly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// CreateOptions configures the environment created by CreateEnvironment
+type CreateOptions struct {
+	// E2E creates an end-to-end encrypted environment, its values are
+	// encrypted by the client, see WithE2E
+	E2E bool
+}
+
+type writeValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type writeResponse struct {
+	Code    int                 `json:"code"`
+	Message string              `json:"message"`
+	Error   string              `json:"error"`
+	Data    EnvironmentResponse `json:"data"`
+}
+
+// CreateEnvironment creates an environment without values in the client's
+// project
+func (c *Client) CreateEnvironment(ctx context.Context, name string, opts CreateOptions) (EnvironmentResponse, error) {
+	body := struct {
+		Name   string       `json:"name"`
+		Values []writeValue `json:"values"`
+		E2E    bool         `json:"e2e,omitempty"`
+	}{Name: name, Values: []writeValue{}, E2E: opts.E2E}
+
+	resp, err := c.write(ctx, http.MethodPost, c.envURL(nil), body)
+	if err != nil {
+		return EnvironmentResponse{}, fmt.Errorf("failed to create env: %w", err)
+	}
+	if opts.E2E {
+		c.requireE2E(name)
+	}
+	return resp.Data, nil
+}
+
+// CreateValue sets key in an environment where it isn't set yet. The value is
+// encrypted when the environment is end-to-end encrypted.
+func (c *Client) CreateValue(ctx context.Context, environmentName, key, value string) error {
+	return c.setValue(ctx, environmentName, key, value, false)
+}
+
+// UpdateValue changes the value of a key set in an environment. The value is
+// encrypted when the environment is end-to-end encrypted.
+func (c *Client) UpdateValue(ctx context.Context, environmentName, key, value string) error {
+	return c.setValue(ctx, environmentName, key, value, true)
+}
+
+func (c *Client) setValue(ctx context.Context, environmentName, key, value string, exists bool) error {
+	env, err := c.getEnvironment(ctx, environmentName)
+	if err != nil {
+		return err
+	}
+
+	set := slices.ContainsFunc(env.Values, func(v EnvValuesResponse) bool { return v.Key == key })
+	switch {
+	case exists && !set:
+		return fmt.Errorf("%s is not set in %s", key, environmentName)
+	case !exists && set:
+		return fmt.Errorf("%s is already set in %s", key, environmentName)
+	}
+
+	value, err = c.encryptValue(env, value)
+	if err != nil {
+		return err
+	}
+
+	body := struct {
+		Values []writeValue `json:"values"`
+	}{Values: []writeValue{{Key: key, Value: value}}}
+
+	endpoint := fmt.Sprintf("%s/api/v1/env/%d", c.BaseURL, env.ID)
+	if _, err := c.write(ctx, http.MethodPut, endpoint, body); err != nil {
+		return fmt.Errorf("failed to set %s: %w", key, err)
+	}
+	return nil
+}
+
+// write sends body as json and decodes the response
+func (c *Client) write(ctx context.Context, method, endpoint string, body any) (writeResponse, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return writeResponse{}, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return writeResponse{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return writeResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return writeResponse{}, errors.New(resp.Status)
+	}
+
+	var response writeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return writeResponse{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.Error != "" {
+		return writeResponse{}, errors.New(response.Error)
+	}
+	return response, nil
+}